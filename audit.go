@@ -0,0 +1,72 @@
+package shelf
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock returns the current time, which is assigned to the fields marked as 'shelf:created-date' and
+// 'shelf:last-modified-date' when the entities are saved.
+type Clock func() time.Time
+
+// AuditorAware returns the current principal of the context, which is assigned to the fields marked as
+// 'shelf:created-by' and 'shelf:last-modified-by' when the entities are saved. The fields are left as they
+// are if it returns nil or a value whose type is not the type of the fields.
+type AuditorAware func(ctx context.Context) interface{}
+
+type auditorContextKey struct{}
+
+var (
+	auditMu      sync.RWMutex
+	clock        Clock        = time.Now
+	auditorAware AuditorAware = auditorFromContext
+)
+
+// SetClock replaces the clock of the audit dates, which defaults to time.Now.
+func SetClock(c Clock) {
+	if c == nil {
+		c = time.Now
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	clock = c
+}
+
+// SetAuditorAware replaces the function returning the current principal, which defaults to returning
+// the auditor passed to WithAuditor.
+func SetAuditorAware(aware AuditorAware) {
+	if aware == nil {
+		aware = auditorFromContext
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditorAware = aware
+}
+
+// Now returns the current time of the clock.
+func Now() time.Time {
+	auditMu.RLock()
+	c := clock
+	auditMu.RUnlock()
+	return c()
+}
+
+// CurrentAuditor returns the current principal of the context.
+func CurrentAuditor(ctx context.Context) interface{} {
+	auditMu.RLock()
+	aware := auditorAware
+	auditMu.RUnlock()
+	return aware(ctx)
+}
+
+// WithAuditor returns a copy of the context carrying the auditor, which is returned by the default AuditorAware.
+func WithAuditor(ctx context.Context, auditor interface{}) context.Context {
+	return context.WithValue(ctx, auditorContextKey{}, auditor)
+}
+
+func auditorFromContext(ctx context.Context) interface{} {
+	return ctx.Value(auditorContextKey{})
+}
@@ -0,0 +1,41 @@
+package shelf
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCurrentAuditor(t *testing.T) {
+	ctx := WithAuditor(context.Background(), "jane")
+
+	if auditor := CurrentAuditor(ctx); auditor != "jane" {
+		t.Errorf("auditor of the context should be jane, but got %v", auditor)
+	}
+
+	if auditor := CurrentAuditor(context.Background()); auditor != nil {
+		t.Errorf("auditor should be nil without WithAuditor, but got %v", auditor)
+	}
+
+	SetAuditorAware(func(ctx context.Context) interface{} {
+		return int64(7)
+	})
+	defer SetAuditorAware(nil)
+
+	if auditor := CurrentAuditor(ctx); auditor != int64(7) {
+		t.Errorf("auditor should be returned by the auditor aware function, but got %v", auditor)
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	fixed := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	SetClock(func() time.Time {
+		return fixed
+	})
+	defer SetClock(nil)
+
+	if now := Now(); !now.Equal(fixed) {
+		t.Errorf("now should be the time of the clock, but got %v", now)
+	}
+}
@@ -0,0 +1,50 @@
+package shelf
+
+import "fmt"
+
+// BatchError is returned when a chunk of a batch operation fails. The rows of the chunk are the
+// ones between Start, inclusive, and End, exclusive, in the order they are passed to the operation.
+type BatchError struct {
+	// Chunk is the index of the failing chunk starting from 0.
+	Chunk int
+	Start int
+	End   int
+	// Err is the error returned by the statement of the chunk.
+	Err error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("shelf: chunk %d of the batch, rows from %d to %d, failed: %v", e.Chunk, e.Start, e.End, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batch calls the function with the bounds of the consecutive chunks of the rows, each of which has
+// at most the given number of rows. It stops at the first failing chunk and returns a BatchError
+// identifying it.
+func Batch(count int, size int, fn func(start, end int) error) error {
+	if size <= 0 {
+		size = count
+	}
+
+	for chunk, start := 0, 0; start < count; chunk, start = chunk+1, start+size {
+		end := start + size
+
+		if end > count {
+			end = count
+		}
+
+		if err := fn(start, end); err != nil {
+			return &BatchError{
+				Chunk: chunk,
+				Start: start,
+				End:   end,
+				Err:   err,
+			}
+		}
+	}
+
+	return nil
+}
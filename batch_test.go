@@ -0,0 +1,46 @@
+package shelf
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	chunks := make([][2]int, 0)
+	errChunk := errors.New("chunk error")
+
+	err := Batch(7, 3, func(start, end int) error {
+		chunks = append(chunks, [2]int{start, end})
+
+		if start == 3 {
+			return errChunk
+		}
+
+		return nil
+	})
+
+	if expected := [][2]int{{0, 3}, {3, 6}}; !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("chunks should be %v, but got %v", expected, chunks)
+	}
+
+	var batchErr *BatchError
+
+	if !errors.As(err, &batchErr) || batchErr.Chunk != 1 || batchErr.Start != 3 || batchErr.End != 6 {
+		t.Fatalf("the error should identify the second chunk, but got %v", err)
+	}
+
+	if !errors.Is(err, errChunk) {
+		t.Errorf("the error should wrap the error of the chunk, but got %v", err)
+	}
+
+	chunks = chunks[:0]
+	err = Batch(7, 3, func(start, end int) error {
+		chunks = append(chunks, [2]int{start, end})
+		return nil
+	})
+
+	if expected := [][2]int{{0, 3}, {3, 6}, {6, 7}}; err != nil || !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("chunks should be %v, but got %v and %v", expected, chunks, err)
+	}
+}
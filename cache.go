@@ -0,0 +1,37 @@
+package shelf
+
+import (
+	"context"
+	"sync"
+)
+
+// CacheClearer removes the cached instances of an entity, e.g. from a session or a second-level cache.
+type CacheClearer func(ctx context.Context, entityName string)
+
+var (
+	cacheClearersMu sync.RWMutex
+	cacheClearers   []CacheClearer
+)
+
+// RegisterCacheClearer adds a clearer which is called after the modifying queries marked as
+// 'ClearAutomatically', since the cached instances of the entity may no longer match the rows.
+func RegisterCacheClearer(clearer CacheClearer) {
+	if clearer == nil {
+		return
+	}
+
+	cacheClearersMu.Lock()
+	defer cacheClearersMu.Unlock()
+	cacheClearers = append(cacheClearers, clearer)
+}
+
+// ClearCache calls the registered clearers for the entity.
+func ClearCache(ctx context.Context, entityName string) {
+	cacheClearersMu.RLock()
+	clearers := cacheClearers
+	cacheClearersMu.RUnlock()
+
+	for _, clearer := range clearers {
+		clearer(ctx, entityName)
+	}
+}
@@ -0,0 +1,25 @@
+package shelf
+
+// Cascade tracks the entities visited by a cascading save or delete operation. The associations of
+// the entities may refer to each other, so the operation is applied once to each of them.
+type Cascade struct {
+	visited map[interface{}]struct{}
+}
+
+// NewCascade returns the tracker of a new cascading operation.
+func NewCascade() *Cascade {
+	return &Cascade{
+		visited: make(map[interface{}]struct{}),
+	}
+}
+
+// Visit reports whether the entity is visited for the first time by the operation. The entities
+// are identified by their pointers.
+func (c *Cascade) Visit(entity interface{}) bool {
+	if _, ok := c.visited[entity]; ok {
+		return false
+	}
+
+	c.visited[entity] = struct{}{}
+	return true
+}
@@ -0,0 +1,25 @@
+package shelf
+
+import "testing"
+
+type cascadeEntity struct {
+	Id int
+}
+
+func TestCascadeVisit(t *testing.T) {
+	cascade := NewCascade()
+	first := &cascadeEntity{Id: 1}
+	second := &cascadeEntity{Id: 1}
+
+	if !cascade.Visit(first) {
+		t.Errorf("the entity should be visited for the first time")
+	}
+
+	if cascade.Visit(first) {
+		t.Errorf("the entity should not be visited twice")
+	}
+
+	if !cascade.Visit(second) {
+		t.Errorf("the entities should be identified by their pointers")
+	}
+}
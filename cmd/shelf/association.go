@@ -0,0 +1,636 @@
+package main
+
+import (
+	"fmt"
+	"github.com/procyon-projects/marker"
+	"github.com/procyon-projects/shelf"
+	"sort"
+	"strings"
+)
+
+const (
+	FetchTypeEager = "EAGER"
+	FetchTypeLazy  = "LAZY"
+)
+
+const (
+	CascadeAll        = "ALL"
+	CascadePersist    = "PERSIST"
+	CascadeSaveUpdate = "SAVE_UPDATE"
+	CascadeRemove     = "REMOVE"
+)
+
+// inverseKinds are the kinds of the owning sides of the relationships mapped by an association of a given kind.
+var inverseKinds = map[shelf.AssociationKind]shelf.AssociationKind{
+	shelf.OneToOne:   shelf.OneToOne,
+	shelf.OneToMany:  shelf.ManyToOne,
+	shelf.ManyToMany: shelf.ManyToMany,
+}
+
+// AssociationMetadata describes a field of an entity marked as one of the relationship markers.
+type AssociationMetadata struct {
+	FieldName string
+	Field     marker.Field
+	Kind      shelf.AssociationKind
+	// Target is the full struct name of the associated entity, which is the key of entityMetadataByStructName.
+	Target string
+	// MappedBy is the field of the target entity owning the relationship, or empty if the entity owns it.
+	MappedBy string
+	// Eager reports whether the association is loaded with the entity. The single-valued associations are
+	// eagerly fetched and the collections are lazily loaded unless the FetchType is given.
+	Eager bool
+	// IsCollection reports whether the field is a slice of the associated entities, and IsPointer reports
+	// whether the field or the elements of the slice are pointers to them.
+	IsCollection bool
+	IsPointer    bool
+	// JoinColumn is the foreign key of the relationship. It is a column of the entity table if the entity owns
+	// a single-valued association, a column of the target table for the other one-to-one and one-to-many
+	// associations, and the column of the join table referring to the entity for many-to-many associations.
+	JoinColumn string
+	// JoinTable and InverseJoinColumn are only used by many-to-many associations, the inverse join column
+	// refers to the target entity.
+	JoinTable         string
+	InverseJoinColumn string
+	// ReferencedField is the field mapped to the column referred by the join column, which is a field of the
+	// target entity if the entity owns the join column, and a field of the entity otherwise.
+	ReferencedField FieldMetadata
+	// ForeignKeyName, Nullable and Unique are the options of the join column given by shelf:join-column,
+	// which are only described by the metamodel.
+	ForeignKeyName string
+	Nullable       bool
+	Unique         bool
+	// CascadeSave reports whether the targets are saved with the entity, and CascadePersist whether only the
+	// new ones are. CascadeRemove reports whether the targets are deleted with the entity.
+	CascadeSave    bool
+	CascadePersist bool
+	CascadeRemove  bool
+	// OrphanRemoval reports whether the targets removed from a one-to-many association are deleted when the
+	// entity is saved. They are also deleted with the entity.
+	OrphanRemoval bool
+	// MapsId is the id field whose column is the join column, given by shelf:maps-id. The id is derived from
+	// the target when the entity is saved, and the reference to the target is built from the id when it is scanned.
+	MapsId string
+}
+
+// OwnsJoinColumn reports whether the join column of the association is a column of the entity table,
+// which is written when the entity is saved and scanned into a reference to the target entity.
+func (association AssociationMetadata) OwnsJoinColumn() bool {
+	return association.MappedBy == "" && (association.Kind == shelf.ManyToOne || association.Kind == shelf.OneToOne)
+}
+
+// ResolveAssociations resolves the targets and the join columns of the entity associations, which needs all
+// the entities to be found first. The associations mapped by another field use the join columns of that field,
+// so they are resolved after the owning sides of all the relationships. The relationships are validated across
+// the entities while they are resolved.
+func ResolveAssociations() {
+	structNames := make([]string, 0)
+
+	for structName := range entityMetadataByStructName {
+		structNames = append(structNames, structName)
+	}
+
+	sort.Strings(structNames)
+
+	for _, structName := range structNames {
+		entity := entityMetadataByStructName[structName]
+		entity.Associations = FindAssociations(entity)
+		entityMetadataByStructName[structName] = entity
+	}
+
+	for _, structName := range structNames {
+		entity := entityMetadataByStructName[structName]
+
+		for index, association := range entity.Associations {
+			var err error
+
+			if association.MappedBy == "" {
+				err = validateOwner(structName, association)
+			} else {
+				err = resolveMappedBy(entity, &entity.Associations[index])
+			}
+
+			if err != nil {
+				errs = append(errs, marker.NewError(err, entity.StructType.File.FullPath, association.Field.Position))
+			}
+		}
+
+		entityMetadataByStructName[structName] = entity
+	}
+
+	validateMappedBy(structNames)
+}
+
+// validateOwner checks that a one-to-one relationship between two entities is not owned by both of them, as
+// each side would have its own join column.
+func validateOwner(structName string, association AssociationMetadata) error {
+	if association.Kind != shelf.OneToOne || association.Target == structName {
+		return nil
+	}
+
+	target := entityMetadataByStructName[association.Target]
+
+	for _, inverse := range target.Associations {
+		if inverse.Kind == shelf.OneToOne && inverse.Target == structName && inverse.MappedBy == "" {
+			return fmt.Errorf("the one-to-one relationship between the fields '%s' and '%s.%s' is owned by both sides, "+
+				"one of them must be mapped by the other one", association.FieldName, target.EntityName, inverse.FieldName)
+		}
+	}
+
+	return nil
+}
+
+// validateMappedBy checks that an owning side of a relationship is mapped by a single inverse side.
+func validateMappedBy(structNames []string) {
+	mappedBy := make(map[string]string)
+
+	for _, structName := range structNames {
+		entity := entityMetadataByStructName[structName]
+
+		for _, association := range entity.Associations {
+			if association.MappedBy == "" || !refersTo(association, structName) {
+				continue
+			}
+
+			owner := association.Target + "." + association.MappedBy
+			inverse := entity.EntityName + "." + association.FieldName
+
+			if previous, ok := mappedBy[owner]; ok {
+				err := fmt.Errorf("the field '%s' of the entity '%s' is already mapped by '%s'",
+					association.MappedBy, entityMetadataByStructName[association.Target].EntityName, previous)
+				errs = append(errs, marker.NewError(err, entity.StructType.File.FullPath, association.Field.Position))
+				continue
+			}
+
+			mappedBy[owner] = inverse
+		}
+	}
+}
+
+// refersTo reports whether the owning side of the relationship refers to the entity, the invalid mappings
+// are already reported by resolveMappedBy.
+func refersTo(association AssociationMetadata, structName string) bool {
+	for _, owner := range entityMetadataByStructName[association.Target].Associations {
+		if owner.FieldName == association.MappedBy {
+			return owner.Target == structName
+		}
+	}
+
+	return false
+}
+
+// FindAssociations returns the associations of the entity, whose join columns are resolved if the entity
+// owns them.
+func FindAssociations(entity EntityMetadata) []AssociationMetadata {
+	associations := make([]AssociationMetadata, 0)
+	file := entity.StructType.File
+
+	for _, field := range entity.StructType.Fields {
+		association, ok, err := findAssociation(entity, field)
+
+		if err == nil && ok && association.OwnsJoinColumn() && association.MapsId == "" {
+			for _, entityField := range entity.Fields {
+				if entityField.ColumnName == association.JoinColumn {
+					err = fmt.Errorf("the join column '%s' of the field '%s' is already mapped to the field '%s'",
+						association.JoinColumn, field.Name, entityField.FieldName)
+				}
+			}
+		}
+
+		if err != nil {
+			errs = append(errs, marker.NewError(err, file.FullPath, field.Position))
+			continue
+		}
+
+		if ok {
+			associations = append(associations, association)
+		}
+	}
+
+	return associations
+}
+
+func findAssociation(entity EntityMetadata, field marker.Field) (AssociationMetadata, bool, error) {
+	association := AssociationMetadata{
+		FieldName: field.Name,
+		Field:     field,
+	}
+
+	markerCount := 0
+
+	for _, associationKind := range associationKinds {
+		markers, ok := field.Markers[associationKind.Marker]
+
+		if !ok {
+			continue
+		}
+
+		association.Kind = associationKind.Kind
+		markerCount++
+
+		for _, candidateMarker := range markers {
+			association.MappedBy = getMappedBy(candidateMarker)
+			association.Eager = isEagerAssociation(associationKind.Kind, getFetchType(candidateMarker))
+			applyCascade(&association, candidateMarker)
+		}
+	}
+
+	_, hasJoinColumn := field.Markers[shelf.MarkerJoinColumn]
+	_, hasJoinTable := field.Markers[shelf.MarkerJoinTable]
+	_, hasMapsId := field.Markers[shelf.MarkerMapsId]
+
+	if markerCount == 0 {
+		if hasJoinColumn || hasJoinTable || hasMapsId {
+			return association, false, fmt.Errorf("'%s', '%s' and '%s' markers can only be used with the relationship markers",
+				shelf.MarkerJoinColumn, shelf.MarkerJoinTable, shelf.MarkerMapsId)
+		}
+
+		return association, false, nil
+	}
+
+	if markerCount > 1 {
+		return association, false, fmt.Errorf("the field '%s' cannot be marked as more than one relationship marker", field.Name)
+	}
+
+	if association.Kind == shelf.ManyToOne && association.MappedBy != "" {
+		return association, false, fmt.Errorf("the field '%s' marked as '%s' owns the relationship, it cannot be mapped by another field",
+			field.Name, shelf.MarkerManyToOne)
+	}
+
+	typ := field.Type
+
+	if arrayType, ok := typ.(*marker.ArrayType); ok {
+		association.IsCollection = true
+		typ = arrayType.ItemType
+	}
+
+	if pointerType, ok := typ.(*marker.PointerType); ok {
+		association.IsPointer = true
+		typ = pointerType.Typ
+	}
+
+	isCollectionKind := association.Kind == shelf.OneToMany || association.Kind == shelf.ManyToMany
+
+	if association.IsCollection != isCollectionKind {
+		if isCollectionKind {
+			return association, false, fmt.Errorf("the type of the field '%s' must be a slice of the associated entity", field.Name)
+		}
+
+		return association, false, fmt.Errorf("the type of the field '%s' must be the associated entity or a pointer to it", field.Name)
+	}
+
+	qualifiedName := GetQualifiedNameFromType(entity.StructType.File, typ)
+	dotIndex := strings.LastIndex(qualifiedName, ".")
+	target, ok := EntityMetadata{}, false
+
+	if dotIndex != -1 {
+		target, ok = entityMetadataByStructName[qualifiedName[:dotIndex]+"#"+qualifiedName[dotIndex+1:]]
+	}
+
+	if !ok {
+		return association, false, fmt.Errorf("the type of the field '%s' must be an entity", field.Name)
+	}
+
+	association.Target = target.StructType.File.Package.Path + "#" + target.StructName
+
+	// the join columns are single columns referring to the id of the entity or of the target
+	if target.IsComposite() {
+		return association, false, fmt.Errorf("the field '%s' cannot refer to the entity '%s' with a composite key",
+			field.Name, target.EntityName)
+	}
+
+	if entity.IsComposite() && (association.MappedBy != "" || association.Kind == shelf.OneToMany || association.Kind == shelf.ManyToMany) {
+		return association, false, fmt.Errorf("the field '%s' of the entity '%s' with a composite key can only own "+
+			"a many-to-one or one-to-one association", field.Name, entity.EntityName)
+	}
+
+	if hasMapsId {
+		err := applyMapsId(entity, target, &association)
+		return association, err == nil, err
+	}
+
+	if hasJoinColumn && (association.MappedBy != "" || association.Kind == shelf.ManyToMany) {
+		return association, false, fmt.Errorf("'%s' marker can only be used on the owning side of a one-to-one, "+
+			"many-to-one or one-to-many association", shelf.MarkerJoinColumn)
+	}
+
+	if hasJoinTable && (association.MappedBy != "" || association.Kind != shelf.ManyToMany) {
+		return association, false, fmt.Errorf("'%s' marker can only be used on the owning side of a many-to-many association",
+			shelf.MarkerJoinTable)
+	}
+
+	if association.MappedBy != "" {
+		return association, true, nil
+	}
+
+	if association.Kind == shelf.ManyToMany {
+		err := applyJoinTable(entity, target, &association)
+		return association, err == nil, err
+	}
+
+	err := applyJoinColumn(entity, target, &association)
+	return association, err == nil, err
+}
+
+// applyJoinColumn resolves the join column of the association given by shelf:join-column, which refers to
+// the id of the target entity by default. The join column of a one-to-many association is a column of the
+// target table referring to the entity.
+func applyJoinColumn(entity EntityMetadata, target EntityMetadata, association *AssociationMetadata) error {
+	joinColumnMarker := shelf.JoinColumnMarker{}
+
+	for _, candidateMarker := range association.Field.Markers[shelf.MarkerJoinColumn] {
+		if typedMarker, ok := candidateMarker.(shelf.JoinColumnMarker); ok {
+			joinColumnMarker = typedMarker
+		}
+	}
+
+	referenced := target
+	prefix := association.FieldName
+
+	if association.Kind == shelf.OneToMany {
+		referenced = entity
+		prefix = entity.StructName
+	}
+
+	referencedColumn := strings.TrimSpace(joinColumnMarker.ReferencedColumn)
+
+	if referencedColumn == "" {
+		referencedColumn = referenced.IdField.ColumnName
+	}
+
+	referencedField, ok := findFieldByColumnName(referenced, referencedColumn)
+
+	if !ok {
+		return fmt.Errorf("the column '%s' referenced by the join column of the field '%s' must be mapped to a field of the entity '%s'",
+			referencedColumn, association.FieldName, referenced.EntityName)
+	}
+
+	association.ReferencedField = referencedField
+	association.JoinColumn = strings.TrimSpace(joinColumnMarker.Name)
+	association.ForeignKeyName = strings.TrimSpace(joinColumnMarker.ForeignKeyName)
+	association.Nullable = joinColumnMarker.Nullable
+	association.Unique = joinColumnMarker.Unique
+
+	if association.JoinColumn == "" {
+		association.JoinColumn = shelf.ToSnakeCase(prefix) + "_" + referencedColumn
+	}
+
+	return nil
+}
+
+// applyMapsId resolves the join column of the association marked as shelf:maps-id, which is the column of the
+// id field mapped by the association. The field is given by the marker if the key is composite.
+func applyMapsId(entity EntityMetadata, target EntityMetadata, association *AssociationMetadata) error {
+	if association.MappedBy != "" || association.Kind != shelf.ManyToOne && association.Kind != shelf.OneToOne {
+		return fmt.Errorf("'%s' marker can only be used on the owning side of a many-to-one or one-to-one association",
+			shelf.MarkerMapsId)
+	}
+
+	if _, ok := association.Field.Markers[shelf.MarkerJoinColumn]; ok {
+		return fmt.Errorf("'%s' marker cannot be used with '%s' marker, the join column is the id column",
+			shelf.MarkerJoinColumn, shelf.MarkerMapsId)
+	}
+
+	keyField := ""
+
+	for _, candidateMarker := range association.Field.Markers[shelf.MarkerMapsId] {
+		if mapsIdMarker, ok := candidateMarker.(shelf.MapsIdMarker); ok {
+			keyField = strings.TrimSpace(mapsIdMarker.Value)
+		}
+	}
+
+	var idField *FieldMetadata
+
+	for _, candidate := range entity.IdFields {
+		if keyField == "" && !entity.IsComposite() || keyField != "" && candidate.KeyField == keyField {
+			idField = candidate
+		}
+	}
+
+	if idField == nil {
+		if keyField == "" {
+			return fmt.Errorf("'%s' marker of the field '%s' must give the field of the composite key of the entity '%s'",
+				shelf.MarkerMapsId, association.FieldName, entity.EntityName)
+		}
+
+		return fmt.Errorf("the field '%s' mapped by the field '%s' must be a field of the composite key of the entity '%s'",
+			keyField, association.FieldName, entity.EntityName)
+	}
+
+	if idField.IsGenerated {
+		return fmt.Errorf("the id field '%s' mapped by the field '%s' cannot be generated", idField.FieldName, association.FieldName)
+	}
+
+	if GetQualifiedNameFromType(idField.File, idField.Type) != GetQualifiedNameFromType(target.IdField.File, target.IdField.Type) {
+		return fmt.Errorf("the type of the id field '%s' mapped by the field '%s' must be the type of the id of the entity '%s'",
+			idField.FieldName, association.FieldName, target.EntityName)
+	}
+
+	association.MapsId = idField.FieldName
+	association.ReferencedField = *target.IdField
+	association.JoinColumn = idField.ColumnName
+	return nil
+}
+
+// applyJoinTable resolves the join table of the many-to-many association given by shelf:join-table, which
+// is named after the tables of the entities by default.
+func applyJoinTable(entity EntityMetadata, target EntityMetadata, association *AssociationMetadata) error {
+	association.ReferencedField = *entity.IdField
+	association.JoinTable = entity.TableName + "_" + target.TableName
+	association.JoinColumn = shelf.ToSnakeCase(entity.StructName) + "_" + entity.IdField.ColumnName
+	association.InverseJoinColumn = shelf.ToSnakeCase(target.StructName) + "_" + target.IdField.ColumnName
+
+	for _, candidateMarker := range association.Field.Markers[shelf.MarkerJoinTable] {
+		joinTableMarker, ok := candidateMarker.(shelf.JoinTableMarker)
+
+		if !ok {
+			continue
+		}
+
+		if len(joinTableMarker.JoinColumns) > 1 || len(joinTableMarker.InverseJoinColumns) > 1 {
+			return fmt.Errorf("the join table of the field '%s' can only have one join column and one inverse join column, "+
+				"as the entities have a single id", association.FieldName)
+		}
+
+		if name := strings.TrimSpace(joinTableMarker.Name); name != "" {
+			association.JoinTable = name
+		}
+
+		if len(joinTableMarker.JoinColumns) == 1 && strings.TrimSpace(joinTableMarker.JoinColumns[0]) != "" {
+			association.JoinColumn = strings.TrimSpace(joinTableMarker.JoinColumns[0])
+		}
+
+		if len(joinTableMarker.InverseJoinColumns) == 1 && strings.TrimSpace(joinTableMarker.InverseJoinColumns[0]) != "" {
+			association.InverseJoinColumn = strings.TrimSpace(joinTableMarker.InverseJoinColumns[0])
+		}
+	}
+
+	if association.JoinColumn == association.InverseJoinColumn {
+		return fmt.Errorf("the join columns of the join table '%s' cannot be both named '%s', they must be given by '%s' marker",
+			association.JoinTable, association.JoinColumn, shelf.MarkerJoinTable)
+	}
+
+	return nil
+}
+
+// findFieldByColumnName returns the field of the entity mapped to the column. The fields of the embedded
+// structs are not returned, as a reference to the entity can only hold its own fields.
+func findFieldByColumnName(entity EntityMetadata, columnName string) (FieldMetadata, bool) {
+	for _, field := range entity.Fields {
+		if field.ColumnName == columnName && !strings.Contains(field.FieldName, ".") {
+			return field, true
+		}
+	}
+
+	return FieldMetadata{}, false
+}
+
+// resolveMappedBy sets the join columns of the inverse side of a relationship to the ones of the owning side.
+func resolveMappedBy(entity EntityMetadata, association *AssociationMetadata) error {
+	target := entityMetadataByStructName[association.Target]
+
+	for _, owner := range target.Associations {
+		if owner.FieldName != association.MappedBy {
+			continue
+		}
+
+		if owner.MappedBy != "" {
+			return fmt.Errorf("the field '%s' of the entity '%s' is mapped by another field, it cannot own the relationship",
+				owner.FieldName, target.EntityName)
+		}
+
+		structName := entity.StructType.File.Package.Path + "#" + entity.StructName
+
+		if owner.Kind != inverseKinds[association.Kind] || owner.Target != structName {
+			return fmt.Errorf("the field '%s' of the entity '%s' must be marked as '%s' and refer to the entity '%s' to map the field '%s'",
+				owner.FieldName, target.EntityName, getAssociationMarker(inverseKinds[association.Kind]), entity.EntityName, association.FieldName)
+		}
+
+		if association.Kind == shelf.ManyToMany {
+			association.ReferencedField = *entity.IdField
+			association.JoinTable = owner.JoinTable
+			association.JoinColumn = owner.InverseJoinColumn
+			association.InverseJoinColumn = owner.JoinColumn
+		} else {
+			// the join column of the owning side refers to the entity
+			association.ReferencedField = owner.ReferencedField
+			association.JoinColumn = owner.JoinColumn
+			association.ForeignKeyName = owner.ForeignKeyName
+			association.Nullable = owner.Nullable
+			association.Unique = owner.Unique
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("there is no association '%s' in the entity '%s'", association.MappedBy, target.EntityName)
+}
+
+// getAssociationMarker returns the name of the marker of the association kind.
+func getAssociationMarker(kind shelf.AssociationKind) string {
+	for _, associationKind := range associationKinds {
+		if associationKind.Kind == kind {
+			return associationKind.Marker
+		}
+	}
+
+	return ""
+}
+
+// GetJoinColumnNames returns the join columns of the entity table, which are selected after the columns of the fields.
+func GetJoinColumnNames(entity EntityMetadata) []string {
+	columns := make([]string, 0)
+
+	for _, association := range entity.Associations {
+		if association.OwnsJoinColumn() {
+			columns = append(columns, association.JoinColumn)
+		}
+	}
+
+	return columns
+}
+
+// FindAssociation returns the association of the entity held by the field.
+func FindAssociation(entity EntityMetadata, fieldName string) (AssociationMetadata, bool) {
+	for _, association := range entity.Associations {
+		if association.FieldName == fieldName {
+			return association, true
+		}
+	}
+
+	return AssociationMetadata{}, false
+}
+
+// ValidateFetchPaths checks that each field of the association paths, e.g. Posts.Tags, is an association of the
+// entity referred by the previous field.
+func ValidateFetchPaths(entity EntityMetadata, paths []string) error {
+	for _, path := range paths {
+		current := entity
+
+		for _, field := range strings.Split(path, ".") {
+			association, ok := FindAssociation(current, strings.TrimSpace(field))
+
+			if !ok {
+				return fmt.Errorf("the field '%s' of the fetch path '%s' is not an association of the entity '%s'",
+					strings.TrimSpace(field), path, current.EntityName)
+			}
+
+			current = entityMetadataByStructName[association.Target]
+		}
+	}
+
+	return nil
+}
+
+func isEagerAssociation(kind shelf.AssociationKind, fetchType string) bool {
+	if fetchType == "" {
+		return kind == shelf.OneToOne || kind == shelf.ManyToOne
+	}
+
+	return fetchType == FetchTypeEager
+}
+
+// applyCascade sets the cascaded operations of the association given by the Cascade and OrphanRemoval arguments.
+func applyCascade(association *AssociationMetadata, candidateMarker interface{}) {
+	cascade := make([]string, 0)
+
+	switch typedMarker := candidateMarker.(type) {
+	case shelf.OneToOneMarker:
+		cascade = typedMarker.Cascade
+	case shelf.OneToManyMarker:
+		cascade = typedMarker.Cascade
+		association.OrphanRemoval = typedMarker.OrphanRemoval
+	case shelf.ManyToOneMarker:
+		cascade = typedMarker.Cascade
+	case shelf.ManyToManyMarker:
+		cascade = typedMarker.Cascade
+	}
+
+	for _, operation := range cascade {
+		switch strings.TrimSpace(operation) {
+		case CascadeAll:
+			association.CascadeSave = true
+			association.CascadeRemove = true
+		case CascadeSaveUpdate:
+			association.CascadeSave = true
+		case CascadePersist:
+			association.CascadePersist = true
+		case CascadeRemove:
+			association.CascadeRemove = true
+		}
+	}
+}
+
+func getFetchType(candidateMarker interface{}) string {
+	switch typedMarker := candidateMarker.(type) {
+	case shelf.OneToOneMarker:
+		return strings.TrimSpace(typedMarker.FetchType)
+	case shelf.OneToManyMarker:
+		return strings.TrimSpace(typedMarker.FetchType)
+	case shelf.ManyToOneMarker:
+		return strings.TrimSpace(typedMarker.FetchType)
+	case shelf.ManyToManyMarker:
+		return strings.TrimSpace(typedMarker.FetchType)
+	}
+
+	return ""
+}
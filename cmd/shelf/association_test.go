@@ -0,0 +1,310 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// associationSource is the package of the association tests. The posts of the users are eagerly loaded,
+// and their other associations are loaded by the loader methods.
+const associationSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+	"time"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Email string
+	// +shelf:one-to-one:MappedBy=User
+	CreditCard *CreditCard
+	// +shelf:one-to-many:FetchType=EAGER,MappedBy=Author
+	Posts []*Post
+	// +shelf:many-to-many
+	Tags []Tag
+}
+
+// +shelf:entity
+// +shelf:table=credit_cards
+type CreditCard struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id     int
+	Number string
+	// +shelf:one-to-one:FetchType=LAZY
+	User *User
+}
+
+// +shelf:entity
+// +shelf:table=posts
+type Post struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id        int
+	Title     string
+	CreatedOn time.Time
+	// +shelf:many-to-one:FetchType=LAZY
+	Author *User
+}
+
+// +shelf:entity
+// +shelf:table=tags
+type Tag struct {
+	// +shelf:id
+	Name string
+}
+`
+
+func TestValidate_Associations(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Source string
+		Errors []string
+	}{
+		{
+			Name: "collection of a single-valued association",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:many-to-one
+	Posts []Post
+}`,
+			Errors: []string{
+				"the type of the field 'Posts' must be the associated entity or a pointer to it",
+			},
+		},
+		{
+			Name: "single value of a collection association",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-many
+	Post Post
+}`,
+			Errors: []string{
+				"the type of the field 'Post' must be a slice of the associated entity",
+			},
+		},
+		{
+			Name: "association of a non-entity",
+			Source: `
+type Attachment struct {
+	Name string
+}
+
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-many
+	Attachments []Attachment
+}`,
+			Errors: []string{
+				"the type of the field 'Attachments' must be an entity",
+			},
+		},
+		{
+			Name: "more than one relationship marker",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-one
+	// +shelf:many-to-one
+	Post *Post
+}`,
+			Errors: []string{
+				"the field 'Post' cannot be marked as more than one relationship marker",
+			},
+		},
+		{
+			Name: "mapped many-to-one",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:many-to-one:MappedBy=Author
+	Post *Post
+}`,
+			Errors: []string{
+				"the field 'Post' marked as 'shelf:many-to-one' owns the relationship, it cannot be mapped by another field",
+			},
+		},
+		{
+			Name: "unknown mapping field",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-many:MappedBy=Comment
+	Posts []Post
+}`,
+			Errors: []string{
+				"there is no association 'Comment' in the entity 'Post'",
+			},
+		},
+		{
+			Name: "join column of a field",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id     int
+	PostId int
+	// +shelf:many-to-one
+	Post *Post
+}`,
+			Errors: []string{
+				"the join column 'post_id' of the field 'Post' is already mapped to the field 'PostId'",
+			},
+		},
+		{
+			Name: "mapping field of another kind",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-many:MappedBy=Author
+	Posts []Post
+}`,
+			Errors: []string{
+				"the field 'Author' of the entity 'Post' must be marked as 'shelf:many-to-one' and refer to the entity 'Comment' to map the field 'Posts'",
+			},
+		},
+		{
+			Name: "one-to-one owned by both sides",
+			Source: `
+// +shelf:entity
+type Citizen struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-one
+	Passport *Passport
+}
+
+// +shelf:entity
+type Passport struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-one
+	Holder *Citizen
+}`,
+			Errors: []string{
+				"the one-to-one relationship between the fields 'Passport' and 'Passport.Holder' is owned by both sides, one of them must be mapped by the other one",
+				"the one-to-one relationship between the fields 'Holder' and 'Citizen.Passport' is owned by both sides, one of them must be mapped by the other one",
+			},
+		},
+		{
+			Name: "field mapped twice",
+			Source: `
+// +shelf:entity
+type Team struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-many:MappedBy=Team
+	Members []Member
+	// +shelf:one-to-many:MappedBy=Team
+	Players []Member
+}
+
+// +shelf:entity
+type Member struct {
+	// +shelf:id
+	Id int
+	// +shelf:many-to-one
+	Team *Team
+}`,
+			Errors: []string{
+				"the field 'Team' of the entity 'Member' is already mapped by 'Team.Members'",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assertErrors(t, validate(t, "fixture", associationSource+testCase.Source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_Associations(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindById(ctx context.Context, id int) (*User, error)
+	LoadCreditCard(ctx context.Context, user *User) error
+	LoadTags(ctx context.Context, users []*User) error
+}
+
+// +shelf:repository="post-repository", Entity=Post
+type PostRepository interface {
+	Save(ctx context.Context, post *Post) error
+	LoadAuthor(ctx context.Context, posts []*Post) error
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				`"SELECT user_id, id, number, user_id FROM credit_cards WHERE user_id IN ("+shelf.Dollar.Placeholders(1, end-start)+") ORDER BY id"`,
+				`"SELECT author_id, id, title, created_on, author_id FROM posts WHERE author_id IN ("+shelf.Dollar.Placeholders(1, end-start)+") ORDER BY id"`,
+				`"SELECT j.user_id, t.name FROM tags t JOIN users_tags j ON j.tag_name = t.name WHERE j.user_id IN ("+shelf.Dollar.Placeholders(1, end-start)+") ORDER BY t.name"`,
+				`"SELECT id, email FROM users WHERE id IN ("+shelf.Dollar.Placeholders(1, end-start)+")"`,
+				// the join column of the author is written with the post
+				`"INSERT INTO posts(title, created_on, author_id) VALUES($1, $2, $3) RETURNING id", post.Title, post.CreatedOn, postAuthorId(post)`,
+				"err = fetchUser(ctx, repository.db, shelf.FetchPlanOf(ctx, repository.fetch), entity)",
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`"SELECT author_id, id, title, created_on, author_id FROM posts WHERE author_id IN ("+shelf.Question.Placeholders(1, end-start)+") ORDER BY id"`,
+				`"INSERT INTO posts(title, created_on, author_id) VALUES(?, ?, ?)", post.Title, post.CreatedOn, postAuthorId(post)`,
+			},
+		},
+		{
+			Dialect: "sqlite",
+			Statements: []string{
+				`"SELECT j.user_id, t.name FROM tags t JOIN users_tags j ON j.tag_name = t.name WHERE j.user_id IN ("+shelf.Question.Placeholders(1, end-start)+") ORDER BY t.name"`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", associationSource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
+
+// TestGenerate_AssociationImports checks that the types of the associated entities, which are not rendered by
+// their loaders, do not add unused imports.
+func TestGenerate_AssociationImports(t *testing.T) {
+	repositories := generate(t, "fixture", associationSource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindById(ctx context.Context, id int) (*User, error)
+}`)
+
+	if strings.Contains(repositories, `"time"`) {
+		t.Errorf("the repositories should not import time, which is not used")
+	}
+}
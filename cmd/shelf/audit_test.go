@@ -0,0 +1,171 @@
+package main
+
+import (
+	"testing"
+)
+
+// auditSource is the package of the audit tests, whose posts have all the audit fields and whose tags
+// only have the created date.
+const auditSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+	"time"
+)
+
+// +shelf:entity
+// +shelf:table=posts
+type Post struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Title string
+	// +shelf:created-date
+	CreatedOn time.Time
+	// +shelf:created-by
+	CreatedBy string
+	// +shelf:last-modified-date
+	UpdatedOn time.Time
+	// +shelf:last-modified-by
+	UpdatedBy string
+}
+
+// +shelf:entity
+// +shelf:table=tags
+type Tag struct {
+	// +shelf:id
+	Name string
+	// +shelf:created-date
+	CreatedOn time.Time
+}
+`
+
+func TestValidate_AuditFields(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Entity string
+		Errors []string
+	}{
+		{
+			Name: "date of an unsupported type",
+			Entity: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:created-date
+	CreatedOn int64
+}`,
+			Errors: []string{
+				"the type of the field 'CreatedOn' marked as 'shelf:created-date' must be time.Time",
+			},
+		},
+		{
+			Name: "audit of the id",
+			Entity: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	// +shelf:created-by
+	Id string
+}`,
+			Errors: []string{
+				"'shelf:created-by' marker cannot be used with 'shelf:id' marker",
+			},
+		},
+		{
+			Name: "more than one audit marker",
+			Entity: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:created-date
+	// +shelf:last-modified-date
+	CreatedOn time.Time
+}`,
+			Errors: []string{
+				"the field 'CreatedOn' cannot be marked as both 'shelf:created-date' and 'shelf:last-modified-date'",
+			},
+		},
+		{
+			Name: "more than one audit field",
+			Entity: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:created-by
+	Author string
+	// +shelf:created-by
+	Owner string
+}`,
+			Errors: []string{
+				"the entity 'Comment' cannot have more than one field marked as 'shelf:created-by'",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assertErrors(t, validate(t, "fixture", auditSource+testCase.Entity), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_AuditedEntities(t *testing.T) {
+	repository := `
+// +shelf:repository="post-repository", Entity=Post
+type PostRepository interface {
+	Save(ctx context.Context, post *Post) error
+	SaveAll(ctx context.Context, posts []*Post) error
+}
+
+// +shelf:repository="tag-repository", Entity=Tag
+type TagRepository interface {
+	Save(ctx context.Context, tag *Tag) error
+	SaveAll(ctx context.Context, tags []*Tag) error
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				"func auditPostCreation(ctx context.Context, entity *Post) {\n\tnow := shelf.Now()\n\tentity.CreatedOn = now\n\tentity.UpdatedOn = now",
+				"if auditor, ok := shelf.CurrentAuditor(ctx).(string); ok {\n\t\tentity.CreatedBy = auditor\n\t}",
+				"func auditPostModification(ctx context.Context, entity *Post) {\n\tentity.UpdatedOn = shelf.Now()",
+				"auditPostCreation(ctx, post)\n\n\t\terr = repository.executor(ctx).QueryRowContext(ctx, \"INSERT INTO posts(title, created_on, created_by, updated_on, updated_by) VALUES($1, $2, $3, $4, $5) RETURNING id\"",
+				// the created fields are not updated
+				"auditPostModification(ctx, post)\n\n\t\t_, err = repository.executor(ctx).ExecContext(ctx, \"UPDATE posts SET title = $1, updated_on = $2, updated_by = $3 WHERE id = $4\", post.Title, post.UpdatedOn, post.UpdatedBy, post.Id)",
+				"auditPostCreation(ctx, entity)\n\t\t\tinserts = append(inserts, entity)",
+				"auditPostModification(ctx, entity)\n\t\t\tupdates = append(updates, entity)",
+				"auditTagCreation(ctx, tag)",
+				`"INSERT INTO tags(name, created_on) VALUES($1, $2) ON CONFLICT (name) DO NOTHING", tag.Name, tag.CreatedOn`,
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`"UPDATE posts SET title = ?, updated_on = ?, updated_by = ? WHERE id = ?", post.Title, post.UpdatedOn, post.UpdatedBy, post.Id`,
+				`"INSERT INTO tags(name, created_on) VALUES(?, ?) ON DUPLICATE KEY UPDATE name = name", tag.Name, tag.CreatedOn`,
+			},
+		},
+		{
+			Dialect: "sqlite",
+			Statements: []string{
+				`"INSERT INTO tags(name, created_on) VALUES(?, ?) ON CONFLICT (name) DO NOTHING", tag.Name, tag.CreatedOn`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", auditSource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"testing"
+)
+
+// batchSource is the package of the batch tests, whose users have generated ids and whose tags do not.
+const batchSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id        int
+	Email     string
+	FirstName string
+}
+
+// +shelf:entity
+// +shelf:table=tags
+type Tag struct {
+	// +shelf:id
+	Name  string
+	Color string
+}
+`
+
+func TestGenerate_InvalidBatchOptions(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		Repository string
+		Dialect    string
+		Errors     []string
+	}{
+		{
+			Name: "batch of a single entity",
+			Repository: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:batch:Size=100
+	Save(ctx context.Context, user *User) error
+}`,
+			Errors: []string{
+				"'shelf:batch' marker can only be used with the methods saving or deleting all the entities",
+			},
+		},
+		{
+			Name: "copy in deleting",
+			Repository: `
+// +shelf:repository="tag-repository", Entity=Tag
+type TagRepository interface {
+	// +shelf:batch:Copy=true
+	DeleteAll(ctx context.Context, tags []*Tag) error
+}`,
+			Errors: []string{
+				"the method 'DeleteAll' cannot use COPY, which is only used in saving the entities",
+			},
+		},
+		{
+			Name: "copy of generated ids",
+			Repository: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:batch:Copy=true
+	SaveAll(ctx context.Context, users []*User) error
+}`,
+			Errors: []string{
+				"the method 'SaveAll' cannot use COPY, which cannot return the generated ids",
+			},
+		},
+		{
+			Name: "copy outside postgres",
+			Repository: `
+// +shelf:repository="tag-repository", Entity=Tag
+type TagRepository interface {
+	// +shelf:batch:Copy=true
+	SaveAll(ctx context.Context, tags []*Tag) error
+}`,
+			Dialect: "mysql",
+			Errors: []string{
+				"the method 'SaveAll' cannot use COPY, which is only supported by postgres",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			dir, remove := writePackage(t, "fixture", map[string]string{"fixture.go": batchSource + testCase.Repository})
+			defer remove()
+
+			args := []string{"generate", "-o", dir}
+
+			if testCase.Dialect != "" {
+				args = append(args, "-a", "dialect="+testCase.Dialect)
+			}
+
+			assertErrors(t, runShelf(t, dir, args...), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_BatchOperations(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	SaveAll(ctx context.Context, users []*User) error
+	// +shelf:batch:Size=100
+	DeleteAllById(ctx context.Context, ids []int) error
+}
+
+// +shelf:repository="tag-repository", Entity=Tag
+type TagRepository interface {
+	// +shelf:batch:Size=500
+	SaveAll(ctx context.Context, tags []*Tag) error
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				`err = shelf.Batch(len(inserts), 1000, func(start, end int) error {`,
+				`rows, err := tx.QueryContext(ctx, "INSERT INTO users(email, first_name) VALUES "+shelf.Dollar.Rows(len(chunk), 2)+" RETURNING id", args...)`,
+				`updateStmt, err := tx.PrepareContext(ctx, "UPDATE users SET email = $1, first_name = $2 WHERE id = $3")`,
+				`err = shelf.Batch(len(args), 100, func(start, end int) error {`,
+				`"DELETE FROM users WHERE id IN ("+shelf.Dollar.Placeholders(1, end-start)+")", args[start:end]...`,
+				`err = shelf.Batch(len(entities), 500, func(start, end int) error {`,
+				`"INSERT INTO tags(name, color) VALUES "+shelf.Dollar.Rows(len(chunk), 2)+" ON CONFLICT (name) DO UPDATE SET color = EXCLUDED.color", args...`,
+				"tx.Rollback()\n\n\t\t// the ids and the versions assigned to the saved entities are restored, since their rows are rolled back\n" +
+					"\t\tfor _, entity := range inserts {\n\t\t\tentity.Id = 0\n\t\t}",
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`insertStmt, err := tx.PrepareContext(ctx, "INSERT INTO users(email, first_name) VALUES(?, ?)")`,
+				`result, err := insertStmt.ExecContext(ctx, entity.Email, entity.FirstName)`,
+				`entity.Id = int(id)`,
+				`"INSERT INTO tags(name, color) VALUES "+shelf.Question.Rows(len(chunk), 2)+" ON DUPLICATE KEY UPDATE color = VALUES(color)", args...`,
+			},
+		},
+		{
+			Dialect: "sqlite",
+			Statements: []string{
+				// the statements are limited to 999 parameters
+				`err = shelf.Batch(len(inserts), 499, func(start, end int) error {`,
+				`err = shelf.Batch(len(entities), 499, func(start, end int) error {`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", batchSource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
+
+func TestGenerate_CopyOperation(t *testing.T) {
+	repositories := generate(t, "fixture", batchSource+`
+// +shelf:repository="tag-repository", Entity=Tag
+type TagRepository interface {
+	// +shelf:batch:Copy=true
+	SaveAll(ctx context.Context, tags []*Tag) error
+}`)
+
+	assertContains(t, repositories,
+		`_, err = tx.ExecContext(ctx, "CREATE TEMPORARY TABLE IF NOT EXISTS shelf_copy_tags (LIKE tags INCLUDING DEFAULTS) ON COMMIT DROP")`,
+		`copyStmt, err := tx.PrepareContext(ctx, "COPY shelf_copy_tags (name, color) FROM STDIN")`,
+		`_, err = copyStmt.ExecContext(ctx, entity.Name, entity.Color)`,
+		`_, err = tx.ExecContext(ctx, "INSERT INTO tags(name, color) SELECT name, color FROM shelf_copy_tags ON CONFLICT (name) DO UPDATE SET color = EXCLUDED.color")`,
+		`_, err = tx.ExecContext(ctx, "DELETE FROM shelf_copy_tags")`,
+	)
+}
+
+func TestRun_BatchOperations(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:batch:Size=2
+	SaveAll(ctx context.Context, users []*User) error
+	// +shelf:batch:Size=2
+	DeleteAllById(ctx context.Context, ids []int) error
+}
+
+// +shelf:repository="tag-repository", Entity=Tag
+type TagRepository interface {
+	// +shelf:batch:Size=2,Copy=true
+	SaveAll(ctx context.Context, tags []*Tag) error
+}`
+
+	runFixture(t, "fixture", batchSource+repository, `package fixture
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestBatchOperations(t *testing.T) {
+	connector := &testConnector{
+		Query: func(query string, args []driver.Value) (driver.Rows, error) {
+			rows := newTestRows([]string{"id"})
+
+			for index := 0; index < len(args)/2; index++ {
+				rows.values = append(rows.values, []driver.Value{int64(len(args)*10 + index)})
+			}
+
+			return rows, nil
+		},
+	}
+	db := openTestDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	users := []*User{{Email: "anna@example.com"}, {Id: 7, Email: "ada@example.com"}, {Email: "alan@example.com"},
+		{Email: "grace@example.com"}}
+
+	if err := NewUserRepository(db).SaveAll(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := []int{users[0].Id, users[1].Id, users[2].Id, users[3].Id}; !reflect.DeepEqual(ids, []int{40, 7, 41, 20}) {
+		t.Errorf("the generated ids should be assigned to the inserted users, but got %v", ids)
+	}
+
+	if err := NewUserRepository(db).DeleteAllById(ctx, []int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewTagRepository(db).SaveAll(ctx, []*Tag{{Name: "go", Color: "blue"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"BEGIN",
+		"INSERT INTO users(email, first_name) VALUES ($1, $2), ($3, $4) RETURNING id",
+		"INSERT INTO users(email, first_name) VALUES ($1, $2) RETURNING id",
+		"UPDATE users SET email = $1, first_name = $2 WHERE id = $3",
+		"COMMIT",
+		"BEGIN",
+		"DELETE FROM users WHERE id IN ($1, $2)",
+		"DELETE FROM users WHERE id IN ($1)",
+		"COMMIT",
+		"BEGIN",
+		"CREATE TEMPORARY TABLE IF NOT EXISTS shelf_copy_tags (LIKE tags INCLUDING DEFAULTS) ON COMMIT DROP",
+		"COPY shelf_copy_tags (name, color) FROM STDIN",
+		"COPY shelf_copy_tags (name, color) FROM STDIN",
+		"INSERT INTO tags(name, color) SELECT name, color FROM shelf_copy_tags ON CONFLICT (name) DO UPDATE SET color = EXCLUDED.color",
+		"DELETE FROM shelf_copy_tags",
+		"COMMIT",
+	}
+
+	if queries := connector.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("the statements should be %q, but got %q", expected, queries)
+	}
+}
+`)
+}
+
+func TestRun_BatchOperations_GeneratedIdsWithoutReturning(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	SaveAll(ctx context.Context, users []*User) error
+}`
+
+	runFixture(t, "fixture", batchSource+repository, `package fixture
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestBatchOperations(t *testing.T) {
+	lastInsertId := int64(100)
+	connector := &testConnector{
+		Exec: func(query string, args []driver.Value) (driver.Result, error) {
+			// the ids are not consecutive, e.g. since another session inserts rows at the same time
+			lastInsertId += 5
+			return testResult{lastInsertId: lastInsertId, rowsAffected: 1}, nil
+		},
+	}
+	db := openTestDB(connector)
+	defer db.Close()
+
+	users := []*User{{Email: "anna@example.com"}, {Email: "ada@example.com"}, {Email: "alan@example.com"}}
+
+	if err := NewUserRepository(db).SaveAll(context.Background(), users); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := []int{users[0].Id, users[1].Id, users[2].Id}; !reflect.DeepEqual(ids, []int{105, 110, 115}) {
+		t.Errorf("the ids returned for each row should be assigned to the users, but got %v", ids)
+	}
+
+	expected := []string{
+		"BEGIN",
+		"INSERT INTO users(email, first_name) VALUES(?, ?)",
+		"INSERT INTO users(email, first_name) VALUES(?, ?)",
+		"INSERT INTO users(email, first_name) VALUES(?, ?)",
+		"COMMIT",
+	}
+
+	if queries := connector.Queries(); !reflect.DeepEqual(queries, expected) {
+		t.Errorf("the statements should be %q, but got %q", expected, queries)
+	}
+}
+`, "-a", "dialect=mysql")
+}
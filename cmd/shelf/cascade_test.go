@@ -0,0 +1,294 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// cascadeSource is the package of the cascade tests, whose users cascade all the operations to their soft-deleted
+// posts and whose soft-deleted accounts own the join columns of their avatars and owners, and cascade their deletes
+// to their versioned sessions.
+const cascadeSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Email string
+	// +shelf:one-to-one:MappedBy=User
+	Profile *Profile
+	// +shelf:one-to-many:MappedBy=Author,Cascade={"ALL"}
+	Posts []*Post
+	// +shelf:many-to-many:Cascade={"PERSIST"}
+	Tags []Tag
+}
+
+// +shelf:entity
+// +shelf:table=profiles
+type Profile struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id  int
+	Bio string
+	// +shelf:one-to-one
+	User *User
+}
+
+// +shelf:entity
+// +shelf:table=posts
+type Post struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Title string
+	// +shelf:soft-delete
+	Deleted bool
+	// +shelf:many-to-one
+	Author *User
+	// +shelf:one-to-many:Cascade={"ALL"},OrphanRemoval=true
+	Comments []Comment
+}
+
+// +shelf:entity
+// +shelf:table=comments
+type Comment struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id   int
+	Text string
+}
+
+// +shelf:entity
+// +shelf:table=tags
+type Tag struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id   int
+	Name string
+}
+
+// +shelf:entity
+// +shelf:table=accounts
+type Account struct {
+	// +shelf:id
+	Code string
+	// +shelf:soft-delete
+	Deleted bool
+	// +shelf:one-to-one:Cascade={"ALL"}
+	Avatar *Image
+	// +shelf:many-to-one:Cascade={"PERSIST"}
+	Owner *Member
+	// +shelf:one-to-many:Cascade={"REMOVE"}
+	Sessions []*Session
+}
+
+// +shelf:entity
+// +shelf:table=sessions
+type Session struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Token string
+	// +shelf:version
+	Version int
+}
+
+// +shelf:entity
+// +shelf:table=images
+type Image struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id  int
+	Url string
+}
+
+// +shelf:entity
+// +shelf:table=members
+type Member struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id   int
+	Name string
+}
+`
+
+func TestValidate_Cascades(t *testing.T) {
+	errors := validate(t, "fixture", cascadeSource+`
+// +shelf:entity
+type Folder struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-many:Cascade={"MERGE"}
+	Files []Image
+}`)
+
+	assertErrors(t, errors, "invalid Cascade option 'MERGE'. Here is the list of valid options ALL, PERSIST, SAVE_UPDATE, REMOVE")
+}
+
+func TestGenerate_Cascades(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	SaveAll(ctx context.Context, users []*User) error
+	DeleteAll(ctx context.Context, users []*User) error
+	DeleteById(ctx context.Context, id int) error
+	DeleteAllById(ctx context.Context, ids []int) error
+}
+
+// +shelf:repository="account-repository", Entity=Account
+type AccountRepository interface {
+	// +shelf:batch:Size=100
+	SaveAll(ctx context.Context, accounts []*Account) error
+	DeleteAll(ctx context.Context, accounts []*Account) error
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				// the targets are saved per chunk, in the transaction of the batch
+				"err := afterSaveUser(ctx, tx, cascade, entity)",
+				"err := beforeSaveAccount(ctx, tx, cascade, entity)",
+				"err = shelf.Batch(len(entities), 100, func(start, end int) error {",
+				// the mapped-by one-to-one side is cleared, the posts are soft-deleted
+				`"UPDATE profiles SET user_id = NULL WHERE user_id IN ("+shelf.Dollar.Placeholders(1, len(ids))+")", ids...`,
+				`"DELETE FROM users_tags WHERE user_id IN ("+shelf.Dollar.Placeholders(1, len(ids))+")", ids...`,
+				`"UPDATE posts SET deleted = TRUE WHERE id IN ("+shelf.Dollar.Placeholders(1, len(ids))+") AND deleted = FALSE", ids...`,
+				`"DELETE FROM comments WHERE id IN ("+shelf.Dollar.Placeholders(1, len(ids))+")", ids...`,
+				`"DELETE FROM users WHERE id IN ("+shelf.Dollar.Placeholders(1, len(ids))+")", ids...`,
+				"return deleteUserEntities(ctx, tx, cascade, entities[start:end])",
+				// the soft-deleted accounts keep their join columns, and their versioned sessions are deleted one at a time
+				`"UPDATE accounts SET deleted = TRUE WHERE code IN ("+shelf.Dollar.Placeholders(1, len(ids))+") AND deleted = FALSE", ids...`,
+				`"DELETE FROM images WHERE id IN ("+shelf.Dollar.Placeholders(1, len(ids))+")", ids...`,
+				`err = loadAccountSessions(ctx, executor, unloaded...)`,
+				`err = shelf.CheckOptimisticLock(executor.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1 AND version = $2", ids[index], entity.Version))`,
+				// the deletes by id load the entities to cascade the delete
+				`row := executor.QueryRowContext(ctx, "SELECT id, email FROM users WHERE id = $1", idParam)`,
+				"return cascadeDeleteUser(ctx, executor, shelf.NewCascade(), entity)",
+				`rows, err := tx.QueryContext(ctx, "SELECT id, email FROM users WHERE id IN ("+shelf.Dollar.Placeholders(1, end-start)+")", args[start:end]...)`,
+				"return deleteUserEntities(ctx, tx, cascade, entities)",
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`"UPDATE profiles SET user_id = NULL WHERE user_id IN ("+shelf.Question.Placeholders(1, len(ids))+")", ids...`,
+				`"DELETE FROM sessions WHERE id = ? AND version = ?", ids[index], entity.Version`,
+				`row := executor.QueryRowContext(ctx, "SELECT id, email FROM users WHERE id = ?", idParam)`,
+			},
+		},
+		{
+			Dialect: "sqlite",
+			Statements: []string{
+				`"UPDATE posts SET deleted = TRUE WHERE id IN ("+shelf.Question.Placeholders(1, len(ids))+") AND deleted = FALSE", ids...`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", cascadeSource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+
+			// the join columns of the soft-deleted rows are kept, so that they still refer to each other
+			for _, statement := range []string{"UPDATE posts SET author_id = NULL", "UPDATE accounts SET avatar_id = NULL",
+				"UPDATE sessions SET account_code = NULL"} {
+				if strings.Contains(repositories, statement) {
+					t.Errorf("the generated source should not contain %q", statement)
+				}
+			}
+		})
+	}
+}
+
+// TestGenerate_CascadesOfCopies checks that the targets referred by the join columns are saved before the chunk is
+// copied.
+func TestGenerate_CascadesOfCopies(t *testing.T) {
+	repositories := generate(t, "fixture", cascadeSource+`
+// +shelf:repository="account-repository", Entity=Account
+type AccountRepository interface {
+	// +shelf:batch:Size=100,Copy=true
+	SaveAll(ctx context.Context, accounts []*Account) error
+}`)
+
+	assertContains(t, repositories,
+		"err := beforeSaveAccount(ctx, tx, cascade, entity)",
+		`copyStmt, err := tx.PrepareContext(ctx, "COPY shelf_copy_accounts (code, deleted, avatar_id, owner_id) FROM STDIN")`,
+	)
+}
+
+// TestRun_CascadesOfSoftDeletes checks that the delete of a soft-deleted entity is cascaded to the targets of its
+// one-to-many association after they are loaded, and that the join columns of the targets are not cleared.
+func TestRun_CascadesOfSoftDeletes(t *testing.T) {
+	repository := `
+// +shelf:repository="account-repository", Entity=Account
+type AccountRepository interface {
+	DeleteAll(ctx context.Context, accounts []*Account) error
+}`
+
+	runFixture(t, "fixture", cascadeSource+repository, `package fixture
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCascadesOfSoftDeletes(t *testing.T) {
+	connector := &testConnector{
+		Query: func(query string, args []driver.Value) (driver.Rows, error) {
+			return newTestRows([]string{"account_code", "id", "token", "version"},
+				[]driver.Value{"a1", int64(7), "t7", int64(2)},
+				[]driver.Value{"a1", int64(8), "t8", int64(1)},
+			), nil
+		},
+		Exec: func(query string, args []driver.Value) (driver.Result, error) {
+			return driver.RowsAffected(1), nil
+		},
+	}
+	db := openTestDB(connector)
+	defer db.Close()
+
+	accounts := []*Account{{Code: "a1"}, {Code: "a2", Sessions: []*Session{}}}
+
+	if err := NewAccountRepository(db).DeleteAll(context.Background(), accounts); err != nil {
+		t.Fatalf("DeleteAll should delete the accounts, but got %v", err)
+	}
+
+	if len(accounts[0].Sessions) != 2 {
+		t.Errorf("the sessions of the account should be loaded, but got %v", accounts[0].Sessions)
+	}
+
+	statements := connector.Statements()
+
+	if len(statements) == 0 || !strings.HasPrefix(statements[1].Query, "SELECT account_code, id, token, version FROM sessions") {
+		t.Fatalf("the sessions of the unloaded account should be selected, but got %v", statements)
+	}
+
+	expected := []testStatement{
+		{Query: "BEGIN"},
+		statements[1],
+		{Query: "DELETE FROM sessions WHERE id = $1 AND version = $2", Args: []driver.Value{int64(7), int64(2)}},
+		{Query: "DELETE FROM sessions WHERE id = $1 AND version = $2", Args: []driver.Value{int64(8), int64(1)}},
+		{Query: "UPDATE accounts SET deleted = TRUE WHERE code IN ($1, $2) AND deleted = FALSE", Args: []driver.Value{"a1", "a2"}},
+		{Query: "COMMIT"},
+	}
+
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("the statements should be %v, but got %v", expected, statements)
+	}
+}
+`)
+}
@@ -18,6 +18,8 @@ package main
 import (
 	"github.com/procyon-projects/marker"
 	"log"
+	"path"
+	"strings"
 )
 
 // printErrors prints error.
@@ -30,7 +32,7 @@ func PrintError(err error) {
 			return
 		}
 
-		log.Errorln(err)
+		log.Println(err)
 		return
 	}
 }
@@ -45,10 +47,10 @@ func PrintErrors(errorList marker.ErrorList) {
 		switch typedErr := err.(type) {
 		case marker.Error:
 			pos := typedErr.Position
-			log.Errorf("%s (%d:%d) : %s\n", typedErr.FileName, pos.Line, pos.Column, typedErr.Error())
+			log.Printf("%s (%d:%d) : %s\n", typedErr.FileName, pos.Line, pos.Column, typedErr.Error())
 		case marker.ParserError:
 			pos := typedErr.Position
-			log.Errorf("%s (%d:%d) : %s\n", typedErr.FileName, pos.Line, pos.Column, typedErr.Error())
+			log.Printf("%s (%d:%d) : %s\n", typedErr.FileName, pos.Line, pos.Column, typedErr.Error())
 		case marker.ErrorList:
 			PrintErrors(typedErr)
 		default:
@@ -77,7 +79,140 @@ func GetFullNameFromType(typ marker.Type) string {
 			name = typed.ImportName + "." + name
 		}
 		return name
+	case *marker.PointerType:
+		return "*" + GetFullNameFromType(typed.Typ)
+	case *marker.ArrayType:
+		return "[]" + GetFullNameFromType(typed.ItemType)
+	case *marker.DictionaryType:
+		return "map[" + GetFullNameFromType(typed.KeyType) + "]" + GetFullNameFromType(typed.ValueType)
+	case *marker.VariadicType:
+		return "..." + GetFullNameFromType(typed.ItemType)
+	case *marker.AnyKindType:
+		return "interface{}"
 	}
 
 	return ""
 }
+
+// GetQualifiedNameFromType returns the type name whose package qualifiers are replaced
+// with the import paths so that types declared in different files can be compared.
+func GetQualifiedNameFromType(file *marker.File, typ marker.Type) string {
+	switch typed := typ.(type) {
+	case *marker.ObjectType:
+		if typed.ImportName != "" {
+			return FindImportPath(file, typed.ImportName) + "." + typed.Name
+		}
+
+		if IsBuiltinType(typed.Name) {
+			return typed.Name
+		}
+
+		return file.Package.Path + "." + typed.Name
+	case *marker.PointerType:
+		return "*" + GetQualifiedNameFromType(file, typed.Typ)
+	case *marker.ArrayType:
+		return "[]" + GetQualifiedNameFromType(file, typed.ItemType)
+	case *marker.DictionaryType:
+		return "map[" + GetQualifiedNameFromType(file, typed.KeyType) + "]" + GetQualifiedNameFromType(file, typed.ValueType)
+	case *marker.VariadicType:
+		return "..." + GetQualifiedNameFromType(file, typed.ItemType)
+	case *marker.AnyKindType:
+		return "interface{}"
+	}
+
+	return ""
+}
+
+// GetImportNamesFromType returns the package qualifiers used by the type.
+func GetImportNamesFromType(typ marker.Type) []string {
+	switch typed := typ.(type) {
+	case *marker.ObjectType:
+		if typed.ImportName != "" {
+			return []string{typed.ImportName}
+		}
+	case *marker.PointerType:
+		return GetImportNamesFromType(typed.Typ)
+	case *marker.ArrayType:
+		return GetImportNamesFromType(typed.ItemType)
+	case *marker.DictionaryType:
+		return append(GetImportNamesFromType(typed.KeyType), GetImportNamesFromType(typed.ValueType)...)
+	case *marker.VariadicType:
+		return GetImportNamesFromType(typed.ItemType)
+	}
+
+	return nil
+}
+
+// FindImportPath returns the path of the package imported with the given name in the file.
+func FindImportPath(file *marker.File, importName string) string {
+	for _, fileImport := range file.Imports {
+		if fileImport.Name == importName {
+			return fileImport.Path
+		}
+
+		if fileImport.Name == "" && GetPackageName(file, fileImport.Path) == importName {
+			return fileImport.Path
+		}
+	}
+
+	return importName
+}
+
+// GetPackageName returns the name of the package imported by the file.
+func GetPackageName(file *marker.File, importPath string) string {
+	rawPackage := file.Package.RawPackage
+
+	if rawPackage != nil && rawPackage.Imports != nil {
+		if importedPackage, ok := rawPackage.Imports[importPath]; ok && importedPackage.Name != "" {
+			return importedPackage.Name
+		}
+	}
+
+	return path.Base(importPath)
+}
+
+func IsBuiltinType(name string) bool {
+	switch name {
+	case "bool", "string", "error", "byte", "rune", "uintptr",
+		"int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64", "complex64", "complex128":
+		return true
+	}
+
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetOption returns the value of the key-value option passed with the args flag.
+func GetOption(options []string, key string) (string, bool) {
+	for _, option := range options {
+		parts := strings.SplitN(option, "=", 2)
+
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
+			return strings.TrimSpace(parts[1]), true
+		}
+	}
+
+	return "", false
+}
+
+// GetDialectOption returns the dialect passed with the args flag, or postgres if it is not passed.
+func GetDialectOption(options []string) (Dialect, error) {
+	name, ok := GetOption(options, "dialect")
+
+	if !ok {
+		name = DialectPostgres
+	}
+
+	return GetDialect(name)
+}
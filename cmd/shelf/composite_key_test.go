@@ -0,0 +1,309 @@
+package main
+
+import (
+	"testing"
+)
+
+// compositeKeySource is the package of the composite key tests. The memberships are keyed by an id class and
+// derive the id of their user, the enrollments are keyed by an embedded id and the profiles share the id of
+// their user.
+const compositeKeySource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Email string
+}
+
+// +shelf:entity
+// +shelf:table=images
+type Image struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id  int
+	Url string
+}
+
+type MembershipKey struct {
+	UserId int
+	Team   string
+}
+
+// +shelf:entity
+// +shelf:table=memberships
+// +shelf:id-class=MembershipKey
+type Membership struct {
+	// +shelf:id
+	UserId int
+	// +shelf:id
+	Team string
+	// +shelf:soft-delete
+	Deleted bool
+	// +shelf:many-to-one:Cascade={"PERSIST"}
+	// +shelf:maps-id="UserId"
+	User *User
+	// +shelf:one-to-one:Cascade={"ALL"}
+	Badge *Image
+}
+
+// +shelf:embeddable
+type EnrollmentKey struct {
+	StudentId int
+	CourseId  int
+}
+
+// +shelf:entity
+// +shelf:table=enrollments
+type Enrollment struct {
+	// +shelf:embedded-id
+	Key   EnrollmentKey
+	Grade int
+}
+
+// +shelf:entity
+// +shelf:table=profiles
+type Profile struct {
+	// +shelf:id
+	Id  int
+	Bio string
+	// +shelf:one-to-one:Cascade={"ALL"}
+	// +shelf:maps-id
+	User *User
+}
+`
+
+func TestValidate_CompositeKeys(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Source string
+		Errors []string
+	}{
+		{
+			Name: "several ids without an id class",
+			Source: `
+// +shelf:entity
+type Grant struct {
+	// +shelf:id
+	UserId int
+	// +shelf:id
+	RoleId int
+}`,
+			Errors: []string{
+				"the entity 'Grant' cannot have more than one field marked as 'shelf:id' unless it is marked as 'shelf:id-class'",
+			},
+		},
+		{
+			Name: "id class of other fields",
+			Source: `
+type GrantKey struct {
+	UserId int
+	RoleId string
+}
+
+// +shelf:entity
+// +shelf:id-class=GrantKey
+type Grant struct {
+	// +shelf:id
+	UserId int
+	// +shelf:id
+	RoleId int
+}
+
+// +shelf:entity
+// +shelf:id-class=MissingKey
+type Permission struct {
+	// +shelf:id
+	UserId int
+	// +shelf:id
+	Name string
+}`,
+			Errors: []string{
+				"the struct 'GrantKey' must have a field 'RoleId' of the type of the id field of the entity 'Grant'",
+				"the struct 'MissingKey' given by 'shelf:id-class' marker must be declared in the package of the entity 'Permission'",
+			},
+		},
+		{
+			Name: "generated field of a composite key",
+			Source: `
+type GrantKey struct {
+	UserId int
+	RoleId int
+}
+
+// +shelf:entity
+// +shelf:id-class=GrantKey
+type Grant struct {
+	// +shelf:id
+	// +shelf:generated-value
+	UserId int
+	// +shelf:id
+	RoleId int
+}`,
+			Errors: []string{
+				"'shelf:generated-value' marker cannot be used with the fields of a composite key",
+			},
+		},
+		{
+			Name: "embedded id with an id field",
+			Source: `
+// +shelf:entity
+type Grade struct {
+	// +shelf:embedded-id
+	Key EnrollmentKey
+	// +shelf:id
+	Id int
+}`,
+			Errors: []string{
+				"the entity 'Grade' with a field marked as 'shelf:embedded-id' cannot have a field marked as 'shelf:id' or be marked as 'shelf:id-class'",
+			},
+		},
+		{
+			Name: "associations of composite keys",
+			Source: `
+// +shelf:entity
+type Course struct {
+	// +shelf:id
+	Id int
+	// +shelf:many-to-one
+	Enrollment *Enrollment
+}
+
+// +shelf:entity
+// +shelf:table=grades
+type Grade struct {
+	// +shelf:embedded-id
+	Key EnrollmentKey
+	// +shelf:one-to-many
+	Images []Image
+}`,
+			Errors: []string{
+				"the field 'Enrollment' cannot refer to the entity 'Enrollment' with a composite key",
+				"the field 'Images' of the entity 'Grade' with a composite key can only own a many-to-one or one-to-one association",
+			},
+		},
+		{
+			Name: "maps-id of an invalid field",
+			Source: `
+// +shelf:entity
+type Badge struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id int
+	// +shelf:one-to-one
+	// +shelf:maps-id
+	User *User
+}
+
+// +shelf:entity
+type Avatar struct {
+	// +shelf:id
+	Code string
+	// +shelf:one-to-one
+	// +shelf:maps-id
+	User *User
+}
+
+// +shelf:entity
+// +shelf:table=grades
+type Grade struct {
+	// +shelf:embedded-id
+	Key EnrollmentKey
+	// +shelf:many-to-one
+	// +shelf:maps-id
+	User *User
+	// +shelf:many-to-one
+	// +shelf:maps-id="TeacherId"
+	Teacher *User
+}`,
+			Errors: []string{
+				"the id field 'Id' mapped by the field 'User' cannot be generated",
+				"the type of the id field 'Code' mapped by the field 'User' must be the type of the id of the entity 'User'",
+				"'shelf:maps-id' marker of the field 'User' must give the field of the composite key of the entity 'Grade'",
+				"the field 'TeacherId' mapped by the field 'Teacher' must be a field of the composite key of the entity 'Grade'",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assertErrors(t, validate(t, "fixture", compositeKeySource+testCase.Source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_CompositeKeys(t *testing.T) {
+	repository := `
+// +shelf:repository="membership-repository", Entity=Membership
+type MembershipRepository interface {
+	SaveAll(ctx context.Context, memberships []*Membership) error
+	DeleteAll(ctx context.Context, memberships []*Membership) error
+	DeleteById(ctx context.Context, id MembershipKey) error
+	DeleteAllById(ctx context.Context, ids []MembershipKey) error
+}
+
+// +shelf:repository="enrollment-repository", Entity=Enrollment
+type EnrollmentRepository interface {
+	FindById(ctx context.Context, id EnrollmentKey) (*Enrollment, error)
+	ExistsById(ctx context.Context, id EnrollmentKey) (bool, error)
+	SaveAll(ctx context.Context, enrollments []*Enrollment) error
+	DeleteAllById(ctx context.Context, ids []EnrollmentKey) error
+}
+
+// +shelf:repository="profile-repository", Entity=Profile
+type ProfileRepository interface {
+	Save(ctx context.Context, profile *Profile) error
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				`"SELECT student_id, course_id, grade FROM enrollments WHERE student_id = $1 AND course_id = $2", idParam.StudentId, idParam.CourseId`,
+				`"SELECT EXISTS(SELECT 1 FROM enrollments WHERE student_id = $1 AND course_id = $2)", idParam.StudentId, idParam.CourseId`,
+				`"INSERT INTO enrollments(student_id, course_id, grade) VALUES "+shelf.Dollar.Rows(len(chunk), 3)+" ON CONFLICT (student_id, course_id) DO UPDATE SET grade = EXCLUDED.grade", args...`,
+				`"DELETE FROM enrollments WHERE (student_id, course_id) IN ("+shelf.Dollar.Rows(end-start, 2)+")", args[start*2:end*2]...`,
+				// the ids mapped by the associations are derived once the associated entities are saved
+				"entity.UserId = entity.User.Id",
+				"deriveMembershipId(entity)",
+				"deriveProfileId(entity)",
+				`"INSERT INTO profiles(id, bio) VALUES($1, $2) ON CONFLICT (id) DO UPDATE SET bio = EXCLUDED.bio", entity.Id, entity.Bio`,
+				// the cascading deletes compare the keys as row values
+				"ids = append(ids, entity.UserId, entity.Team)",
+				`"UPDATE memberships SET deleted = TRUE WHERE (user_id, team) IN ("+shelf.Dollar.Rows(len(entities), 2)+") AND deleted = FALSE", ids...`,
+				`"SELECT user_id, team, deleted, badge_id FROM memberships WHERE user_id = $1 AND team = $2 AND deleted = FALSE", idParam.UserId, idParam.Team`,
+				`"SELECT user_id, team, deleted, badge_id FROM memberships WHERE (user_id, team) IN ("+shelf.Dollar.Rows(end-start, 2)+") AND deleted = FALSE", args[start*2:end*2]...`,
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`"SELECT student_id, course_id, grade FROM enrollments WHERE student_id = ? AND course_id = ?", idParam.StudentId, idParam.CourseId`,
+				`"DELETE FROM enrollments WHERE (student_id, course_id) IN ("+shelf.Question.Rows(end-start, 2)+")", args[start*2:end*2]...`,
+			},
+		},
+		{
+			Dialect: "sqlite",
+			Statements: []string{
+				`"UPDATE memberships SET deleted = TRUE WHERE (user_id, team) IN ("+shelf.Question.Rows(len(entities), 2)+") AND deleted = FALSE", ids...`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", compositeKeySource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
@@ -19,4 +19,26 @@ const (
 	AppName    = "shelf"
 	AppVersion = "1.0.0"
 	PkgId      = "github.com/procyon-projects/shelf"
+
+	// DefaultBatchSize is the maximum number of the rows in a statement of the batch operations,
+	// unless it is set by the shelf:batch marker.
+	DefaultBatchSize = 1000
+
+	// DefaultAllocationSize is the number of the ids allocated at once by the SEQUENCE and TABLE strategies,
+	// unless it is set by the shelf:generated-value marker.
+	DefaultAllocationSize = 50
+	// IdTable is the table of the TABLE strategy, whose rows hold the next ids of the sequences in the
+	// next_value column, and whose name column holds the sequence names.
+	IdTable = "shelf_sequences"
+)
+
+// The strategies generating the ids, see shelf:generated-value.
+const (
+	GenerationIdentity  = "IDENTITY"
+	GenerationSequence  = "SEQUENCE"
+	GenerationTable     = "TABLE"
+	GenerationUUID      = "UUID"
+	GenerationULID      = "ULID"
+	GenerationSnowflake = "SNOWFLAKE"
+	GenerationCustom    = "CUSTOM"
 )
@@ -0,0 +1,721 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/procyon-projects/marker"
+	"github.com/procyon-projects/shelf"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CustomQuery is the query defined by the shelf:query marker of a repository method. Unless it is
+// a native query, the entity names and the property names in the query are translated into the
+// table names and the column names, e.g. 'FROM User WHERE FirstName = %1' is translated into
+// 'SELECT id, first_name, ... FROM user WHERE first_name = ?'.
+type CustomQuery struct {
+	Kind QueryKind
+	// SQL is the translated query whose parameters are question marks.
+	SQL string
+	// CountSQL counts the rows matching a select query.
+	CountSQL string
+	// Parameters contains the indexes of the method parameters bound to the question marks in order.
+	Parameters []int
+	// Orders is the ORDER BY clause of a select query, which is kept apart from the query so that
+	// the runtime sort can be applied after it.
+	Orders string
+	// Modifying reports whether the method is marked as shelf:modifying, which is required to update or delete.
+	Modifying          bool
+	ClearAutomatically bool
+}
+
+// CustomQueryMethod binds a custom query to the parameters and the return values of a repository method.
+type CustomQueryMethod struct {
+	Query         CustomQuery
+	Result        QueryResult
+	SortIndex     int
+	PageableIndex int
+	Projection    *ProjectionMetadata
+	FetchSize     int
+}
+
+type queryTokenKind int
+
+const (
+	textToken queryTokenKind = iota
+	identifierToken
+	literalToken
+	parameterToken
+)
+
+type queryToken struct {
+	Kind  queryTokenKind
+	Value string
+}
+
+// queryKeywords contains the words which are never translated into the table or the column names.
+var queryKeywords = map[string]bool{
+	"SELECT": true, "DISTINCT": true, "FROM": true, "AS": true, "WHERE": true, "AND": true, "OR": true, "NOT": true,
+	"IS": true, "NULL": true, "TRUE": true, "FALSE": true, "IN": true, "LIKE": true, "ILIKE": true, "BETWEEN": true,
+	"EXISTS": true, "ORDER": true, "BY": true, "ASC": true, "DESC": true, "NULLS": true, "FIRST": true, "LAST": true,
+	"GROUP": true, "HAVING": true, "LIMIT": true, "OFFSET": true, "JOIN": true, "INNER": true, "LEFT": true,
+	"RIGHT": true, "OUTER": true, "ON": true, "UPDATE": true, "SET": true, "DELETE": true, "CASE": true, "WHEN": true,
+	"THEN": true, "ELSE": true, "END": true, "ALL": true, "ANY": true, "UNION": true, "ESCAPE": true,
+}
+
+// ResolveCustomQueryMethod translates the query of the method and checks whether its parameters and
+// return values match the query. The table names are quoted by the dialect if they are reserved words.
+func ResolveCustomQueryMethod(metadata RepositoryMetadata, method marker.Method, dialect Dialect) (CustomQueryMethod, error) {
+	customMethod := CustomQueryMethod{}
+	var queryMarker *shelf.QueryMarker
+
+	for _, candidateMarker := range method.Markers[shelf.MarkerQuery] {
+		if typedMarker, ok := candidateMarker.(shelf.QueryMarker); ok {
+			queryMarker = &typedMarker
+		}
+	}
+
+	if queryMarker == nil {
+		return customMethod, fmt.Errorf("the method '%s' does not have any '%s' marker", method.Name, shelf.MarkerQuery)
+	}
+
+	var parameters []marker.TypeInfo
+	parameters, customMethod.SortIndex, customMethod.PageableIndex = resolveQueryParameters(method)
+
+	_, includingDeleted := method.Markers[shelf.MarkerIncludingDeleted]
+
+	if includingDeleted && metadata.Entity.SoftDeleteField == nil {
+		return customMethod, fmt.Errorf("the method '%s' cannot include the deleted entities, since the entity '%s' "+
+			"has no field marked as '%s'", method.Name, metadata.Entity.EntityName, shelf.MarkerSoftDelete)
+	}
+
+	query, err := findCustomQuery(metadata, method, *queryMarker, includingDeleted, dialect)
+
+	if err != nil {
+		return customMethod, err
+	}
+
+	for _, parameter := range query.Parameters {
+		if parameter >= len(parameters) {
+			return customMethod, fmt.Errorf("the method '%s' does not have any parameter for %%%d", method.Name, parameter)
+		}
+	}
+
+	for _, candidateMarker := range method.Markers[shelf.MarkerModifying] {
+		if modifyingMarker, ok := candidateMarker.(shelf.ModifyingMarker); ok {
+			query.Modifying = true
+			query.ClearAutomatically = modifyingMarker.ClearAutomatically
+		}
+	}
+
+	customMethod.Query = query
+
+	if query.Kind != SelectQuery && !query.Modifying {
+		return customMethod, fmt.Errorf("the method '%s' must be marked as '%s' to update or delete", method.Name, shelf.MarkerModifying)
+	}
+
+	if query.Kind == SelectQuery && query.Modifying {
+		return customMethod, fmt.Errorf("the method '%s' cannot be marked as '%s' to select", method.Name, shelf.MarkerModifying)
+	}
+
+	if (customMethod.SortIndex != -1 || customMethod.PageableIndex != -1) && query.Kind != SelectQuery {
+		return customMethod, fmt.Errorf("the method '%s' cannot take in a sort or a pageable", method.Name)
+	}
+
+	for index := 1; index < len(parameters); index++ {
+		if !containsIndex(query.Parameters, index) {
+			return customMethod, fmt.Errorf("the parameter '%s' of the method '%s' is not used in the query as %%%d",
+				parameters[index].Name, method.Name, index)
+		}
+	}
+
+	if query.Kind == SelectQuery && isScalarResult(method) {
+		customMethod.Result = ScalarResult
+
+		if customMethod.SortIndex != -1 || customMethod.PageableIndex != -1 {
+			return customMethod, fmt.Errorf("the method '%s' cannot take in a sort or a pageable to return a single value", method.Name)
+		}
+
+		return customMethod, nil
+	}
+
+	customMethod.Result, customMethod.Projection, customMethod.FetchSize, err = resolveQueryMethodResult(metadata, method,
+		query.Kind, customMethod.PageableIndex)
+
+	if err != nil {
+		return customMethod, err
+	}
+
+	if query.Kind == SelectQuery && strings.HasPrefix(strings.ToUpper(query.SQL), "FROM") {
+		columns := getColumnNamesOfEntity(metadata.Entity)
+
+		if customMethod.Projection != nil {
+			columns = strings.Join(customMethod.Projection.Columns(), ", ")
+		}
+
+		customMethod.Query.SQL = "SELECT " + columns + " " + customMethod.Query.SQL
+	}
+
+	return customMethod, nil
+}
+
+// findCustomQuery translates the query of the marker, or the named query referred by the marker.
+func findCustomQuery(metadata RepositoryMetadata, method marker.Method, queryMarker shelf.QueryMarker,
+	includingDeleted bool, dialect Dialect) (CustomQuery, error) {
+	name := strings.TrimSpace(queryMarker.Name)
+
+	if name == "" {
+		query, err := TranslateCustomQuery(metadata.Entity, queryMarker.Value, queryMarker.NativeQuery, includingDeleted,
+			dialect)
+
+		if err != nil {
+			return query, fmt.Errorf("the query of the method '%s' cannot be translated: %s", method.Name, err)
+		}
+
+		return query, nil
+	}
+
+	namedQuery, ok := namedQueriesByName[name]
+
+	if !ok {
+		return CustomQuery{}, fmt.Errorf("there is no named query with name '%s'", name)
+	}
+
+	if namedQuery.Entity.EntityName != metadata.Entity.EntityName {
+		return CustomQuery{}, fmt.Errorf("the named query '%s' is declared on the entity '%s', not on '%s'",
+			name, namedQuery.Entity.EntityName, metadata.Entity.EntityName)
+	}
+
+	query, err := TranslateCustomQuery(namedQuery.Entity, namedQuery.Text, namedQuery.NativeQuery, includingDeleted,
+		dialect)
+
+	if err != nil {
+		return query, fmt.Errorf("the named query '%s' cannot be used by the method '%s': %s", name, method.Name, err)
+	}
+
+	return query, nil
+}
+
+// TranslateCustomQuery translates the query text. The parameters of the query are referred as
+// %1, %2 and so on, which are the parameters of the method after the context. Since the columns
+// in the SET clause cannot be qualified in every database, the aliases of the columns assigned
+// by an update query are removed, e.g. 'UPDATE User u SET u.Age = %1' is translated into
+// 'UPDATE users u SET age = ?'. The soft-deleted entities are excluded from the select queries
+// unless they are included.
+func TranslateCustomQuery(entity EntityMetadata, text string, nativeQuery bool, includingDeleted bool,
+	dialect Dialect) (CustomQuery, error) {
+	query := CustomQuery{}
+
+	tokens, err := tokenizeQuery(strings.TrimSpace(text))
+
+	if err != nil {
+		return query, err
+	}
+
+	query.Kind, err = getCustomQueryKind(tokens)
+
+	if err != nil {
+		return query, err
+	}
+
+	aliases := make(map[string]EntityMetadata)
+
+	if !nativeQuery {
+		aliases, err = findQueryEntities(entity, tokens)
+
+		if err != nil {
+			return query, err
+		}
+	} else if query.Kind == SelectQuery && !strings.EqualFold(tokens[0].Value, "SELECT") {
+		return query, errors.New("native queries must contain the select clause")
+	}
+
+	var builder strings.Builder
+	fromIndex := -1
+	orderIndex := -1
+	// the condition excluding the soft-deleted entities is added to the where clause, which is followed by
+	// the first clause of the rest of the query
+	whereIndex := -1
+	clauseIndex := -1
+	qualifier := ""
+	hasUnion := false
+	depth := 0
+	inSetClause := false
+
+	for index, token := range tokens {
+		switch token.Kind {
+		case parameterToken:
+			parameter, _ := strconv.Atoi(token.Value[1:])
+
+			if parameter < 1 {
+				return query, fmt.Errorf("the parameters start from %%1, but got '%s'", token.Value)
+			}
+
+			query.Parameters = append(query.Parameters, parameter)
+			builder.WriteString("?")
+		case identifierToken:
+			upperValue := strings.ToUpper(token.Value)
+
+			// the clauses of the subqueries are skipped
+			if upperValue == "FROM" && fromIndex == -1 && depth == 0 {
+				fromIndex = builder.Len()
+				qualifier = getQueryEntityQualifier(entity, tokens, index, dialect)
+			} else if upperValue == "ORDER" && depth == 0 && isNextIdentifier(tokens, index, "BY") {
+				orderIndex = builder.Len()
+			} else if upperValue == "SET" && depth == 0 && query.Kind == UpdateQuery {
+				inSetClause = true
+			} else if upperValue == "WHERE" && depth == 0 {
+				inSetClause = false
+			}
+
+			if depth == 0 {
+				switch upperValue {
+				case "WHERE":
+					if whereIndex == -1 {
+						whereIndex = builder.Len()
+					}
+				case "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET":
+					if clauseIndex == -1 {
+						clauseIndex = builder.Len()
+					}
+				case "UNION":
+					hasUnion = true
+				}
+			}
+
+			if nativeQuery {
+				builder.WriteString(token.Value)
+				continue
+			}
+
+			translated, err := translateQueryIdentifier(entity, aliases, tokens, index, dialect)
+
+			if err != nil {
+				return query, err
+			}
+
+			if inSetClause && depth == 0 && isAssignmentTarget(tokens, index) {
+				translated = translated[strings.Index(translated, ".")+1:]
+			}
+
+			builder.WriteString(translated)
+		default:
+			if token.Value == "(" {
+				depth++
+			} else if token.Value == ")" {
+				depth--
+			}
+
+			builder.WriteString(token.Value)
+		}
+	}
+
+	if depth != 0 {
+		return query, errors.New("the parentheses are not balanced")
+	}
+
+	translated := builder.String()
+
+	if query.Kind == SelectQuery && entity.SoftDeleteField != nil && !includingDeleted {
+		// the native queries, the unions and the queries selecting from a subquery must exclude them themselves
+		if nativeQuery || hasUnion || qualifier == "" {
+			return query, fmt.Errorf("the soft-deleted entities of '%s' cannot be excluded automatically, "+
+				"exclude them in the query and mark the method as '%s'", entity.EntityName, shelf.MarkerIncludingDeleted)
+		}
+
+		end := len(translated)
+
+		if clauseIndex != -1 {
+			end = clauseIndex
+		}
+
+		_, predicate := GetSoftDeleteConditions(*entity.SoftDeleteField)
+		conditions := qualifier + "." + predicate
+		start := end
+
+		if whereIndex != -1 {
+			conditions = "(" + strings.TrimSpace(translated[whereIndex+len("WHERE"):end]) + ") AND " + conditions
+			start = whereIndex
+		}
+
+		excluded := strings.TrimSpace(translated[:start]) + " WHERE " + conditions
+
+		if end != len(translated) {
+			excluded += " " + translated[end:]
+		}
+
+		if orderIndex != -1 {
+			orderIndex += len(excluded) - len(translated)
+		}
+
+		translated = excluded
+	}
+
+	query.SQL = strings.TrimSpace(translated)
+
+	if query.Kind == SelectQuery && orderIndex != -1 {
+		query.SQL = strings.TrimSpace(translated[:orderIndex])
+		orders := strings.TrimSpace(translated[orderIndex+len("ORDER"):])
+		query.Orders = strings.TrimSpace(orders[len("BY"):])
+	}
+
+	if query.Kind == SelectQuery && fromIndex != -1 {
+		end := len(translated)
+
+		if orderIndex != -1 {
+			end = orderIndex
+		}
+
+		query.CountSQL = "SELECT COUNT(*) " + strings.TrimSpace(translated[fromIndex:end])
+	}
+
+	return query, nil
+}
+
+// tokenizeQuery splits the query into the identifiers, the string literals, the parameters and the rest of the text.
+func tokenizeQuery(text string) ([]queryToken, error) {
+	tokens := make([]queryToken, 0)
+	runes := []rune(text)
+
+	for index := 0; index < len(runes); {
+		character := runes[index]
+		start := index
+
+		switch {
+		case character == '\'':
+			index++
+
+			for index < len(runes) {
+				if runes[index] == '\'' && index+1 < len(runes) && runes[index+1] == '\'' {
+					index += 2
+					continue
+				}
+
+				if runes[index] == '\'' {
+					break
+				}
+
+				index++
+			}
+
+			if index == len(runes) {
+				return nil, errors.New("the string literal is not closed")
+			}
+
+			index++
+			tokens = append(tokens, queryToken{Kind: literalToken, Value: string(runes[start:index])})
+		case character == '%':
+			index++
+
+			for index < len(runes) && unicode.IsDigit(runes[index]) {
+				index++
+			}
+
+			if index == start+1 {
+				return nil, errors.New("'%' must be followed by the number of the parameter")
+			}
+
+			tokens = append(tokens, queryToken{Kind: parameterToken, Value: string(runes[start:index])})
+		case character == '?' || character == '$':
+			return nil, fmt.Errorf("'%c' cannot be used as a parameter, use %%1, %%2 and so on instead", character)
+		case unicode.IsLetter(character) || character == '_':
+			for index < len(runes) && (unicode.IsLetter(runes[index]) || unicode.IsDigit(runes[index]) ||
+				runes[index] == '_' || runes[index] == '.') {
+				index++
+			}
+
+			tokens = append(tokens, queryToken{Kind: identifierToken, Value: string(runes[start:index])})
+		default:
+			index++
+			tokens = append(tokens, queryToken{Kind: textToken, Value: string(character)})
+		}
+	}
+
+	return tokens, nil
+}
+
+func getCustomQueryKind(tokens []queryToken) (QueryKind, error) {
+	if len(tokens) != 0 && tokens[0].Kind == identifierToken {
+		switch strings.ToUpper(tokens[0].Value) {
+		case "SELECT", "FROM":
+			return SelectQuery, nil
+		case "UPDATE":
+			return UpdateQuery, nil
+		case "DELETE":
+			return DeleteQuery, nil
+		}
+	}
+
+	return SelectQuery, errors.New("the query must start with SELECT, FROM, UPDATE or DELETE")
+}
+
+// findQueryEntities returns the entities referred in the query by their aliases. The entities
+// without an alias are referred by their names.
+func findQueryEntities(entity EntityMetadata, tokens []queryToken) (map[string]EntityMetadata, error) {
+	aliases := make(map[string]EntityMetadata)
+
+	for index, token := range tokens {
+		if token.Kind != identifierToken {
+			continue
+		}
+
+		switch strings.ToUpper(token.Value) {
+		case "FROM", "JOIN", "UPDATE":
+		default:
+			continue
+		}
+
+		entityIndex := nextIdentifierIndex(tokens, index)
+
+		if entityIndex == -1 {
+			return nil, fmt.Errorf("'%s' must be followed by an entity name", token.Value)
+		}
+
+		entityMetadata, ok := findEntityByName(tokens[entityIndex].Value)
+
+		if !ok {
+			// subqueries are selected by the FROM keyword as well
+			if tokens[entityIndex].Value == "(" {
+				continue
+			}
+
+			return nil, fmt.Errorf("there is no entity with name '%s'", tokens[entityIndex].Value)
+		}
+
+		aliases[entityMetadata.EntityName] = entityMetadata
+		aliasIndex := nextIdentifierIndex(tokens, entityIndex)
+
+		if aliasIndex != -1 && strings.EqualFold(tokens[aliasIndex].Value, "AS") {
+			aliasIndex = nextIdentifierIndex(tokens, aliasIndex)
+		}
+
+		if aliasIndex != -1 && !queryKeywords[strings.ToUpper(tokens[aliasIndex].Value)] {
+			aliases[tokens[aliasIndex].Value] = entityMetadata
+		}
+	}
+
+	if len(aliases) == 0 {
+		return nil, fmt.Errorf("the query must refer to the entity '%s'", entity.EntityName)
+	}
+
+	return aliases, nil
+}
+
+// translateQueryIdentifier translates the identifier at the index into a table name, a column name or
+// the column list of an alias, and keeps the keywords, the function names and the aliases as they are.
+func translateQueryIdentifier(entity EntityMetadata, aliases map[string]EntityMetadata, tokens []queryToken, index int,
+	dialect Dialect) (string, error) {
+	value := tokens[index].Value
+
+	if queryKeywords[strings.ToUpper(value)] {
+		return value, nil
+	}
+
+	// the function names are followed by a parenthesis
+	if nextIndex := nextTokenIndex(tokens, index); nextIndex != -1 && tokens[nextIndex].Value == "(" {
+		return value, nil
+	}
+
+	previousIndex := previousIdentifierIndex(tokens, index)
+
+	if previousIndex != -1 {
+		switch strings.ToUpper(tokens[previousIndex].Value) {
+		case "FROM", "JOIN", "UPDATE":
+			if entityMetadata, ok := findEntityByName(value); ok {
+				return dialect.Quote(entityMetadata.TableName), nil
+			}
+		case "AS":
+			return value, nil
+		default:
+			// the alias following the entity name is kept as it is
+			if _, ok := findEntityByName(tokens[previousIndex].Value); ok {
+				if _, ok := aliases[value]; ok {
+					return value, nil
+				}
+			}
+		}
+	}
+
+	// the fields of the embedded structs are referred by their paths, e.g. Address.City
+	if field, ok := findEntityField(entity, value); ok {
+		return field.ColumnName, nil
+	}
+
+	if dotIndex := strings.Index(value, "."); dotIndex != -1 {
+		alias := value[:dotIndex]
+		aliasEntity, ok := aliases[alias]
+
+		if !ok {
+			return "", fmt.Errorf("there is no entity with alias '%s'", alias)
+		}
+
+		field, ok := findEntityField(aliasEntity, value[dotIndex+1:])
+
+		if !ok {
+			return "", fmt.Errorf("there is no property '%s' in the entity '%s'", value[dotIndex+1:], aliasEntity.EntityName)
+		}
+
+		return alias + "." + field.ColumnName, nil
+	}
+
+	if aliasEntity, ok := aliases[value]; ok {
+		// an alias which is not followed by a property selects the whole entity, e.g. 'SELECT u FROM User u'
+		if isInsideCount(tokens, index) {
+			return value + "." + aliasEntity.IdField.ColumnName, nil
+		}
+
+		columns := make([]string, 0)
+
+		for _, field := range aliasEntity.Fields {
+			columns = append(columns, value+"."+field.ColumnName)
+		}
+
+		for _, column := range GetJoinColumnNames(aliasEntity) {
+			columns = append(columns, value+"."+column)
+		}
+
+		return strings.Join(columns, ", "), nil
+	}
+
+	return "", fmt.Errorf("there is no property '%s' in the entity '%s'", value, entity.EntityName)
+}
+
+// getQueryEntityQualifier returns the alias of the entity selected by the FROM keyword at the index, or its
+// table name quoted by the dialect if it has no alias. It returns an empty string if another entity or a
+// subquery is selected.
+func getQueryEntityQualifier(entity EntityMetadata, tokens []queryToken, index int, dialect Dialect) string {
+	entityIndex := nextIdentifierIndex(tokens, index)
+
+	if entityIndex == -1 {
+		return ""
+	}
+
+	entityMetadata, ok := findEntityByName(tokens[entityIndex].Value)
+
+	if !ok || entityMetadata.EntityName != entity.EntityName {
+		return ""
+	}
+
+	aliasIndex := nextIdentifierIndex(tokens, entityIndex)
+
+	if aliasIndex != -1 && strings.EqualFold(tokens[aliasIndex].Value, "AS") {
+		aliasIndex = nextIdentifierIndex(tokens, aliasIndex)
+	}
+
+	if aliasIndex != -1 && !queryKeywords[strings.ToUpper(tokens[aliasIndex].Value)] {
+		return tokens[aliasIndex].Value
+	}
+
+	return dialect.Quote(entity.TableName)
+}
+
+func findEntityByName(name string) (EntityMetadata, bool) {
+	structName, ok := entitiesByName[name]
+
+	if !ok {
+		return EntityMetadata{}, false
+	}
+
+	entityMetadata, ok := entityMetadataByStructName[structName]
+	return entityMetadata, ok
+}
+
+func isInsideCount(tokens []queryToken, index int) bool {
+	previousIndex := previousTokenIndex(tokens, index)
+
+	if previousIndex == -1 || tokens[previousIndex].Value != "(" {
+		return false
+	}
+
+	functionIndex := previousTokenIndex(tokens, previousIndex)
+	return functionIndex != -1 && strings.EqualFold(tokens[functionIndex].Value, "COUNT")
+}
+
+// isAssignmentTarget reports whether the identifier at the index is followed by an equals sign.
+func isAssignmentTarget(tokens []queryToken, index int) bool {
+	nextIndex := nextTokenIndex(tokens, index)
+	return nextIndex != -1 && tokens[nextIndex].Value == "=" &&
+		(nextIndex+1 == len(tokens) || tokens[nextIndex+1].Value != "=")
+}
+
+func isNextIdentifier(tokens []queryToken, index int, value string) bool {
+	nextIndex := nextIdentifierIndex(tokens, index)
+	return nextIndex != -1 && strings.EqualFold(tokens[nextIndex].Value, value)
+}
+
+// nextIdentifierIndex returns the index of the next identifier, or -1 if the next token is not an identifier.
+// A parenthesis is returned as well, so that the subqueries can be detected.
+func nextIdentifierIndex(tokens []queryToken, index int) int {
+	nextIndex := nextTokenIndex(tokens, index)
+
+	if nextIndex == -1 || (tokens[nextIndex].Kind != identifierToken && tokens[nextIndex].Value != "(") {
+		return -1
+	}
+
+	return nextIndex
+}
+
+func previousIdentifierIndex(tokens []queryToken, index int) int {
+	previousIndex := previousTokenIndex(tokens, index)
+
+	if previousIndex == -1 || tokens[previousIndex].Kind != identifierToken {
+		return -1
+	}
+
+	return previousIndex
+}
+
+// nextTokenIndex returns the index of the next token which is not a whitespace.
+func nextTokenIndex(tokens []queryToken, index int) int {
+	for nextIndex := index + 1; nextIndex < len(tokens); nextIndex++ {
+		if strings.TrimSpace(tokens[nextIndex].Value) != "" {
+			return nextIndex
+		}
+	}
+
+	return -1
+}
+
+func previousTokenIndex(tokens []queryToken, index int) int {
+	for previousIndex := index - 1; previousIndex >= 0; previousIndex-- {
+		if strings.TrimSpace(tokens[previousIndex].Value) != "" {
+			return previousIndex
+		}
+	}
+
+	return -1
+}
+
+func containsIndex(indexes []int, index int) bool {
+	for _, candidate := range indexes {
+		if candidate == index {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isScalarResult reports whether the method returns a single value of a builtin type.
+func isScalarResult(method marker.Method) bool {
+	returnValues := GetResultValues(method)
+
+	if len(returnValues) != 1 {
+		return false
+	}
+
+	objectType, ok := returnValues[0].Type.(*marker.ObjectType)
+	return ok && objectType.ImportName == "" && IsBuiltinType(objectType.Name)
+}
+
+func getColumnNamesOfEntity(entity EntityMetadata) string {
+	columns := make([]string, 0)
+
+	for _, field := range entity.Fields {
+		columns = append(columns, field.ColumnName)
+	}
+
+	columns = append(columns, GetJoinColumnNames(entity)...)
+	return strings.Join(columns, ", ")
+}
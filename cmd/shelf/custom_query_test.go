@@ -0,0 +1,203 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestValidate_CustomQueries(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Method string
+		Errors []string
+	}{
+		{
+			Name: "unknown property",
+			Method: `// +shelf:query="FROM User WHERE LastName = %1"
+	FindUsers(ctx context.Context, lastName string) []*User`,
+			Errors: []string{
+				"the query of the method 'FindUsers' cannot be translated: there is no property 'LastName' in the entity 'User'",
+			},
+		},
+		{
+			Name: "unknown entity",
+			Method: `// +shelf:query="FROM Account WHERE Email = %1"
+	FindUsers(ctx context.Context, email string) []*User`,
+			Errors: []string{
+				"the query of the method 'FindUsers' cannot be translated: there is no entity with name 'Account'",
+			},
+		},
+		{
+			Name: "missing parameter",
+			Method: `// +shelf:query="FROM User WHERE Email = %1 AND Age = %2"
+	FindUsers(ctx context.Context, email string) []*User`,
+			Errors: []string{
+				"the method 'FindUsers' does not have any parameter for %2",
+			},
+		},
+		{
+			Name: "unused parameter",
+			Method: `// +shelf:query="FROM User WHERE Email = %1"
+	FindUsers(ctx context.Context, email string, age int) []*User`,
+			Errors: []string{
+				"the parameter 'age' of the method 'FindUsers' is not used in the query as %2",
+			},
+		},
+		{
+			Name: "unbalanced parentheses",
+			Method: `// +shelf:query="FROM User WHERE (Email = %1"
+	FindUsers(ctx context.Context, email string) []*User`,
+			Errors: []string{
+				"the query of the method 'FindUsers' cannot be translated: the parentheses are not balanced",
+			},
+		},
+		{
+			Name: "native query without select",
+			Method: `// +shelf:query="FROM users WHERE email = %1", NativeQuery=true
+	FindUsers(ctx context.Context, email string) []*User`,
+			Errors: []string{
+				"the query of the method 'FindUsers' cannot be translated: native queries must contain the select clause",
+			},
+		},
+		{
+			Name: "update without modifying",
+			Method: `// +shelf:query="UPDATE User SET Age = %1 WHERE Email = %2"
+	UpdateAge(ctx context.Context, age int, email string) int64`,
+			Errors: []string{
+				"the method 'UpdateAge' must be marked as 'shelf:modifying' to update or delete",
+			},
+		},
+		{
+			Name: "modifying select",
+			Method: `// +shelf:query="FROM User WHERE Email = %1"
+	// +shelf:modifying
+	FindUsers(ctx context.Context, email string) []*User`,
+			Errors: []string{
+				"the method 'FindUsers' cannot be marked as 'shelf:modifying' to select",
+			},
+		},
+		{
+			Name: "sorted update",
+			Method: `// +shelf:query="UPDATE User SET Age = %1"
+	// +shelf:modifying
+	UpdateAge(ctx context.Context, age int, sort shelf.Sort) int64`,
+			Errors: []string{
+				"the method 'UpdateAge' cannot take in a sort or a pageable",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			source := querySource + `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	` + testCase.Method + `
+}`
+
+			assertErrors(t, validate(t, "fixture", source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_CustomQueries(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:query="FROM User u WHERE u.Email = %1 AND u.Age > %2 ORDER BY u.FirstName"
+	FindAdults(ctx context.Context, email string, age int) ([]*User, error)
+	// +shelf:query="FROM User WHERE FirstName LIKE %1"
+	FindPage(ctx context.Context, firstName string, pageable shelf.Pageable) ([]*User, shelf.Page, error)
+	// +shelf:query="SELECT COUNT(*) FROM User WHERE Age = %1"
+	CountAge(ctx context.Context, age int) (int64, error)
+	// +shelf:query="SELECT id, email, first_name, age FROM users WHERE email = %1", NativeQuery=true
+	FindNative(ctx context.Context, email string) (*User, error)
+	// +shelf:query="UPDATE User SET Age = %1 WHERE Email = %2"
+	// +shelf:modifying:ClearAutomatically=true
+	UpdateAge(ctx context.Context, age int, email string) (int64, error)
+	// +shelf:query="UPDATE User u SET u.FirstName = %1, u.Age = u.Age + 1 WHERE u.Email = %2"
+	// +shelf:modifying
+	UpdateFirstName(ctx context.Context, firstName string, email string) (int64, error)
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				`query := "SELECT id, email, first_name, age FROM users u WHERE u.email = $1 AND u.age > $2 ORDER BY u.first_name"`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE first_name LIKE $1"`,
+				`countQuery := "SELECT COUNT(*) FROM users WHERE first_name LIKE $1"`,
+				`query := "SELECT COUNT(*) FROM users WHERE age = $1"`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE email = $1"`,
+				`query := "UPDATE users SET age = $1 WHERE email = $2"`,
+				`shelf.ClearCache(ctx, "User")`,
+				`query := "UPDATE users u SET first_name = $1, age = u.age + 1 WHERE u.email = $2"`,
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`query := "SELECT id, email, first_name, age FROM users u WHERE u.email = ? AND u.age > ? ORDER BY u.first_name"`,
+				`query := "UPDATE users SET age = ? WHERE email = ?"`,
+			},
+		},
+		{
+			Dialect: "sqlite",
+			Statements: []string{
+				`query := "SELECT COUNT(*) FROM users WHERE age = ?"`,
+				`query := "UPDATE users SET age = ? WHERE email = ?"`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", querySource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
+
+func TestRun_ModifyingQueries(t *testing.T) {
+	runFixture(t, "fixture", querySource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:query="DELETE FROM User u WHERE u.Age < %1"
+	// +shelf:modifying
+	DeleteMinors(ctx context.Context, age int) (int64, error)
+}`, `package fixture
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestModifyingQueries(t *testing.T) {
+	connector := &testConnector{
+		Exec: func(query string, args []driver.Value) (driver.Result, error) {
+			return driver.RowsAffected(2), nil
+		},
+	}
+	db := openTestDB(connector)
+	defer db.Close()
+
+	deleted, err := NewUserRepository(db).DeleteMinors(context.Background(), 18)
+
+	if err != nil || deleted != 2 {
+		t.Errorf("DeleteMinors should return the number of the deleted rows, but got %d and %v", deleted, err)
+	}
+
+	expected := []testStatement{
+		{Query: "DELETE FROM users u WHERE u.age < $1", Args: []driver.Value{int64(18)}},
+	}
+
+	if statements := connector.Statements(); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("the statements should be %v, but got %v", expected, statements)
+	}
+}
+`)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright © 2021 Shelf Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DialectPostgres = "postgres"
+	DialectMysql    = "mysql"
+	DialectSqlite   = "sqlite"
+)
+
+// Dialect contains the database specific parts of the generated statements.
+type Dialect struct {
+	Name string
+	// PlaceholderFormat is the shelf.PlaceholderFormat used by the generated code at runtime.
+	PlaceholderFormat string
+	// SupportsReturning reports whether the generated id can be fetched with a RETURNING clause.
+	// Otherwise, it is fetched with sql.Result.LastInsertId.
+	SupportsReturning bool
+	// SupportsCursors reports whether the rows can be fetched in batches by a server-side cursor,
+	// which is declared for the methods marked as shelf:fetch-size.
+	SupportsCursors bool
+	// SupportsSequences reports whether the ids can be allocated from a sequence.
+	SupportsSequences bool
+	// MaxParameters is the maximum number of the parameters in a statement.
+	MaxParameters int
+}
+
+var dialects = map[string]Dialect{
+	DialectPostgres: {
+		Name:              DialectPostgres,
+		PlaceholderFormat: "shelf.Dollar",
+		SupportsReturning: true,
+		SupportsCursors:   true,
+		SupportsSequences: true,
+		MaxParameters:     65535,
+	},
+	DialectMysql: {
+		Name:              DialectMysql,
+		PlaceholderFormat: "shelf.Question",
+		SupportsReturning: false,
+		SupportsSequences: false,
+		MaxParameters:     65535,
+	},
+	DialectSqlite: {
+		Name:              DialectSqlite,
+		PlaceholderFormat: "shelf.Question",
+		SupportsReturning: true,
+		SupportsSequences: false,
+		// the default limit of the SQLite versions before 3.32.0
+		MaxParameters: 999,
+	},
+}
+
+// GetDialect returns the dialect with the given name.
+func GetDialect(name string) (Dialect, error) {
+	dialect, ok := dialects[strings.ToLower(strings.TrimSpace(name))]
+
+	if !ok {
+		names := make([]string, 0)
+
+		for dialectName := range dialects {
+			names = append(names, dialectName)
+		}
+
+		sort.Strings(names)
+		return Dialect{}, fmt.Errorf("unsupported dialect '%s'. Here is the list of supported dialects %s", name, strings.Join(names, ", "))
+	}
+
+	return dialect, nil
+}
+
+// reservedWords contains the SQL keywords which cannot be used as identifiers unless they are quoted.
+var reservedWords = map[string]bool{
+	"all": true, "and": true, "as": true, "by": true, "case": true, "check": true, "column": true,
+	"constraint": true, "create": true, "default": true, "delete": true, "desc": true, "distinct": true,
+	"from": true, "grant": true, "group": true, "having": true, "in": true, "index": true, "insert": true,
+	"into": true, "join": true, "key": true, "limit": true, "not": true, "null": true, "offset": true,
+	"on": true, "or": true, "order": true, "primary": true, "references": true, "select": true,
+	"table": true, "to": true, "union": true, "unique": true, "update": true, "user": true, "values": true,
+	"where": true, "with": true,
+}
+
+// Quote quotes the identifier if it is a reserved SQL word such as user or order.
+func (d Dialect) Quote(identifier string) string {
+	if !reservedWords[strings.ToLower(identifier)] {
+		return identifier
+	}
+
+	if d.Name == DialectMysql {
+		return "`" + identifier + "`"
+	}
+
+	return `"` + identifier + `"`
+}
+
+// Placeholder returns the positional parameter at the given index starting from 1.
+func (d Dialect) Placeholder(index int) string {
+	if d.PlaceholderFormat == "shelf.Dollar" {
+		return "$" + strconv.Itoa(index)
+	}
+
+	return "?"
+}
+
+// Rebind replaces the question marks in the query with the positional parameters of the dialect.
+func (d Dialect) Rebind(query string) string {
+	var builder strings.Builder
+	index := 0
+	inLiteral := false
+
+	for _, character := range query {
+		if character == '\'' {
+			inLiteral = !inLiteral
+		}
+
+		if character == '?' && !inLiteral {
+			index++
+			builder.WriteString(d.Placeholder(index))
+			continue
+		}
+
+		builder.WriteRune(character)
+	}
+
+	return builder.String()
+}
+
+// Upsert returns the clause which turns an insert statement into an update when the id already exists.
+func (d Dialect) Upsert(idColumns []string, columns []string) string {
+	assignments := make([]string, 0)
+
+	if d.Name == DialectMysql {
+		for _, column := range columns {
+			assignments = append(assignments, column+" = VALUES("+column+")")
+		}
+
+		if len(assignments) == 0 {
+			assignments = append(assignments, idColumns[0]+" = "+idColumns[0])
+		}
+
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+	}
+
+	if len(columns) == 0 {
+		return "ON CONFLICT (" + strings.Join(idColumns, ", ") + ") DO NOTHING"
+	}
+
+	for _, column := range columns {
+		assignments = append(assignments, column+" = EXCLUDED."+column)
+	}
+
+	return "ON CONFLICT (" + strings.Join(idColumns, ", ") + ") DO UPDATE SET " + strings.Join(assignments, ", ")
+}
+
+// BatchSize returns the number of the rows in a statement of a batch operation, which is limited by
+// the maximum number of the parameters in a statement.
+func (d Dialect) BatchSize(size int, columns int) int {
+	if columns != 0 && size*columns > d.MaxParameters {
+		return d.MaxParameters / columns
+	}
+
+	return size
+}
+
+// Pagination returns the Go expression appending the limit and the offset to a query.
+func (d Dialect) Pagination(limit string, offset string) string {
+	return `" LIMIT " + strconv.Itoa(` + limit + `) + " OFFSET " + strconv.Itoa(` + offset + `)`
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/procyon-projects/marker"
 	"github.com/procyon-projects/shelf"
+	"strconv"
 	"strings"
 )
 
@@ -12,6 +13,76 @@ type EntityMetadata struct {
 	TableName  string
 	StructName string
 	StructType marker.StructType
+	// IdField is the first field of the primary key, and IdFields are all of them. The key is composite if it
+	// is held by IdClass, which is given by shelf:id-class or by the type of the field marked as shelf:embedded-id.
+	IdField  *FieldMetadata
+	IdFields []*FieldMetadata
+	IdClass  *marker.StructType
+	// VersionField is the field used for the optimistic locking, which is nil if the entity is not versioned.
+	VersionField *FieldMetadata
+	// SoftDeleteField is the field marking the entity as deleted, which is nil if the entity rows are deleted.
+	SoftDeleteField *FieldMetadata
+	Fields          []FieldMetadata
+	// Associations are resolved by ResolveAssociations after all the entities are found.
+	Associations []AssociationMetadata
+}
+
+// IsComposite reports whether the primary key of the entity is held by a struct.
+func (entity EntityMetadata) IsComposite() bool {
+	return entity.IdClass != nil
+}
+
+type FieldMetadata struct {
+	// FieldName is the path of the field in the entity, e.g. Address.City for a field of an embedded struct.
+	FieldName  string
+	ColumnName string
+	Type       marker.Type
+	IsId       bool
+	// KeyField is the path of the field in the struct of a composite key, which is empty for the other fields.
+	KeyField    string
+	IsGenerated bool
+	// Generation is the strategy generating the values of the id field, which is only set if IsGenerated is.
+	Generation GenerationMetadata
+	IsVersion  bool
+	// IsSoftDelete reports whether the field marks the entity as deleted, see GetSoftDeleteConditions.
+	IsSoftDelete bool
+	// AuditMarker is the audit marker of the field, e.g. shelf:created-date, which is empty if the field is not audited.
+	AuditMarker string
+	// Enum is the mapping of the field marked as shelf:enumerated, which is nil for the other fields.
+	Enum  *EnumMetadata
+	Field marker.Field
+	// File is the file declaring the field, which is the file of the embeddable for the embedded fields.
+	File *marker.File
+}
+
+// GenerationMetadata is the strategy generating the values of an id field, see shelf:generated-value.
+type GenerationMetadata struct {
+	Strategy string
+	// SequenceName and AllocationSize are only used by the SEQUENCE and TABLE strategies.
+	SequenceName   string
+	AllocationSize int
+	// Generator is the name of the shelf.IdGenerator of the UUID, ULID, SNOWFLAKE and CUSTOM strategies.
+	Generator string
+}
+
+// IsDatabaseGenerated reports whether the id is generated by the database when the entity is inserted.
+// Otherwise, it is assigned before the entity is inserted.
+func (generation GenerationMetadata) IsDatabaseGenerated() bool {
+	return generation.Strategy == GenerationIdentity
+}
+
+// EnumMetadata is the mapping of an enum field marked as shelf:enumerated, whose type is a named integer type.
+// The constants of the enum are the constants of the type declared in its package.
+type EnumMetadata struct {
+	// Name is the name of the enum type, and File is the file declaring it.
+	Name string
+	File *marker.File
+	// Ordinal reports whether the constants are stored by their values instead of their names.
+	Ordinal   bool
+	Constants []string
+	// IsStringer reports whether the constants are stored by the names returned by the String method
+	// of the type instead of the names they are declared with.
+	IsStringer bool
 }
 
 func ValidateEntityMarkers(structType marker.StructType) bool {
@@ -24,6 +95,11 @@ func ValidateEntityMarkers(structType marker.StructType) bool {
 	isValid := true
 
 	for name, values := range markers {
+		// an entity can declare more than one named query
+		if name == shelf.MarkerNamedQuery {
+			continue
+		}
+
 		if values != nil && len(values) > 1 {
 			err := fmt.Errorf("the struct cannot be marked twice as '%s' marker", name)
 			errs = append(errs, marker.NewError(err, structType.File.FullPath, marker.Position{
@@ -49,12 +125,16 @@ func FindEntities(structTypes []marker.StructType) {
 			continue
 		}
 
-		markers, ok := markerValues[shelf.MarkerEntity]
+		entityMarkers, ok := markerValues[shelf.MarkerEntity]
 
 		if !ok {
-			return
+			continue
 		}
 
+		markers := make([]interface{}, 0)
+		markers = append(markers, entityMarkers...)
+		markers = append(markers, markerValues[shelf.MarkerTable]...)
+
 		var err error
 		entityName := strings.TrimSpace(structType.Name)
 		tableName := shelf.ToSnakeCase(strings.TrimSpace(structType.Name))
@@ -84,8 +164,8 @@ func FindEntities(structTypes []marker.StructType) {
 					tableName = tableNameValue
 				}
 
-				if _, ok := entitiesByTableName[tableNameValue]; ok {
-					err = fmt.Errorf("there is already an entity with table name '%s'", tableNameValue)
+				if _, ok := entitiesByTableName[tableName]; ok {
+					err = fmt.Errorf("there is already an entity with table name '%s'", tableName)
 					errs = append(errs, marker.NewError(err, structType.File.FullPath, marker.Position{
 						Line:   structType.Position.Line,
 						Column: structType.Position.Column,
@@ -96,13 +176,46 @@ func FindEntities(structTypes []marker.StructType) {
 		}
 
 		if err == nil {
+			fields, ok := FindEntityFields(structType)
+
+			if !ok {
+				continue
+			}
+
+			idClass, err := FindIdClass(structType, fields)
+
+			if err != nil {
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, marker.Position{
+					Line:   structType.Position.Line,
+					Column: structType.Position.Column,
+				}))
+				continue
+			}
+
 			entityMetadata := EntityMetadata{
 				EntityName: entityName,
 				TableName:  tableName,
 				StructName: structType.Name,
 				StructType: structType,
+				IdClass:    idClass,
+				Fields:     fields,
 			}
 
+			for index, field := range fields {
+				if field.IsId {
+					entityMetadata.IdFields = append(entityMetadata.IdFields, &entityMetadata.Fields[index])
+				}
+
+				if field.IsVersion {
+					entityMetadata.VersionField = &entityMetadata.Fields[index]
+				}
+
+				if field.IsSoftDelete {
+					entityMetadata.SoftDeleteField = &entityMetadata.Fields[index]
+				}
+			}
+
+			entityMetadata.IdField = entityMetadata.IdFields[0]
 			fullStructName := structType.File.Package.Path + "#" + structType.Name
 
 			entityMetadataByStructName[fullStructName] = entityMetadata
@@ -111,3 +224,747 @@ func FindEntities(structTypes []marker.StructType) {
 		}
 	}
 }
+
+func FindEntityFields(structType marker.StructType) ([]FieldMetadata, bool) {
+	fields := make([]FieldMetadata, 0)
+	idFieldCount := 0
+	embeddedIdCount := 0
+	versionFieldCount := 0
+	softDeleteFieldCount := 0
+
+	for _, field := range structType.Fields {
+		if !field.IsExported {
+			continue
+		}
+
+		if _, ok := field.Markers[shelf.MarkerEmbeddedId]; ok {
+			embeddedFields, err := FindEmbeddedFields(structType.File, field)
+
+			if err != nil {
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+				return nil, false
+			}
+
+			for index := range embeddedFields {
+				if embeddedFields[index].AuditMarker != "" {
+					err = fmt.Errorf("'%s' marker cannot be used with the fields of the embedded id '%s'",
+						embeddedFields[index].AuditMarker, field.Name)
+					errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+					return nil, false
+				}
+
+				embeddedFields[index].IsId = true
+				embeddedFields[index].KeyField = strings.TrimPrefix(embeddedFields[index].FieldName, field.Name+".")
+			}
+
+			fields = append(fields, embeddedFields...)
+			embeddedIdCount++
+			continue
+		}
+
+		if _, ok := field.Markers[shelf.MarkerEmbedded]; ok {
+			embeddedFields, err := FindEmbeddedFields(structType.File, field)
+
+			if err != nil {
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+				return nil, false
+			}
+
+			fields = append(fields, embeddedFields...)
+			continue
+		}
+
+		if _, ok := field.Markers[shelf.MarkerAttributeOverride]; ok {
+			err := fmt.Errorf("'%s' marker can only be used with '%s' or '%s' marker",
+				shelf.MarkerAttributeOverride, shelf.MarkerEmbedded, shelf.MarkerEmbeddedId)
+			errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+			return nil, false
+		}
+
+		if !IsColumnField(field) {
+			continue
+		}
+
+		fieldMetadata := FieldMetadata{
+			FieldName:  field.Name,
+			ColumnName: shelf.ToSnakeCase(field.Name),
+			Type:       field.Type,
+			Field:      field,
+			File:       structType.File,
+		}
+
+		for _, candidateMarker := range field.Markers[shelf.MarkerColumn] {
+			if columnMarker, ok := candidateMarker.(shelf.ColumnMarker); ok && strings.TrimSpace(columnMarker.Name) != "" {
+				fieldMetadata.ColumnName = strings.TrimSpace(columnMarker.Name)
+			}
+		}
+
+		enum, err := GetEnum(structType.File, field)
+
+		if err != nil {
+			errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+			return nil, false
+		}
+
+		fieldMetadata.Enum = enum
+
+		if _, ok := field.Markers[shelf.MarkerId]; ok {
+			fieldMetadata.IsId = true
+			idFieldCount++
+		}
+
+		if _, ok := field.Markers[shelf.MarkerGeneratedValue]; ok {
+			if !fieldMetadata.IsId {
+				err := fmt.Errorf("'%s' marker can only be used with '%s' marker", shelf.MarkerGeneratedValue, shelf.MarkerId)
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+				return nil, false
+			}
+
+			generation, err := GetGeneration(structType.File, field)
+
+			if err != nil {
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+				return nil, false
+			}
+
+			fieldMetadata.IsGenerated = true
+			fieldMetadata.Generation = generation
+		}
+
+		auditMarker, err := GetAuditMarker(structType.File, field)
+
+		if err == nil && auditMarker != "" && fieldMetadata.IsId {
+			err = fmt.Errorf("'%s' marker cannot be used with '%s' marker", auditMarker, shelf.MarkerId)
+		}
+
+		if err != nil {
+			errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+			return nil, false
+		}
+
+		fieldMetadata.AuditMarker = auditMarker
+
+		if _, ok := field.Markers[shelf.MarkerVersion]; ok {
+			if fieldMetadata.IsId {
+				err := fmt.Errorf("'%s' marker cannot be used with '%s' marker", shelf.MarkerVersion, shelf.MarkerId)
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+				return nil, false
+			}
+
+			if !IsVersionType(structType.File, field.Type) {
+				err := fmt.Errorf("the type of the field '%s' marked as '%s' must be an integer or time.Time", field.Name, shelf.MarkerVersion)
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+				return nil, false
+			}
+
+			fieldMetadata.IsVersion = true
+			versionFieldCount++
+		}
+
+		if _, ok := field.Markers[shelf.MarkerSoftDelete]; ok {
+			err := ValidateSoftDeleteField(fieldMetadata)
+
+			if err == nil && (fieldMetadata.IsId || fieldMetadata.IsVersion) {
+				err = fmt.Errorf("'%s' marker cannot be used with '%s' or '%s' marker", shelf.MarkerSoftDelete, shelf.MarkerId, shelf.MarkerVersion)
+			}
+
+			if err != nil {
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Position))
+				return nil, false
+			}
+
+			fieldMetadata.IsSoftDelete = true
+			softDeleteFieldCount++
+		}
+
+		fields = append(fields, fieldMetadata)
+	}
+
+	_, hasIdClass := structType.Markers[shelf.MarkerIdClass]
+
+	if embeddedIdCount > 1 {
+		err := fmt.Errorf("the entity '%s' cannot have more than one field marked as '%s'", structType.Name, shelf.MarkerEmbeddedId)
+		errs = append(errs, marker.NewError(err, structType.File.FullPath, structType.Position))
+		return nil, false
+	}
+
+	if embeddedIdCount == 1 && (idFieldCount != 0 || hasIdClass) {
+		err := fmt.Errorf("the entity '%s' with a field marked as '%s' cannot have a field marked as '%s' or be marked as '%s'",
+			structType.Name, shelf.MarkerEmbeddedId, shelf.MarkerId, shelf.MarkerIdClass)
+		errs = append(errs, marker.NewError(err, structType.File.FullPath, structType.Position))
+		return nil, false
+	}
+
+	if idFieldCount == 0 && embeddedIdCount == 0 {
+		err := fmt.Errorf("the entity '%s' must have a field marked as '%s'", structType.Name, shelf.MarkerId)
+		errs = append(errs, marker.NewError(err, structType.File.FullPath, structType.Position))
+		return nil, false
+	}
+
+	if idFieldCount > 1 && !hasIdClass {
+		err := fmt.Errorf("the entity '%s' cannot have more than one field marked as '%s' unless it is marked as '%s'",
+			structType.Name, shelf.MarkerId, shelf.MarkerIdClass)
+		errs = append(errs, marker.NewError(err, structType.File.FullPath, structType.Position))
+		return nil, false
+	}
+
+	for index, field := range fields {
+		if !field.IsId || embeddedIdCount == 0 && !hasIdClass {
+			continue
+		}
+
+		if field.IsGenerated {
+			err := fmt.Errorf("'%s' marker cannot be used with the fields of a composite key", shelf.MarkerGeneratedValue)
+			errs = append(errs, marker.NewError(err, structType.File.FullPath, field.Field.Position))
+			return nil, false
+		}
+
+		if hasIdClass {
+			fields[index].KeyField = field.FieldName
+		}
+	}
+
+	if versionFieldCount > 1 {
+		err := fmt.Errorf("the entity '%s' cannot have more than one field marked as '%s'", structType.Name, shelf.MarkerVersion)
+		errs = append(errs, marker.NewError(err, structType.File.FullPath, structType.Position))
+		return nil, false
+	}
+
+	if softDeleteFieldCount > 1 {
+		err := fmt.Errorf("the entity '%s' cannot have more than one field marked as '%s'", structType.Name, shelf.MarkerSoftDelete)
+		errs = append(errs, marker.NewError(err, structType.File.FullPath, structType.Position))
+		return nil, false
+	}
+
+	fieldsByColumnName := make(map[string]string)
+
+	for _, field := range fields {
+		if other, ok := fieldsByColumnName[field.ColumnName]; ok {
+			err := fmt.Errorf("the fields '%s' and '%s' of the entity '%s' cannot be mapped to the same column '%s'",
+				other, field.FieldName, structType.Name, field.ColumnName)
+			errs = append(errs, marker.NewError(err, structType.File.FullPath, structType.Position))
+			return nil, false
+		}
+
+		fieldsByColumnName[field.ColumnName] = field.FieldName
+	}
+
+	auditFieldCounts := make(map[string]int)
+
+	for _, field := range fields {
+		if field.AuditMarker == "" {
+			continue
+		}
+
+		auditFieldCounts[field.AuditMarker]++
+
+		if auditFieldCounts[field.AuditMarker] > 1 {
+			err := fmt.Errorf("the entity '%s' cannot have more than one field marked as '%s'", structType.Name, field.AuditMarker)
+			errs = append(errs, marker.NewError(err, structType.File.FullPath, structType.Position))
+			return nil, false
+		}
+	}
+
+	return fields, true
+}
+
+// GetGeneration returns the generation strategy of the id field marked as shelf:generated-value, and checks
+// that the strategy can generate the values of the field type. The SEQUENCE and TABLE strategies allocate the
+// integer ids in blocks, IDENTITY and SNOWFLAKE generate integers and UUID and ULID generate strings.
+func GetGeneration(file *marker.File, field marker.Field) (GenerationMetadata, error) {
+	generation := GenerationMetadata{
+		Strategy: GenerationIdentity,
+	}
+
+	for _, candidateMarker := range field.Markers[shelf.MarkerGeneratedValue] {
+		generatedValueMarker, ok := candidateMarker.(shelf.GeneratedValueMarker)
+
+		if !ok {
+			continue
+		}
+
+		if strategy := strings.TrimSpace(generatedValueMarker.Strategy); strategy != "" {
+			generation.Strategy = strategy
+		}
+
+		generation.SequenceName = strings.TrimSpace(generatedValueMarker.SequenceName)
+		generation.AllocationSize = generatedValueMarker.AllocationSize
+		generation.Generator = strings.TrimSpace(generatedValueMarker.Generator)
+	}
+
+	switch generation.Strategy {
+	case GenerationSequence, GenerationTable:
+		if generation.AllocationSize == 0 {
+			generation.AllocationSize = DefaultAllocationSize
+		}
+	case GenerationUUID, GenerationULID, GenerationSnowflake:
+		generation.Generator = generation.Strategy
+	}
+
+	typeName := getUnderlyingType(file, field.Type)
+
+	switch generation.Strategy {
+	case GenerationUUID, GenerationULID:
+		if typeName != "string" {
+			return generation, fmt.Errorf("the id field '%s' generated by the %s strategy must be a string", field.Name, generation.Strategy)
+		}
+	case GenerationSnowflake:
+		if typeName != "int" && typeName != "int64" && typeName != "uint64" {
+			return generation, fmt.Errorf("the id field '%s' generated by the %s strategy must be an int, int64 or uint64",
+				field.Name, generation.Strategy)
+		}
+	case GenerationCustom:
+	default:
+		switch typeName {
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		default:
+			return generation, fmt.Errorf("the id field '%s' generated by the %s strategy must be an integer", field.Name, generation.Strategy)
+		}
+	}
+
+	return generation, nil
+}
+
+// ValidateGenerationStrategies checks that the strategies of the generated ids are supported by the dialect.
+func ValidateGenerationStrategies(dialect Dialect) {
+	for _, entity := range entityMetadataByStructName {
+		idField := entity.IdField
+
+		if !idField.IsGenerated || idField.Generation.Strategy != GenerationSequence || dialect.SupportsSequences {
+			continue
+		}
+
+		err := fmt.Errorf("the %s strategy of the id field '%s' is not supported by %s, which has no sequences",
+			GenerationSequence, idField.FieldName, dialect.Name)
+		errs = append(errs, marker.NewError(err, entity.StructType.File.FullPath, idField.Field.Position))
+	}
+}
+
+// FindIdClass returns the struct holding the composite key of the entity, or nil if the key is not composite.
+// The struct given by shelf:id-class must declare the fields marked as shelf:id with the same types, and no
+// other fields.
+func FindIdClass(structType marker.StructType, fields []FieldMetadata) (*marker.StructType, error) {
+	for _, field := range structType.Fields {
+		if _, ok := field.Markers[shelf.MarkerEmbeddedId]; ok {
+			idClass := structTypesByQualifiedName[GetQualifiedNameFromType(structType.File, field.Type)]
+			return &idClass, nil
+		}
+	}
+
+	idClassName := ""
+
+	for _, candidateMarker := range structType.Markers[shelf.MarkerIdClass] {
+		if idClassMarker, ok := candidateMarker.(shelf.IdClassMarker); ok {
+			idClassName = strings.TrimSpace(idClassMarker.Name)
+		}
+	}
+
+	if idClassName == "" {
+		return nil, nil
+	}
+
+	idClass, ok := structTypesByQualifiedName[structType.File.Package.Path+"."+idClassName]
+
+	if !ok {
+		return nil, fmt.Errorf("the struct '%s' given by '%s' marker must be declared in the package of the entity '%s'",
+			idClassName, shelf.MarkerIdClass, structType.Name)
+	}
+
+	keyFields := make(map[string]marker.Field)
+
+	for _, field := range idClass.Fields {
+		if field.IsExported {
+			keyFields[field.Name] = field
+		}
+	}
+
+	idFieldCount := 0
+
+	for _, field := range fields {
+		if !field.IsId {
+			continue
+		}
+
+		keyField, ok := keyFields[field.FieldName]
+
+		if !ok || GetQualifiedNameFromType(idClass.File, keyField.Type) != GetQualifiedNameFromType(field.File, field.Type) {
+			return nil, fmt.Errorf("the struct '%s' must have a field '%s' of the type of the id field of the entity '%s'",
+				idClassName, field.FieldName, structType.Name)
+		}
+
+		idFieldCount++
+	}
+
+	if idFieldCount != len(keyFields) {
+		return nil, fmt.Errorf("the struct '%s' can only have the fields marked as '%s' in the entity '%s'",
+			idClassName, shelf.MarkerId, structType.Name)
+	}
+
+	return &idClass, nil
+}
+
+// GetEnum returns the mapping of the field marked as shelf:enumerated, or nil if the field is not an enum. The
+// constants of the enum are found in the const declarations of the package of the type, e.g. an iota block.
+func GetEnum(file *marker.File, field marker.Field) (*EnumMetadata, error) {
+	mapping := ""
+
+	for _, candidateMarker := range field.Markers[shelf.MarkerEnumerated] {
+		if enumeratedMarker, ok := candidateMarker.(shelf.EnumeratedMarker); ok {
+			mapping = strings.TrimSpace(enumeratedMarker.Value)
+		}
+	}
+
+	if mapping == "" {
+		return nil, nil
+	}
+
+	qualifiedName := GetQualifiedNameFromType(file, field.Type)
+	userDefinedType, ok := userDefinedTypesByQualifiedName[qualifiedName]
+
+	switch getUnderlyingType(file, field.Type) {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+	default:
+		ok = false
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("the type of the field '%s' marked as '%s' must be a named integer type, e.g. type Status int",
+			field.Name, shelf.MarkerEnumerated)
+	}
+
+	enum := &EnumMetadata{
+		Name:    userDefinedType.Name,
+		File:    userDefinedType.File,
+		Ordinal: mapping == "ORDINAL",
+	}
+
+	for _, constant := range constantsByQualifiedTypeName[qualifiedName] {
+		enum.Constants = append(enum.Constants, constant.Name)
+	}
+
+	if len(enum.Constants) == 0 {
+		return nil, fmt.Errorf("the enum type '%s' of the field '%s' has no constants", userDefinedType.Name, field.Name)
+	}
+
+	// the String method must be callable on the constants
+	for _, method := range userDefinedType.Methods {
+		if method.Name != "String" || len(method.Parameters) != 0 || len(method.ReturnValues) != 1 ||
+			GetFullNameFromType(method.ReturnValues[0].Type) != "string" {
+			continue
+		}
+
+		if _, isPointer := method.Receiver.Type.(*marker.PointerType); !isPointer {
+			enum.IsStringer = true
+		}
+	}
+
+	return enum, nil
+}
+
+// FindEmbeddedFields returns the fields of the embeddable struct embedded into an entity by the field.
+// The columns of the embedded fields are named after the fields with the prefix of the shelf:embedded
+// marker, unless they are overridden by the shelf:attribute-override markers of the embedding field.
+// The columns of an embedded id are not prefixed, as they are named after the fields of the key.
+func FindEmbeddedFields(file *marker.File, field marker.Field) ([]FieldMetadata, error) {
+	prefix := ""
+
+	if _, ok := field.Markers[shelf.MarkerEmbedded]; ok {
+		prefix = GetEmbeddedPrefix(field)
+	}
+
+	return findEmbeddedFields(file, field, field.Name, prefix, nil)
+}
+
+// GetEmbeddedPrefix returns the prefix of the columns of the fields embedded by the field, which is the
+// name of the field in snake case followed by an underscore unless it is given by the shelf:embedded marker.
+func GetEmbeddedPrefix(field marker.Field) string {
+	prefix := shelf.ToSnakeCase(field.Name) + "_"
+
+	for _, candidateMarker := range field.Markers[shelf.MarkerEmbedded] {
+		if embeddedMarker, ok := candidateMarker.(shelf.EmbeddedMarker); ok {
+			if embeddedMarker.NoPrefix {
+				prefix = ""
+			} else if strings.TrimSpace(embeddedMarker.Prefix) != "" {
+				prefix = strings.TrimSpace(embeddedMarker.Prefix)
+			}
+		}
+	}
+
+	return prefix
+}
+
+// findEmbeddedFields returns the fields of the embeddable embedded by the field at the path, including the
+// fields of the nested embeddables, whose prefixes are appended to the prefix. The embeddables holds the
+// embeddables the field is nested in, which cannot be embedded again.
+func findEmbeddedFields(file *marker.File, field marker.Field, path string, prefix string, embeddables []string) ([]FieldMetadata, error) {
+	qualifiedName := GetQualifiedNameFromType(file, field.Type)
+	structType, ok := structTypesByQualifiedName[qualifiedName]
+
+	if !ok {
+		return nil, fmt.Errorf("the type of the field '%s' marked as '%s' must be a struct", path, shelf.MarkerEmbedded)
+	}
+
+	if _, ok := structType.Markers[shelf.MarkerEmbeddable]; !ok {
+		return nil, fmt.Errorf("the type of the field '%s' must be marked as '%s'", path, shelf.MarkerEmbeddable)
+	}
+
+	for _, embeddable := range embeddables {
+		if embeddable == qualifiedName {
+			return nil, fmt.Errorf("the embeddable '%s' cannot embed itself through the field '%s'", structType.Name, path)
+		}
+	}
+
+	embeddables = append(embeddables, qualifiedName)
+	fields := make([]FieldMetadata, 0)
+
+	for _, embeddedField := range structType.Fields {
+		if !embeddedField.IsExported {
+			continue
+		}
+
+		if _, ok := embeddedField.Markers[shelf.MarkerEmbedded]; ok {
+			nestedFields, err := findEmbeddedFields(structType.File, embeddedField, path+"."+embeddedField.Name,
+				prefix+GetEmbeddedPrefix(embeddedField), embeddables)
+
+			if err != nil {
+				return nil, err
+			}
+
+			fields = append(fields, nestedFields...)
+			continue
+		}
+
+		if !IsColumnField(embeddedField) {
+			continue
+		}
+
+		fieldMetadata := FieldMetadata{
+			FieldName:  path + "." + embeddedField.Name,
+			ColumnName: prefix + shelf.ToSnakeCase(embeddedField.Name),
+			Type:       embeddedField.Type,
+			Field:      embeddedField,
+			File:       structType.File,
+		}
+
+		// the names given by the shelf:column markers are not prefixed
+		for _, candidateMarker := range embeddedField.Markers[shelf.MarkerColumn] {
+			if columnMarker, ok := candidateMarker.(shelf.ColumnMarker); ok && strings.TrimSpace(columnMarker.Name) != "" {
+				fieldMetadata.ColumnName = strings.TrimSpace(columnMarker.Name)
+			}
+		}
+
+		auditMarker, err := GetAuditMarker(structType.File, embeddedField)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fieldMetadata.AuditMarker = auditMarker
+		fieldMetadata.Enum, err = GetEnum(structType.File, embeddedField)
+
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, fieldMetadata)
+	}
+
+	err := applyAttributeOverrides(structType, field, path, fields)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// applyAttributeOverrides sets the columns of the embedded fields overridden by the shelf:attribute-override
+// markers of the field at the path. The overrides of the outer fields are applied after the nested ones, so
+// they take precedence.
+func applyAttributeOverrides(structType marker.StructType, field marker.Field, path string, fields []FieldMetadata) error {
+	overridden := make(map[string]bool)
+
+	for _, candidateMarker := range field.Markers[shelf.MarkerAttributeOverride] {
+		overrideMarker, ok := candidateMarker.(shelf.AttributeOverrideMarker)
+
+		if !ok {
+			continue
+		}
+
+		name := strings.TrimSpace(overrideMarker.Name)
+
+		if overridden[name] {
+			return fmt.Errorf("the field '%s' of the embeddable '%s' cannot be overridden more than once", name, structType.Name)
+		}
+
+		overridden[name] = true
+		found := false
+
+		for index := range fields {
+			if fields[index].FieldName == path+"."+name {
+				fields[index].ColumnName = strings.TrimSpace(overrideMarker.ColumnName)
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("the embeddable '%s' has no column field '%s' to be overridden by the field '%s'",
+				structType.Name, name, path)
+		}
+	}
+
+	return nil
+}
+
+// GetPropertyName returns the name of the entity property, which is the path of the field without
+// the dots, e.g. AddressCity for Address.City.
+func GetPropertyName(fieldName string) string {
+	return strings.Replace(fieldName, ".", "", -1)
+}
+
+// GetAuditMarker returns the audit marker of the field, or an empty string if the field is not audited.
+func GetAuditMarker(file *marker.File, field marker.Field) (string, error) {
+	auditMarkers := []string{
+		shelf.MarkerCreatedDate,
+		shelf.MarkerLastModifiedDate,
+		shelf.MarkerCreatedBy,
+		shelf.MarkerLastModifiedBy,
+	}
+
+	auditMarker := ""
+
+	for _, markerName := range auditMarkers {
+		if _, ok := field.Markers[markerName]; !ok {
+			continue
+		}
+
+		if auditMarker != "" {
+			return "", fmt.Errorf("the field '%s' cannot be marked as both '%s' and '%s'", field.Name, auditMarker, markerName)
+		}
+
+		isDate := markerName == shelf.MarkerCreatedDate || markerName == shelf.MarkerLastModifiedDate
+
+		if isDate && GetQualifiedNameFromType(file, field.Type) != "time.Time" {
+			return "", fmt.Errorf("the type of the field '%s' marked as '%s' must be time.Time", field.Name, markerName)
+		}
+
+		auditMarker = markerName
+	}
+
+	return auditMarker, nil
+}
+
+// IsVersionType reports whether the values of the type can be used as the version of an entity.
+func IsVersionType(file *marker.File, typ marker.Type) bool {
+	switch GetQualifiedNameFromType(file, typ) {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "time.Time":
+		return true
+	}
+
+	return false
+}
+
+// ValidateSoftDeleteField checks that the field marked as shelf:soft-delete is a bool or a nullable time,
+// or a status whose deleted value is given by the marker. The deleted value of an enum mapped by the names
+// of its constants must be one of the names.
+func ValidateSoftDeleteField(field FieldMetadata) error {
+	value := getSoftDeleteValue(field.Field)
+	fieldName := field.Field.Name
+
+	switch getUnderlyingType(field.File, field.Type) {
+	case "bool", "*time.Time", "database/sql.NullTime":
+		if value != "" {
+			return fmt.Errorf("the deleted value of the field '%s' cannot be given, since it is not a status", fieldName)
+		}
+
+		return nil
+	case "string":
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		if field.Enum != nil && !field.Enum.Ordinal {
+			if value != "" && !field.Enum.IsStringer && !containsString(field.Enum.Constants, value) {
+				return fmt.Errorf("the deleted value of the field '%s' must be one of the constants of '%s'", fieldName, field.Enum.Name)
+			}
+		} else if _, err := strconv.ParseInt(value, 10, 64); value != "" && err != nil {
+			return fmt.Errorf("the deleted value of the field '%s' must be an integer", fieldName)
+		}
+	default:
+		return fmt.Errorf("the type of the field '%s' marked as '%s' must be a bool, a nullable time, "+
+			"a string or an integer", fieldName, shelf.MarkerSoftDelete)
+	}
+
+	if value == "" {
+		return fmt.Errorf("the deleted value of the status field '%s' must be given, e.g. %s=\"DELETED\"", fieldName, shelf.MarkerSoftDelete)
+	}
+
+	return nil
+}
+
+// GetSoftDeleteConditions returns the assignment marking an entity as deleted and the condition matching
+// the entities not deleted, e.g. deleted = TRUE and deleted = FALSE.
+func GetSoftDeleteConditions(field FieldMetadata) (string, string) {
+	column := field.ColumnName
+	value := getSoftDeleteValue(field.Field)
+	typeName := getUnderlyingType(field.File, field.Type)
+
+	// the enums mapped by the names of their constants are stored as strings
+	if field.Enum != nil && !field.Enum.Ordinal {
+		typeName = "string"
+	}
+
+	switch typeName {
+	case "bool":
+		return column + " = TRUE", column + " = FALSE"
+	case "*time.Time", "database/sql.NullTime":
+		return column + " = CURRENT_TIMESTAMP", column + " IS NULL"
+	case "string":
+		value = "'" + strings.Replace(value, "'", "''", -1) + "'"
+	}
+
+	return column + " = " + value, column + " <> " + value
+}
+
+func getSoftDeleteValue(field marker.Field) string {
+	for _, candidateMarker := range field.Markers[shelf.MarkerSoftDelete] {
+		if softDeleteMarker, ok := candidateMarker.(shelf.SoftDeleteMarker); ok {
+			return strings.TrimSpace(softDeleteMarker.Value)
+		}
+	}
+
+	return ""
+}
+
+// getUnderlyingType returns the qualified name of the type, or the builtin type it is declared
+// with if it is a named type such as a status enum.
+func getUnderlyingType(file *marker.File, typ marker.Type) string {
+	qualifiedName := GetQualifiedNameFromType(file, typ)
+
+	if userDefinedType, ok := userDefinedTypesByQualifiedName[qualifiedName]; ok {
+		if objectType, ok := userDefinedType.ActualType.(*marker.ObjectType); ok && objectType.ImportName == "" && IsBuiltinType(objectType.Name) {
+			return objectType.Name
+		}
+	}
+
+	return qualifiedName
+}
+
+// IsColumnField reports whether the field is mapped to a column of the entity table.
+func IsColumnField(field marker.Field) bool {
+	nonColumnMarkers := []string{
+		shelf.MarkerTransient,
+		shelf.MarkerEmbedded,
+		shelf.MarkerEmbeddedId,
+		shelf.MarkerOneToOne,
+		shelf.MarkerOneToMany,
+		shelf.MarkerManyToOne,
+		shelf.MarkerManyToMany,
+	}
+
+	for _, markerName := range nonColumnMarkers {
+		if _, ok := field.Markers[markerName]; ok {
+			return false
+		}
+	}
+
+	return true
+}
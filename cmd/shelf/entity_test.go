@@ -0,0 +1,207 @@
+package main
+
+import (
+	"testing"
+)
+
+// embeddedSource is the package of the embedded field tests, to which the entities are appended.
+const embeddedSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:embeddable
+type Address struct {
+	City string
+	// +shelf:column=zip
+	PostCode string
+}
+
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// +shelf:embeddable
+type Contact struct {
+	Phone string
+	// +shelf:embedded
+	Address Address
+}
+`
+
+func TestValidate_EmbeddedFields(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Field  string
+		Errors []string
+	}{
+		{
+			Name: "embedded non-struct",
+			Field: `// +shelf:embedded
+	Address string`,
+			Errors: []string{
+				"the type of the field 'Address' marked as 'shelf:embedded' must be a struct",
+			},
+		},
+		{
+			Name: "embedded non-embeddable",
+			Field: `// +shelf:embedded
+	Location Location`,
+			Errors: []string{
+				"the type of the field 'Location' must be marked as 'shelf:embeddable'",
+			},
+		},
+		{
+			Name: "attribute override of a non-embedded field",
+			Field: `// +shelf:attribute-override=City, ColumnName="city"
+	City string`,
+			Errors: []string{
+				"'shelf:attribute-override' marker can only be used with 'shelf:embedded' or 'shelf:embedded-id' marker",
+			},
+		},
+		{
+			Name: "attribute override of an unknown field",
+			Field: `// +shelf:embedded
+	// +shelf:attribute-override=Country, ColumnName="country"
+	Address Address`,
+			Errors: []string{
+				"the embeddable 'Address' has no column field 'Country' to be overridden by the field 'Address'",
+			},
+		},
+		{
+			Name: "attribute overridden twice",
+			Field: `// +shelf:embedded
+	// +shelf:attribute-override=City, ColumnName="city"
+	// +shelf:attribute-override=City, ColumnName="town"
+	Address Address`,
+			Errors: []string{
+				"the field 'City' of the embeddable 'Address' cannot be overridden more than once",
+			},
+		},
+		{
+			Name: "prefix with NoPrefix",
+			Field: `// +shelf:embedded:Prefix="home_",NoPrefix=true
+	Address Address`,
+			Errors: []string{
+				"'Prefix' cannot be used with 'NoPrefix'",
+			},
+		},
+		{
+			Name: "embeddables of the same columns",
+			Field: `// +shelf:embedded:NoPrefix=true
+	Home Address
+	// +shelf:embedded:NoPrefix=true
+	Work Address`,
+			Errors: []string{
+				"the fields 'Home.City' and 'Work.City' of the entity 'User' cannot be mapped to the same column 'city'",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			source := embeddedSource + `
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	Id int
+	` + testCase.Field + `
+}
+
+var _ context.Context
+`
+
+			assertErrors(t, validate(t, "fixture", source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_EmbeddedFields(t *testing.T) {
+	repository := `
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Email string
+	// +shelf:embedded
+	// +shelf:attribute-override=City, ColumnName="home_city"
+	Address Address
+}
+
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Save(ctx context.Context, user *User) error
+	FindByAddressCity(ctx context.Context, city string) ([]*User, error)
+	FindAll(ctx context.Context) ([]*User, error)
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				`const userColumns = "id, email, home_city, zip"`,
+				"&entity.Address.City,\n\t\t&entity.Address.PostCode,",
+				`func scanUser(scanner shelf.RowScanner, entity *User) error {`,
+				`AddressCity:     stringAttribute{shelf.Attribute{Column: "home_city"}},`,
+				`"INSERT INTO users(email, home_city, zip) VALUES($1, $2, $3) RETURNING id", user.Email, user.Address.City, user.Address.PostCode`,
+				`"UPDATE users SET email = $1, home_city = $2, zip = $3 WHERE id = $4", user.Email, user.Address.City, user.Address.PostCode, user.Id`,
+				`query := "SELECT id, email, home_city, zip FROM users WHERE home_city = $1"`,
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`"INSERT INTO users(email, home_city, zip) VALUES(?, ?, ?)", user.Email, user.Address.City, user.Address.PostCode`,
+				`query := "SELECT id, email, home_city, zip FROM users WHERE home_city = ?"`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", embeddedSource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
+
+// TestGenerate_EmbeddedPrefixes checks that the columns of the embedded fields are prefixed by the names of the
+// embedding fields, unless they are given by the markers, and that the nested embeddables are flattened.
+// The columns named by shelf:column are not prefixed, so they are overridden where Address is embedded again.
+func TestGenerate_EmbeddedPrefixes(t *testing.T) {
+	repositories := generate(t, "fixture", embeddedSource+`
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	Id int
+	// +shelf:embedded
+	Home Address
+	// +shelf:embedded:Prefix="office_"
+	// +shelf:attribute-override=PostCode, ColumnName="office_zip"
+	Work Address
+	// +shelf:embedded
+	// +shelf:attribute-override="Address.PostCode", ColumnName="contact_zip"
+	Contact Contact
+}
+
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindByContactAddressCity(ctx context.Context, city string) ([]*User, error)
+}`)
+
+	assertContains(t, repositories,
+		`const userColumns = "id, home_city, zip, office_city, office_zip, contact_phone, contact_address_city, contact_zip"`,
+		"&entity.Contact.Address.City,",
+		`query := "SELECT id, home_city, zip, office_city, office_zip, contact_phone, contact_address_city, contact_zip FROM users WHERE contact_address_city = $1"`,
+	)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// enumSource is the package of the enum tests, whose tasks store their statuses by name, their priorities by
+// value and their labels by the names returned by the String method of Priority.
+const enumSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+
+	"github.com/procyon-projects/shelf"
+)
+
+type TaskStatus int
+
+const (
+	Open TaskStatus = iota
+	Done
+)
+
+type Priority int
+
+const (
+	Low Priority = iota + 1
+	High
+)
+
+func (priority Priority) String() string {
+	if priority == High {
+		return "high"
+	}
+
+	return "low"
+}
+
+// +shelf:entity
+// +shelf:table=tasks
+type Task struct {
+	// +shelf:id
+	Id int
+	// +shelf:enumerated=STRING
+	Status TaskStatus
+	// +shelf:enumerated=ORDINAL
+	Priority Priority
+	// +shelf:enumerated=STRING
+	Label Priority
+}
+`
+
+func TestValidate_InvalidEnums(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Source string
+		Errors []string
+	}{
+		{
+			Name: "enums of the wrong types",
+			Source: `
+type Color string
+
+const Red Color = "red"
+
+type Size int
+
+// +shelf:entity
+type Shirt struct {
+	// +shelf:id
+	Id int
+	// +shelf:enumerated=STRING
+	Color Color
+}
+
+// +shelf:entity
+type Hat struct {
+	// +shelf:id
+	Id int
+	// +shelf:enumerated=ORDINAL
+	Size Size
+}`,
+			Errors: []string{
+				"the type of the field 'Color' marked as 'shelf:enumerated' must be a named integer type, e.g. type Status int",
+				"the enum type 'Size' of the field 'Size' has no constants",
+			},
+		},
+		{
+			Name: "ordered comparisons of the enums stored by name",
+			Source: `
+// +shelf:repository="task-repository", Entity=Task
+type TaskRepository interface {
+	FindByStatusGreaterThan(ctx context.Context, status TaskStatus) ([]*Task, error)
+	CountByLabelBetween(ctx context.Context, from Priority, to Priority) (int64, error)
+}`,
+			Errors: []string{
+				"the method 'FindByStatusGreaterThan' cannot compare the order of the property 'Status', since its enum is stored by name",
+				"the method 'CountByLabelBetween' cannot compare the order of the property 'Label', since its enum is stored by name",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assertErrors(t, validate(t, "fixture", enumSource+testCase.Source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_Enums(t *testing.T) {
+	repository := `
+// +shelf:repository="task-repository", Entity=Task
+type TaskRepository interface {
+	FindByStatus(ctx context.Context, status TaskStatus) ([]*Task, error)
+	FindByStatusIn(ctx context.Context, statuses []TaskStatus) ([]*Task, error)
+	FindByPriorityGreaterThan(ctx context.Context, priority Priority) ([]*Task, error)
+	CountByPriorityBetween(ctx context.Context, from Priority, to Priority) (int64, error)
+	FindByLabel(ctx context.Context, label Priority) ([]*Task, error)
+	FindAll(ctx context.Context, specification shelf.Specification) ([]*Task, error)
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				`query := "SELECT id, status, priority, label FROM tasks WHERE status = $1"`,
+				"rows, err := repository.executor(ctx).QueryContext(ctx, query, taskStatusName(status))",
+				"query, args := shelf.Dollar.Bind(query, taskStatusNameSlice(statuses))",
+				`query := "SELECT id, status, priority, label FROM tasks WHERE priority > $1"`,
+				"rows, err := repository.executor(ctx).QueryContext(ctx, query, priorityOrdinal(priority))",
+				`query := "SELECT COUNT(*) FROM tasks WHERE priority BETWEEN $1 AND $2"`,
+				"err := repository.executor(ctx).QueryRowContext(ctx, query, priorityOrdinal(from), priorityOrdinal(to)).Scan(&count)",
+				"rows, err := repository.executor(ctx).QueryContext(ctx, query, priorityName(label))",
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`query := "SELECT id, status, priority, label FROM tasks WHERE status = ?"`,
+				"query, args := shelf.Question.Bind(query, taskStatusNameSlice(statuses))",
+				`query := "SELECT COUNT(*) FROM tasks WHERE priority BETWEEN ? AND ?"`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", enumSource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+
+			// the constants are stored by their declared names unless the enum has a String method
+			assertContains(t, repositories,
+				"type taskStatusName TaskStatus",
+				"type priorityOrdinal Priority",
+				"type priorityName Priority",
+				"var taskStatusNames = []string{\n\t\"Open\",\n\t\"Done\",\n}",
+				"var priorityNames = []string{\n\tLow.String(),\n\tHigh.String(),\n}",
+				"(*taskStatusName)(&entity.Status),",
+				"(*priorityOrdinal)(&entity.Priority),",
+				"(*priorityName)(&entity.Label),",
+				`return nil, &shelf.UnknownEnumValueError{Enum: "TaskStatus", Value: TaskStatus(value)}`,
+				"Status:   taskStatusNameAttribute{shelf.Attribute{Column: \"status\"}},",
+				"func (attribute priorityOrdinalAttribute) GreaterThan(value Priority) shelf.Predicate {",
+				"func (attribute taskStatusNameAttribute) In(values ...TaskStatus) shelf.Predicate {",
+			)
+
+			if strings.Contains(repositories, "func (attribute taskStatusNameAttribute) GreaterThan(") ||
+				strings.Contains(repositories, "func (attribute priorityNameAttribute) Between(") {
+				t.Errorf("the attributes of the enums stored by name should not compare the order of the values")
+			}
+		})
+	}
+}
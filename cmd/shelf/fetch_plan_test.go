@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestValidate_FetchPlans(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Source string
+		Errors []string
+	}{
+		{
+			Name: "method not returning the entities",
+			Source: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:fetch="Posts"
+	Count(ctx context.Context) (int64, error)
+}`,
+			Errors: []string{
+				"'shelf:fetch' marker can only be used with the methods returning the entities",
+			},
+		},
+		{
+			Name: "path of a field which is not an association",
+			Source: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:fetch="Posts.Title"
+	FindAll(ctx context.Context) ([]*User, error)
+	// +shelf:fetch="Email"
+	FindById(ctx context.Context, id int) (*User, error)
+}`,
+			Errors: []string{
+				"the field 'Title' of the fetch path 'Posts.Title' is not an association of the entity 'Post'",
+				"the field 'Email' of the fetch path 'Email' is not an association of the entity 'User'",
+			},
+		},
+		{
+			Name: "fluent method of a field which is not an association",
+			Source: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	LoadEmail() UserRepository
+}`,
+			Errors: []string{
+				"repository methods must take in one parameter of type context.Context at least",
+			},
+		},
+		{
+			Name: "fluent method returning an error",
+			Source: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	LoadTags() (UserRepository, error)
+}`,
+			Errors: []string{
+				"repository methods must take in one parameter of type context.Context at least",
+				"the reserved method 'LoadTags' must be in the form of 'LoadTags(context.Context, *User) error' or " +
+					"'LoadTags(context.Context, []*User) error' or 'LoadTags() UserRepository'",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assertErrors(t, validate(t, "fixture", associationSource+testCase.Source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_FetchPlans(t *testing.T) {
+	repositories := generate(t, "fixture", associationSource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	LoadTags() UserRepository
+	FindAll(ctx context.Context) ([]*User, error)
+	// +shelf:fetch="Posts.Author, Tags"
+	FindById(ctx context.Context, id int) (*User, error)
+}`)
+
+	assertContains(t, repositories,
+		// the fluent methods return a copy of the repository
+		"copied := *repository",
+		`copied.fetch = repository.fetch.With("Tags")`,
+		"return &copied",
+		"err = fetchUser(ctx, repository.db, shelf.FetchPlanOf(ctx, repository.fetch), entities...)",
+		`err = fetchUser(ctx, repository.db, shelf.FetchPlanOf(ctx, repository.fetch, shelf.NewFetchPlan("Posts.Author", "Tags")), entity)`,
+		// the eager associations are fetched without a plan, the nested paths by the fetchers of the targets
+		`plan = shelf.NewFetchPlan("CreditCard", "Posts")`,
+		`err := plan.Validate("User", "CreditCard", "Posts", "Tags")`,
+		"err = fetchPost(ctx, db, nested, targets...)",
+		`if _, ok := plan["Tags"]; !ok {`,
+	)
+}
@@ -30,10 +30,17 @@ var generateCmd = &cobra.Command{
 	Short: "Generate Go files by processing markers",
 	Long:  `The generate command helps your code generation process by processing markers`,
 	Run: func(cmd *cobra.Command, args []string) {
+		dialect, err := GetDialectOption(options)
+
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
 		packages, err := marker.LoadPackages(paths...)
 
 		if err != nil {
-			log.Errorln(err)
+			log.Println(err)
 			return
 		}
 
@@ -41,7 +48,7 @@ var generateCmd = &cobra.Command{
 		err = RegisterDefinitions(registry)
 
 		if err != nil {
-			log.Errorln(err)
+			log.Println(err)
 			return
 		}
 
@@ -53,7 +60,21 @@ var generateCmd = &cobra.Command{
 			return
 		}
 
-		err = ProcessMarkers(collector, packages)
+		err = ProcessMarkers(collector, packages, dialect)
+
+		if err != nil {
+			PrintError(err)
+			return
+		}
+
+		err = GenerateRepositories(outputPath, dialect)
+
+		if err != nil {
+			PrintError(err)
+			return
+		}
+
+		err = GenerateMetamodels(outputPath)
 
 		if err != nil {
 			PrintError(err)
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"testing"
+)
+
+// generatedValueSource is the package of the id generation tests, whose orders are numbered by a sequence,
+// invoices by the id table and tickets by UUIDs.
+const generatedValueSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=invoices
+type Invoice struct {
+	// +shelf:id
+	// +shelf:generated-value:Strategy=TABLE
+	Id     int
+	Number string
+	// +shelf:version
+	Version int
+}
+
+type TicketId string
+
+// +shelf:entity
+// +shelf:table=tickets
+type Ticket struct {
+	// +shelf:id
+	// +shelf:generated-value:Strategy=UUID
+	Id    TicketId
+	Title string
+}
+`
+
+// orderSource is the entity whose ids are allocated from a sequence, which is only supported by postgres.
+const orderSource = `
+// +shelf:entity
+// +shelf:table=orders
+type Order struct {
+	// +shelf:id
+	// +shelf:generated-value:Strategy=SEQUENCE,SequenceName=order_ids,AllocationSize=20
+	Id    int64
+	Total int
+}`
+
+func TestGenerate_InvalidGeneratedValues(t *testing.T) {
+	testCases := []struct {
+		Name    string
+		Source  string
+		Dialect string
+		Errors  []string
+	}{
+		{
+			Name: "ids of the wrong types",
+			Source: `
+// +shelf:entity
+type Coupon struct {
+	// +shelf:id
+	// +shelf:generated-value:Strategy=UUID
+	Id int
+}
+
+// +shelf:entity
+type Voucher struct {
+	// +shelf:id
+	// +shelf:generated-value:Strategy=SNOWFLAKE
+	Id int32
+}
+
+// +shelf:entity
+type Receipt struct {
+	// +shelf:id
+	// +shelf:generated-value:Strategy=SEQUENCE
+	Id string
+}`,
+			Errors: []string{
+				"the id field 'Id' generated by the UUID strategy must be a string",
+				"the id field 'Id' generated by the SNOWFLAKE strategy must be an int, int64 or uint64",
+				"the id field 'Id' generated by the SEQUENCE strategy must be an integer",
+			},
+		},
+		{
+			Name:    "sequences of mysql",
+			Source:  orderSource,
+			Dialect: "mysql",
+			Errors: []string{
+				"the SEQUENCE strategy of the id field 'Id' is not supported by mysql, which has no sequences",
+			},
+		},
+		{
+			Name: "copying the entities with generated ids",
+			Source: `
+// +shelf:repository="ticket-repository", Entity=Ticket
+type TicketRepository interface {
+	// +shelf:batch:Copy=true
+	SaveAll(ctx context.Context, tickets []*Ticket) error
+}`,
+			Errors: []string{
+				"the method 'SaveAll' cannot use COPY, which cannot tell the entities with the generated ids apart",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			dir, remove := writePackage(t, "fixture", map[string]string{"fixture.go": generatedValueSource + testCase.Source})
+			defer remove()
+
+			// the strategies unsupported by the dialect and the COPY of the batches are reported by the generator
+			args := []string{"generate", "-o", dir}
+
+			if testCase.Dialect != "" {
+				args = append(args, "-a", "dialect="+testCase.Dialect)
+			}
+
+			assertErrors(t, runShelf(t, dir, args...), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_GeneratedValues(t *testing.T) {
+	repository := `
+// +shelf:repository="invoice-repository", Entity=Invoice
+type InvoiceRepository interface {
+	SaveAll(ctx context.Context, invoices []*Invoice) error
+}
+
+// +shelf:repository="ticket-repository", Entity=Ticket
+type TicketRepository interface {
+	Save(ctx context.Context, ticket *Ticket) error
+	SaveAll(ctx context.Context, tickets []*Ticket) error
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Source     string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Source: orderSource + `
+
+// +shelf:repository="order-repository", Entity=Order
+type OrderRepository interface {
+	Save(ctx context.Context, order *Order) error
+}`,
+			Statements: []string{
+				"var orderIdSequence = shelf.NewSequence(20, func(ctx context.Context, executor shelf.Executor) (int64, error) {",
+				`err := executor.QueryRowContext(ctx, "SELECT nextval('order_ids')").Scan(&id)`,
+				"err = generateOrderId(ctx, repository.executor(ctx), order)",
+				`err := executor.QueryRowContext(ctx, "UPDATE shelf_sequences SET next_value = next_value + 50 WHERE name = 'invoices_seq' RETURNING next_value - 50").Scan(&id)`,
+				`return shelf.GenerateId(ctx, "UUID", "Ticket", &entity.Id)`,
+				"err := generateTicketId(ctx, repository.executor(ctx), entity)",
+				// the ids are restored if the entities are not saved
+				"inserted.Id = \"\"",
+				"ticket.Id = \"\"",
+				"entity.Id = \"\"",
+				"entity.Id = 0",
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`result, err := executor.ExecContext(ctx, "UPDATE shelf_sequences SET next_value = LAST_INSERT_ID(next_value + 50) WHERE name = 'invoices_seq'")`,
+				"return id - 50, err",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			source := generatedValueSource + testCase.Source + repository
+			repositories := generate(t, "fixture", source, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
@@ -0,0 +1,2662 @@
+/*
+Copyright © 2021 Shelf Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/procyon-projects/marker"
+	"github.com/procyon-projects/shelf"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+type RepositoryFileTemplateData struct {
+	PackageName    string
+	Imports        []ImportTemplateData
+	SortProperties []SortPropertiesTemplateData
+	AttributeTypes []AttributeTypeTemplateData
+	Metamodels     []MetamodelTemplateData
+	Enums          []EnumTemplateData
+	Scanners       []ScannerTemplateData
+	Loaders        []LoaderTemplateData
+	Fetchers       []FetcherTemplateData
+	Savers         []CascadeTemplateData
+	Deleters       []CascadeTemplateData
+	IdGenerators   []IdGeneratorTemplateData
+	Auditors       []AuditorTemplateData
+	Projections    []ProjectionTemplateData
+	Repositories   []RepositoryTemplateData
+}
+
+type ImportTemplateData struct {
+	Name string
+	Path string
+}
+
+type RepositoryTemplateData struct {
+	Name         string
+	Type         string
+	Constructor  string
+	ReceiverName string
+	Methods      []MethodTemplateData
+}
+
+type MethodTemplateData struct {
+	Name         string
+	Parameters   []ParameterTemplateData
+	ReturnValues []string
+	Body         string
+}
+
+// TransactionalTemplateData is passed to the template generating the methods marked as shelf:transactional.
+type TransactionalTemplateData struct {
+	Receiver    string
+	Context     string
+	Inner       string
+	Options     string
+	Arguments   []string
+	ResultTypes []string
+	ZeroValues  []string
+}
+
+// propagations maps the propagation options of the shelf:transactional marker to their constants.
+// The default propagation is not listed, as it is not set explicitly.
+var propagations = map[string]string{
+	"REQUIRES_NEW": "shelf.PropagationRequiresNew",
+	"SUPPORTS":     "shelf.PropagationSupports",
+	"NEVER":        "shelf.PropagationNever",
+	"NESTED":       "shelf.PropagationNested",
+}
+
+// isolationLevels maps the isolation options of the shelf:transactional marker to their constants.
+var isolationLevels = map[string]string{
+	"READ_UNCOMMITTED": "sql.LevelReadUncommitted",
+	"READ_COMMITTED":   "sql.LevelReadCommitted",
+	"REPEATABLE_READ":  "sql.LevelRepeatableRead",
+	"SERIALIZABLE":     "sql.LevelSerializable",
+}
+
+type SortPropertiesTemplateData struct {
+	Name       string
+	Entity     string
+	Properties []ColumnTemplateData
+}
+
+// MetamodelTemplateData describes the metamodel of an entity, whose attributes build the specifications.
+type MetamodelTemplateData struct {
+	Name       string
+	Entity     string
+	Attributes []AttributeTemplateData
+}
+
+type AttributeTemplateData struct {
+	Field  string
+	Column string
+	Type   string
+}
+
+// AttributeTypeTemplateData describes an attribute type whose predicates take in the values of a field type.
+type AttributeTypeTemplateData struct {
+	Name      string
+	ValueType string
+	IsString  bool
+	IsBool    bool
+	// IsOrdered reports whether the values can be compared by order, which the enums stored by name cannot.
+	IsOrdered bool
+	// Converter is the type which the values of an enum are converted to, which is empty for the other types.
+	Converter string
+}
+
+// ScannerTemplateData describes the functions scanning the rows into an entity without reflection.
+type ScannerTemplateData struct {
+	Name   string
+	Entity string
+	Type   string
+	// Columns is the name of the constant listing the scanned columns.
+	Columns     string
+	ColumnNames string
+	// Pointers is the name of the function returning the pointers which the columns are scanned into.
+	Pointers      string
+	FieldPointers []string
+	// References are the join columns of the entity, which are scanned after the fields.
+	References []ReferenceTemplateData
+	// MapsIds are the associations marked as shelf:maps-id, whose references are built from the id fields.
+	// Derive is the name of the function setting the id fields to the ids of the associated entities, or
+	// empty if there is no such association.
+	MapsIds []ReferenceTemplateData
+	Derive  string
+}
+
+// ReferenceTemplateData describes a join column of the entity table, which is scanned into a reference to the
+// associated entity holding only its id, e.g. &User{Id: authorId}.
+type ReferenceTemplateData struct {
+	// Name is the function returning the id of the associated entity, which is saved into the join column.
+	Name      string
+	Field     string
+	Variable  string
+	Target    string
+	IdField   string
+	IdType    string
+	IsPointer bool
+	// MapsId is the id field of the entity whose column is the join column, see shelf:maps-id.
+	MapsId string
+}
+
+// LoaderTemplateData describes the function loading an association of the entities in batches.
+type LoaderTemplateData struct {
+	Name    string
+	Entity  string
+	Type    string
+	Field   string
+	Target  string
+	Scanner string
+	// Reference reports whether the join column is in the entity table, in which case the references of the
+	// entities are replaced by the loaded entities. Otherwise, the join column referring to the entities is
+	// selected before the columns of the associated entities.
+	Reference    bool
+	IsCollection bool
+	IsPointer    bool
+	// Key is the id field of the associated entities for the references, and the one of the entities otherwise.
+	Key     string
+	KeyType string
+	// Query is followed by the placeholders of the keys and Suffix.
+	Query             string
+	Suffix            string
+	PlaceholderFormat string
+	BatchSize         int
+}
+
+// FetcherTemplateData describes the function loading the associations of the queried entities given by a
+// fetch plan, and registering the other ones in the session of the context.
+type FetcherTemplateData struct {
+	Name   string
+	Entity string
+	Type   string
+	// Eager contains the fields of the eager associations, which are fetched if no fetch plan is given.
+	Eager        []string
+	Associations []FetchTemplateData
+}
+
+// FetchTemplateData describes an association loaded by a fetcher, or by the session of the queried entities
+// on first access if it is not in the fetch plan.
+type FetchTemplateData struct {
+	Field  string
+	Loader string
+	// Fetcher is the function fetching the nested paths of the fetch plan for the associated entities, or empty
+	// if they have no association.
+	Fetcher      string
+	Target       string
+	TargetEntity string
+	IsCollection bool
+	IsPointer    bool
+}
+
+// CascadeTemplateData describes the function saving or deleting an entity together with the targets of its
+// associations the operation is cascaded to, which are visited in dependency order.
+type CascadeTemplateData struct {
+	Name   string
+	Entity string
+	Type   string
+	// Statement is the function saving the entity alone for a saver, whose body is generated by the save
+	// template. For a deleter, it is the statement deleting the entities, which is followed by the placeholders
+	// of their ids and StatementSuffix.
+	Statement       string
+	StatementSuffix string
+	Body            string
+	// Entities is the function of a deleter deleting the entities of a chunk, whose ids are the values of
+	// IdFields bound to the placeholders given by Ids. IdField is the first of them. The entities are deleted
+	// one at a time by a statement taking the ids and VersionField if they are versioned.
+	Entities     string
+	IdField      string
+	IdFields     []string
+	Ids          string
+	VersionField string
+	// Before are applied before the statement of the entity, and After after it. The steps of a saver are
+	// applied to an entity by BeforeFunction and AfterFunction, which are also called for the chunks of the
+	// batch saves.
+	Before            []CascadeStepTemplateData
+	After             []CascadeStepTemplateData
+	BeforeFunction    string
+	AfterFunction     string
+	PlaceholderFormat string
+}
+
+// CascadeStepTemplateData describes how the operation of a cascading function applies to an association.
+type CascadeStepTemplateData struct {
+	Field        string
+	IsCollection bool
+	IsPointer    bool
+	// Loader loads the association of the entities before its targets are deleted, unless it is loaded.
+	Loader string
+	// Cascade is the cascading function called for each target, or empty if the operation is not cascaded.
+	// Condition filters the targets, such as the new ones for PERSIST.
+	Cascade   string
+	Condition string
+	// BackReference is the field of the targets referring to the entity, which is set before they are saved.
+	BackReference          string
+	IsBackReferencePointer bool
+	// Key is the field of the entity referred by the join column, and TargetId is the id field of the targets.
+	Key      string
+	TargetId string
+	// Unlink is executed with the key before the targets are visited, and Link with the key and the id of
+	// each target. Orphans is executed with the key after the targets are saved, and it is followed by the
+	// ids of the targets which are not orphans if there is any. The Unlink statements of a deleter are
+	// followed by the placeholders of the keys of the entities of a chunk.
+	Unlink            string
+	Link              string
+	Orphans           string
+	OrphansIn         string
+	PlaceholderFormat string
+}
+
+// IdGeneratorTemplateData describes the function assigning the id of an entity before it is inserted, which
+// is generated for all the strategies of shelf:generated-value except for IDENTITY.
+type IdGeneratorTemplateData struct {
+	Name   string
+	Entity string
+	Type   string
+	Field  string
+	IdType string
+	// Sequence is the variable allocating the ids of the SEQUENCE and TABLE strategies, which is empty for the
+	// other strategies. Its blocks are allocated by the Query, whose result is the first id of the block unless
+	// it is returned by LastInsertId. Source describes the sequence or the row of the id table.
+	Sequence       string
+	Source         string
+	AllocationSize int
+	Query          string
+	LastInsertId   bool
+	// Generator is the name of the shelf.IdGenerator of the other strategies.
+	Generator string
+}
+
+// EnumTemplateData describes the conversions of an enum type marked as shelf:enumerated, which check that the
+// values are constants of the enum. Constants is the variable listing the constants, and Names is the one listing
+// the names they are stored by.
+type EnumTemplateData struct {
+	Name      string
+	Type      string
+	Constants string
+	Names     string
+	Values    []EnumConstantTemplateData
+	// String is the type storing the enum by the names of its constants, e.g. userStatusName, and Ordinal is the one
+	// storing it by their values, e.g. userStatusOrdinal. They are empty unless the mapping is used by a field.
+	String  string
+	Ordinal string
+}
+
+type EnumConstantTemplateData struct {
+	Constant string
+	// Name is the expression of the name the constant is stored by, which is returned by its String method
+	// if the enum has one.
+	Name string
+}
+
+// AuditorTemplateData describes the functions filling the audit fields of an entity before it is saved.
+type AuditorTemplateData struct {
+	Entity string
+	Type   string
+	// Creation is called before the entity is inserted, and Modification before it is updated. Modification
+	// is empty if the entity has no last-modified field.
+	Creation         string
+	Modification     string
+	CreatedDate      *ColumnTemplateData
+	LastModifiedDate *ColumnTemplateData
+	CreatedBy        *ColumnTemplateData
+	LastModifiedBy   *ColumnTemplateData
+}
+
+type ProjectionTemplateData struct {
+	Name   string
+	Type   string
+	Fields []ProjectionFieldTemplateData
+}
+
+type ProjectionFieldTemplateData struct {
+	Name      string
+	Field     string
+	Type      string
+	FieldType string
+	IsNested  bool
+}
+
+type ParameterTemplateData struct {
+	Name string
+	Type string
+}
+
+type ColumnTemplateData struct {
+	Name string
+	// Field is the path of the field in the entity, and Property is its name without the dots.
+	Field     string
+	Property  string
+	Type      string
+	Generated bool
+	Zero      string
+	// Underlying is the type which the values of a named type are converted to, which is its builtin underlying
+	// type or the type converting an enum, see useEnum.
+	Underlying string
+	// Reference is the function returning the value of a join column, which is empty for the columns of the fields.
+	Reference string
+	// KeyField is the path of the field in the struct of a composite key, which is empty for the other columns.
+	KeyField string
+}
+
+// QueryTemplateData is passed to the templates generating the bodies of repository methods.
+type QueryTemplateData struct {
+	Receiver     string
+	Context      string
+	Parameters   []string
+	ReturnValues []string
+	ZeroValues   []string
+	ReturnsError bool
+	Entity       string
+	// Table is quoted by the dialect and escaped to be embedded in the string literals of the statements.
+	Table string
+	// IdColumn is the first column of the primary key, and IdColumns are all of them.
+	IdColumn     ColumnTemplateData
+	IdColumns    []ColumnTemplateData
+	Columns      []ColumnTemplateData
+	ValueColumns []ColumnTemplateData
+	// Version is the column used for the optimistic locking, which is nil if the entity is not versioned.
+	// UpdateColumns are the value columns assigned the values of the entity fields by the updates, which are
+	// all of them except for the version and the created audit fields.
+	Version       *ColumnTemplateData
+	UpdateColumns []ColumnTemplateData
+	// Auditor fills the audit fields of the entity, which is nil if the entity has no audit field.
+	Auditor *AuditorTemplateData
+	// SoftDelete marks the entities as deleted instead of deleting their rows, which is nil if the entity
+	// has no soft-delete field.
+	SoftDelete *SoftDeleteTemplateData
+	// ResultType is the type allocated for each row, which is either the entity or a projection.
+	ResultType string
+	// ResultElement is the element type of the returned slices.
+	ResultElement string
+	// ResultFields contains the paths of the fields which the selected columns are scanned into, and
+	// ResultConverters contains the types the columns are converted to, which are empty if they are not.
+	ResultFields     []string
+	ResultConverters []string
+	// Scanner is the generated function scanning a row into the entity, or empty if the rows are scanned into a projection.
+	Scanner string
+	// Fetch is the generated function loading the associations of the queried entities, or empty if the entity
+	// has no association or the rows are scanned into a projection. FetchPlan is the expression of the fetch plan
+	// passed to it, which is nil unless it is given by the context, the repository or the shelf:fetch marker.
+	Fetch     string
+	FetchPlan string
+	// FetchPath is the association added to the fetch plan of the repository returned by a fluent method.
+	FetchPath string
+	// Loader is the generated function loading the association of the entities passed to a loader method.
+	Loader string
+	// Executor is the expression of the executor of the save and delete statements, which are also generated
+	// for the cascading functions.
+	Executor string
+	// Cascade contains the generated functions saving or deleting the entity with its associations, or nil
+	// if the operation is not cascaded to any association.
+	Cascade *CascadeTemplateData
+	// Derive is the generated function setting the id fields mapped by the associations before the entities
+	// are saved, or empty if the entity has no such association.
+	Derive string
+	// IdGenerator is the generated function assigning the id before the entity is inserted, or empty unless
+	// the id is generated by a strategy other than IDENTITY.
+	IdGenerator string
+	Dialect     Dialect
+	Query       *DerivedQueryTemplateData
+	// BatchSize is the maximum number of the rows in a statement of a batch operation.
+	BatchSize int
+	// Copy reports whether the entities are copied into the staging table with COPY instead of multi-row upserts.
+	Copy         bool
+	StagingTable string
+}
+
+// SoftDeleteTemplateData contains the conditions of the soft-deleted entities, see GetSoftDeleteConditions.
+type SoftDeleteTemplateData struct {
+	Assignment string
+	Predicate  string
+}
+
+// DerivedQueryTemplateData is passed to the templates generating the bodies of derived query methods.
+type DerivedQueryTemplateData struct {
+	// Result is one of single, list, page, slice, count, exists, cursor or empty if the method returns no value.
+	Result string
+	// Select contains the selected columns and the computed expressions.
+	Select string
+	SQL    string
+	// CountSQL counts the rows matching the query. It is only used by the methods returning a page.
+	CountSQL string
+	// Arguments contains the Go expressions bound to the parameters of the query in order.
+	Arguments []string
+	// Bind reports whether the query is bound at runtime, which is needed to expand the slice arguments.
+	Bind bool
+	// Orders contains the static orders which are applied before the runtime sort.
+	Orders         []string
+	Sort           string
+	SortProperties string
+	Pageable       string
+	Pagination     string
+	// FetchSize is the number of the rows fetched at once by a server-side cursor, or zero if it is not used.
+	FetchSize int
+	// ClearCache is the name of the entity whose cache is cleared after a modifying query, or empty.
+	ClearCache string
+	// Specification is the shelf.Specification parameter whose WHERE clause is appended to the query at runtime.
+	Specification string
+	// Suffix is appended to the query after the WHERE clause of the specification.
+	Suffix string
+}
+
+// CompositeId reports whether the primary key of the entity has more than one column.
+func (data QueryTemplateData) CompositeId() bool {
+	return len(data.IdColumns) > 1
+}
+
+// Return returns the statement which returns the given values from the generated method.
+func (data QueryTemplateData) Return(values ...string) string {
+	if data.ReturnsError {
+		values = append(values, "nil")
+	}
+
+	if len(values) == 0 {
+		return "return"
+	}
+
+	return "return " + strings.Join(values, ", ")
+}
+
+// ReturnError returns the statement which returns the given error from the generated method.
+// If the method does not return an error, the zero values are returned.
+func (data QueryTemplateData) ReturnError(err string) string {
+	values := make([]string, 0)
+
+	values = append(values, data.ZeroValues...)
+
+	if data.ReturnsError {
+		values = append(values, err)
+	}
+
+	if len(values) == 0 {
+		return "return"
+	}
+
+	return "return " + strings.Join(values, ", ")
+}
+
+// ErrorReturn returns the statement which returns from the generated method when an error occurs.
+// The error is only returned if the method returns an error, otherwise the zero values are returned.
+func (data QueryTemplateData) ErrorReturn() string {
+	return data.ReturnError("shelf.TranslateError(err)")
+}
+
+// CascadesBefore reports whether the steps of the cascading save are applied to the entities of a chunk
+// before they are saved.
+func (data QueryTemplateData) CascadesBefore() bool {
+	return data.Cascade != nil && len(data.Cascade.Before) != 0
+}
+
+// CascadesAfter reports whether the steps of the cascading save are applied to the entities of a chunk
+// after they are saved.
+func (data QueryTemplateData) CascadesAfter() bool {
+	return data.Cascade != nil && len(data.Cascade.After) != 0
+}
+
+type RepositoryGenerator struct {
+	dialect        Dialect
+	packagePath    string
+	imports        map[string]string
+	sortProperties map[string]SortPropertiesTemplateData
+	attributeTypes map[string]AttributeTypeTemplateData
+	metamodels     map[string]MetamodelTemplateData
+	enums          map[string]EnumTemplateData
+	scanners       map[string]ScannerTemplateData
+	loaders        map[string]LoaderTemplateData
+	fetchers       map[string]FetcherTemplateData
+	savers         map[string]CascadeTemplateData
+	deleters       map[string]CascadeTemplateData
+	idGenerators   map[string]IdGeneratorTemplateData
+	auditors       map[string]AuditorTemplateData
+	projections    map[string]ProjectionTemplateData
+}
+
+func NewRepositoryGenerator(dialect Dialect) *RepositoryGenerator {
+	return &RepositoryGenerator{
+		dialect:        dialect,
+		imports:        make(map[string]string),
+		sortProperties: make(map[string]SortPropertiesTemplateData),
+		attributeTypes: make(map[string]AttributeTypeTemplateData),
+		metamodels:     make(map[string]MetamodelTemplateData),
+		enums:          make(map[string]EnumTemplateData),
+		scanners:       make(map[string]ScannerTemplateData),
+		loaders:        make(map[string]LoaderTemplateData),
+		fetchers:       make(map[string]FetcherTemplateData),
+		savers:         make(map[string]CascadeTemplateData),
+		deleters:       make(map[string]CascadeTemplateData),
+		idGenerators:   make(map[string]IdGeneratorTemplateData),
+		auditors:       make(map[string]AuditorTemplateData),
+		projections:    make(map[string]ProjectionTemplateData),
+	}
+}
+
+// GenerateRepositories generates a file containing the repository implementations for each package.
+func GenerateRepositories(outputPath string, dialect Dialect) error {
+	repositoriesByPackage := make(map[string][]RepositoryMetadata)
+
+	for _, metadata := range repositoryMetadataByInterfaceName {
+		packagePath := metadata.InterfaceType.File.Package.Path
+		repositoriesByPackage[packagePath] = append(repositoriesByPackage[packagePath], metadata)
+	}
+
+	for _, repositories := range repositoriesByPackage {
+		sort.Slice(repositories, func(i, j int) bool {
+			return repositories[i].InterfaceType.Name < repositories[j].InterfaceType.Name
+		})
+
+		packageName := repositories[0].InterfaceType.File.Package.Name
+		source, err := NewRepositoryGenerator(dialect).Generate(packageName, repositories)
+
+		if err != nil {
+			return err
+		}
+
+		if source == nil {
+			continue
+		}
+
+		err = os.MkdirAll(outputPath, os.ModePerm)
+
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(filepath.Join(outputPath, packageName+"_repositories.go"), source, 0644)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return marker.NewErrorList(errs)
+}
+
+// Generate returns the formatted source of the repository implementations in a package.
+// If any of the repository methods cannot be generated, it returns nil.
+func (generator *RepositoryGenerator) Generate(packageName string, repositories []RepositoryMetadata) ([]byte, error) {
+	generator.use("context")
+	generator.use("database/sql")
+	generator.packagePath = repositories[0].InterfaceType.File.Package.Path
+
+	data := RepositoryFileTemplateData{
+		PackageName: packageName,
+	}
+
+	isValid := true
+
+	for _, repository := range repositories {
+		repositoryData, ok := generator.generateRepository(repository)
+
+		if !ok {
+			isValid = false
+			continue
+		}
+
+		data.Repositories = append(data.Repositories, repositoryData)
+	}
+
+	if !isValid {
+		return nil, nil
+	}
+
+	for importPath, importName := range generator.imports {
+		data.Imports = append(data.Imports, ImportTemplateData{
+			Name: importName,
+			Path: importPath,
+		})
+	}
+
+	sort.Slice(data.Imports, func(i, j int) bool {
+		return data.Imports[i].Path < data.Imports[j].Path
+	})
+
+	for _, sortProperties := range generator.sortProperties {
+		data.SortProperties = append(data.SortProperties, sortProperties)
+	}
+
+	sort.Slice(data.SortProperties, func(i, j int) bool {
+		return data.SortProperties[i].Name < data.SortProperties[j].Name
+	})
+
+	for _, attributeType := range generator.attributeTypes {
+		data.AttributeTypes = append(data.AttributeTypes, attributeType)
+	}
+
+	sort.Slice(data.AttributeTypes, func(i, j int) bool {
+		return data.AttributeTypes[i].Name < data.AttributeTypes[j].Name
+	})
+
+	for _, metamodel := range generator.metamodels {
+		data.Metamodels = append(data.Metamodels, metamodel)
+	}
+
+	sort.Slice(data.Metamodels, func(i, j int) bool {
+		return data.Metamodels[i].Name < data.Metamodels[j].Name
+	})
+
+	for _, enum := range generator.enums {
+		data.Enums = append(data.Enums, enum)
+	}
+
+	sort.Slice(data.Enums, func(i, j int) bool {
+		return data.Enums[i].Constants < data.Enums[j].Constants
+	})
+
+	for _, scanner := range generator.scanners {
+		data.Scanners = append(data.Scanners, scanner)
+	}
+
+	sort.Slice(data.Scanners, func(i, j int) bool {
+		return data.Scanners[i].Name < data.Scanners[j].Name
+	})
+
+	for _, loader := range generator.loaders {
+		data.Loaders = append(data.Loaders, loader)
+	}
+
+	sort.Slice(data.Loaders, func(i, j int) bool {
+		return data.Loaders[i].Name < data.Loaders[j].Name
+	})
+
+	for _, fetcher := range generator.fetchers {
+		data.Fetchers = append(data.Fetchers, fetcher)
+	}
+
+	sort.Slice(data.Fetchers, func(i, j int) bool {
+		return data.Fetchers[i].Name < data.Fetchers[j].Name
+	})
+
+	for _, saver := range generator.savers {
+		data.Savers = append(data.Savers, saver)
+	}
+
+	sort.Slice(data.Savers, func(i, j int) bool {
+		return data.Savers[i].Name < data.Savers[j].Name
+	})
+
+	for _, deleter := range generator.deleters {
+		data.Deleters = append(data.Deleters, deleter)
+	}
+
+	sort.Slice(data.Deleters, func(i, j int) bool {
+		return data.Deleters[i].Name < data.Deleters[j].Name
+	})
+
+	for _, idGenerator := range generator.idGenerators {
+		data.IdGenerators = append(data.IdGenerators, idGenerator)
+	}
+
+	sort.Slice(data.IdGenerators, func(i, j int) bool {
+		return data.IdGenerators[i].Name < data.IdGenerators[j].Name
+	})
+
+	for _, auditor := range generator.auditors {
+		data.Auditors = append(data.Auditors, auditor)
+	}
+
+	sort.Slice(data.Auditors, func(i, j int) bool {
+		return data.Auditors[i].Creation < data.Auditors[j].Creation
+	})
+
+	for _, projection := range generator.projections {
+		data.Projections = append(data.Projections, projection)
+	}
+
+	sort.Slice(data.Projections, func(i, j int) bool {
+		return data.Projections[i].Type < data.Projections[j].Type
+	})
+
+	var buffer bytes.Buffer
+	err := template.Must(template.New("repository").Parse(repositoryFileTemplate)).Execute(&buffer, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Source(buffer.Bytes())
+}
+
+func (generator *RepositoryGenerator) generateRepository(repository RepositoryMetadata) (RepositoryTemplateData, bool) {
+	interfaceType := repository.InterfaceType
+	typeName := string(unicode.ToLower(rune(interfaceType.Name[0]))) + interfaceType.Name[1:] + "Impl"
+
+	data := RepositoryTemplateData{
+		Name:         interfaceType.Name,
+		Type:         typeName,
+		Constructor:  "New" + interfaceType.Name,
+		ReceiverName: "repository",
+	}
+
+	generator.useMetamodel(repository)
+	isValid := true
+
+	for _, method := range interfaceType.Methods {
+		methodData, err := generator.generateMethod(repository, data.ReceiverName, method)
+
+		if err != nil {
+			errs = append(errs, marker.NewError(err, method.File.FullPath, marker.Position{
+				Line:   method.Position.Line,
+				Column: method.Position.Column,
+			}))
+			isValid = false
+			continue
+		}
+
+		if _, ok := method.Markers[shelf.MarkerTransactional]; ok {
+			transactionalData, err := generator.generateTransactionalMethod(data.ReceiverName, method, methodData)
+
+			if err != nil {
+				errs = append(errs, marker.NewError(err, method.File.FullPath, marker.Position{
+					Line:   method.Position.Line,
+					Column: method.Position.Column,
+				}))
+				isValid = false
+				continue
+			}
+
+			// the generated method is renamed to an unexported one which is called in the transaction
+			data.Methods = append(data.Methods, transactionalData)
+			methodData.Name = getInTxMethodName(method.Name)
+		}
+
+		data.Methods = append(data.Methods, methodData)
+	}
+
+	return data, isValid
+}
+
+// generateTransactionalMethod returns the method calling the generated method in a transaction, whose context
+// carries the transaction.
+func (generator *RepositoryGenerator) generateTransactionalMethod(receiver string, method marker.Method,
+	methodData MethodTemplateData) (MethodTemplateData, error) {
+	data := TransactionalTemplateData{
+		Receiver: receiver,
+		Context:  methodData.Parameters[0].Name,
+		Inner:    getInTxMethodName(method.Name),
+		Options:  generator.getTxOptions(method),
+	}
+
+	for _, parameter := range methodData.Parameters {
+		data.Arguments = append(data.Arguments, parameter.Name)
+	}
+
+	for _, returnValue := range GetResultValues(method) {
+		data.ResultTypes = append(data.ResultTypes, GetFullNameFromType(returnValue.Type))
+		data.ZeroValues = append(data.ZeroValues, GetZeroValueOfType(method.File, returnValue.Type))
+	}
+
+	var buffer bytes.Buffer
+	err := template.Must(template.New("transactional").Parse(transactionalTemplate)).Execute(&buffer, data)
+
+	if err != nil {
+		return methodData, err
+	}
+
+	return MethodTemplateData{
+		Name:         methodData.Name,
+		Parameters:   methodData.Parameters,
+		ReturnValues: methodData.ReturnValues,
+		Body:         strings.TrimSpace(buffer.String()),
+	}, nil
+}
+
+// getInTxMethodName returns the name of the generated method called by a transactional method in its transaction.
+func getInTxMethodName(name string) string {
+	return string(unicode.ToLower(rune(name[0]))) + name[1:] + "InTx"
+}
+
+// getTxOptions returns the expression of the transaction options of the shelf:transactional marker,
+// or nil if all the options are the defaults.
+func (generator *RepositoryGenerator) getTxOptions(method marker.Method) string {
+	options := make([]string, 0)
+
+	for _, candidateMarker := range method.Markers[shelf.MarkerTransactional] {
+		transactionalMarker, ok := candidateMarker.(shelf.TransactionalMarker)
+
+		if !ok {
+			continue
+		}
+
+		if propagation, ok := propagations[strings.TrimSpace(transactionalMarker.Propagation)]; ok {
+			options = append(options, "Propagation: "+propagation)
+		}
+
+		if isolation, ok := isolationLevels[strings.TrimSpace(transactionalMarker.Isolation)]; ok {
+			options = append(options, "Isolation: "+isolation)
+		}
+
+		if transactionalMarker.ReadOnly {
+			options = append(options, "ReadOnly: true")
+		}
+	}
+
+	if len(options) == 0 {
+		return "nil"
+	}
+
+	return "&shelf.TxOptions{" + strings.Join(options, ", ") + "}"
+}
+
+func (generator *RepositoryGenerator) generateMethod(repository RepositoryMetadata, receiver string, method marker.Method) (MethodTemplateData, error) {
+	data := MethodTemplateData{
+		Name: method.Name,
+	}
+
+	queryData := generator.getEntityQueryTemplateData(repository.Entity, generator.getEntityTypeName(repository))
+	queryData.Receiver = receiver
+
+	for index, parameter := range method.Parameters {
+		name := parameter.Name
+
+		if name == "" || name == "_" {
+			name = "param" + strconv.Itoa(index)
+		} else if IsGeneratedVariableName(name) {
+			name = name + "Param"
+		}
+
+		generator.useTypeImports(method.File, parameter.Type)
+		data.Parameters = append(data.Parameters, ParameterTemplateData{
+			Name: name,
+			Type: GetFullNameFromType(parameter.Type),
+		})
+		queryData.Parameters = append(queryData.Parameters, name)
+	}
+
+	for _, returnValue := range method.ReturnValues {
+		generator.useTypeImports(method.File, returnValue.Type)
+		data.ReturnValues = append(data.ReturnValues, GetFullNameFromType(returnValue.Type))
+	}
+
+	for _, returnValue := range GetResultValues(method) {
+		queryData.ReturnValues = append(queryData.ReturnValues, GetFullNameFromType(returnValue.Type))
+		queryData.ZeroValues = append(queryData.ZeroValues, GetZeroValueOfType(method.File, returnValue.Type))
+	}
+
+	if HasErrorReturnValue(method) {
+		generator.use("github.com/procyon-projects/shelf")
+		queryData.ReturnsError = true
+	}
+
+	if len(queryData.Parameters) != 0 {
+		queryData.Context = queryData.Parameters[0]
+		queryData.Executor = receiver + ".executor(" + queryData.Context + ")"
+	}
+
+	methodTemplate := ""
+	reservedMethod, isReserved := FindReservedRepositoryMethod(repository, method)
+
+	_, isCustom := method.Markers[shelf.MarkerQuery]
+
+	if isCustom {
+		customMethod, err := ResolveCustomQueryMethod(repository, method, generator.dialect)
+
+		if err != nil {
+			return data, err
+		}
+
+		if err = generator.validateFetchSize(method, customMethod.FetchSize); err != nil {
+			return data, err
+		}
+
+		if customMethod.Projection != nil {
+			generator.useProjection(repository, method, customMethod.Projection, &queryData)
+		}
+
+		queryData.Query = generator.getCustomQueryTemplateData(repository, method, queryData, customMethod)
+		methodTemplate = getCustomQueryTemplate(customMethod)
+	} else if isReserved {
+		methodTemplate = reservedMethod.Template
+	}
+
+	// the reserved methods taking in a sort, a pageable or a specification are generated as the derived queries
+	if !isCustom && (!isReserved || methodTemplate == findTemplate || methodTemplate == countByTemplate) {
+		derivedMethod, err := ResolveDerivedQueryMethod(repository, method)
+
+		if err != nil {
+			return data, err
+		}
+
+		if err = generator.validateFetchSize(method, derivedMethod.FetchSize); err != nil {
+			return data, err
+		}
+
+		if derivedMethod.Projection != nil {
+			generator.useProjection(repository, method, derivedMethod.Projection, &queryData)
+		}
+
+		queryData.Query = generator.getDerivedQueryTemplateData(repository, queryData, derivedMethod)
+
+		if !isReserved {
+			methodTemplate = getDerivedQueryTemplate(derivedMethod.Query.Kind)
+		}
+	}
+
+	switch methodTemplate {
+	case findByIdTemplate, findAllTemplate, findAllByIdTemplate, findTemplate:
+		if queryData.Scanner != "" {
+			queryData.Fetch = generator.useFetcher(repository.Entity, queryData.Entity)
+			queryData.FetchPlan = getFetchPlan(method, receiver, queryData.Context)
+		}
+	case loadTemplate, loadAllTemplate:
+		association, _ := FindLoaderAssociation(repository, method)
+		queryData.Loader = generator.useLoader(repository.Entity, queryData.Entity, association)
+	case fetchPlanTemplate:
+		association, _ := FindLoaderAssociation(repository, method)
+		queryData.FetchPath = association.FieldName
+	case saveTemplate, saveAllTemplate:
+		// the zero values of the id and the version, and the type of the ids fetched by LastInsertId are
+		// only rendered by the methods saving the entities
+		generator.useType(repository.Entity.IdField.File, repository.Entity.IdField.Type)
+
+		if versionField := repository.Entity.VersionField; versionField != nil {
+			generator.useType(versionField.File, versionField.Type)
+		}
+
+		if before, after := generator.getSaveSteps(repository.Entity); len(before) != 0 || len(after) != 0 {
+			queryData.Cascade = generator.useSaver(repository.Entity)
+		}
+	case deleteTemplate, deleteByIdTemplate, deleteAllEntitiesTemplate, deleteAllByIdTemplate:
+		if before, after := generator.getDeleteSteps(repository.Entity); len(before) != 0 || len(after) != 0 {
+			queryData.Cascade = generator.useDeleter(repository.Entity)
+		}
+	}
+
+	err := generator.applyBatchOptions(repository, method, methodTemplate, &queryData)
+
+	if err != nil {
+		return data, err
+	}
+
+	body, err := generator.execute(methodTemplate, queryData)
+
+	if err != nil {
+		return data, err
+	}
+
+	// the templates of the methods returning no values end without a return statement
+	if queryData.ReturnsError && len(queryData.ReturnValues) == 0 {
+		body = body + "\n\n" + queryData.Return()
+	}
+
+	data.Body = body
+	return data, nil
+}
+
+// validateFetchSize checks whether the dialect supports the server-side cursor of a method marked as shelf:fetch-size.
+func (generator *RepositoryGenerator) validateFetchSize(method marker.Method, fetchSize int) error {
+	if fetchSize != 0 && !generator.dialect.SupportsCursors {
+		return fmt.Errorf("the '%s' marker of the method '%s' is not supported by %s, which has no server-side cursors",
+			shelf.MarkerFetchSize, method.Name, generator.dialect.Name)
+	}
+
+	return nil
+}
+
+// getEntityQueryTemplateData returns the template data of the columns of the entity, which is completed by
+// generateMethod for each repository method.
+func (generator *RepositoryGenerator) getEntityQueryTemplateData(entity EntityMetadata, entityType string) QueryTemplateData {
+	queryData := QueryTemplateData{
+		Entity:  entityType,
+		Table:   escapeString(generator.dialect.Quote(entity.TableName)),
+		Dialect: generator.dialect,
+	}
+
+	for _, field := range entity.Fields {
+		column := generator.getColumn(field)
+
+		if field.IsId {
+			queryData.IdColumns = append(queryData.IdColumns, column)
+		}
+
+		if field.IsVersion {
+			queryData.Version = &column
+		}
+
+		if !field.IsId {
+			queryData.ValueColumns = append(queryData.ValueColumns, column)
+		}
+
+		isCreated := field.AuditMarker == shelf.MarkerCreatedDate || field.AuditMarker == shelf.MarkerCreatedBy
+
+		if !field.IsId && !field.IsVersion && !isCreated {
+			queryData.UpdateColumns = append(queryData.UpdateColumns, column)
+		}
+
+		queryData.Columns = append(queryData.Columns, column)
+		queryData.ResultFields = append(queryData.ResultFields, column.Field)
+		queryData.ResultConverters = append(queryData.ResultConverters, column.Underlying)
+	}
+
+	// the join columns of the entity table are written and scanned after the columns of the fields
+	for _, column := range generator.getJoinColumns(entity) {
+		queryData.ValueColumns = append(queryData.ValueColumns, column)
+		queryData.UpdateColumns = append(queryData.UpdateColumns, column)
+		queryData.Columns = append(queryData.Columns, column)
+	}
+
+	queryData.IdColumn = queryData.IdColumns[0]
+	queryData.ResultType = queryData.Entity
+	queryData.ResultElement = "*" + queryData.Entity
+	queryData.Scanner = generator.useScanner(entity, queryData.Entity, queryData.Columns)
+	queryData.Derive = generator.scanners[queryData.Scanner].Derive
+	queryData.IdGenerator = generator.useIdGenerator(entity, queryData.Entity)
+	queryData.Auditor = generator.useAuditor(entity, queryData)
+
+	if entity.SoftDeleteField != nil {
+		assignment, predicate := GetSoftDeleteConditions(*entity.SoftDeleteField)
+		queryData.SoftDelete = &SoftDeleteTemplateData{
+			Assignment: escapeString(assignment),
+			Predicate:  escapeString(predicate),
+		}
+	}
+
+	return queryData
+}
+
+// applyBatchOptions sets the batch size of the batch operations, which is limited by the parameters of the
+// dialect, and whether the entities are upserted through COPY. Since COPY FROM STDIN is not a part of
+// database/sql, it requires a driver running it through the prepared statements, e.g. github.com/lib/pq.
+func (generator *RepositoryGenerator) applyBatchOptions(repository RepositoryMetadata, method marker.Method, methodTemplate string,
+	queryData *QueryTemplateData) error {
+	batchMarker := shelf.BatchMarker{}
+	_, hasBatchMarker := method.Markers[shelf.MarkerBatch]
+
+	for _, candidateMarker := range method.Markers[shelf.MarkerBatch] {
+		if typedMarker, ok := candidateMarker.(shelf.BatchMarker); ok {
+			batchMarker = typedMarker
+		}
+	}
+
+	size := DefaultBatchSize
+
+	if batchMarker.Size != 0 {
+		size = batchMarker.Size
+	}
+
+	switch methodTemplate {
+	case saveAllTemplate:
+		columns := len(queryData.Columns)
+
+		if queryData.IdColumn.Generated {
+			columns = len(queryData.ValueColumns)
+		}
+
+		queryData.BatchSize = generator.dialect.BatchSize(size, columns)
+	case deleteAllEntitiesTemplate, deleteAllByIdTemplate:
+		queryData.BatchSize = generator.dialect.BatchSize(size, len(queryData.IdColumns))
+	default:
+		if hasBatchMarker {
+			return fmt.Errorf("'%s' marker can only be used with the methods saving or deleting all the entities", shelf.MarkerBatch)
+		}
+
+		return nil
+	}
+
+	if !batchMarker.Copy {
+		return nil
+	}
+
+	if methodTemplate != saveAllTemplate {
+		return fmt.Errorf("the method '%s' cannot use COPY, which is only used in saving the entities", method.Name)
+	}
+
+	if generator.dialect.Name != DialectPostgres {
+		return fmt.Errorf("the method '%s' cannot use COPY, which is only supported by %s", method.Name, DialectPostgres)
+	}
+
+	if queryData.IdColumn.Generated {
+		return fmt.Errorf("the method '%s' cannot use COPY, which cannot return the generated ids", method.Name)
+	}
+
+	if queryData.IdGenerator != "" {
+		return fmt.Errorf("the method '%s' cannot use COPY, which cannot tell the entities with the generated ids apart", method.Name)
+	}
+
+	if queryData.Version != nil {
+		return fmt.Errorf("the method '%s' cannot use COPY, which cannot check the versions of the entities", method.Name)
+	}
+
+	queryData.Copy = true
+	queryData.StagingTable = "shelf_copy_" + repository.Entity.TableName
+	return nil
+}
+
+// getDerivedQueryTemplateData builds the statements of the derived query and the Go expressions bound to them.
+func (generator *RepositoryGenerator) getDerivedQueryTemplateData(repository RepositoryMetadata, queryData QueryTemplateData,
+	derivedMethod DerivedQueryMethod) *DerivedQueryTemplateData {
+	query := derivedMethod.Query
+	data := &DerivedQueryTemplateData{
+		Result: getQueryResultName(derivedMethod.Result),
+		Select: getColumnNames(queryData.Columns),
+	}
+
+	if derivedMethod.Projection != nil {
+		data.Select = escapeString(strings.Join(derivedMethod.Projection.Columns(), ", "))
+	}
+
+	conditions := ""
+	hasOr := false
+
+	for index, condition := range query.Conditions {
+		if index != 0 {
+			conditions += " " + condition.Connector + " "
+			hasOr = hasOr || condition.Connector == "OR"
+		}
+
+		conditions += fmt.Sprintf(condition.Operator.Format, condition.Field.ColumnName)
+
+		if condition.Operator.IsSlice {
+			data.Bind = true
+		}
+
+		for _, argumentIndex := range derivedMethod.Arguments[index] {
+			argument := queryData.Parameters[argumentIndex]
+
+			if condition.Operator.ArgumentFormat != "" {
+				argument = fmt.Sprintf(condition.Operator.ArgumentFormat, argument)
+			}
+
+			// the enums are bound as they are stored, and the slices of the IN operators are converted as a whole
+			if condition.Field.Enum != nil && !condition.Operator.IsString {
+				argument = generator.getEnumArgument(condition.Field, argument, condition.Operator.IsSlice)
+			}
+
+			data.Arguments = append(data.Arguments, argument)
+		}
+	}
+
+	for _, order := range query.Orders {
+		data.Orders = append(data.Orders, order.Field.ColumnName+" "+order.Direction)
+	}
+
+	if derivedMethod.SpecificationIndex != -1 {
+		data.Specification = queryData.Parameters[derivedMethod.SpecificationIndex]
+		data.Arguments = append(data.Arguments, "whereArgs...")
+		data.Bind = true
+	}
+
+	// the soft-deleted entities are excluded unless the method includes them
+	if queryData.SoftDelete != nil && !query.IncludingDeleted {
+		predicate := queryData.SoftDelete.Predicate
+
+		if data.Specification != "" {
+			data.Specification = "shelf.AllOf(" + data.Specification + ", shelf.NewPredicate(\"" + predicate + "\"))"
+		} else if conditions != "" && hasOr {
+			conditions = "(" + conditions + ") AND " + predicate
+		} else if conditions != "" {
+			conditions = conditions + " AND " + predicate
+		} else {
+			conditions = predicate
+		}
+	}
+
+	where := ""
+
+	if conditions != "" {
+		where = " WHERE " + conditions
+	}
+
+	switch query.Kind {
+	case SelectQuery:
+		data.SQL = "SELECT " + data.Select + " FROM " + queryData.Table + where
+		data.CountSQL = "SELECT COUNT(*) FROM " + queryData.Table + where
+	case CountQuery:
+		data.SQL = "SELECT COUNT(*) FROM " + queryData.Table + where
+	case ExistsQuery:
+		data.SQL = "SELECT EXISTS(SELECT 1 FROM " + queryData.Table + where + ")"
+	case DeleteQuery:
+		if queryData.SoftDelete != nil {
+			data.SQL = "UPDATE " + queryData.Table + " SET " + queryData.SoftDelete.Assignment + where
+		} else {
+			data.SQL = "DELETE FROM " + queryData.Table + where
+		}
+	}
+
+	generator.applyQueryOptions(repository, queryData, data, derivedMethod.Result, derivedMethod.SortIndex,
+		derivedMethod.PageableIndex, derivedMethod.FetchSize)
+	return data
+}
+
+// getCustomQueryTemplateData binds the translated query of the shelf:query marker to the parameters of the method.
+func (generator *RepositoryGenerator) getCustomQueryTemplateData(repository RepositoryMetadata, method marker.Method,
+	queryData QueryTemplateData, customMethod CustomQueryMethod) *DerivedQueryTemplateData {
+	query := customMethod.Query
+	data := &DerivedQueryTemplateData{
+		Result:   getQueryResultName(customMethod.Result),
+		SQL:      escapeString(query.SQL),
+		CountSQL: escapeString(query.CountSQL),
+	}
+
+	if query.Orders != "" {
+		data.Orders = append(data.Orders, escapeString(query.Orders))
+	}
+
+	for _, parameterIndex := range query.Parameters {
+		data.Arguments = append(data.Arguments, generator.getCustomQueryArgument(repository.Entity, method.File,
+			method.Parameters[parameterIndex].Type, queryData.Parameters[parameterIndex]))
+
+		if arrayType, ok := method.Parameters[parameterIndex].Type.(*marker.ArrayType); ok && GetFullNameFromType(arrayType.ItemType) != "byte" {
+			data.Bind = true
+		}
+	}
+
+	if query.ClearAutomatically {
+		data.ClearCache = repository.Entity.EntityName
+	}
+
+	generator.applyQueryOptions(repository, queryData, data, customMethod.Result, customMethod.SortIndex,
+		customMethod.PageableIndex, customMethod.FetchSize)
+	return data
+}
+
+// applyQueryOptions applies the pagination, the sort and the fetch size to the query, and rebinds it
+// to the placeholders of the dialect unless it is bound at runtime.
+func (generator *RepositoryGenerator) applyQueryOptions(repository RepositoryMetadata, queryData QueryTemplateData,
+	data *DerivedQueryTemplateData, result QueryResult, sortIndex, pageableIndex, fetchSize int) {
+	if pageableIndex != -1 {
+		data.Pageable = queryData.Parameters[pageableIndex]
+		data.Sort = data.Pageable + ".Sort"
+
+		limit := data.Pageable + ".Size"
+
+		// a slice fetches one more row to find out whether there is a next slice
+		if result == SliceResult {
+			limit = limit + "+1"
+		}
+
+		data.Pagination = generator.dialect.Pagination(limit, data.Pageable+".Offset()")
+		generator.use("strconv")
+	} else if sortIndex != -1 {
+		data.Sort = queryData.Parameters[sortIndex]
+	}
+
+	if data.Sort != "" {
+		data.SortProperties = generator.useSortProperties(repository, queryData.Columns)
+	} else if len(data.Orders) != 0 && data.Specification != "" {
+		data.Suffix = " ORDER BY " + strings.Join(data.Orders, ", ")
+	} else if len(data.Orders) != 0 {
+		data.SQL = data.SQL + " ORDER BY " + strings.Join(data.Orders, ", ")
+	}
+
+	data.FetchSize = fetchSize
+
+	if result == CursorResult || data.ClearCache != "" {
+		generator.use("github.com/procyon-projects/shelf")
+	}
+
+	if data.Bind {
+		generator.use("github.com/procyon-projects/shelf")
+	} else {
+		data.SQL = generator.dialect.Rebind(data.SQL)
+		data.CountSQL = generator.dialect.Rebind(data.CountSQL)
+	}
+}
+
+// useProjection makes the generated method scan the rows into the projection returned by the method.
+// The interface projections are implemented by the structs generated in the repository file.
+func (generator *RepositoryGenerator) useProjection(repository RepositoryMetadata, method marker.Method, projection *ProjectionMetadata,
+	queryData *QueryTemplateData) {
+	elementType := method.ReturnValues[0].Type
+
+	if arrayType, ok := elementType.(*marker.ArrayType); ok {
+		elementType = arrayType.ItemType
+	}
+
+	queryData.ResultElement = GetFullNameFromType(elementType)
+	queryData.ResultType = strings.TrimPrefix(queryData.ResultElement, "*")
+	queryData.ResultFields = projection.Paths()
+	queryData.ResultConverters = generator.getProjectionConverters(repository.Entity, projection)
+	queryData.Scanner = ""
+
+	if projection.IsInterface() {
+		queryData.ResultType = generator.generateProjection(projection)
+	}
+}
+
+// getEnumArgument returns the argument of the enum field converted to the value it is stored by, or the slice of
+// the converted values.
+func (generator *RepositoryGenerator) getEnumArgument(field FieldMetadata, argument string, isSlice bool) string {
+	converter := generator.useEnum(field)
+
+	if isSlice {
+		return converter + "Slice(" + argument + ")"
+	}
+
+	return converter + "(" + argument + ")"
+}
+
+// getCustomQueryArgument returns the argument bound to a custom query. The parameters of an enum type are not
+// compared with a field by the query, so they are converted by the mapping of the first field of their type.
+func (generator *RepositoryGenerator) getCustomQueryArgument(entity EntityMetadata, file *marker.File, typ marker.Type,
+	argument string) string {
+	arrayType, isSlice := typ.(*marker.ArrayType)
+
+	if isSlice {
+		typ = arrayType.ItemType
+	}
+
+	typeName := GetQualifiedNameFromType(file, typ)
+
+	for _, field := range entity.Fields {
+		if field.Enum != nil && GetQualifiedNameFromType(field.File, field.Type) == typeName {
+			return generator.getEnumArgument(field, argument, isSlice)
+		}
+	}
+
+	return argument
+}
+
+// getProjectionConverters returns the types which the columns are converted to when they are scanned into the
+// fields of the projection, in the order of its paths. The columns of the enum fields are converted if they are
+// scanned into the fields of the enum type, and the other columns are not.
+func (generator *RepositoryGenerator) getProjectionConverters(entity EntityMetadata, projection *ProjectionMetadata) []string {
+	converters := make([]string, 0)
+
+	for _, field := range projection.Fields {
+		if field.Nested != nil {
+			converters = append(converters, generator.getProjectionConverters(entity, field.Nested)...)
+			continue
+		}
+
+		converter := ""
+
+		for _, entityField := range entity.Fields {
+			if entityField.Enum == nil || field.IsComputed || field.Column != entityField.ColumnName {
+				continue
+			}
+
+			if GetQualifiedNameFromType(field.File, field.Type) == GetQualifiedNameFromType(entityField.File, entityField.Type) {
+				converter = generator.useEnum(entityField)
+			}
+		}
+
+		converters = append(converters, converter)
+	}
+
+	return converters
+}
+
+// generateProjection adds the implementation of the interface projection and its nested projections
+// to the generated file and returns its name.
+func (generator *RepositoryGenerator) generateProjection(projection *ProjectionMetadata) string {
+	interfaceType := projection.InterfaceType
+	data := ProjectionTemplateData{
+		Name: interfaceType.Name,
+		Type: GetProjectionTypeName(interfaceType.Name),
+	}
+
+	if _, ok := generator.projections[data.Type]; ok {
+		return data.Type
+	}
+
+	for _, field := range projection.Fields {
+		fieldData := ProjectionFieldTemplateData{
+			Name:  field.Name,
+			Field: GetProjectionFieldName(field.Name),
+			Type:  generator.useTypeName(field.File, field.Type),
+		}
+
+		fieldData.FieldType = fieldData.Type
+
+		if field.Nested != nil {
+			fieldData.FieldType = generator.generateProjection(field.Nested)
+			fieldData.IsNested = true
+		}
+
+		data.Fields = append(data.Fields, fieldData)
+	}
+
+	generator.projections[data.Type] = data
+	return data.Type
+}
+
+// useMetamodel adds the metamodel of the repository entity and its attribute types to the generated file.
+func (generator *RepositoryGenerator) useMetamodel(repository RepositoryMetadata) {
+	name := repository.Entity.StructName + "_"
+
+	if _, ok := generator.metamodels[name]; ok {
+		return
+	}
+
+	data := MetamodelTemplateData{
+		Name:   name,
+		Entity: repository.Entity.StructName,
+	}
+
+	for _, field := range repository.Entity.Fields {
+		valueType := generator.useTypeName(field.File, field.Type)
+		attributeType := AttributeTypeTemplateData{
+			Name:      getAttributeTypeName(valueType),
+			ValueType: valueType,
+			IsString:  valueType == "string",
+			IsBool:    valueType == "bool",
+			IsOrdered: true,
+		}
+
+		// the attributes of an enum type differ by the mapping of the field
+		if field.Enum != nil {
+			attributeType.Converter = generator.useEnum(field)
+			attributeType.IsOrdered = field.Enum.Ordinal
+			attributeType.Name = getAttributeTypeName(attributeType.Converter)
+		}
+
+		generator.attributeTypes[attributeType.Name] = attributeType
+		data.Attributes = append(data.Attributes, AttributeTemplateData{
+			Field:  GetPropertyName(field.FieldName),
+			Column: field.ColumnName,
+			Type:   attributeType.Name,
+		})
+	}
+
+	generator.metamodels[name] = data
+	generator.use("github.com/procyon-projects/shelf")
+}
+
+// useScanner adds the functions scanning the rows into the entity to the generated file and returns the
+// name of the scan function. The fields of a named type whose underlying type is a builtin type, such as
+// an ordinal enum, are scanned through a pointer of the builtin type, so that the database driver does not
+// need reflection to convert the values. The join columns are scanned into the references to the associated
+// entities, see ReferenceTemplateData.
+func (generator *RepositoryGenerator) useScanner(entity EntityMetadata, entityType string, columns []ColumnTemplateData) string {
+	structName := entity.StructName
+	typeName := string(unicode.ToLower(rune(structName[0]))) + structName[1:]
+	name := "scan" + structName
+
+	if _, ok := generator.scanners[name]; ok {
+		return name
+	}
+
+	data := ScannerTemplateData{
+		Name:        name,
+		Entity:      structName,
+		Type:        entityType,
+		Columns:     typeName + "Columns",
+		ColumnNames: getColumnNames(columns),
+		Pointers:    typeName + "Pointers",
+	}
+
+	for _, column := range columns {
+		if column.Reference == "" {
+			data.FieldPointers = append(data.FieldPointers, getFieldPointer("entity", column))
+		}
+	}
+
+	for _, association := range entity.Associations {
+		if !association.OwnsJoinColumn() {
+			continue
+		}
+
+		target := entityMetadataByStructName[association.Target]
+		fieldName := association.FieldName
+		reference := ReferenceTemplateData{
+			Name:      getReferenceName(entity, association),
+			Field:     fieldName,
+			Variable:  string(unicode.ToLower(rune(fieldName[0]))) + fieldName[1:] + "Id",
+			Target:    generator.getStructTypeName(target),
+			IdField:   association.ReferencedField.FieldName,
+			IdType:    generator.useTypeName(association.ReferencedField.File, association.ReferencedField.Type),
+			IsPointer: association.IsPointer,
+			MapsId:    association.MapsId,
+		}
+
+		if association.MapsId != "" {
+			data.MapsIds = append(data.MapsIds, reference)
+			data.Derive = "derive" + structName + "Id"
+		} else {
+			data.References = append(data.References, reference)
+		}
+	}
+
+	generator.scanners[name] = data
+	generator.use("github.com/procyon-projects/shelf")
+	return name
+}
+
+// getColumn returns the column of the entity field.
+func (generator *RepositoryGenerator) getColumn(field FieldMetadata) ColumnTemplateData {
+	return ColumnTemplateData{
+		Name:       field.ColumnName,
+		Field:      field.FieldName,
+		Property:   GetPropertyName(field.FieldName),
+		Type:       generator.getTypeName(field.File, field.Type),
+		Generated:  field.IsGenerated && field.Generation.IsDatabaseGenerated(),
+		Zero:       GetZeroValueOfField(field),
+		Underlying: generator.getUnderlyingTypeName(field),
+		KeyField:   field.KeyField,
+	}
+}
+
+// getJoinColumns returns the join columns of the entity table, whose values are the ids of the associated entities
+// or the columns referenced by shelf:join-column. The join columns mapped to the id fields are the columns of the fields.
+func (generator *RepositoryGenerator) getJoinColumns(entity EntityMetadata) []ColumnTemplateData {
+	columns := make([]ColumnTemplateData, 0)
+
+	for _, association := range entity.Associations {
+		if !association.OwnsJoinColumn() || association.MapsId != "" {
+			continue
+		}
+
+		columns = append(columns, ColumnTemplateData{
+			Name:      association.JoinColumn,
+			Field:     association.FieldName,
+			Property:  association.FieldName,
+			Type:      generator.getTypeName(association.ReferencedField.File, association.ReferencedField.Type),
+			Reference: getReferenceName(entity, association),
+		})
+	}
+
+	return columns
+}
+
+// getReferenceName returns the name of the function returning the id of the entity associated by the join column.
+func getReferenceName(entity EntityMetadata, association AssociationMetadata) string {
+	structName := entity.StructName
+	return string(unicode.ToLower(rune(structName[0]))) + structName[1:] + association.FieldName + "Id"
+}
+
+// useFetcher adds the function loading the associations of the entity given by a fetch plan to the generated
+// file and returns its name, or empty if the entity has no association. The eager associations are fetched if
+// no plan is given, and the nested paths of the plan are fetched by the functions of the associated entities,
+// which are added too. The associations out of the plan are registered in the session of the context, see
+// shelf.Session.
+func (generator *RepositoryGenerator) useFetcher(entity EntityMetadata, entityType string) string {
+	structName := entity.StructName
+	name := "fetch" + structName
+
+	if _, ok := generator.fetchers[name]; ok {
+		return name
+	}
+
+	if len(entity.Associations) == 0 {
+		return ""
+	}
+
+	data := FetcherTemplateData{
+		Name:   name,
+		Entity: structName,
+		Type:   entityType,
+	}
+
+	// the fetcher is added before the ones of the targets, which may refer back to the entity
+	generator.fetchers[name] = data
+
+	for _, association := range entity.Associations {
+		target := entityMetadataByStructName[association.Target]
+		targetType := generator.getStructTypeName(target)
+
+		if association.Eager {
+			data.Eager = append(data.Eager, association.FieldName)
+		}
+
+		data.Associations = append(data.Associations, FetchTemplateData{
+			Field:        association.FieldName,
+			Loader:       generator.useLoader(entity, entityType, association),
+			Fetcher:      generator.useFetcher(target, targetType),
+			Target:       targetType,
+			TargetEntity: target.StructName,
+			IsCollection: association.IsCollection,
+			IsPointer:    association.IsPointer,
+		})
+	}
+
+	generator.fetchers[name] = data
+	generator.use("database/sql")
+	return name
+}
+
+// getFetchPlan returns the expression of the fetch plan of the method, which is the plan of the context, the
+// plan of the repository given by its fluent methods, or the paths of the shelf:fetch marker in order.
+func getFetchPlan(method marker.Method, receiver, context string) string {
+	plans := []string{receiver + ".fetch"}
+
+	if paths, ok := GetFetchPaths(method); ok {
+		for index, path := range paths {
+			paths[index] = strconv.Quote(path)
+		}
+
+		plans = append(plans, "shelf.NewFetchPlan("+strings.Join(paths, ", ")+")")
+	}
+
+	return "shelf.FetchPlanOf(" + context + ", " + strings.Join(plans, ", ") + ")"
+}
+
+// useLoader adds the function loading the association of the entities to the generated file and returns its name.
+// The associated entities are queried in batches by the values referenced by the join column, which are the ids
+// of the entities, or the ids of their references if the join column is in the entity table.
+func (generator *RepositoryGenerator) useLoader(entity EntityMetadata, entityType string, association AssociationMetadata) string {
+	name := "load" + entity.StructName + association.FieldName
+
+	if _, ok := generator.loaders[name]; ok {
+		return name
+	}
+
+	target := entityMetadataByStructName[association.Target]
+	targetType := generator.getStructTypeName(target)
+	targetColumns := make([]ColumnTemplateData, 0)
+
+	for _, field := range target.Fields {
+		targetColumns = append(targetColumns, generator.getColumn(field))
+	}
+
+	targetColumns = append(targetColumns, generator.getJoinColumns(target)...)
+
+	data := LoaderTemplateData{
+		Name:              name,
+		Entity:            entity.StructName,
+		Type:              entityType,
+		Field:             association.FieldName,
+		Target:            targetType,
+		Scanner:           generator.useScanner(target, targetType, targetColumns),
+		Reference:         association.OwnsJoinColumn(),
+		IsCollection:      association.IsCollection,
+		IsPointer:         association.IsPointer,
+		Key:               association.ReferencedField.FieldName,
+		KeyType:           generator.useTypeName(association.ReferencedField.File, association.ReferencedField.Type),
+		PlaceholderFormat: generator.dialect.PlaceholderFormat,
+		BatchSize:         generator.dialect.BatchSize(DefaultBatchSize, 1),
+	}
+
+	predicate := ""
+
+	if target.SoftDeleteField != nil {
+		_, predicate = GetSoftDeleteConditions(*target.SoftDeleteField)
+	}
+
+	switch {
+	case data.Reference:
+		// the references are replaced even if the associated entities are soft-deleted, as the join columns refer to them
+		data.Query = "SELECT " + getColumnNames(targetColumns) + " FROM " + generator.dialect.Quote(target.TableName) + " WHERE " +
+			association.ReferencedField.ColumnName + " IN ("
+		data.Suffix = ")"
+	case association.Kind == shelf.ManyToMany:
+		columns := make([]string, 0)
+
+		for _, column := range targetColumns {
+			columns = append(columns, "t."+column.Name)
+		}
+
+		data.Query = "SELECT j." + association.JoinColumn + ", " + strings.Join(columns, ", ") + " FROM " + generator.dialect.Quote(target.TableName) +
+			" t JOIN " + generator.dialect.Quote(association.JoinTable) + " j ON j." + association.InverseJoinColumn + " = t." + target.IdField.ColumnName +
+			" WHERE j." + association.JoinColumn + " IN ("
+		data.Suffix = ")"
+
+		if predicate != "" {
+			data.Suffix += " AND t." + predicate
+		}
+
+		data.Suffix += " ORDER BY t." + target.IdField.ColumnName
+	default:
+		data.Query = "SELECT " + association.JoinColumn + ", " + getColumnNames(targetColumns) + " FROM " + generator.dialect.Quote(target.TableName) +
+			" WHERE " + association.JoinColumn + " IN ("
+		data.Suffix = ")"
+
+		if predicate != "" {
+			data.Suffix += " AND " + predicate
+		}
+
+		data.Suffix += " ORDER BY " + target.IdField.ColumnName
+	}
+
+	data.Query = escapeString(data.Query)
+	data.Suffix = escapeString(data.Suffix)
+
+	generator.loaders[name] = data
+	generator.use("github.com/procyon-projects/shelf")
+	return name
+}
+
+// useSaver adds the function saving the entity with the associations the save is cascaded to, and the
+// functions saving the entity alone and applying the steps of the save, to the generated file and returns
+// them. The functions of the targets are added too, as the cascading function calls them.
+func (generator *RepositoryGenerator) useSaver(entity EntityMetadata) *CascadeTemplateData {
+	name := "cascadeSave" + entity.StructName
+
+	if data, ok := generator.savers[name]; ok {
+		return &data
+	}
+
+	entityType := generator.getStructTypeName(entity)
+	data := CascadeTemplateData{
+		Name:           name,
+		Entity:         entity.StructName,
+		Type:           entityType,
+		Statement:      "save" + entity.StructName,
+		BeforeFunction: "beforeSave" + entity.StructName,
+		AfterFunction:  "afterSave" + entity.StructName,
+	}
+
+	// the saver is added before the ones of the targets, which may cascade the save back to the entity
+	generator.savers[name] = data
+	data.Before, data.After = generator.getSaveSteps(entity)
+	data.Body = generator.executeStatement(saveTemplate, entity, entityType)
+
+	// the zero values of the ids and the versions of the targets are rendered by their saves
+	generator.useType(entity.IdField.File, entity.IdField.Type)
+
+	if versionField := entity.VersionField; versionField != nil {
+		generator.useType(versionField.File, versionField.Type)
+	}
+
+	for _, association := range entity.Associations {
+		if association.CascadeSave || association.CascadePersist {
+			generator.useSaver(entityMetadataByStructName[association.Target])
+		}
+	}
+
+	generator.savers[name] = data
+	generator.use("github.com/procyon-projects/shelf")
+	return &data
+}
+
+// getSaveSteps returns the steps of the save of the entity. The targets referred by the join columns of the
+// entity are saved before it, and the other ones after it, as their join columns refer to the entity. The
+// join tables and the join columns of the targets of a one-to-many association owned by the entity are
+// written after the targets are saved, unless the association is not loaded.
+func (generator *RepositoryGenerator) getSaveSteps(entity EntityMetadata) ([]CascadeStepTemplateData, []CascadeStepTemplateData) {
+	before := make([]CascadeStepTemplateData, 0)
+	after := make([]CascadeStepTemplateData, 0)
+
+	for _, association := range entity.Associations {
+		target := entityMetadataByStructName[association.Target]
+		step := generator.getCascadeStep(association, target)
+
+		if association.CascadeSave || association.CascadePersist {
+			step.Cascade = "cascadeSave" + target.StructName
+		}
+
+		if association.CascadePersist && !association.CascadeSave {
+			step.Condition = getNewEntityCondition(target)
+		}
+
+		if association.OwnsJoinColumn() {
+			if step.Cascade != "" {
+				before = append(before, step)
+			}
+
+			continue
+		}
+
+		isOwner := association.MappedBy == ""
+
+		switch {
+		case association.Kind == shelf.ManyToMany && isOwner:
+			joinTable := generator.dialect.Quote(association.JoinTable)
+			step.Unlink = "DELETE FROM " + joinTable + " WHERE " + association.JoinColumn + " = " +
+				generator.dialect.Placeholder(1)
+			step.Link = "INSERT INTO " + joinTable + "(" + association.JoinColumn + ", " +
+				association.InverseJoinColumn + ") VALUES(" + generator.dialect.Placeholder(1) + ", " +
+				generator.dialect.Placeholder(2) + ")"
+		case association.Kind == shelf.OneToMany && isOwner:
+			step.Link = "UPDATE " + generator.dialect.Quote(target.TableName) + " SET " + association.JoinColumn + " = " +
+				generator.dialect.Placeholder(1) + " WHERE " + target.IdField.ColumnName + " = " + generator.dialect.Placeholder(2)
+		case step.Cascade != "" && association.Kind != shelf.ManyToMany:
+			// the join columns of the targets are written by their saves
+			owner, _ := FindAssociation(target, association.MappedBy)
+			step.BackReference = owner.FieldName
+			step.IsBackReferencePointer = owner.IsPointer
+		}
+
+		if association.OrphanRemoval {
+			assignment, predicate := "", ""
+
+			if target.SoftDeleteField != nil {
+				assignment, predicate = GetSoftDeleteConditions(*target.SoftDeleteField)
+			}
+
+			if assignment != "" {
+				step.Orphans = "UPDATE " + generator.dialect.Quote(target.TableName) + " SET " + assignment
+			} else {
+				step.Orphans = "DELETE FROM " + generator.dialect.Quote(target.TableName)
+			}
+
+			step.Orphans += " WHERE " + association.JoinColumn + " = " + generator.dialect.Placeholder(1)
+
+			if predicate != "" {
+				step.Orphans += " AND " + predicate
+			}
+
+			step.OrphansIn = " AND " + target.IdField.ColumnName + " NOT IN ("
+		}
+
+		if step.Cascade != "" || step.Link != "" || step.Orphans != "" {
+			after = append(after, escapeStep(step))
+		}
+	}
+
+	return before, after
+}
+
+// useDeleter adds the function deleting the entity with the associations the delete is cascaded to, and the
+// function deleting the entities of a chunk with their associations, to the generated file and returns them.
+// The entities of a chunk are deleted by a single statement, and the associations of all of them are loaded
+// and unlinked together.
+func (generator *RepositoryGenerator) useDeleter(entity EntityMetadata) *CascadeTemplateData {
+	name := "cascadeDelete" + entity.StructName
+
+	if data, ok := generator.deleters[name]; ok {
+		return &data
+	}
+
+	table := generator.dialect.Quote(entity.TableName)
+	data := CascadeTemplateData{
+		Name:              name,
+		Entity:            entity.StructName,
+		Type:              generator.getStructTypeName(entity),
+		Statement:         "DELETE FROM " + table,
+		StatementSuffix:   ")",
+		Entities:          "delete" + entity.StructName + "Entities",
+		IdField:           entity.IdField.FieldName,
+		Ids:               generator.dialect.PlaceholderFormat + ".Placeholders(1, len(ids))",
+		PlaceholderFormat: generator.dialect.PlaceholderFormat,
+	}
+
+	for _, idField := range entity.IdFields {
+		data.IdFields = append(data.IdFields, idField.FieldName)
+	}
+
+	if len(entity.IdFields) > 1 {
+		data.Ids = generator.dialect.PlaceholderFormat + ".Rows(len(entities), " + strconv.Itoa(len(entity.IdFields)) + ")"
+	}
+
+	predicate := ""
+
+	if entity.SoftDeleteField != nil {
+		var assignment string
+		assignment, predicate = GetSoftDeleteConditions(*entity.SoftDeleteField)
+		data.Statement = "UPDATE " + table + " SET " + assignment
+		data.StatementSuffix = ") AND " + predicate
+	}
+
+	if versionField := entity.VersionField; versionField != nil {
+		// the versioned entities are deleted one at a time, so that the one changed by another transaction is found
+		predicates := make([]string, 0)
+
+		for index, idField := range entity.IdFields {
+			predicates = append(predicates, idField.ColumnName+" = "+generator.dialect.Placeholder(index+1))
+		}
+
+		data.VersionField = versionField.FieldName
+		data.Statement += " WHERE " + strings.Join(predicates, " AND ") + " AND " + versionField.ColumnName + " = " +
+			generator.dialect.Placeholder(len(entity.IdFields)+1)
+
+		if predicate != "" {
+			data.Statement += " AND " + predicate
+		}
+
+		data.StatementSuffix = ""
+	} else {
+		data.Statement += " WHERE " + getIdIn(entity)
+	}
+
+	data.Statement = escapeString(data.Statement)
+	data.StatementSuffix = escapeString(data.StatementSuffix)
+
+	generator.deleters[name] = data
+	data.Before, data.After = generator.getDeleteSteps(entity)
+
+	for _, association := range entity.Associations {
+		if association.CascadeRemove || association.OrphanRemoval {
+			generator.useDeleter(entityMetadataByStructName[association.Target])
+		}
+	}
+
+	generator.deleters[name] = data
+	generator.use("github.com/procyon-projects/shelf")
+	return &data
+}
+
+// getDeleteSteps returns the steps of the delete of the entity. The targets referred by the join columns of
+// the entity are deleted after it, and the other ones before it, after they are loaded. Each target is deleted
+// or soft-deleted as its own entity is. The rows of the join tables referring to the entity are removed and
+// the join columns of the targets which are not deleted are cleared, unless the entity is soft-deleted, as its
+// row is kept. The join columns of the targets the delete is cascaded to are kept, so that the soft-deleted
+// ones still refer to the entity.
+func (generator *RepositoryGenerator) getDeleteSteps(entity EntityMetadata) ([]CascadeStepTemplateData, []CascadeStepTemplateData) {
+	before := make([]CascadeStepTemplateData, 0)
+	after := make([]CascadeStepTemplateData, 0)
+	isSoftDeleted := entity.SoftDeleteField != nil
+
+	for _, association := range entity.Associations {
+		target := entityMetadataByStructName[association.Target]
+		step := generator.getCascadeStep(association, target)
+
+		if association.CascadeRemove || association.OrphanRemoval {
+			step.Cascade = "cascadeDelete" + target.StructName
+		}
+
+		if association.OwnsJoinColumn() {
+			if step.Cascade != "" {
+				after = append(after, step)
+			}
+
+			continue
+		}
+
+		if step.Cascade != "" {
+			step.Loader = generator.useLoader(entity, generator.getStructTypeName(entity), association)
+		}
+
+		switch {
+		case isSoftDeleted:
+			// the rows referring to the entity are kept with its row
+		case association.Kind == shelf.ManyToMany:
+			step.Unlink = "DELETE FROM " + generator.dialect.Quote(association.JoinTable) + " WHERE " +
+				association.JoinColumn + " IN ("
+		case step.Cascade == "":
+			// the rows of the targets which are not deleted would still refer to the entity
+			step.Unlink = "UPDATE " + generator.dialect.Quote(target.TableName) + " SET " + association.JoinColumn +
+				" = NULL WHERE " + association.JoinColumn + " IN ("
+		}
+
+		if step.Cascade != "" || step.Unlink != "" {
+			before = append(before, escapeStep(step))
+		}
+	}
+
+	return before, after
+}
+
+// getIdIn returns the start of the predicate comparing the id columns of the entity to a list of values,
+// which are row values if the key is composite, e.g. (user_id, role_id) IN (.
+func getIdIn(entity EntityMetadata) string {
+	if len(entity.IdFields) == 1 {
+		return entity.IdField.ColumnName + " IN ("
+	}
+
+	names := make([]string, 0)
+
+	for _, idField := range entity.IdFields {
+		names = append(names, idField.ColumnName)
+	}
+
+	return "(" + strings.Join(names, ", ") + ") IN ("
+}
+
+// getCascadeStep returns the step of a cascading function for the association without any operation.
+func (generator *RepositoryGenerator) getCascadeStep(association AssociationMetadata, target EntityMetadata) CascadeStepTemplateData {
+	return CascadeStepTemplateData{
+		Field:             association.FieldName,
+		IsCollection:      association.IsCollection,
+		IsPointer:         association.IsPointer,
+		Key:               association.ReferencedField.FieldName,
+		TargetId:          target.IdField.FieldName,
+		PlaceholderFormat: generator.dialect.PlaceholderFormat,
+	}
+}
+
+// escapeStep escapes the statements of the step, which are quoted in the generated code.
+func escapeStep(step CascadeStepTemplateData) CascadeStepTemplateData {
+	step.Unlink = escapeString(step.Unlink)
+	step.Link = escapeString(step.Link)
+	step.Orphans = escapeString(step.Orphans)
+	return step
+}
+
+// getNewEntityCondition returns the condition of the targets of a PERSIST cascade which are not saved yet.
+// The entities without a generated id or a version cannot be told apart, so all of them are saved.
+func getNewEntityCondition(entity EntityMetadata) string {
+	if entity.IdField.IsGenerated {
+		return "target." + entity.IdField.FieldName + " == " + GetZeroValueOfField(*entity.IdField)
+	}
+
+	for _, field := range entity.Fields {
+		if !field.IsVersion {
+			continue
+		}
+
+		if GetFullNameFromType(field.Type) == "time.Time" {
+			return "target." + field.FieldName + ".IsZero()"
+		}
+
+		return "target." + field.FieldName + " == 0"
+	}
+
+	return ""
+}
+
+// executeStatement returns the body of the function saving the entity alone, which is generated by the method
+// template with the parameters of the cascading functions.
+func (generator *RepositoryGenerator) executeStatement(methodTemplate string, entity EntityMetadata, entityType string) string {
+	queryData := generator.getEntityQueryTemplateData(entity, entityType)
+	queryData.Context = "ctx"
+	queryData.Executor = "executor"
+	queryData.Parameters = []string{"ctx", "entity"}
+	queryData.ReturnsError = true
+
+	body, err := generator.execute(methodTemplate, queryData)
+
+	if err != nil {
+		errs = append(errs, marker.NewError(err, entity.StructType.File.FullPath, entity.StructType.Position))
+	}
+
+	return body + "\n\n" + queryData.Return()
+}
+
+// useIdGenerator adds the function assigning the id of the entity before it is inserted to the generated file and
+// returns its name, or empty if the id is not generated or it is generated by the database. The ids of the SEQUENCE
+// and TABLE strategies are allocated from a shelf.Sequence, and the other ones are generated by the shelf.IdGenerator
+// registered with the name of the strategy or the Generator argument.
+func (generator *RepositoryGenerator) useIdGenerator(entity EntityMetadata, entityType string) string {
+	idField := entity.IdField
+
+	if !idField.IsGenerated || idField.Generation.IsDatabaseGenerated() {
+		return ""
+	}
+
+	structName := entity.StructName
+	name := "generate" + structName + "Id"
+
+	if _, ok := generator.idGenerators[name]; ok {
+		return name
+	}
+
+	generation := idField.Generation
+	data := IdGeneratorTemplateData{
+		Name:      name,
+		Entity:    entity.EntityName,
+		Type:      entityType,
+		Field:     idField.FieldName,
+		IdType:    generator.useTypeName(idField.File, idField.Type),
+		Generator: generation.Generator,
+	}
+
+	sequenceName := generation.SequenceName
+
+	if sequenceName == "" {
+		sequenceName = entity.TableName + "_seq"
+	}
+
+	size := strconv.Itoa(generation.AllocationSize)
+
+	switch generation.Strategy {
+	case GenerationSequence:
+		data.Source = "the sequence " + sequenceName
+		data.Query = escapeString("SELECT nextval('" + sequenceName + "')")
+	case GenerationTable:
+		data.Source = "the row " + sequenceName + " of " + IdTable
+		data.Query = escapeString("UPDATE " + IdTable + " SET next_value = next_value + " + size + " WHERE name = '" +
+			sequenceName + "' RETURNING next_value - " + size)
+
+		// the id is returned by LAST_INSERT_ID, which is kept for the connection
+		if !generator.dialect.SupportsReturning {
+			data.Query = escapeString("UPDATE " + IdTable + " SET next_value = LAST_INSERT_ID(next_value + " + size +
+				") WHERE name = '" + sequenceName + "'")
+			data.LastInsertId = true
+		}
+	}
+
+	if data.Query != "" {
+		data.Sequence = string(unicode.ToLower(rune(structName[0]))) + structName[1:] + "IdSequence"
+		data.AllocationSize = generation.AllocationSize
+	}
+
+	generator.idGenerators[name] = data
+	generator.use("github.com/procyon-projects/shelf")
+	return name
+}
+
+// useEnum adds the conversions of the enum type of the field to the generated file and returns the type converting
+// the values of the field for its mapping, which is used in place of the underlying type of the other named types.
+func (generator *RepositoryGenerator) useEnum(field FieldMetadata) string {
+	enum := field.Enum
+	typeName := generator.useTypeName(field.File, field.Type)
+	prefix := string(unicode.ToLower(rune(enum.Name[0]))) + enum.Name[1:]
+	key := enum.File.Package.Path + "." + enum.Name
+	data, ok := generator.enums[key]
+
+	if !ok {
+		data = EnumTemplateData{
+			Name:      enum.Name,
+			Type:      typeName,
+			Constants: prefix + "Constants",
+			Names:     prefix + "Names",
+		}
+
+		// the constants are qualified like the type if it is declared in another package
+		qualifier := ""
+
+		if index := strings.LastIndex(typeName, "."); index != -1 {
+			qualifier = typeName[:index+1]
+		}
+
+		for _, constant := range enum.Constants {
+			value := EnumConstantTemplateData{
+				Constant: qualifier + constant,
+				Name:     strconv.Quote(constant),
+			}
+
+			if enum.IsStringer {
+				value.Name = qualifier + constant + ".String()"
+			}
+
+			data.Values = append(data.Values, value)
+		}
+	}
+
+	name := prefix + "Name"
+
+	if enum.Ordinal {
+		name = prefix + "Ordinal"
+		data.Ordinal = name
+	} else {
+		data.String = name
+	}
+
+	generator.enums[key] = data
+	generator.use("database/sql/driver")
+	generator.use("github.com/procyon-projects/shelf")
+	return name
+}
+
+// useAuditor adds the functions filling the audit fields of the entity to the generated file
+// and returns them, or nil if the entity has no audit field.
+func (generator *RepositoryGenerator) useAuditor(entity EntityMetadata, queryData QueryTemplateData) *AuditorTemplateData {
+	structName := entity.StructName
+	data := AuditorTemplateData{
+		Entity:   structName,
+		Type:     queryData.Entity,
+		Creation: "audit" + structName + "Creation",
+	}
+
+	hasAuditField := false
+
+	for index, field := range entity.Fields {
+		column := queryData.Columns[index]
+
+		switch field.AuditMarker {
+		case shelf.MarkerCreatedDate:
+			data.CreatedDate = &column
+		case shelf.MarkerLastModifiedDate:
+			data.LastModifiedDate = &column
+		case shelf.MarkerCreatedBy:
+			data.CreatedBy = &column
+			generator.useType(field.File, field.Type)
+		case shelf.MarkerLastModifiedBy:
+			data.LastModifiedBy = &column
+			generator.useType(field.File, field.Type)
+		default:
+			continue
+		}
+
+		hasAuditField = true
+	}
+
+	if !hasAuditField {
+		return nil
+	}
+
+	if data.LastModifiedDate != nil || data.LastModifiedBy != nil {
+		data.Modification = "audit" + structName + "Modification"
+	}
+
+	generator.auditors[data.Creation] = data
+	generator.use("github.com/procyon-projects/shelf")
+	return &data
+}
+
+// useSortProperties adds the map of the sortable properties of the entity to the generated file and returns its name.
+func (generator *RepositoryGenerator) useSortProperties(repository RepositoryMetadata, columns []ColumnTemplateData) string {
+	structName := repository.Entity.StructName
+	name := string(unicode.ToLower(rune(structName[0]))) + structName[1:] + "SortProperties"
+	properties := make([]ColumnTemplateData, 0)
+
+	// the join columns are not properties of the entity
+	for _, column := range columns {
+		if column.Reference == "" {
+			properties = append(properties, column)
+		}
+	}
+
+	generator.sortProperties[name] = SortPropertiesTemplateData{
+		Name:       name,
+		Entity:     structName,
+		Properties: properties,
+	}
+
+	return name
+}
+
+func getDerivedQueryTemplate(kind QueryKind) string {
+	switch kind {
+	case CountQuery:
+		return countByTemplate
+	case ExistsQuery:
+		return existsByTemplate
+	case DeleteQuery:
+		return deleteByTemplate
+	}
+
+	return findTemplate
+}
+
+// getAttributeTypeName returns the name of the attribute type generated for the value type, e.g. timeTimeAttribute
+// for time.Time and pointerStringAttribute for *string.
+func getAttributeTypeName(valueType string) string {
+	replacer := strings.NewReplacer("*", " pointer ", "[]", " slice ", "map[", " map ", "]", " ", ".", " ")
+	name := ""
+
+	for index, word := range strings.Fields(replacer.Replace(valueType)) {
+		if index == 0 {
+			name += string(unicode.ToLower(rune(word[0]))) + word[1:]
+		} else {
+			name += string(unicode.ToUpper(rune(word[0]))) + word[1:]
+		}
+	}
+
+	return name + "Attribute"
+}
+
+func getCustomQueryTemplate(customMethod CustomQueryMethod) string {
+	if customMethod.Result == ScalarResult {
+		return scalarTemplate
+	}
+
+	if customMethod.Query.Kind != SelectQuery {
+		return deleteByTemplate
+	}
+
+	return findTemplate
+}
+
+func getQueryResultName(result QueryResult) string {
+	switch result {
+	case SingleResult:
+		return "single"
+	case ListResult:
+		return "list"
+	case PageResult:
+		return "page"
+	case SliceResult:
+		return "slice"
+	case CountResult:
+		return "count"
+	case ExistsResult:
+		return "exists"
+	case CursorResult:
+		return "cursor"
+	}
+
+	return ""
+}
+
+// escapeString escapes the text so that it can be placed in a Go string literal.
+func escapeString(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ", "\t", " ").Replace(text)
+}
+
+func getColumnNames(columns []ColumnTemplateData) string {
+	names := make([]string, 0)
+
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func (generator *RepositoryGenerator) execute(text string, data QueryTemplateData) (string, error) {
+	tmpl, err := template.New("method").Funcs(generator.templateFunctions()).Parse(text)
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmpl.Parse(sqlTemplates)
+
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	err = tmpl.Execute(&buffer, data)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buffer.String()), nil
+}
+
+func (generator *RepositoryGenerator) templateFunctions() template.FuncMap {
+	return template.FuncMap{
+		"add": func(a, b int) int {
+			return a + b
+		},
+		"placeholder": func(index int) string {
+			return generator.dialect.Placeholder(index)
+		},
+		"placeholders": func(start, count int) string {
+			placeholders := make([]string, 0)
+
+			for index := start; index < start+count; index++ {
+				placeholders = append(placeholders, generator.dialect.Placeholder(index))
+			}
+
+			return strings.Join(placeholders, ", ")
+		},
+		"placeholderFormat": func() string {
+			generator.use("github.com/procyon-projects/shelf")
+			return generator.dialect.PlaceholderFormat
+		},
+		"columns": getColumnNames,
+		"assignments": func(start int, columns []ColumnTemplateData) string {
+			assignments := make([]string, 0)
+
+			for index, column := range columns {
+				assignments = append(assignments, column.Name+" = "+generator.dialect.Placeholder(start+index))
+			}
+
+			return strings.Join(assignments, ", ")
+		},
+		"idPredicate": func(start int, idColumns []ColumnTemplateData) string {
+			predicates := make([]string, 0)
+
+			for index, column := range idColumns {
+				predicates = append(predicates, column.Name+" = "+generator.dialect.Placeholder(start+index))
+			}
+
+			return strings.Join(predicates, " AND ")
+		},
+		"idIn": func(idColumns []ColumnTemplateData, count string) string {
+			generator.use("github.com/procyon-projects/shelf")
+
+			if len(idColumns) == 1 {
+				return idColumns[0].Name + " IN (\"+" + generator.dialect.PlaceholderFormat + ".Placeholders(1, " + count + ")+\")"
+			}
+
+			// the composite keys are compared as row values, e.g. (a, b) IN (($1, $2), ($3, $4))
+			return "(" + getColumnNames(idColumns) + ") IN (\"+" + generator.dialect.PlaceholderFormat + ".Rows(" + count + ", " +
+				strconv.Itoa(len(idColumns)) + ")+\")"
+		},
+		"keyFields": func(key string, idColumns []ColumnTemplateData) string {
+			if len(idColumns) == 1 {
+				return key
+			}
+
+			fields := make([]string, 0)
+
+			for _, column := range idColumns {
+				fields = append(fields, key+"."+column.KeyField)
+			}
+
+			return strings.Join(fields, ", ")
+		},
+		"upsert": func(idColumns []ColumnTemplateData, columns []ColumnTemplateData) string {
+			idNames := make([]string, 0)
+			names := make([]string, 0)
+
+			for _, column := range idColumns {
+				idNames = append(idNames, column.Name)
+			}
+
+			for _, column := range columns {
+				names = append(names, column.Name)
+			}
+
+			return generator.dialect.Upsert(idNames, names)
+		},
+		"fields": func(prefix string, columns []ColumnTemplateData) string {
+			fields := make([]string, 0)
+
+			for _, column := range columns {
+				if column.Reference != "" {
+					fields = append(fields, column.Reference+"("+prefix+")")
+				} else if column.Underlying != "" {
+					fields = append(fields, column.Underlying+"("+prefix+"."+column.Field+")")
+				} else {
+					fields = append(fields, prefix+"."+column.Field)
+				}
+			}
+
+			return strings.Join(fields, ", ")
+		},
+		"nextVersion": func(prefix string, version ColumnTemplateData) string {
+			if version.Type == "time.Time" {
+				generator.use("time")
+				// the databases store the timestamps in microseconds, which the version is compared with
+				return "time.Now().Truncate(time.Microsecond)"
+			}
+
+			return prefix + "." + version.Field + " + 1"
+		},
+		"isZeroVersion": func(prefix string, version ColumnTemplateData) string {
+			if version.Type == "time.Time" {
+				return prefix + "." + version.Field + ".IsZero()"
+			}
+
+			return prefix + "." + version.Field + " == 0"
+		},
+		"pointers": func(prefix string, paths []string, converters []string) string {
+			pointers := make([]string, 0)
+
+			for index, path := range paths {
+				pointers = append(pointers, getFieldPointer(prefix, ColumnTemplateData{
+					Field:      path,
+					Underlying: converters[index],
+				}))
+			}
+
+			return strings.Join(pointers, ", ")
+		},
+	}
+}
+
+// use adds the package to the imports of the generated file.
+func (generator *RepositoryGenerator) use(importPath string) {
+	if _, ok := generator.imports[importPath]; !ok {
+		generator.imports[importPath] = ""
+	}
+}
+
+func (generator *RepositoryGenerator) useTypeImports(file *marker.File, typ marker.Type) {
+	for _, importName := range GetImportNamesFromType(typ) {
+		importPath := FindImportPath(file, importName)
+
+		if GetPackageName(file, importPath) == importName {
+			generator.use(importPath)
+		} else {
+			generator.imports[importPath] = importName
+		}
+	}
+}
+
+// getFieldPointer returns the pointer which the column is scanned into, e.g. (*int)(&entity.Status).
+func getFieldPointer(prefix string, column ColumnTemplateData) string {
+	if column.Underlying != "" {
+		return "(*" + column.Underlying + ")(&" + prefix + "." + column.Field + ")"
+	}
+
+	return "&" + prefix + "." + column.Field
+}
+
+// getUnderlyingTypeName returns the type which the values of the field are converted to, which is the type
+// converting an enum or the builtin type underlying another named type, or empty if the field is not of such a type.
+func (generator *RepositoryGenerator) getUnderlyingTypeName(field FieldMetadata) string {
+	if field.Enum != nil {
+		return generator.useEnum(field)
+	}
+
+	return GetUnderlyingTypeName(field)
+}
+
+// GetUnderlyingTypeName returns the builtin type underlying the named type of the field, or empty if
+// the field is not of such a type. The enums are not converted to their underlying types, as their
+// values are checked against their constants, see useEnum.
+func GetUnderlyingTypeName(field FieldMetadata) string {
+	if _, ok := field.Type.(*marker.ObjectType); !ok || field.Enum != nil {
+		return ""
+	}
+
+	userDefinedType, ok := userDefinedTypesByQualifiedName[GetQualifiedNameFromType(field.File, field.Type)]
+
+	if !ok {
+		return ""
+	}
+
+	if objectType, ok := userDefinedType.ActualType.(*marker.ObjectType); ok && objectType.ImportName == "" && IsBuiltinType(objectType.Name) {
+		return objectType.Name
+	}
+
+	return ""
+}
+
+// getTypeName returns the type declared in the file as it is referred in the generated package. The imports
+// of the type are not added to the generated file, see useTypeName.
+func (generator *RepositoryGenerator) getTypeName(file *marker.File, typ marker.Type) string {
+	switch typed := typ.(type) {
+	case *marker.ObjectType:
+		if typed.ImportName != "" {
+			return GetFullNameFromType(typed)
+		}
+
+		if IsBuiltinType(typed.Name) || file.Package.Path == generator.packagePath {
+			return typed.Name
+		}
+
+		return file.Package.Name + "." + typed.Name
+	case *marker.PointerType:
+		return "*" + generator.getTypeName(file, typed.Typ)
+	case *marker.ArrayType:
+		return "[]" + generator.getTypeName(file, typed.ItemType)
+	case *marker.DictionaryType:
+		return "map[" + generator.getTypeName(file, typed.KeyType) + "]" + generator.getTypeName(file, typed.ValueType)
+	}
+
+	return GetFullNameFromType(typ)
+}
+
+// useTypeName returns the type declared in the file as it is referred in the generated package, and adds
+// its imports to the generated file, which must only be done for the types rendered in the file.
+func (generator *RepositoryGenerator) useTypeName(file *marker.File, typ marker.Type) string {
+	generator.useType(file, typ)
+	return generator.getTypeName(file, typ)
+}
+
+// useType adds the imports of the type declared in the file to the generated file.
+func (generator *RepositoryGenerator) useType(file *marker.File, typ marker.Type) {
+	switch typed := typ.(type) {
+	case *marker.ObjectType:
+		if typed.ImportName != "" {
+			generator.useTypeImports(file, typed)
+		} else if !IsBuiltinType(typed.Name) && file.Package.Path != generator.packagePath {
+			generator.use(file.Package.Path)
+		}
+	case *marker.PointerType:
+		generator.useType(file, typed.Typ)
+	case *marker.ArrayType:
+		generator.useType(file, typed.ItemType)
+	case *marker.DictionaryType:
+		generator.useType(file, typed.KeyType)
+		generator.useType(file, typed.ValueType)
+	}
+}
+
+// getEntityTypeName returns the name of the entity struct as it is referred in the repository package.
+func (generator *RepositoryGenerator) getEntityTypeName(repository RepositoryMetadata) string {
+	entityFile := repository.Entity.StructType.File
+	repositoryFile := repository.InterfaceType.File
+
+	if entityFile.Package.Path == repositoryFile.Package.Path {
+		return repository.Entity.StructName
+	}
+
+	for _, fileImport := range repositoryFile.Imports {
+		if fileImport.Path == entityFile.Package.Path && fileImport.Name != "" {
+			generator.imports[fileImport.Path] = fileImport.Name
+			return fileImport.Name + "." + repository.Entity.StructName
+		}
+	}
+
+	generator.use(entityFile.Package.Path)
+	return entityFile.Package.Name + "." + repository.Entity.StructName
+}
+
+// getStructTypeName returns the name of the entity struct as it is referred in the generated package.
+func (generator *RepositoryGenerator) getStructTypeName(entity EntityMetadata) string {
+	entityFile := entity.StructType.File
+
+	if entityFile.Package.Path == generator.packagePath {
+		return entity.StructName
+	}
+
+	generator.use(entityFile.Package.Path)
+	return entityFile.Package.Name + "." + entity.StructName
+}
+
+// IsGeneratedVariableName reports whether the name is used by the variables declared in the generated methods.
+func IsGeneratedVariableName(name string) bool {
+	switch name {
+	case "err", "args", "rows", "tx", "entity", "entities", "count", "exists", "repository",
+		"insertStmt", "updateStmt", "upsertStmt", "query", "orderBy", "total", "countQuery", "countArgs",
+		"hasNext", "result", "affected", "id", "scan", "cursor", "value", "where", "whereArgs", "row",
+		"inserts", "updates", "chunk", "start", "end", "index", "copyStmt", "deleteStmt", "version", "versions", "executor",
+		"cascade", "inserted":
+		return true
+	}
+
+	return false
+}
+
+// GetZeroValueOfType returns the zero value expression of the type declared in the file.
+func GetZeroValueOfType(file *marker.File, typ marker.Type) string {
+	if _, ok := interfaceTypesByQualifiedName[GetQualifiedNameFromType(file, typ)]; ok {
+		return "nil"
+	}
+
+	return GetZeroValue(GetFullNameFromType(typ))
+}
+
+// GetZeroValueOfField returns the zero value expression of the field type. The zero values of the types
+// defined by the builtin types are the untyped constants of their underlying types, e.g. "" for a string id.
+func GetZeroValueOfField(field FieldMetadata) string {
+	if underlying := getUnderlyingType(field.File, field.Type); IsBuiltinType(underlying) {
+		return GetZeroValue(underlying)
+	}
+
+	return GetZeroValue(GetFullNameFromType(field.Type))
+}
+
+// GetZeroValue returns the zero value expression of the given type.
+func GetZeroValue(typeName string) string {
+	switch typeName {
+	case "bool":
+		return "false"
+	case "string":
+		return `""`
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64", "byte", "rune", "uintptr":
+		return "0"
+	}
+
+	if strings.HasPrefix(typeName, "*") || strings.HasPrefix(typeName, "[]") ||
+		strings.HasPrefix(typeName, "map[") || typeName == "error" || typeName == "interface{}" {
+		return "nil"
+	}
+
+	return typeName + "{}"
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"testing"
+)
+
+// joinColumnSource is the package of the join column tests, whose posts refer to their writers by their emails.
+const joinColumnSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Email string
+	// +shelf:many-to-many
+	// +shelf:join-table="user_roles", JoinColumns={"member_id"}, InverseJoinColumns={"role_code"}
+	Roles []Role
+}
+
+// +shelf:entity
+// +shelf:table=roles
+type Role struct {
+	// +shelf:id
+	Code string
+}
+
+// +shelf:entity
+// +shelf:table=posts
+type Post struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Title string
+	// +shelf:many-to-one
+	// +shelf:join-column="writer_email", ReferencedColumn="email", ForeignKeyName="fk_posts_writer", Unique=true
+	Writer *User
+}
+`
+
+func TestValidate_JoinColumns(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Source string
+		Errors []string
+	}{
+		{
+			Name: "join column without a relationship",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:join-column="post_id"
+	PostId int
+}`,
+			Errors: []string{
+				"'shelf:join-column', 'shelf:join-table' and 'shelf:maps-id' markers can only be used with the relationship markers",
+			},
+		},
+		{
+			Name: "join column of the inverse side",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-many:MappedBy=Writer
+	// +shelf:join-column="writer_id"
+	Posts []Post
+}`,
+			Errors: []string{
+				"'shelf:join-column' marker can only be used on the owning side of a one-to-one, many-to-one or one-to-many association",
+			},
+		},
+		{
+			Name: "join table of a single-valued association",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:many-to-one
+	// +shelf:join-table="comment_posts"
+	Post *Post
+}`,
+			Errors: []string{
+				"'shelf:join-table' marker can only be used on the owning side of a many-to-many association",
+			},
+		},
+		{
+			Name: "unmapped referenced column",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:many-to-one
+	// +shelf:join-column="post_slug", ReferencedColumn="slug"
+	Post *Post
+}`,
+			Errors: []string{
+				"the column 'slug' referenced by the join column of the field 'Post' must be mapped to a field of the entity 'Post'",
+			},
+		},
+		{
+			Name: "more than one join column of a join table",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:many-to-many
+	// +shelf:join-table="comment_roles", JoinColumns={"comment_id", "comment_version"}
+	Roles []Role
+}`,
+			Errors: []string{
+				"the join table of the field 'Roles' can only have one join column and one inverse join column, as the entities have a single id",
+			},
+		},
+		{
+			Name: "join columns of the same name",
+			Source: `
+// +shelf:entity
+type Node struct {
+	// +shelf:id
+	Id int
+	// +shelf:many-to-many
+	Children []Node
+}`,
+			Errors: []string{
+				"the join columns of the join table 'node_node' cannot be both named 'node_id', they must be given by 'shelf:join-table' marker",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assertErrors(t, validate(t, "fixture", joinColumnSource+testCase.Source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_JoinColumns(t *testing.T) {
+	repository := `
+// +shelf:repository="post-repository", Entity=Post
+type PostRepository interface {
+	Save(ctx context.Context, post *Post) error
+	LoadWriter(ctx context.Context, posts []*Post) error
+}
+
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	LoadRoles(ctx context.Context, users []*User) error
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				"return entity.Writer.Email",
+				`"INSERT INTO posts(title, writer_email) VALUES($1, $2) RETURNING id", post.Title, postWriterId(post)`,
+				`"SELECT id, email FROM users WHERE email IN ("+shelf.Dollar.Placeholders(1, end-start)+")"`,
+				`"SELECT j.member_id, t.code FROM roles t JOIN user_roles j ON j.role_code = t.code WHERE j.member_id IN ("+shelf.Dollar.Placeholders(1, end-start)+") ORDER BY t.code"`,
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`"INSERT INTO posts(title, writer_email) VALUES(?, ?)", post.Title, postWriterId(post)`,
+				`"SELECT id, email FROM users WHERE email IN ("+shelf.Question.Placeholders(1, end-start)+")"`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			generated := generateFiles(t, "fixture", map[string]string{"fixture.go": joinColumnSource + repository},
+				"-a", "dialect="+testCase.Dialect)
+
+			assertContains(t, generated["fixture_repositories.go"], testCase.Statements...)
+			assertContains(t, generated["fixture_metamodel.go"],
+				"JoinColumn: &shelf.JoinColumn{\n\t\t\t\tTable:            \"posts\",\n\t\t\t\tName:             \"writer_email\",\n"+
+					"\t\t\t\tReferencedTable:  \"users\",\n\t\t\t\tReferencedColumn: \"email\",\n\t\t\t\tForeignKeyName:   \"fk_posts_writer\",\n"+
+					"\t\t\t\tNullable:         false,\n\t\t\t\tUnique:           true,",
+				"JoinTable: &shelf.JoinTable{\n\t\t\t\tName:               \"user_roles\",\n\t\t\t\tJoinColumns:        []string{\"member_id\"},\n"+
+					"\t\t\t\tInverseJoinColumns: []string{\"role_code\"},",
+			)
+		})
+	}
+}
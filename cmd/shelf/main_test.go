@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// shelfCommand is the shelf command built by TestMain, which the tests run on the packages they write to testdata.
+var shelfCommand string
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "shelf")
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	shelfCommand = filepath.Join(dir, "shelf")
+	output, err := exec.Command("go", "build", "-o", shelfCommand, ".").CombinedOutput()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n%s", err, output)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// writePackage writes the files into a package in testdata and returns its directory, which is removed
+// by the returned function.
+func writePackage(t *testing.T, name string, files map[string]string) (string, func()) {
+	dir := filepath.Join("testdata", name)
+	err := os.MkdirAll(dir, 0755)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for fileName, source := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, fileName), []byte(source), 0644)
+
+		if err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+// runShelf runs the command on the package and returns the messages of the errors it reports.
+func runShelf(t *testing.T, dir string, args ...string) []string {
+	command := exec.Command(shelfCommand, append(args, "-p", "./"+filepath.ToSlash(dir))...)
+	output, err := command.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("%s\n%s", err, output)
+	}
+
+	messages := make([]string, 0)
+
+	for _, line := range strings.Split(string(output), "\n") {
+		// the errors of the files are reported as 'file (line:column) : message'
+		if index := strings.Index(line, " : "); index != -1 {
+			messages = append(messages, line[index+3:])
+		} else if strings.TrimSpace(line) != "" {
+			messages = append(messages, line)
+		}
+	}
+
+	return messages
+}
+
+// validate returns the errors reported by the validate command for the source.
+func validate(t *testing.T, name string, source string, args ...string) []string {
+	dir, remove := writePackage(t, name, map[string]string{name + ".go": source})
+	defer remove()
+
+	return runShelf(t, dir, append([]string{"validate"}, args...)...)
+}
+
+// generate returns the repositories generated for the source after checking that they compile.
+func generate(t *testing.T, name string, source string, args ...string) string {
+	return generateFiles(t, name, map[string]string{name + ".go": source}, args...)[name+"_repositories.go"]
+}
+
+// generateFiles returns the files generated for the files of a package by their names after checking that they compile.
+func generateFiles(t *testing.T, name string, files map[string]string, args ...string) map[string]string {
+	dir, remove := writePackage(t, name, files)
+	defer remove()
+
+	messages := runShelf(t, dir, append([]string{"generate", "-o", dir}, args...)...)
+
+	if len(messages) != 0 {
+		t.Fatalf("the repositories should be generated, but got %s", strings.Join(messages, "\n"))
+	}
+
+	output, err := exec.Command("go", "vet", "./"+filepath.ToSlash(dir)).CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("the generated repositories should compile, but got %s\n%s", err, output)
+	}
+
+	fileInfos, err := ioutil.ReadDir(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated := make(map[string]string)
+
+	for _, fileInfo := range fileInfos {
+		if _, ok := files[fileInfo.Name()]; ok {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, fileInfo.Name()))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		generated[fileInfo.Name()] = string(content)
+	}
+
+	return generated
+}
+
+// runFixture generates the repositories for the source and runs the tests in the test source against them.
+// The fake database of driver_test.go in the shelf package is copied into the package, so that the tests
+// can check the statements run by the generated code.
+func runFixture(t *testing.T, name string, source string, test string, args ...string) {
+	driverSource, err := ioutil.ReadFile(filepath.Join("..", "..", "driver_test.go"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, remove := writePackage(t, name, map[string]string{
+		name + ".go":      source,
+		name + "_test.go": test,
+		"driver_test.go":  strings.Replace(string(driverSource), "package shelf", "package "+name, 1),
+	})
+	defer remove()
+
+	messages := runShelf(t, dir, append([]string{"generate", "-o", dir}, args...)...)
+
+	if len(messages) != 0 {
+		t.Fatalf("the repositories should be generated, but got %s", strings.Join(messages, "\n"))
+	}
+
+	output, err := exec.Command("go", "test", "./"+filepath.ToSlash(dir)).CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("the generated repositories should pass the tests, but got %s\n%s", err, output)
+	}
+}
+
+// assertErrors checks that the messages are the expected ones in any order.
+func assertErrors(t *testing.T, messages []string, expected ...string) {
+	if len(messages) != len(expected) {
+		t.Errorf("the errors should be %q, but got %q", expected, messages)
+		return
+	}
+
+	for _, message := range expected {
+		found := false
+
+		for _, actual := range messages {
+			found = found || actual == message
+		}
+
+		if !found {
+			t.Errorf("the errors should contain %q, but got %q", message, messages)
+		}
+	}
+}
+
+// assertContains checks that the generated source contains the fragments, e.g. the statements of the methods.
+func assertContains(t *testing.T, source string, fragments ...string) {
+	for _, fragment := range fragments {
+		if !strings.Contains(source, fragment) {
+			t.Errorf("the generated source should contain %q", fragment)
+		}
+	}
+}
+
+// TestGenerate_Sample generates the repositories of the sample in test/package1, which must always compile.
+func TestGenerate_Sample(t *testing.T) {
+	sources, err := filepath.Glob(filepath.Join("..", "..", "test", "package1", "*.go"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+
+	for _, source := range sources {
+		if strings.HasSuffix(source, "_repositories.go") || strings.HasSuffix(source, "_metamodel.go") {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(source)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files[filepath.Base(source)] = string(content)
+	}
+
+	generateFiles(t, "package1", files)
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"github.com/procyon-projects/marker"
+	"github.com/procyon-projects/shelf"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+type MetamodelFileTemplateData struct {
+	PackageName string
+	// Imports are the packages used by the associations, which are described by shelf.Association, and by
+	// their accessors.
+	Imports  []ImportTemplateData
+	Entities []EntityMetaTemplateData
+}
+
+// EntityMetaTemplateData describes the static metamodel of an entity, e.g. UserMeta.Columns.Email.
+type EntityMetaTemplateData struct {
+	Name         string
+	Type         string
+	Entity       string
+	StructName   string
+	Table        string
+	IdField      string
+	IdColumn     string
+	Columns      []ColumnTemplateData
+	Associations []AssociationTemplateData
+	Enums        []EnumMetaTemplateData
+	Accessors    []AccessorTemplateData
+}
+
+// EnumMetaTemplateData describes the column of an enum field, see shelf.Enum. Names and Constants are the
+// expressions of the stored names and of the constants of the enum.
+type EnumMetaTemplateData struct {
+	Property  string
+	Column    string
+	Ordinal   bool
+	Names     []string
+	Constants []string
+}
+
+type AssociationTemplateData struct {
+	Field      string
+	Kind       string
+	Target     string
+	MappedBy   string
+	JoinColumn *shelf.JoinColumn
+	JoinTable  *shelf.JoinTable
+}
+
+// AccessorTemplateData describes the method of an entity returning its association, which is loaded on first
+// access by the session of the context unless it is fetched with the entity, e.g.
+// func (entity *User) GetPosts(ctx) ([]*Post, error).
+type AccessorTemplateData struct {
+	Name  string
+	Field string
+	Type  string
+}
+
+var associationKinds = []struct {
+	Marker string
+	Kind   shelf.AssociationKind
+}{
+	{Marker: shelf.MarkerOneToOne, Kind: shelf.OneToOne},
+	{Marker: shelf.MarkerOneToMany, Kind: shelf.OneToMany},
+	{Marker: shelf.MarkerManyToOne, Kind: shelf.ManyToOne},
+	{Marker: shelf.MarkerManyToMany, Kind: shelf.ManyToMany},
+}
+
+// GenerateMetamodels generates a file containing the static metamodels of the entities for each package.
+func GenerateMetamodels(outputPath string) error {
+	entitiesByPackage := make(map[string][]EntityMetadata)
+
+	for _, entity := range entityMetadataByStructName {
+		packagePath := entity.StructType.File.Package.Path
+		entitiesByPackage[packagePath] = append(entitiesByPackage[packagePath], entity)
+	}
+
+	for _, entities := range entitiesByPackage {
+		sort.Slice(entities, func(i, j int) bool {
+			return entities[i].StructName < entities[j].StructName
+		})
+
+		packageName := entities[0].StructType.File.Package.Name
+		source, err := GenerateMetamodelFile(packageName, entities)
+
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(outputPath, os.ModePerm)
+
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(filepath.Join(outputPath, packageName+"_metamodel.go"), source, 0644)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GenerateMetamodelFile returns the formatted source of the static metamodels of the entities in a package.
+func GenerateMetamodelFile(packageName string, entities []EntityMetadata) ([]byte, error) {
+	data := MetamodelFileTemplateData{
+		PackageName: packageName,
+	}
+
+	imports := make(map[string]string)
+
+	for _, entity := range entities {
+		entityData := EntityMetaTemplateData{
+			Name:       entity.StructName + "Meta",
+			Type:       string(unicode.ToLower(rune(entity.StructName[0]))) + entity.StructName[1:] + "Meta",
+			Entity:     entity.EntityName,
+			StructName: entity.StructName,
+			Table:      entity.TableName,
+			IdField:    entity.IdField.FieldName,
+			IdColumn:   entity.IdField.ColumnName,
+		}
+
+		for _, field := range entity.Fields {
+			entityData.Columns = append(entityData.Columns, ColumnTemplateData{
+				Name:     field.ColumnName,
+				Field:    field.FieldName,
+				Property: GetPropertyName(field.FieldName),
+			})
+		}
+
+		entityData.Associations = getAssociations(entity)
+		entityData.Enums = getEnums(entity, imports)
+		entityData.Accessors = getAccessors(entity, imports)
+
+		if len(entityData.Associations) != 0 || len(entityData.Enums) != 0 {
+			imports["github.com/procyon-projects/shelf"] = "shelf"
+		}
+
+		data.Entities = append(data.Entities, entityData)
+	}
+
+	for importPath, importName := range imports {
+		data.Imports = append(data.Imports, ImportTemplateData{
+			Name: importName,
+			Path: importPath,
+		})
+	}
+
+	sort.Slice(data.Imports, func(i, j int) bool {
+		return data.Imports[i].Path < data.Imports[j].Path
+	})
+
+	var buffer bytes.Buffer
+	err := template.Must(template.New("metamodel").Parse(metamodelFileTemplate)).Execute(&buffer, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Source(buffer.Bytes())
+}
+
+// getAssociations returns the associations of the entity resolved by ResolveAssociations.
+func getAssociations(entity EntityMetadata) []AssociationTemplateData {
+	associations := make([]AssociationTemplateData, 0)
+
+	for _, association := range entity.Associations {
+		target := entityMetadataByStructName[association.Target]
+		data := AssociationTemplateData{
+			Field:    association.FieldName,
+			Kind:     string(association.Kind),
+			Target:   target.EntityName,
+			MappedBy: association.MappedBy,
+		}
+
+		if association.Kind == shelf.ManyToMany {
+			data.JoinTable = &shelf.JoinTable{
+				Name:               association.JoinTable,
+				JoinColumns:        []string{association.JoinColumn},
+				InverseJoinColumns: []string{association.InverseJoinColumn},
+			}
+		} else {
+			data.JoinColumn = &shelf.JoinColumn{
+				Table:            entity.TableName,
+				Name:             association.JoinColumn,
+				ReferencedTable:  target.TableName,
+				ReferencedColumn: association.ReferencedField.ColumnName,
+				ForeignKeyName:   association.ForeignKeyName,
+				Nullable:         association.Nullable,
+				Unique:           association.Unique,
+			}
+
+			// the join column of a one-to-many association and of the inverse side is in the target table
+			if !association.OwnsJoinColumn() {
+				data.JoinColumn.Table, data.JoinColumn.ReferencedTable = target.TableName, entity.TableName
+			}
+		}
+
+		associations = append(associations, data)
+	}
+
+	return associations
+}
+
+// getEnums returns the columns of the enum fields of the entity and adds the packages of the enums declared in
+// the other packages to the imports.
+func getEnums(entity EntityMetadata, imports map[string]string) []EnumMetaTemplateData {
+	enums := make([]EnumMetaTemplateData, 0)
+
+	for _, field := range entity.Fields {
+		enum := field.Enum
+
+		if enum == nil {
+			continue
+		}
+
+		data := EnumMetaTemplateData{
+			Property: GetPropertyName(field.FieldName),
+			Column:   field.ColumnName,
+			Ordinal:  enum.Ordinal,
+		}
+
+		qualifier := ""
+
+		if enum.File.Package.Path != entity.StructType.File.Package.Path {
+			qualifier = enum.File.Package.Name + "."
+			imports[enum.File.Package.Path] = enum.File.Package.Name
+		}
+
+		for _, constant := range enum.Constants {
+			name := strconv.Quote(constant)
+
+			if enum.IsStringer {
+				name = qualifier + constant + ".String()"
+			}
+
+			data.Names = append(data.Names, name)
+			data.Constants = append(data.Constants, qualifier+constant)
+		}
+
+		enums = append(enums, data)
+	}
+
+	return enums
+}
+
+// getAccessors returns the accessors of the associations of the entity and adds the packages they use to the
+// imports. Any association can be lazy, as the fetch plans override the fetch types. The associations whose
+// accessor name is already used by the entity have no accessor.
+func getAccessors(entity EntityMetadata, imports map[string]string) []AccessorTemplateData {
+	accessors := make([]AccessorTemplateData, 0)
+	file := entity.StructType.File
+
+	for _, association := range entity.Associations {
+		name := "Get" + association.FieldName
+
+		if hasMember(entity.StructType, name) {
+			continue
+		}
+
+		for _, importName := range GetImportNamesFromType(association.Field.Type) {
+			imports[FindImportPath(file, importName)] = importName
+		}
+
+		imports["context"] = ""
+		imports["github.com/procyon-projects/shelf"] = "shelf"
+
+		accessors = append(accessors, AccessorTemplateData{
+			Name:  name,
+			Field: association.FieldName,
+			Type:  GetFullNameFromType(association.Field.Type),
+		})
+	}
+
+	return accessors
+}
+
+// hasMember reports whether the struct has a field or a method with the given name.
+func hasMember(structType marker.StructType, name string) bool {
+	for _, field := range structType.Fields {
+		if field.Name == name {
+			return true
+		}
+	}
+
+	for _, method := range structType.Methods {
+		if method.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getMappedBy(candidateMarker interface{}) string {
+	switch typedMarker := candidateMarker.(type) {
+	case shelf.OneToOneMarker:
+		return strings.TrimSpace(typedMarker.MappedBy)
+	case shelf.OneToManyMarker:
+		return strings.TrimSpace(typedMarker.MappedBy)
+	case shelf.ManyToOneMarker:
+		return strings.TrimSpace(typedMarker.MappedBy)
+	case shelf.ManyToManyMarker:
+		return strings.TrimSpace(typedMarker.MappedBy)
+	}
+
+	return ""
+}
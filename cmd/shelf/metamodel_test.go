@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerate_StaticMetamodels(t *testing.T) {
+	generated := generateFiles(t, "fixture", map[string]string{"fixture.go": `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id        int
+	FirstName string
+	// +shelf:one-to-many:MappedBy=Author
+	Posts []*Post
+}
+
+// +shelf:entity=Article
+// +shelf:table=posts
+type Post struct {
+	// +shelf:id
+	Id    int
+	Title string
+	// +shelf:many-to-one
+	Author *User
+}
+`})
+
+	metamodels, ok := generated["fixture_metamodel.go"]
+
+	if !ok {
+		t.Fatalf("the metamodels should be generated in fixture_metamodel.go, but got %d files", len(generated))
+	}
+
+	assertContains(t, metamodels,
+		`UserEntity = "User"`,
+		`UserTable = "users"`,
+		`PostEntity = "Article"`,
+		`IdColumn: "id"`,
+		`FirstName: "first_name",`,
+		`Kind:     shelf.OneToMany,`,
+		`Target:   "Article",`,
+		`MappedBy: "Author",`,
+		`Kind:     shelf.ManyToOne,`,
+	)
+}
+
+// TestGenerate_LazyAssociations checks that the associations have accessors, unless the entity already has a
+// member with their name, and that the queried entities register the lazy ones in the session of the context.
+func TestGenerate_LazyAssociations(t *testing.T) {
+	generated := generateFiles(t, "fixture", map[string]string{"fixture.go": `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	Id int
+	// +shelf:one-to-many:MappedBy=Author
+	Posts []*Post
+	// +shelf:many-to-many
+	Tags []Tag
+}
+
+func (user *User) GetTags() []Tag {
+	return user.Tags
+}
+
+// +shelf:entity
+// +shelf:table=posts
+type Post struct {
+	// +shelf:id
+	Id int
+	// +shelf:many-to-one
+	Author *User
+}
+
+// +shelf:entity
+// +shelf:table=tags
+type Tag struct {
+	// +shelf:id
+	Name string
+}
+
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindAll(ctx context.Context) ([]*User, error)
+}
+`})
+
+	assertContains(t, generated["fixture_metamodel.go"],
+		`"context"`,
+		"func (entity *User) GetPosts(ctx context.Context) ([]*Post, error) {",
+		`err := shelf.LoadLazy(ctx, entity, "Posts")`,
+		"func (entity *Post) GetAuthor(ctx context.Context) (*User, error) {",
+	)
+
+	if strings.Contains(generated["fixture_metamodel.go"], "GetTags(ctx") {
+		t.Errorf("the metamodels should not contain the accessor GetTags, which is declared by the entity")
+	}
+
+	assertContains(t, generated["fixture_repositories.go"],
+		"if session := shelf.SessionOf(ctx); session != nil {",
+		`session.Register(targets, "Posts", func(ctx context.Context, targets []interface{}) error {`,
+		`session.Register(targets, "Tags", func(ctx context.Context, targets []interface{}) error {`,
+	)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"github.com/procyon-projects/marker"
+	"github.com/procyon-projects/shelf"
+	"strings"
+)
+
+// NamedQueryMetadata is a query declared on an entity by the shelf:named-query marker. It is checked once
+// and translated for each repository method referring to it, since the methods decide whether the
+// soft-deleted entities are excluded and the repositories decide the dialect.
+type NamedQueryMetadata struct {
+	Name        string
+	Entity      EntityMetadata
+	Text        string
+	NativeQuery bool
+}
+
+// FindNamedQueries translates the named queries declared on the entities. The names must be unique
+// across all the loaded packages.
+func FindNamedQueries(structTypes []marker.StructType) {
+	for _, structType := range structTypes {
+		namedQueryMarkers, ok := structType.Markers[shelf.MarkerNamedQuery]
+
+		if !ok {
+			continue
+		}
+
+		entityMetadata, ok := entityMetadataByStructName[structType.File.Package.Path+"#"+structType.Name]
+
+		if !ok {
+			// the invalid entities are already reported
+			if _, isEntity := structType.Markers[shelf.MarkerEntity]; !isEntity {
+				err := fmt.Errorf("'%s' marker can only be used with '%s' marker", shelf.MarkerNamedQuery, shelf.MarkerEntity)
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, marker.Position{
+					Line:   structType.Position.Line,
+					Column: structType.Position.Column,
+				}))
+			}
+
+			continue
+		}
+
+		for _, candidateMarker := range namedQueryMarkers {
+			namedQueryMarker, ok := candidateMarker.(shelf.NamedQueryMarker)
+
+			if !ok {
+				continue
+			}
+
+			name := strings.TrimSpace(namedQueryMarker.Name)
+
+			if namedQuery, ok := namedQueriesByName[name]; ok {
+				err := fmt.Errorf("there is already a named query with name '%s' on the entity '%s'", name, namedQuery.Entity.EntityName)
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, marker.Position{
+					Line:   structType.Position.Line,
+					Column: structType.Position.Column,
+				}))
+				continue
+			}
+
+			// the soft-deleted entities are excluded when the methods referring to the query are resolved, and
+			// the dialect only changes the quotes of the table names, which are not validated
+			_, err := TranslateCustomQuery(entityMetadata, namedQueryMarker.Query, namedQueryMarker.NativeQuery, true,
+				dialects[DialectPostgres])
+
+			if err != nil {
+				err = fmt.Errorf("the named query '%s' cannot be translated: %s", name, err)
+				errs = append(errs, marker.NewError(err, structType.File.FullPath, marker.Position{
+					Line:   structType.Position.Line,
+					Column: structType.Position.Column,
+				}))
+				continue
+			}
+
+			namedQueriesByName[name] = NamedQueryMetadata{
+				Name:        name,
+				Entity:      entityMetadata,
+				Text:        namedQueryMarker.Query,
+				NativeQuery: namedQueryMarker.NativeQuery,
+			}
+		}
+	}
+}
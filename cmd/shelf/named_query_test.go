@@ -0,0 +1,170 @@
+package main
+
+import (
+	"testing"
+)
+
+// namedQuerySource is the package of the named query tests, to which the repositories are appended.
+const namedQuerySource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=users
+// +shelf:named-query="User.findAdults", Query="FROM User WHERE Age >= %1 ORDER BY FirstName"
+// +shelf:named-query="User.findByEmail", Query="SELECT id, email, first_name, age FROM users WHERE email = %1", NativeQuery=true
+// +shelf:named-query="User.updateAge", Query="UPDATE User SET Age = %1 WHERE Email = %2"
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id        int
+	Email     string
+	FirstName string
+	Age       int
+}
+
+// +shelf:entity
+// +shelf:table=accounts
+// +shelf:named-query="Account.findAll", Query="FROM Account"
+type Account struct {
+	// +shelf:id
+	Id   int
+	Name string
+}
+`
+
+func TestValidate_NamedQueries(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Source string
+		Errors []string
+	}{
+		{
+			Name: "unknown named query",
+			Source: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:query=Name="User.findChildren"
+	FindChildren(ctx context.Context, age int) []*User
+}`,
+			Errors: []string{
+				"there is no named query with name 'User.findChildren'",
+			},
+		},
+		{
+			Name: "named query of another entity",
+			Source: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:query=Name="Account.findAll"
+	FindAccounts(ctx context.Context) []*User
+}`,
+			Errors: []string{
+				"the named query 'Account.findAll' is declared on the entity 'Account', not on 'User'",
+			},
+		},
+		{
+			Name: "missing parameter",
+			Source: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:query=Name="User.updateAge"
+	// +shelf:modifying
+	UpdateAge(ctx context.Context, age int) int64
+}`,
+			Errors: []string{
+				"the method 'UpdateAge' does not have any parameter for %2",
+			},
+		},
+		{
+			Name: "duplicate name",
+			Source: `
+// +shelf:entity
+// +shelf:table=posts
+// +shelf:named-query="User.findAdults", Query="FROM Post"
+type Post struct {
+	// +shelf:id
+	Id int
+}`,
+			Errors: []string{
+				"there is already a named query with name 'User.findAdults' on the entity 'User'",
+			},
+		},
+		{
+			Name: "untranslatable query",
+			Source: `
+// +shelf:entity
+// +shelf:table=posts
+// +shelf:named-query="Post.findByTitle", Query="FROM Post WHERE Title = %1"
+type Post struct {
+	// +shelf:id
+	Id int
+}`,
+			Errors: []string{
+				"the named query 'Post.findByTitle' cannot be translated: there is no property 'Title' in the entity 'Post'",
+			},
+		},
+		{
+			Name: "named query without entity",
+			Source: `
+// +shelf:named-query="Post.findAll", Query="FROM Post"
+type Post struct {
+	Id int
+}`,
+			Errors: []string{
+				"'shelf:named-query' marker can only be used with 'shelf:entity' marker",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assertErrors(t, validate(t, "fixture", namedQuerySource+testCase.Source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_NamedQueries(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:query=Name="User.findAdults"
+	FindAdults(ctx context.Context, age int) ([]*User, error)
+	// +shelf:query=Name="User.findByEmail"
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	// +shelf:query=Name="User.updateAge"
+	// +shelf:modifying
+	UpdateAge(ctx context.Context, age int, email string) (int64, error)
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				`query := "SELECT id, email, first_name, age FROM users WHERE age >= $1 ORDER BY first_name"`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE email = $1"`,
+				`query := "UPDATE users SET age = $1 WHERE email = $2"`,
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`query := "SELECT id, email, first_name, age FROM users WHERE age >= ? ORDER BY first_name"`,
+				`query := "UPDATE users SET age = ? WHERE email = ?"`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", namedQuerySource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
@@ -27,8 +27,17 @@ var (
 	entitiesByName             = make(map[string]string, 0)
 	entitiesByTableName        = make(map[string]string, 0)
 
+	namedQueriesByName = make(map[string]NamedQueryMetadata)
+
 	repositoryMetadataByInterfaceName = make(map[string]RepositoryMetadata)
 	repositoriesByName                = make(map[string]string, 0)
+
+	structTypesByQualifiedName      = make(map[string]marker.StructType)
+	interfaceTypesByQualifiedName   = make(map[string]marker.InterfaceType)
+	userDefinedTypesByQualifiedName = make(map[string]marker.UserDefinedType)
+	// constantsByQualifiedTypeName holds the typed constants of the packages in the order they are declared,
+	// which are the constants of the enum types.
+	constantsByQualifiedTypeName = make(map[string][]marker.ConstValue)
 )
 
 // Register your marker definitions.
@@ -40,16 +49,27 @@ func RegisterDefinitions(registry *marker.Registry) error {
 	}{
 		{Name: shelf.MarkerEntity, Level: marker.StructTypeLevel, Output: &shelf.EntityMarker{}},
 		{Name: shelf.MarkerTable, Level: marker.StructTypeLevel, Output: &shelf.TableMarker{}},
+		{Name: shelf.MarkerIdClass, Level: marker.StructTypeLevel, Output: &shelf.IdClassMarker{}},
+		{Name: shelf.MarkerNamedQuery, Level: marker.StructTypeLevel, Output: &shelf.NamedQueryMarker{}},
 
 		{Name: shelf.MarkerId, Level: marker.FieldLevel, Output: &shelf.IdMarker{}},
+		{Name: shelf.MarkerEmbeddedId, Level: marker.FieldLevel, Output: &shelf.EmbeddedIdMarker{}},
 		{Name: shelf.MarkerGeneratedValue, Level: marker.FieldLevel, Output: &shelf.GeneratedValueMarker{}},
 		{Name: shelf.MarkerColumn, Level: marker.FieldLevel, Output: &shelf.ColumnMarker{}},
 		{Name: shelf.MarkerLob, Level: marker.FieldLevel, Output: &shelf.LobMarker{}},
 		{Name: shelf.MarkerTransient, Level: marker.FieldLevel, Output: &shelf.TransientMarker{}},
+		{Name: shelf.MarkerVersion, Level: marker.FieldLevel, Output: &shelf.VersionMarker{}},
+		{Name: shelf.MarkerSoftDelete, Level: marker.FieldLevel, Output: &shelf.SoftDeleteMarker{}},
 		{Name: shelf.MarkerEnumerated, Level: marker.FieldLevel, Output: &shelf.EnumeratedMarker{}},
 
 		{Name: shelf.MarkerRepository, Level: marker.InterfaceTypeLevel, Output: &shelf.RepositoryMarker{}},
 		{Name: shelf.MarkerQuery, Level: marker.InterfaceMethodLevel, Output: &shelf.QueryMarker{}},
+		{Name: shelf.MarkerFetch, Level: marker.InterfaceMethodLevel, Output: &shelf.FetchMarker{}},
+		{Name: shelf.MarkerFetchSize, Level: marker.InterfaceMethodLevel, Output: &shelf.FetchSizeMarker{}},
+		{Name: shelf.MarkerModifying, Level: marker.InterfaceMethodLevel, Output: &shelf.ModifyingMarker{}},
+		{Name: shelf.MarkerIncludingDeleted, Level: marker.InterfaceMethodLevel, Output: &shelf.IncludingDeletedMarker{}},
+		{Name: shelf.MarkerTransactional, Level: marker.InterfaceMethodLevel, Output: &shelf.TransactionalMarker{}},
+		{Name: shelf.MarkerBatch, Level: marker.InterfaceMethodLevel, Output: &shelf.BatchMarker{}},
 
 		{Name: shelf.MarkerEmbeddable, Level: marker.StructTypeLevel, Output: &shelf.EmbeddableMarker{}},
 		{Name: shelf.MarkerEmbedded, Level: marker.FieldLevel, Output: &shelf.EmbeddedMarker{}},
@@ -60,8 +80,16 @@ func RegisterDefinitions(registry *marker.Registry) error {
 		{Name: shelf.MarkerOneToMany, Level: marker.FieldLevel, Output: &shelf.OneToManyMarker{}},
 		{Name: shelf.MarkerManyToOne, Level: marker.FieldLevel, Output: &shelf.ManyToOneMarker{}},
 		{Name: shelf.MarkerManyToMany, Level: marker.FieldLevel, Output: &shelf.ManyToManyMarker{}},
+		{Name: shelf.MarkerJoinColumn, Level: marker.FieldLevel, Output: &shelf.JoinColumnMarker{}},
+		{Name: shelf.MarkerJoinTable, Level: marker.FieldLevel, Output: &shelf.JoinTableMarker{}},
 
 		{Name: shelf.MarkerTemporal, Level: marker.FieldLevel, Output: &shelf.TemporalMarker{}},
+		{Name: shelf.MarkerCreatedDate, Level: marker.FieldLevel, Output: &shelf.CreatedDateMarker{}},
+		{Name: shelf.MarkerLastModifiedDate, Level: marker.FieldLevel, Output: &shelf.LastModifiedDateMarker{}},
+		{Name: shelf.MarkerCreatedBy, Level: marker.FieldLevel, Output: &shelf.CreatedByMarker{}},
+		{Name: shelf.MarkerLastModifiedBy, Level: marker.FieldLevel, Output: &shelf.LastModifiedByMarker{}},
+
+		{Name: shelf.MarkerValue, Level: marker.FieldLevel | marker.InterfaceMethodLevel, Output: &shelf.ValueMarker{}},
 	}
 
 	for _, m := range markers {
@@ -74,11 +102,56 @@ func RegisterDefinitions(registry *marker.Registry) error {
 	return nil
 }
 
-// Process your markers.
-func ProcessMarkers(collector *marker.Collector, pkgs []*marker.Package) error {
+// Process your markers. The entities are validated against the dialect of the generated repositories.
+func ProcessMarkers(collector *marker.Collector, pkgs []*marker.Package, dialect Dialect) error {
+	files := make([]*marker.File, 0)
+
 	marker.EachFile(collector, pkgs, func(file *marker.File, err error) {
+		if file != nil {
+			files = append(files, file)
+		}
+	})
+
+	// repositories refer to entities by name and may return any of the types as projections, and
+	// entities may embed the structs and the enums of the other files, so all types must be found first
+	for _, file := range files {
+		for _, structType := range file.StructTypes {
+			structTypesByQualifiedName[file.Package.Path+"."+structType.Name] = structType
+		}
+
+		for _, interfaceType := range file.InterfaceTypes {
+			interfaceTypesByQualifiedName[file.Package.Path+"."+interfaceType.Name] = interfaceType
+		}
+
+		for _, userDefinedType := range file.UserDefinedTypes {
+			userDefinedTypesByQualifiedName[file.Package.Path+"."+userDefinedType.Name] = userDefinedType
+		}
+
+		for _, constant := range file.Consts {
+			if constant.Type != nil && constant.Type.ImportName == "" && constant.Name != "_" {
+				qualifiedTypeName := file.Package.Path + "." + constant.Type.Name
+				constantsByQualifiedTypeName[qualifiedTypeName] = append(constantsByQualifiedTypeName[qualifiedTypeName], constant)
+			}
+		}
+	}
+
+	for _, file := range files {
 		FindEntities(file.StructTypes)
+	}
+
+	ValidateGenerationStrategies(dialect)
+
+	// the associations refer to the entities of any file
+	ResolveAssociations()
+
+	// named queries may join the other entities
+	for _, file := range files {
+		FindNamedQueries(file.StructTypes)
+	}
+
+	for _, file := range files {
 		FindRepositories(file.InterfaceTypes)
-	})
+	}
+
 	return marker.NewErrorList(errs)
 }
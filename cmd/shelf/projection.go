@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"github.com/procyon-projects/marker"
+	"github.com/procyon-projects/shelf"
+	"strings"
+	"unicode"
+)
+
+// ProjectionMetadata describes a struct or an interface which holds a subset of the entity fields.
+type ProjectionMetadata struct {
+	// TypeName is the qualified name of the projection type.
+	TypeName      string
+	StructType    *marker.StructType
+	InterfaceType *marker.InterfaceType
+	Fields        []ProjectionField
+}
+
+// ProjectionField is a field of a struct projection or a getter method of an interface projection.
+type ProjectionField struct {
+	Name string
+	Type marker.Type
+	File *marker.File
+	// Column is the entity column or the computed expression selected for the field.
+	Column     string
+	IsComputed bool
+	// Nested is the projection of the field if it groups other fields.
+	Nested *ProjectionMetadata
+}
+
+func (projection ProjectionMetadata) IsInterface() bool {
+	return projection.InterfaceType != nil
+}
+
+// Columns returns the columns and the computed expressions selected for the projection.
+func (projection ProjectionMetadata) Columns() []string {
+	columns := make([]string, 0)
+
+	for _, field := range projection.Fields {
+		if field.Nested != nil {
+			columns = append(columns, field.Nested.Columns()...)
+			continue
+		}
+
+		columns = append(columns, field.Column)
+	}
+
+	return columns
+}
+
+// Paths returns the paths of the fields which the selected columns are scanned into.
+func (projection ProjectionMetadata) Paths() []string {
+	paths := make([]string, 0)
+
+	for _, field := range projection.Fields {
+		name := field.Name
+
+		if projection.IsInterface() {
+			name = GetProjectionFieldName(field.Name)
+		}
+
+		if field.Nested != nil {
+			for _, path := range field.Nested.Paths() {
+				paths = append(paths, name+"."+path)
+			}
+
+			continue
+		}
+
+		paths = append(paths, name)
+	}
+
+	return paths
+}
+
+// GetProjectionFieldName returns the name of the field holding the value of a getter method
+// in the generated implementation of an interface projection.
+func GetProjectionFieldName(methodName string) string {
+	return string(unicode.ToLower(rune(methodName[0]))) + methodName[1:]
+}
+
+// GetProjectionTypeName returns the name of the generated implementation of an interface projection.
+func GetProjectionTypeName(interfaceName string) string {
+	return GetProjectionFieldName(interfaceName) + "Projection"
+}
+
+// FindProjection resolves the projection type returned by a repository method. A struct projection
+// must be returned as a pointer, whereas an interface projection is returned as it is.
+func FindProjection(entity EntityMetadata, file *marker.File, typ marker.Type) (*ProjectionMetadata, bool, error) {
+	if pointerType, ok := typ.(*marker.PointerType); ok {
+		structType, ok := structTypesByQualifiedName[GetQualifiedNameFromType(file, pointerType.Typ)]
+
+		if !ok || IsEntityStruct(structType) {
+			return nil, false, nil
+		}
+
+		projection, err := resolveStructProjection(entity, structType, "", make(map[string]bool))
+		return projection, true, err
+	}
+
+	interfaceType, ok := interfaceTypesByQualifiedName[GetQualifiedNameFromType(file, typ)]
+
+	if !ok || IsRepositoryInterface(interfaceType) {
+		return nil, false, nil
+	}
+
+	projection, err := resolveInterfaceProjection(entity, interfaceType, "", make(map[string]bool))
+	return projection, true, err
+}
+
+func resolveStructProjection(entity EntityMetadata, structType marker.StructType, prefix string,
+	visited map[string]bool) (*ProjectionMetadata, error) {
+	typeName := structType.File.Package.Path + "." + structType.Name
+
+	if visited[typeName] {
+		return nil, fmt.Errorf("the projection '%s' cannot refer to itself", structType.Name)
+	}
+
+	visited[typeName] = true
+	defer delete(visited, typeName)
+
+	projection := &ProjectionMetadata{
+		TypeName:   typeName,
+		StructType: &structType,
+	}
+
+	for _, field := range structType.Fields {
+		if !field.IsExported {
+			continue
+		}
+
+		projectionField, err := resolveProjectionField(entity, structType.Name, structType.File, field.Name, field.Type,
+			field.Markers, prefix, false, visited)
+
+		if err != nil {
+			return nil, err
+		}
+
+		projection.Fields = append(projection.Fields, projectionField)
+	}
+
+	if len(projection.Fields) == 0 {
+		return nil, fmt.Errorf("the projection '%s' must have at least one exported field", structType.Name)
+	}
+
+	return projection, nil
+}
+
+func resolveInterfaceProjection(entity EntityMetadata, interfaceType marker.InterfaceType, prefix string,
+	visited map[string]bool) (*ProjectionMetadata, error) {
+	typeName := interfaceType.File.Package.Path + "." + interfaceType.Name
+
+	if visited[typeName] {
+		return nil, fmt.Errorf("the projection '%s' cannot refer to itself", interfaceType.Name)
+	}
+
+	visited[typeName] = true
+	defer delete(visited, typeName)
+
+	projection := &ProjectionMetadata{
+		TypeName:      typeName,
+		InterfaceType: &interfaceType,
+	}
+
+	for _, method := range interfaceType.Methods {
+		if len(method.Parameters) != 0 || len(method.ReturnValues) != 1 {
+			return nil, fmt.Errorf("the method '%s' of the projection '%s' must take in no parameters and return a single value",
+				method.Name, interfaceType.Name)
+		}
+
+		projectionField, err := resolveProjectionField(entity, interfaceType.Name, interfaceType.File, method.Name,
+			method.ReturnValues[0].Type, method.Markers, prefix, true, visited)
+
+		if err != nil {
+			return nil, err
+		}
+
+		projection.Fields = append(projection.Fields, projectionField)
+	}
+
+	if len(projection.Fields) == 0 {
+		return nil, fmt.Errorf("the projection '%s' must have at least one method", interfaceType.Name)
+	}
+
+	return projection, nil
+}
+
+// resolveProjectionField matches the field of a projection with a property of the entity. A field marked
+// as shelf:value is computed by its expression, and a field whose type is another projection is resolved
+// against the entity properties prefixed with its name. Struct projections can only nest struct projections,
+// and interface projections can only nest interface projections.
+func resolveProjectionField(entity EntityMetadata, projectionName string, file *marker.File, name string, typ marker.Type,
+	markers marker.MarkerValues, prefix string, isInterface bool, visited map[string]bool) (ProjectionField, error) {
+	projectionField := ProjectionField{
+		Name: name,
+		Type: typ,
+		File: file,
+	}
+
+	for _, candidateMarker := range markers[shelf.MarkerValue] {
+		if valueMarker, ok := candidateMarker.(shelf.ValueMarker); ok {
+			projectionField.Column = strings.TrimSpace(valueMarker.Value)
+			projectionField.IsComputed = true
+			return projectionField, nil
+		}
+	}
+
+	if entityField, ok := findEntityField(entity, prefix+name, name); ok {
+		typeName := GetQualifiedNameFromType(file, typ)
+		fieldTypeName := GetQualifiedNameFromType(entityField.File, entityField.Type)
+
+		if typeName != fieldTypeName {
+			return projectionField, fmt.Errorf("the field '%s' of the projection '%s' must be of type '%s'",
+				name, projectionName, GetFullNameFromType(entityField.Type))
+		}
+
+		projectionField.Column = entityField.ColumnName
+		return projectionField, nil
+	}
+
+	qualifiedName := GetQualifiedNameFromType(file, typ)
+
+	if structType, ok := structTypesByQualifiedName[qualifiedName]; ok && !isInterface && !IsEntityStruct(structType) {
+		nested, err := resolveStructProjection(entity, structType, prefix+name+".", visited)
+
+		if err != nil {
+			return projectionField, err
+		}
+
+		projectionField.Nested = nested
+		return projectionField, nil
+	}
+
+	if interfaceType, ok := interfaceTypesByQualifiedName[qualifiedName]; ok && isInterface && !IsRepositoryInterface(interfaceType) {
+		nested, err := resolveInterfaceProjection(entity, interfaceType, prefix+name+".", visited)
+
+		if err != nil {
+			return projectionField, err
+		}
+
+		projectionField.Nested = nested
+		return projectionField, nil
+	}
+
+	return projectionField, fmt.Errorf("the field '%s' of the projection '%s' does not match any property of the entity '%s', "+
+		"mark it as '%s' to compute its value", name, projectionName, entity.EntityName, shelf.MarkerValue)
+}
+
+// findEntityField returns the entity field with the first matching name.
+func findEntityField(entity EntityMetadata, names ...string) (FieldMetadata, bool) {
+	for _, name := range names {
+		for _, field := range entity.Fields {
+			if field.FieldName == name {
+				return field, true
+			}
+		}
+	}
+
+	return FieldMetadata{}, false
+}
+
+func IsEntityStruct(structType marker.StructType) bool {
+	_, ok := entityMetadataByStructName[structType.File.Package.Path+"#"+structType.Name]
+	return ok
+}
+
+func IsRepositoryInterface(interfaceType marker.InterfaceType) bool {
+	_, ok := interfaceType.Markers[shelf.MarkerRepository]
+	return ok
+}
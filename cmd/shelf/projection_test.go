@@ -0,0 +1,124 @@
+package main
+
+import (
+	"testing"
+)
+
+// projectionSource is the package of the projection tests, to which the projections and the repositories are appended.
+const projectionSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id        int
+	Email     string
+	FirstName string
+	LastName  string
+}
+`
+
+func TestValidate_Projections(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		Projection string
+		Result     string
+		Errors     []string
+	}{
+		{
+			Name: "unknown property",
+			Projection: `
+type UserName struct {
+	FullName string
+}`,
+			Errors: []string{
+				"the field 'FullName' of the projection 'UserName' does not match any property of the entity 'User', mark it as 'shelf:value' to compute its value",
+			},
+		},
+		{
+			Name: "wrong property type",
+			Projection: `
+type UserName struct {
+	FirstName []byte
+}`,
+			Errors: []string{
+				"the field 'FirstName' of the projection 'UserName' must be of type 'string'",
+			},
+		},
+		{
+			Name: "no exported fields",
+			Projection: `
+type UserName struct {
+	firstName string
+}`,
+			Errors: []string{
+				"the projection 'UserName' must have at least one exported field",
+			},
+		},
+		{
+			Name: "interface method with parameters",
+			Projection: `
+type UserName interface {
+	FirstName(prefix string) string
+}`,
+			Result: "UserName",
+			Errors: []string{
+				"the method 'FirstName' of the projection 'UserName' must take in no parameters and return a single value",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			result := testCase.Result
+
+			if result == "" {
+				result = "*UserName"
+			}
+
+			source := projectionSource + testCase.Projection + `
+
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindNameById(ctx context.Context, id int) (` + result + `, error)
+}`
+
+			assertErrors(t, validate(t, "fixture", source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_Projections(t *testing.T) {
+	repositories := generate(t, "fixture", projectionSource+`
+type UserName struct {
+	FirstName string
+	// +shelf:value="first_name || ' ' || last_name"
+	FullName string
+}
+
+type UserEmail interface {
+	Id() int
+	Email() string
+}
+
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindNameById(ctx context.Context, id int) (*UserName, error)
+	FindByLastName(ctx context.Context, lastName string) ([]UserEmail, error)
+}`)
+
+	assertContains(t, repositories,
+		`query := "SELECT first_name, first_name || ' ' || last_name FROM users WHERE id = $1"`,
+		`err = rows.Scan(&entity.FirstName, &entity.FullName)`,
+		`type userEmailProjection struct {`,
+		`func (projection *userEmailProjection) Email() string {`,
+		`query := "SELECT id, email FROM users WHERE last_name = $1"`,
+		`err = rows.Scan(&entity.id, &entity.email)`,
+	)
+}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/procyon-projects/marker"
+	"github.com/procyon-projects/shelf"
+	"sort"
+	"strings"
+)
+
+type QueryKind int
+
+const (
+	SelectQuery QueryKind = iota
+	CountQuery
+	ExistsQuery
+	UpdateQuery
+	DeleteQuery
+)
+
+type QueryResult int
+
+const (
+	NoResult QueryResult = iota
+	SingleResult
+	ListResult
+	PageResult
+	SliceResult
+	CountResult
+	ExistsResult
+	CursorResult
+	// ScalarResult is the single value selected by a custom query.
+	ScalarResult
+)
+
+// QueryOperator is a keyword following a property in the name of a derived query method.
+type QueryOperator struct {
+	Keyword string
+	// Arguments is the number of the method parameters consumed by the operator.
+	Arguments int
+	// Format is the SQL condition, where %s is replaced with the column and ? with the arguments.
+	Format string
+	// ArgumentFormat is the Go expression wrapping the argument, where %s is replaced with the parameter.
+	ArgumentFormat string
+	IsSlice        bool
+	IsString       bool
+	// IsOrdered reports whether the operator compares the order of the values.
+	IsOrdered bool
+}
+
+var queryOperators = []QueryOperator{
+	{Keyword: "", Arguments: 1, Format: "%s = ?"},
+	{Keyword: "Is", Arguments: 1, Format: "%s = ?"},
+	{Keyword: "Equals", Arguments: 1, Format: "%s = ?"},
+	{Keyword: "Not", Arguments: 1, Format: "%s <> ?"},
+	{Keyword: "IsNot", Arguments: 1, Format: "%s <> ?"},
+	{Keyword: "GreaterThan", Arguments: 1, Format: "%s > ?", IsOrdered: true},
+	{Keyword: "GreaterThanEqual", Arguments: 1, Format: "%s >= ?", IsOrdered: true},
+	{Keyword: "LessThan", Arguments: 1, Format: "%s < ?", IsOrdered: true},
+	{Keyword: "LessThanEqual", Arguments: 1, Format: "%s <= ?", IsOrdered: true},
+	{Keyword: "After", Arguments: 1, Format: "%s > ?", IsOrdered: true},
+	{Keyword: "Before", Arguments: 1, Format: "%s < ?", IsOrdered: true},
+	{Keyword: "Between", Arguments: 2, Format: "%s BETWEEN ? AND ?", IsOrdered: true},
+	{Keyword: "IsNull", Format: "%s IS NULL"},
+	{Keyword: "Null", Format: "%s IS NULL"},
+	{Keyword: "IsNotNull", Format: "%s IS NOT NULL"},
+	{Keyword: "NotNull", Format: "%s IS NOT NULL"},
+	{Keyword: "In", Arguments: 1, Format: "%s IN (?)", IsSlice: true},
+	{Keyword: "NotIn", Arguments: 1, Format: "%s NOT IN (?)", IsSlice: true},
+	{Keyword: "True", Format: "%s = TRUE"},
+	{Keyword: "False", Format: "%s = FALSE"},
+	{Keyword: "Like", Arguments: 1, Format: "%s LIKE ?", IsString: true},
+	{Keyword: "NotLike", Arguments: 1, Format: "%s NOT LIKE ?", IsString: true},
+	{Keyword: "StartingWith", Arguments: 1, Format: "%s LIKE ?", ArgumentFormat: `%s + "%%"`, IsString: true},
+	{Keyword: "EndingWith", Arguments: 1, Format: "%s LIKE ?", ArgumentFormat: `"%%" + %s`, IsString: true},
+	{Keyword: "Containing", Arguments: 1, Format: "%s LIKE ?", ArgumentFormat: `"%%" + %s + "%%"`, IsString: true},
+}
+
+var queryPrefixes = []struct {
+	Prefix string
+	Kind   QueryKind
+}{
+	{Prefix: "Find", Kind: SelectQuery},
+	{Prefix: "Read", Kind: SelectQuery},
+	{Prefix: "Get", Kind: SelectQuery},
+	{Prefix: "Query", Kind: SelectQuery},
+	{Prefix: "Stream", Kind: SelectQuery},
+	{Prefix: "Count", Kind: CountQuery},
+	{Prefix: "Exists", Kind: ExistsQuery},
+	{Prefix: "Delete", Kind: DeleteQuery},
+	{Prefix: "Remove", Kind: DeleteQuery},
+}
+
+type QueryCondition struct {
+	// Connector is the logical operator joining the condition to the previous one.
+	Connector string
+	Field     FieldMetadata
+	Operator  QueryOperator
+}
+
+type QueryOrder struct {
+	Field     FieldMetadata
+	Direction string
+}
+
+// DerivedQuery is the query derived from the name of a repository method such as FindByFirstNameAndLastName.
+type DerivedQuery struct {
+	Kind       QueryKind
+	Conditions []QueryCondition
+	Orders     []QueryOrder
+	// IncludingDeleted reports whether the query also finds the soft-deleted entities, e.g. FindAllIncludingDeleted.
+	IncludingDeleted bool
+}
+
+// IncludingDeletedKeyword follows the prefix of the derived queries finding the soft-deleted entities as well.
+const IncludingDeletedKeyword = "IncludingDeleted"
+
+// DerivedQueryMethod binds a derived query to the parameters and the return values of a repository method.
+type DerivedQueryMethod struct {
+	Query  DerivedQuery
+	Result QueryResult
+	// Arguments contains the indexes of the parameters consumed by each condition.
+	Arguments [][]int
+	// SortIndex is the index of the shelf.Sort parameter, or -1.
+	SortIndex int
+	// PageableIndex is the index of the shelf.Pageable parameter, or -1.
+	PageableIndex int
+	// SpecificationIndex is the index of the shelf.Specification parameter, or -1.
+	SpecificationIndex int
+	// Projection is the type holding the selected subset of the entity fields, or nil if the entities are returned.
+	Projection *ProjectionMetadata
+	// FetchSize is the number of the rows fetched at once by a cursor, or zero if the driver decides.
+	FetchSize int
+}
+
+// ParseDerivedQuery parses the name of a repository method into a query on the entity.
+func ParseDerivedQuery(entity EntityMetadata, name string) (DerivedQuery, error) {
+	query := DerivedQuery{}
+	remaining := ""
+	matched := false
+
+	for _, queryPrefix := range queryPrefixes {
+		if strings.HasPrefix(name, queryPrefix.Prefix) {
+			query.Kind = queryPrefix.Kind
+			remaining = strings.TrimPrefix(name[len(queryPrefix.Prefix):], "All")
+			matched = true
+			break
+		}
+	}
+
+	if !matched {
+		return query, fmt.Errorf("the method '%s' does not start with any of the query prefixes", name)
+	}
+
+	orderIndex := strings.Index(remaining, "OrderBy")
+	orders := ""
+
+	if orderIndex != -1 {
+		orders = remaining[orderIndex+len("OrderBy"):]
+		remaining = remaining[:orderIndex]
+	}
+
+	description := remaining
+
+	if byIndex := strings.Index(remaining, "By"); byIndex != -1 {
+		description = remaining[:byIndex]
+	}
+
+	if strings.HasSuffix(description, IncludingDeletedKeyword) {
+		query.IncludingDeleted = true
+		remaining = strings.TrimSuffix(description, IncludingDeletedKeyword) + remaining[len(description):]
+	}
+
+	// the text between the prefix and 'By' only describes the result, e.g. FindSummariesByEmail
+	if byIndex := strings.Index(remaining, "By"); byIndex > 0 {
+		remaining = remaining[byIndex:]
+	}
+
+	if remaining != "" {
+		if !strings.HasPrefix(remaining, "By") || len(remaining) == len("By") {
+			return query, fmt.Errorf("the method '%s' must be followed by 'By' and the conditions", name)
+		}
+
+		conditions, ok := parseQueryConditions(entity, remaining[len("By"):], "")
+
+		if !ok {
+			return query, fmt.Errorf("the conditions of the method '%s' cannot be resolved, "+
+				"make sure that the properties exist in the entity '%s'", name, entity.EntityName)
+		}
+
+		query.Conditions = conditions
+	}
+
+	if orderIndex != -1 {
+		if query.Kind != SelectQuery {
+			return query, fmt.Errorf("the method '%s' cannot be ordered", name)
+		}
+
+		queryOrders, ok := parseQueryOrders(entity, orders)
+
+		if !ok || len(queryOrders) == 0 {
+			return query, fmt.Errorf("the orders of the method '%s' cannot be resolved, "+
+				"make sure that the properties exist in the entity '%s'", name, entity.EntityName)
+		}
+
+		query.Orders = queryOrders
+	}
+
+	return query, nil
+}
+
+// parseQueryConditions resolves the conditions by trying the longest property and operator names first
+// and backtracking when the rest of the text cannot be resolved.
+func parseQueryConditions(entity EntityMetadata, text string, connector string) ([]QueryCondition, bool) {
+	for _, field := range getFieldsByNameLength(entity) {
+		propertyName := GetPropertyName(field.FieldName)
+
+		if !strings.HasPrefix(text, propertyName) {
+			continue
+		}
+
+		afterField := text[len(propertyName):]
+
+		for _, operator := range getOperatorsByKeywordLength() {
+			if !strings.HasPrefix(afterField, operator.Keyword) {
+				continue
+			}
+
+			condition := QueryCondition{
+				Connector: connector,
+				Field:     field,
+				Operator:  operator,
+			}
+
+			rest := afterField[len(operator.Keyword):]
+
+			if rest == "" {
+				return []QueryCondition{condition}, true
+			}
+
+			for _, nextConnector := range []string{"And", "Or"} {
+				if !strings.HasPrefix(rest, nextConnector) {
+					continue
+				}
+
+				conditions, ok := parseQueryConditions(entity, rest[len(nextConnector):], strings.ToUpper(nextConnector))
+
+				if ok {
+					return append([]QueryCondition{condition}, conditions...), true
+				}
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func parseQueryOrders(entity EntityMetadata, text string) ([]QueryOrder, bool) {
+	if text == "" {
+		return nil, true
+	}
+
+	for _, field := range getFieldsByNameLength(entity) {
+		propertyName := GetPropertyName(field.FieldName)
+
+		if !strings.HasPrefix(text, propertyName) {
+			continue
+		}
+
+		rest := text[len(propertyName):]
+
+		for _, direction := range []string{"Desc", "Asc", ""} {
+			if !strings.HasPrefix(rest, direction) {
+				continue
+			}
+
+			orders, ok := parseQueryOrders(entity, rest[len(direction):])
+
+			if ok {
+				order := QueryOrder{
+					Field:     field,
+					Direction: "ASC",
+				}
+
+				if direction == "Desc" {
+					order.Direction = "DESC"
+				}
+
+				return append([]QueryOrder{order}, orders...), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// getFieldsByNameLength returns the entity fields sorted by the length of their names. The id field
+// can always be referred as Id unless the key is composite, so that the reserved methods such as FindById
+// can be derived as well.
+func getFieldsByNameLength(entity EntityMetadata) []FieldMetadata {
+	fields := make([]FieldMetadata, len(entity.Fields))
+	copy(fields, entity.Fields)
+
+	if _, ok := findEntityField(entity, "Id"); !ok && entity.IdField != nil && !entity.IsComposite() {
+		idField := *entity.IdField
+		idField.FieldName = "Id"
+		fields = append(fields, idField)
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		return len(GetPropertyName(fields[i].FieldName)) > len(GetPropertyName(fields[j].FieldName))
+	})
+
+	return fields
+}
+
+func getOperatorsByKeywordLength() []QueryOperator {
+	operators := make([]QueryOperator, len(queryOperators))
+	copy(operators, queryOperators)
+
+	sort.SliceStable(operators, func(i, j int) bool {
+		return len(operators[i].Keyword) > len(operators[j].Keyword)
+	})
+
+	return operators
+}
+
+// ResolveDerivedQueryMethod parses the name of the method and checks whether its parameters
+// and return values match the query.
+func ResolveDerivedQueryMethod(metadata RepositoryMetadata, method marker.Method) (DerivedQueryMethod, error) {
+	query, err := ParseDerivedQuery(metadata.Entity, method.Name)
+
+	if err != nil {
+		return DerivedQueryMethod{}, err
+	}
+
+	if query.IncludingDeleted && metadata.Entity.SoftDeleteField == nil {
+		return DerivedQueryMethod{}, fmt.Errorf("the method '%s' cannot include the deleted entities, since the entity '%s' "+
+			"has no field marked as '%s'", method.Name, metadata.Entity.EntityName, shelf.MarkerSoftDelete)
+	}
+
+	if query.IncludingDeleted && query.Kind == DeleteQuery {
+		return DerivedQueryMethod{}, fmt.Errorf("the method '%s' cannot include the deleted entities, since it deletes them", method.Name)
+	}
+
+	derivedMethod := DerivedQueryMethod{
+		Query:              query,
+		SortIndex:          -1,
+		PageableIndex:      -1,
+		SpecificationIndex: -1,
+	}
+
+	var parameters []marker.TypeInfo
+	parameters, derivedMethod.SortIndex, derivedMethod.PageableIndex = resolveQueryParameters(method)
+
+	if derivedMethod.SortIndex != -1 || derivedMethod.PageableIndex != -1 {
+		if query.Kind != SelectQuery {
+			return derivedMethod, fmt.Errorf("the method '%s' cannot take in a sort or a pageable", method.Name)
+		}
+	}
+
+	index := 1
+
+	// a specification is built at runtime in place of the conditions derived from the name
+	if len(parameters) > 1 && GetQualifiedNameFromType(method.File, parameters[1].Type) == ShelfSpecificationType {
+		if len(query.Conditions) != 0 || (query.Kind != SelectQuery && query.Kind != CountQuery) {
+			return derivedMethod, fmt.Errorf("the method '%s' can only take in a shelf.Specification to find or count without any condition", method.Name)
+		}
+
+		derivedMethod.SpecificationIndex = index
+		index++
+	}
+
+	for _, condition := range query.Conditions {
+		// the names of the constants are not ordered as their values
+		if condition.Operator.IsOrdered && condition.Field.Enum != nil && !condition.Field.Enum.Ordinal {
+			return derivedMethod, fmt.Errorf("the method '%s' cannot compare the order of the property '%s', since its enum is stored by name",
+				method.Name, condition.Field.FieldName)
+		}
+
+		indexes := make([]int, 0)
+
+		for argument := 0; argument < condition.Operator.Arguments; argument++ {
+			if index >= len(parameters) {
+				return derivedMethod, fmt.Errorf("the method '%s' does not have enough parameters for its conditions", method.Name)
+			}
+
+			err = validateQueryArgument(metadata.Entity, method, parameters[index], condition)
+
+			if err != nil {
+				return derivedMethod, err
+			}
+
+			indexes = append(indexes, index)
+			index++
+		}
+
+		derivedMethod.Arguments = append(derivedMethod.Arguments, indexes)
+	}
+
+	if index != len(parameters) {
+		return derivedMethod, fmt.Errorf("the method '%s' has more parameters than its conditions need", method.Name)
+	}
+
+	derivedMethod.Result, derivedMethod.Projection, derivedMethod.FetchSize, err = resolveQueryMethodResult(metadata, method,
+		query.Kind, derivedMethod.PageableIndex)
+
+	if err != nil {
+		return derivedMethod, err
+	}
+
+	return derivedMethod, nil
+}
+
+// resolveQueryParameters returns the parameters bound to the query, and the indexes of the trailing
+// shelf.Sort or shelf.Pageable parameters or -1.
+func resolveQueryParameters(method marker.Method) ([]marker.TypeInfo, int, int) {
+	parameters := method.Parameters
+	lastIndex := len(parameters) - 1
+
+	if lastIndex > 0 {
+		switch GetQualifiedNameFromType(method.File, parameters[lastIndex].Type) {
+		case ShelfSortType:
+			return parameters[:lastIndex], lastIndex, -1
+		case ShelfPageableType:
+			return parameters[:lastIndex], -1, lastIndex
+		}
+	}
+
+	return parameters, -1, -1
+}
+
+// resolveQueryMethodResult resolves the result of a query method and checks whether it matches
+// the pageable parameter and the fetch size.
+func resolveQueryMethodResult(metadata RepositoryMetadata, method marker.Method, kind QueryKind,
+	pageableIndex int) (QueryResult, *ProjectionMetadata, int, error) {
+	result, projection, err := resolveQueryResult(metadata, method, kind)
+
+	if err != nil {
+		return result, projection, 0, err
+	}
+
+	if (result == PageResult || result == SliceResult) && pageableIndex == -1 {
+		return result, projection, 0, fmt.Errorf("the method '%s' must take in a shelf.Pageable as the last parameter to return a page or a slice", method.Name)
+	}
+
+	if pageableIndex != -1 && result == SingleResult {
+		return result, projection, 0, fmt.Errorf("the method '%s' cannot take in a shelf.Pageable to return a single entity", method.Name)
+	}
+
+	fetchSize := 0
+
+	for _, candidateMarker := range method.Markers[shelf.MarkerFetchSize] {
+		if fetchSizeMarker, ok := candidateMarker.(shelf.FetchSizeMarker); ok {
+			if result != CursorResult {
+				return result, projection, 0, fmt.Errorf("'%s' marker can only be used with the methods returning *shelf.Cursor", shelf.MarkerFetchSize)
+			}
+
+			fetchSize = fetchSizeMarker.Size
+		}
+	}
+
+	return result, projection, fetchSize, nil
+}
+
+func validateQueryArgument(entity EntityMetadata, method marker.Method, parameter marker.TypeInfo, condition QueryCondition) error {
+	typeName := GetQualifiedNameFromType(method.File, parameter.Type)
+	fieldTypeName := GetQualifiedNameFromType(condition.Field.File, condition.Field.Type)
+	expectedTypeName := GetFullNameFromType(condition.Field.Type)
+
+	if condition.Operator.IsString {
+		fieldTypeName = "string"
+		expectedTypeName = "string"
+	}
+
+	if condition.Operator.IsSlice {
+		fieldTypeName = "[]" + fieldTypeName
+		expectedTypeName = "[]" + expectedTypeName
+	}
+
+	if typeName != fieldTypeName {
+		return fmt.Errorf("the parameter '%s' of the method '%s' must be of type '%s' for the property '%s'",
+			parameter.Name, method.Name, expectedTypeName, condition.Field.FieldName)
+	}
+
+	return nil
+}
+
+func resolveQueryResult(metadata RepositoryMetadata, method marker.Method, kind QueryKind) (QueryResult, *ProjectionMetadata, error) {
+	returnValues := GetResultValues(method)
+	entity := metadata.Entity
+	file := method.File
+
+	isKind := func(index int, kind RepositoryValueKind) bool {
+		return IsRepositoryValueKind(entity, file, returnValues[index].Type, kind)
+	}
+
+	switch kind {
+	case SelectQuery:
+		if len(returnValues) == 0 || len(returnValues) > 2 {
+			break
+		}
+
+		if len(returnValues) == 1 && isKind(0, CursorValue) {
+			return CursorResult, nil, nil
+		}
+
+		isSlice, projection, ok, err := resolveQueryElement(entity, file, returnValues[0].Type)
+
+		if err != nil {
+			return NoResult, nil, err
+		}
+
+		if !ok {
+			break
+		}
+
+		if len(returnValues) == 1 && !isSlice {
+			return SingleResult, projection, nil
+		} else if len(returnValues) == 1 && isSlice {
+			return ListResult, projection, nil
+		} else if isSlice && isKind(1, PageValue) {
+			return PageResult, projection, nil
+		} else if isSlice && isKind(1, SliceValue) {
+			return SliceResult, projection, nil
+		}
+	case CountQuery:
+		if len(returnValues) == 1 && isKind(0, IntegerValue) {
+			return CountResult, nil, nil
+		}
+
+		return NoResult, nil, fmt.Errorf("the method '%s' must return an integer", method.Name)
+	case ExistsQuery:
+		if len(returnValues) == 1 && isKind(0, BoolValue) {
+			return ExistsResult, nil, nil
+		}
+
+		return NoResult, nil, fmt.Errorf("the method '%s' must return bool", method.Name)
+	case UpdateQuery, DeleteQuery:
+		if len(returnValues) == 0 {
+			return NoResult, nil, nil
+		} else if len(returnValues) == 1 && isKind(0, IntegerValue) {
+			return CountResult, nil, nil
+		}
+
+		return NoResult, nil, fmt.Errorf("the method '%s' can only return the number of the affected rows", method.Name)
+	default:
+		return NoResult, nil, errors.New("unknown query kind")
+	}
+
+	return NoResult, nil, fmt.Errorf("the method '%s' must return *%s, []*%s, ([]*%s, shelf.Page), ([]*%s, shelf.Slice) "+
+		"or *shelf.Cursor, or a projection in place of *%s", method.Name, entity.StructName, entity.StructName, entity.StructName,
+		entity.StructName, entity.StructName)
+}
+
+// resolveQueryElement reports whether the type is the entity or a projection of the entity, or a slice of them.
+func resolveQueryElement(entity EntityMetadata, file *marker.File, typ marker.Type) (bool, *ProjectionMetadata, bool, error) {
+	if IsRepositoryValueKind(entity, file, typ, EntityValue) {
+		return false, nil, true, nil
+	}
+
+	if IsRepositoryValueKind(entity, file, typ, EntitySliceValue) {
+		return true, nil, true, nil
+	}
+
+	isSlice := false
+
+	if arrayType, ok := typ.(*marker.ArrayType); ok {
+		isSlice = true
+		typ = arrayType.ItemType
+	}
+
+	projection, ok, err := FindProjection(entity, file, typ)
+	return isSlice, projection, ok, err
+}
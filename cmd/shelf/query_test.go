@@ -0,0 +1,329 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// querySource is the package of the derived query tests, to which the repositories are appended.
+const querySource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+
+	"github.com/procyon-projects/shelf"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id        int
+	Email     string
+	FirstName string
+	Age       int
+}
+
+var _ shelf.Pageable
+`
+
+func TestValidate_DerivedQueries(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Method string
+		Errors []string
+	}{
+		{
+			Name:   "unknown property",
+			Method: "FindByLastName(ctx context.Context, lastName string) []*User",
+			Errors: []string{
+				"the conditions of the method 'FindByLastName' cannot be resolved, make sure that the properties exist in the entity 'User'",
+			},
+		},
+		{
+			Name:   "unknown order",
+			Method: "FindByAgeOrderByLastName(ctx context.Context, age int) []*User",
+			Errors: []string{
+				"the orders of the method 'FindByAgeOrderByLastName' cannot be resolved, make sure that the properties exist in the entity 'User'",
+			},
+		},
+		{
+			Name:   "missing conditions",
+			Method: "FindBy(ctx context.Context) []*User",
+			Errors: []string{
+				"the method 'FindBy' must be followed by 'By' and the conditions",
+			},
+		},
+		{
+			Name:   "missing parameter",
+			Method: "FindByEmailAndAge(ctx context.Context, email string) []*User",
+			Errors: []string{
+				"the method 'FindByEmailAndAge' does not have enough parameters for its conditions",
+			},
+		},
+		{
+			Name:   "extra parameter",
+			Method: "FindByEmail(ctx context.Context, email string, age int) []*User",
+			Errors: []string{
+				"the method 'FindByEmail' has more parameters than its conditions need",
+			},
+		},
+		{
+			Name:   "wrong parameter type",
+			Method: "FindByAge(ctx context.Context, age string) []*User",
+			Errors: []string{
+				"the parameter 'age' of the method 'FindByAge' must be of type 'int' for the property 'Age'",
+			},
+		},
+		{
+			Name:   "wrong slice type",
+			Method: "FindByAgeIn(ctx context.Context, ages []string) []*User",
+			Errors: []string{
+				"the parameter 'ages' of the method 'FindByAgeIn' must be of type '[]int' for the property 'Age'",
+			},
+		},
+		{
+			Name:   "ordered count",
+			Method: "CountByAgeOrderByEmail(ctx context.Context, age int) int64",
+			Errors: []string{
+				"the method 'CountByAgeOrderByEmail' cannot be ordered",
+			},
+		},
+		{
+			Name:   "page without pageable",
+			Method: "FindByAge(ctx context.Context, age int) ([]*User, shelf.Page)",
+			Errors: []string{
+				"the method 'FindByAge' must take in a shelf.Pageable as the last parameter to return a page or a slice",
+			},
+		},
+		{
+			Name:   "single entity with pageable",
+			Method: "FindByEmail(ctx context.Context, email string, pageable shelf.Pageable) *User",
+			Errors: []string{
+				"the method 'FindByEmail' cannot take in a shelf.Pageable to return a single entity",
+			},
+		},
+		{
+			Name:   "sorted delete",
+			Method: "DeleteByAge(ctx context.Context, age int, sort shelf.Sort) int64",
+			Errors: []string{
+				"the method 'DeleteByAge' cannot take in a sort or a pageable",
+			},
+		},
+		{
+			Name:   "count returning an entity",
+			Method: "CountByAge(ctx context.Context, age int) *User",
+			Errors: []string{
+				"the method 'CountByAge' must return an integer",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			source := querySource + `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	` + testCase.Method + `
+}`
+
+			assertErrors(t, validate(t, "fixture", source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_DerivedQueries(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Save(ctx context.Context, user *User) error
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	FindByAgeGreaterThanOrderByFirstNameDesc(ctx context.Context, age int) ([]*User, error)
+	FindByFirstNameLike(ctx context.Context, firstName string, pageable shelf.Pageable) ([]*User, shelf.Page, error)
+	FindByAgeBetween(ctx context.Context, from, to int, sort shelf.Sort) ([]*User, error)
+	FindByEmailIn(ctx context.Context, emails []string) ([]*User, error)
+	CountByAge(ctx context.Context, age int) (int64, error)
+	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	DeleteByAgeLessThan(ctx context.Context, age int) (int64, error)
+	FindAll(ctx context.Context, pageable shelf.Pageable) ([]*User, shelf.Slice, error)
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				`"INSERT INTO users(email, first_name, age) VALUES($1, $2, $3) RETURNING id", user.Email, user.FirstName, user.Age).Scan(&user.Id)`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE email = $1"`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE age > $1 ORDER BY first_name DESC"`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE first_name LIKE $1"`,
+				`query += " LIMIT " + strconv.Itoa(pageable.Size) + " OFFSET " + strconv.Itoa(pageable.Offset())`,
+				`countQuery := "SELECT COUNT(*) FROM users WHERE first_name LIKE $1"`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE age BETWEEN $1 AND $2"`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE email IN (?)"`,
+				`query, args := shelf.Dollar.Bind(query, emails)`,
+				`query := "SELECT COUNT(*) FROM users WHERE age = $1"`,
+				`query := "SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)"`,
+				`query := "DELETE FROM users WHERE age < $1"`,
+				`query += " LIMIT " + strconv.Itoa(pageable.Size+1) + " OFFSET " + strconv.Itoa(pageable.Offset())`,
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`"INSERT INTO users(email, first_name, age) VALUES(?, ?, ?)", user.Email, user.FirstName, user.Age)`,
+				`id, err = result.LastInsertId()`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE email = ?"`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE age BETWEEN ? AND ?"`,
+				`query, args := shelf.Question.Bind(query, emails)`,
+				`query := "DELETE FROM users WHERE age < ?"`,
+			},
+		},
+		{
+			Dialect: "sqlite",
+			Statements: []string{
+				`"INSERT INTO users(email, first_name, age) VALUES(?, ?, ?) RETURNING id", user.Email, user.FirstName, user.Age).Scan(&user.Id)`,
+				`query := "SELECT id, email, first_name, age FROM users WHERE email = ?"`,
+				`query, args := shelf.Question.Bind(query, emails)`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", querySource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
+
+func TestRun_DerivedQueries(t *testing.T) {
+	runFixture(t, "fixture", querySource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindByEmailIn(ctx context.Context, emails []string) ([]*User, error)
+	FindByEmailNotIn(ctx context.Context, emails []string) ([]*User, error)
+	FindByFirstNameLike(ctx context.Context, firstName string, pageable shelf.Pageable) ([]*User, shelf.Page, error)
+	CountByAge(ctx context.Context, age int) (int64, error)
+}`, `package fixture
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/procyon-projects/shelf"
+)
+
+func TestDerivedQueries(t *testing.T) {
+	connector := &testConnector{
+		Query: func(query string, args []driver.Value) (driver.Rows, error) {
+			if strings.HasPrefix(query, "SELECT COUNT(*)") {
+				return newTestRows([]string{"count"}, []driver.Value{int64(3)}), nil
+			}
+
+			return newTestRows([]string{"id", "email", "first_name", "age"},
+				[]driver.Value{int64(1), "anna@example.com", "Anna", int64(30)},
+				[]driver.Value{int64(2), "ada@example.com", "Ada", int64(36)}), nil
+		},
+	}
+	db := openTestDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	repository := NewUserRepository(db)
+
+	users, err := repository.FindByEmailIn(ctx, []string{"anna@example.com", "ada@example.com"})
+
+	if err != nil || len(users) != 2 || users[1].FirstName != "Ada" {
+		t.Errorf("FindByEmailIn should return the users, but got %v and %v", users, err)
+	}
+
+	_, err = repository.FindByEmailIn(ctx, []string{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repository.FindByEmailNotIn(ctx, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users, page, err := repository.FindByFirstNameLike(ctx, "A%", shelf.PageRequest(0, 2))
+
+	if err != nil || len(users) != 2 || page.TotalElements != 3 || page.TotalPages != 2 {
+		t.Errorf("FindByFirstNameLike should return the first page, but got %v, %+v and %v", users, page, err)
+	}
+
+	count, err := repository.CountByAge(ctx, 30)
+
+	if err != nil || count != 3 {
+		t.Errorf("CountByAge should return 3, but got %d and %v", count, err)
+	}
+
+	expected := []testStatement{
+		{Query: "SELECT id, email, first_name, age FROM users WHERE email IN ($1, $2)",
+			Args: []driver.Value{"anna@example.com", "ada@example.com"}},
+		{Query: "SELECT id, email, first_name, age FROM users WHERE 1 = 0", Args: []driver.Value{}},
+		{Query: "SELECT id, email, first_name, age FROM users WHERE 1 = 1", Args: []driver.Value{}},
+		{Query: "SELECT id, email, first_name, age FROM users WHERE first_name LIKE $1 LIMIT 2 OFFSET 0",
+			Args: []driver.Value{"A%"}},
+		{Query: "SELECT COUNT(*) FROM users WHERE first_name LIKE $1", Args: []driver.Value{"A%"}},
+		{Query: "SELECT COUNT(*) FROM users WHERE age = $1", Args: []driver.Value{int64(30)}},
+	}
+
+	if statements := connector.Statements(); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("the statements should be %v, but got %v", expected, statements)
+	}
+}
+`)
+}
+
+func TestGenerate_UnsupportedDialect(t *testing.T) {
+	dir, remove := writePackage(t, "fixture", map[string]string{"fixture.go": querySource})
+	defer remove()
+
+	messages := runShelf(t, dir, "generate", "-o", dir, "-a", "dialect=oracle")
+	assertContains(t, strings.Join(messages, "\n"), "unsupported dialect 'oracle'. Here is the list of supported dialects mysql, postgres, sqlite")
+}
+
+func TestGenerate_Cursors(t *testing.T) {
+	repositories := generate(t, "fixture", querySource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindByAge(ctx context.Context, age int) (*shelf.Cursor, error)
+	// +shelf:fetch-size:Size=100
+	FindByEmail(ctx context.Context, email string) (*shelf.Cursor, error)
+}`)
+
+	assertContains(t, repositories,
+		`rows, err := repository.executor(ctx).QueryContext(ctx, query, age)`,
+		`return shelf.NewCursor(ctx, rows, scan), nil`,
+		`cursor, err := shelf.NewFetchCursor(ctx, repository.db, 100, scan, query, email)`,
+	)
+}
+
+func TestGenerate_FetchSizeDialects(t *testing.T) {
+	for _, dialect := range []string{"mysql", "sqlite"} {
+		t.Run(dialect, func(t *testing.T) {
+			dir, remove := writePackage(t, "fixture", map[string]string{"fixture.go": querySource + `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:fetch-size:Size=100
+	FindByAge(ctx context.Context, age int) (*shelf.Cursor, error)
+}`})
+			defer remove()
+
+			assertErrors(t, runShelf(t, dir, "generate", "-o", dir, "-a", "dialect="+dialect),
+				"the 'shelf:fetch-size' marker of the method 'FindByAge' is not supported by "+dialect+", which has no server-side cursors")
+		})
+	}
+}
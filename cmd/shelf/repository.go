@@ -8,23 +8,111 @@ import (
 	"strings"
 )
 
-var reservedRepositoryMethods = map[string]int{
-	"Count":         2,
-	"ExistsById":    2,
-	"Delete":        2,
-	"DeleteById":    2,
-	"DeleteAll":     2,
-	"DeleteAllById": 2,
-	"Save":          2,
-	"SaveAll":       2,
-	"FindById":      2,
-	"FindAll":       2,
-	"FindAllById":   2,
+type RepositoryValueKind int
+
+const (
+	ContextValue RepositoryValueKind = iota
+	IdValue
+	IdSliceValue
+	EntityValue
+	EntitySliceValue
+	IntegerValue
+	BoolValue
+	SortValue
+	PageableValue
+	PageValue
+	SliceValue
+	CursorValue
+	SpecificationValue
+	// RepositoryValue is the repository interface itself, which is returned by the fluent methods.
+	RepositoryValue
+)
+
+const (
+	ShelfSortType     = PkgId + ".Sort"
+	ShelfPageableType = PkgId + ".Pageable"
+	ShelfPageType     = PkgId + ".Page"
+	ShelfSliceType    = PkgId + ".Slice"
+	ShelfCursorType   = "*" + PkgId + ".Cursor"
+
+	ShelfSpecificationType = PkgId + ".Specification"
+)
+
+// ReservedRepositoryMethod describes the expected signature of a reserved repository method
+// and the template used to generate its body.
+type ReservedRepositoryMethod struct {
+	Parameters   []RepositoryValueKind
+	ReturnValues []RepositoryValueKind
+	Template     string
+	// Fluent is set for the methods returning a copy of the repository, which return no error as they do
+	// not access the database.
+	Fluent bool
+}
+
+var reservedRepositoryMethods = map[string][]ReservedRepositoryMethod{
+	"Count": {
+		{Parameters: []RepositoryValueKind{ContextValue}, ReturnValues: []RepositoryValueKind{IntegerValue}, Template: countTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue, SpecificationValue}, ReturnValues: []RepositoryValueKind{IntegerValue}, Template: countByTemplate},
+	},
+	"ExistsById": {
+		{Parameters: []RepositoryValueKind{ContextValue, IdValue}, ReturnValues: []RepositoryValueKind{BoolValue}, Template: existsByIdTemplate},
+	},
+	"Delete": {
+		{Parameters: []RepositoryValueKind{ContextValue, EntityValue}, Template: deleteTemplate},
+	},
+	"DeleteById": {
+		{Parameters: []RepositoryValueKind{ContextValue, IdValue}, Template: deleteByIdTemplate},
+	},
+	"DeleteAll": {
+		{Parameters: []RepositoryValueKind{ContextValue}, Template: deleteAllTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue, EntitySliceValue}, Template: deleteAllEntitiesTemplate},
+	},
+	"DeleteAllById": {
+		{Parameters: []RepositoryValueKind{ContextValue, IdSliceValue}, Template: deleteAllByIdTemplate},
+	},
+	"Save": {
+		{Parameters: []RepositoryValueKind{ContextValue, EntityValue}, Template: saveTemplate},
+	},
+	"SaveAll": {
+		{Parameters: []RepositoryValueKind{ContextValue, EntitySliceValue}, Template: saveAllTemplate},
+	},
+	"FindById": {
+		{Parameters: []RepositoryValueKind{ContextValue, IdValue}, ReturnValues: []RepositoryValueKind{EntityValue}, Template: findByIdTemplate},
+	},
+	"FindAll": {
+		{Parameters: []RepositoryValueKind{ContextValue}, ReturnValues: []RepositoryValueKind{EntitySliceValue}, Template: findAllTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue, SortValue}, ReturnValues: []RepositoryValueKind{EntitySliceValue}, Template: findTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue, PageableValue}, ReturnValues: []RepositoryValueKind{EntitySliceValue, PageValue}, Template: findTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue, PageableValue}, ReturnValues: []RepositoryValueKind{EntitySliceValue, SliceValue}, Template: findTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue}, ReturnValues: []RepositoryValueKind{CursorValue}, Template: findTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue, SortValue}, ReturnValues: []RepositoryValueKind{CursorValue}, Template: findTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue, SpecificationValue}, ReturnValues: []RepositoryValueKind{EntitySliceValue}, Template: findTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue, SpecificationValue, SortValue}, ReturnValues: []RepositoryValueKind{EntitySliceValue}, Template: findTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue, SpecificationValue, PageableValue}, ReturnValues: []RepositoryValueKind{EntitySliceValue, PageValue}, Template: findTemplate},
+		{Parameters: []RepositoryValueKind{ContextValue, SpecificationValue, PageableValue}, ReturnValues: []RepositoryValueKind{EntitySliceValue, SliceValue}, Template: findTemplate},
+	},
+	"FindAllById": {
+		{Parameters: []RepositoryValueKind{ContextValue, IdSliceValue}, ReturnValues: []RepositoryValueKind{EntitySliceValue}, Template: findAllByIdTemplate},
+	},
+}
+
+// LoaderMethodPrefix is followed by the field of an association in the names of the methods loading it,
+// e.g. LoadPosts loads the Posts of the given users.
+const LoaderMethodPrefix = "Load"
+
+// loaderMethods are the forms of the methods loading an association of the given entities, and of the fluent
+// methods returning a copy of the repository fetching the association with the queried entities, e.g.
+// LoadPosts() UserRepository.
+var loaderMethods = []ReservedRepositoryMethod{
+	{Parameters: []RepositoryValueKind{ContextValue, EntityValue}, Template: loadTemplate},
+	{Parameters: []RepositoryValueKind{ContextValue, EntitySliceValue}, Template: loadAllTemplate},
+	{ReturnValues: []RepositoryValueKind{RepositoryValue}, Template: fetchPlanTemplate, Fluent: true},
 }
 
 type RepositoryMetadata struct {
 	RepositoryName string
 	EntityName     string
+	Entity         EntityMetadata
 	InterfaceType  marker.InterfaceType
 }
 
@@ -66,7 +154,7 @@ func FindRepositories(interfaceTypes []marker.InterfaceType) {
 		markers, ok := markerValues[shelf.MarkerRepository]
 
 		if !ok {
-			return
+			continue
 		}
 
 		var err error
@@ -107,9 +195,12 @@ func FindRepositories(interfaceTypes []marker.InterfaceType) {
 			metadata := RepositoryMetadata{
 				RepositoryName: repositoryName,
 				EntityName:     entityName,
+				Entity:         entityMetadataByStructName[entitiesByName[entityName]],
 				InterfaceType:  interfaceType,
 			}
 
+			ValidateRepositoryMethods(metadata)
+
 			fullInterfaceName := interfaceType.File.Package.Path + "#" + interfaceType.Name
 			repositoryMetadataByInterfaceName[fullInterfaceName] = metadata
 			repositoriesByName[repositoryName] = fullInterfaceName
@@ -118,11 +209,276 @@ func FindRepositories(interfaceTypes []marker.InterfaceType) {
 	}
 }
 
-func ValidateRepositoryMethods(methods []marker.Method) {
-	for _, method := range methods {
-		ValidateRepositoryMethodParameters(method)
+func ValidateRepositoryMethods(metadata RepositoryMetadata) {
+	for _, method := range metadata.InterfaceType.Methods {
+		// the fluent methods are the only ones taking in no context
+		if !IsFluentRepositoryMethod(metadata, method) {
+			ValidateRepositoryMethodParameters(method)
+		}
+
+		ValidateRepositoryMethodReturnValues(method)
 		ValidateXMarkers(method)
+		ValidateTransactionalMethod(method)
+		ValidateFetchMarker(metadata, method)
+
+		if _, ok := method.Markers[shelf.MarkerQuery]; ok {
+			ValidateCustomQueryMethod(metadata, method)
+		} else if len(GetReservedRepositoryMethods(metadata, method)) != 0 {
+			ValidateReservedRepositoryMethod(metadata, method)
+		} else {
+			ValidateDerivedQueryMethod(metadata, method)
+		}
+	}
+}
+
+// ValidateDerivedQueryMethod checks whether a query can be derived from the method.
+func ValidateDerivedQueryMethod(metadata RepositoryMetadata, method marker.Method) {
+	if len(method.Parameters) == 0 {
+		return
+	}
+
+	_, err := ResolveDerivedQueryMethod(metadata, method)
+
+	if err != nil {
+		errs = append(errs, marker.NewError(err, method.File.FullPath, marker.Position{
+			Line:   method.Position.Line,
+			Column: method.Position.Column,
+		}))
+	}
+}
+
+// ValidateCustomQueryMethod checks whether the query of the method can be translated and matches its signature.
+func ValidateCustomQueryMethod(metadata RepositoryMetadata, method marker.Method) {
+	if len(method.Parameters) == 0 {
+		return
 	}
+
+	// the dialect only changes the quotes of the table names, which are not validated
+	_, err := ResolveCustomQueryMethod(metadata, method, dialects[DialectPostgres])
+
+	if err != nil {
+		errs = append(errs, marker.NewError(err, method.File.FullPath, marker.Position{
+			Line:   method.Position.Line,
+			Column: method.Position.Column,
+		}))
+	}
+}
+
+// ValidateReservedRepositoryMethod checks whether the signature of a reserved method matches one of
+// its expected forms.
+func ValidateReservedRepositoryMethod(metadata RepositoryMetadata, method marker.Method) {
+	if _, ok := FindReservedRepositoryMethod(metadata, method); ok {
+		return
+	}
+
+	// the reserved finder methods can return a projection in place of the entity
+	if IsReservedProjectionMethod(metadata, method) {
+		return
+	}
+
+	signatures := make([]string, 0)
+
+	for _, reservedMethod := range GetReservedRepositoryMethods(metadata, method) {
+		signatures = append(signatures, GetReservedRepositoryMethodSignature(metadata, method.Name, reservedMethod))
+	}
+
+	err := fmt.Errorf("the reserved method '%s' must be in the form of %s",
+		method.Name, strings.Join(signatures, " or "))
+	errs = append(errs, marker.NewError(err, method.File.FullPath, marker.Position{
+		Line:   method.Position.Line,
+		Column: method.Position.Column,
+	}))
+}
+
+// IsReservedProjectionMethod reports whether the reserved method is a finder method returning a projection.
+func IsReservedProjectionMethod(metadata RepositoryMetadata, method marker.Method) bool {
+	if !strings.HasPrefix(method.Name, "Find") || len(method.Parameters) == 0 {
+		return false
+	}
+
+	derivedMethod, err := ResolveDerivedQueryMethod(metadata, method)
+	return err == nil && derivedMethod.Projection != nil
+}
+
+// GetReservedRepositoryMethods returns the reserved forms of the method, which are the loader forms if the
+// method loads an association of the entity.
+func GetReservedRepositoryMethods(metadata RepositoryMetadata, method marker.Method) []ReservedRepositoryMethod {
+	if _, ok := FindLoaderAssociation(metadata, method); ok {
+		return loaderMethods
+	}
+
+	return reservedRepositoryMethods[method.Name]
+}
+
+// FindLoaderAssociation returns the association of the entity loaded by the method, e.g. Posts for LoadPosts.
+func FindLoaderAssociation(metadata RepositoryMetadata, method marker.Method) (AssociationMetadata, bool) {
+	if !strings.HasPrefix(method.Name, LoaderMethodPrefix) {
+		return AssociationMetadata{}, false
+	}
+
+	return FindAssociation(metadata.Entity, strings.TrimPrefix(method.Name, LoaderMethodPrefix))
+}
+
+// FindReservedRepositoryMethod returns the reserved method whose expected signature matches the method.
+// The reserved methods must return an error as the last value, since they cannot report the failures
+// of the database otherwise, except for the fluent ones.
+func FindReservedRepositoryMethod(metadata RepositoryMetadata, method marker.Method) (ReservedRepositoryMethod, bool) {
+	returnValues := GetResultValues(method)
+
+	for _, reservedMethod := range GetReservedRepositoryMethods(metadata, method) {
+		if reservedMethod.Fluent == HasErrorReturnValue(method) {
+			continue
+		}
+
+		if len(reservedMethod.Parameters) != len(method.Parameters) || len(reservedMethod.ReturnValues) != len(returnValues) {
+			continue
+		}
+
+		matched := true
+
+		for index, kind := range reservedMethod.Parameters {
+			if !isRepositoryMethodValueKind(metadata, method.File, method.Parameters[index].Type, kind) {
+				matched = false
+			}
+		}
+
+		for index, kind := range reservedMethod.ReturnValues {
+			if !isRepositoryMethodValueKind(metadata, method.File, returnValues[index].Type, kind) {
+				matched = false
+			}
+		}
+
+		if matched {
+			return reservedMethod, true
+		}
+	}
+
+	return ReservedRepositoryMethod{}, false
+}
+
+// IsFluentRepositoryMethod reports whether the method returns a copy of the repository fetching an association.
+func IsFluentRepositoryMethod(metadata RepositoryMetadata, method marker.Method) bool {
+	reservedMethod, ok := FindReservedRepositoryMethod(metadata, method)
+	return ok && reservedMethod.Fluent
+}
+
+// isRepositoryMethodValueKind reports whether the type is of the kind, which can be the repository interface.
+func isRepositoryMethodValueKind(metadata RepositoryMetadata, file *marker.File, typ marker.Type, kind RepositoryValueKind) bool {
+	if kind == RepositoryValue {
+		interfaceType := metadata.InterfaceType
+		return GetQualifiedNameFromType(file, typ) == interfaceType.File.Package.Path+"."+interfaceType.Name
+	}
+
+	return IsRepositoryValueKind(metadata.Entity, file, typ, kind)
+}
+
+func IsRepositoryValueKind(entity EntityMetadata, file *marker.File, typ marker.Type, kind RepositoryValueKind) bool {
+	entityFile := entity.StructType.File
+	typeName := GetQualifiedNameFromType(file, typ)
+	idTypeName := GetQualifiedNameFromType(entityFile, entity.IdField.Type)
+
+	if entity.IsComposite() {
+		idTypeName = entity.IdClass.File.Package.Path + "." + entity.IdClass.Name
+	}
+
+	entityTypeName := entityFile.Package.Path + "." + entity.StructName
+
+	switch kind {
+	case ContextValue:
+		return typeName == "context.Context"
+	case IdValue:
+		return typeName == idTypeName
+	case IdSliceValue:
+		return typeName == "[]"+idTypeName
+	case EntityValue:
+		return typeName == "*"+entityTypeName
+	case EntitySliceValue:
+		return typeName == "[]*"+entityTypeName
+	case IntegerValue:
+		switch typeName {
+		case "int", "int32", "int64", "uint", "uint32", "uint64":
+			return true
+		}
+	case BoolValue:
+		return typeName == "bool"
+	case SortValue:
+		return typeName == ShelfSortType
+	case PageableValue:
+		return typeName == ShelfPageableType
+	case PageValue:
+		return typeName == ShelfPageType
+	case SliceValue:
+		return typeName == ShelfSliceType
+	case CursorValue:
+		return typeName == ShelfCursorType
+	case SpecificationValue:
+		return typeName == ShelfSpecificationType
+	}
+
+	return false
+}
+
+func GetReservedRepositoryMethodSignature(metadata RepositoryMetadata, name string, reservedMethod ReservedRepositoryMethod) string {
+	entity := metadata.Entity
+	idTypeName := GetFullNameFromType(entity.IdField.Type)
+
+	if entity.IsComposite() {
+		idTypeName = entity.IdClass.Name
+	}
+
+	typeNames := func(kinds []RepositoryValueKind) []string {
+		names := make([]string, 0)
+
+		for _, kind := range kinds {
+			switch kind {
+			case ContextValue:
+				names = append(names, "context.Context")
+			case IdValue:
+				names = append(names, idTypeName)
+			case IdSliceValue:
+				names = append(names, "[]"+idTypeName)
+			case EntityValue:
+				names = append(names, "*"+entity.StructName)
+			case EntitySliceValue:
+				names = append(names, "[]*"+entity.StructName)
+			case IntegerValue:
+				names = append(names, "int64")
+			case BoolValue:
+				names = append(names, "bool")
+			case SortValue:
+				names = append(names, "shelf.Sort")
+			case PageableValue:
+				names = append(names, "shelf.Pageable")
+			case PageValue:
+				names = append(names, "shelf.Page")
+			case SliceValue:
+				names = append(names, "shelf.Slice")
+			case CursorValue:
+				names = append(names, "*shelf.Cursor")
+			case SpecificationValue:
+				names = append(names, "shelf.Specification")
+			case RepositoryValue:
+				names = append(names, metadata.InterfaceType.Name)
+			}
+		}
+
+		return names
+	}
+
+	signature := name + "(" + strings.Join(typeNames(reservedMethod.Parameters), ", ") + ")"
+	returnValues := typeNames(reservedMethod.ReturnValues)
+
+	if !reservedMethod.Fluent {
+		returnValues = append(returnValues, "error")
+	}
+
+	if len(returnValues) == 1 {
+		signature = signature + " " + returnValues[0]
+	} else if len(returnValues) > 1 {
+		signature = signature + " (" + strings.Join(returnValues, ", ") + ")"
+	}
+
+	return "'" + signature + "'"
 }
 
 func ValidateXMarkers(method marker.Method) {
@@ -135,6 +491,16 @@ func ValidateXMarkers(method marker.Method) {
 	markers, ok := markerValues[shelf.MarkerQuery]
 
 	if !ok {
+		for _, markerName := range []string{shelf.MarkerModifying, shelf.MarkerIncludingDeleted} {
+			if _, ok := markerValues[markerName]; ok {
+				err := fmt.Errorf("'%s' marker can only be used with '%s' marker", markerName, shelf.MarkerQuery)
+				errs = append(errs, marker.NewError(err, method.File.FullPath, marker.Position{
+					Line:   method.Position.Line,
+					Column: method.Position.Column,
+				}))
+			}
+		}
+
 		return
 	}
 
@@ -158,6 +524,22 @@ func ValidateXMarkers(method marker.Method) {
 	}
 }
 
+// ValidateTransactionalMethod checks that a method marked as shelf:transactional returns an error,
+// since the transaction cannot be rolled back if the method cannot report its failures.
+func ValidateTransactionalMethod(method marker.Method) {
+	if _, ok := method.Markers[shelf.MarkerTransactional]; !ok {
+		return
+	}
+
+	if !HasErrorReturnValue(method) {
+		err := fmt.Errorf("the method '%s' marked as '%s' must return an error as the last value", method.Name, shelf.MarkerTransactional)
+		errs = append(errs, marker.NewError(err, method.File.FullPath, marker.Position{
+			Line:   method.Position.Line,
+			Column: method.Position.Column,
+		}))
+	}
+}
+
 func ValidateRepositoryMethodParameters(method marker.Method) {
 
 	if method.Parameters == nil || len(method.Parameters) < 1 {
@@ -182,3 +564,70 @@ func ValidateRepositoryMethodParameters(method marker.Method) {
 		}
 	}
 }
+
+// ValidateRepositoryMethodReturnValues checks that the error is returned as the last value if it is returned.
+func ValidateRepositoryMethodReturnValues(method marker.Method) {
+	for index, returnValue := range method.ReturnValues {
+		if GetFullNameFromType(returnValue.Type) == "error" && index != len(method.ReturnValues)-1 {
+			err := errors.New("repository methods can only return error as the last value")
+			errs = append(errs, marker.NewError(err, method.File.FullPath, marker.Position{
+				Line:   method.Position.Line,
+				Column: method.Position.Column,
+			}))
+			return
+		}
+	}
+}
+
+// ValidateFetchMarker checks that the method marked as shelf:fetch returns the entities, and that the fields of
+// the association paths are associations.
+func ValidateFetchMarker(metadata RepositoryMetadata, method marker.Method) {
+	paths, ok := GetFetchPaths(method)
+
+	if !ok {
+		return
+	}
+
+	var err error
+	returnValues := GetResultValues(method)
+
+	if len(returnValues) == 0 || !IsRepositoryValueKind(metadata.Entity, method.File, returnValues[0].Type, EntityValue) &&
+		!IsRepositoryValueKind(metadata.Entity, method.File, returnValues[0].Type, EntitySliceValue) {
+		err = fmt.Errorf("'%s' marker can only be used with the methods returning the entities", shelf.MarkerFetch)
+	} else {
+		err = ValidateFetchPaths(metadata.Entity, paths)
+	}
+
+	if err != nil {
+		errs = append(errs, marker.NewError(err, method.File.FullPath, marker.Position{
+			Line:   method.Position.Line,
+			Column: method.Position.Column,
+		}))
+	}
+}
+
+// GetFetchPaths returns the association paths of the shelf:fetch marker of the method, if it is marked.
+func GetFetchPaths(method marker.Method) ([]string, bool) {
+	for _, candidateMarker := range method.Markers[shelf.MarkerFetch] {
+		if fetchMarker, ok := candidateMarker.(shelf.FetchMarker); ok {
+			return fetchMarker.Paths(), true
+		}
+	}
+
+	return nil, false
+}
+
+// HasErrorReturnValue reports whether the last value returned by the method is an error.
+func HasErrorReturnValue(method marker.Method) bool {
+	count := len(method.ReturnValues)
+	return count != 0 && GetFullNameFromType(method.ReturnValues[count-1].Type) == "error"
+}
+
+// GetResultValues returns the values returned by the method except for the trailing error.
+func GetResultValues(method marker.Method) []marker.TypeInfo {
+	if HasErrorReturnValue(method) {
+		return method.ReturnValues[:len(method.ReturnValues)-1]
+	}
+
+	return method.ReturnValues
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"testing"
+)
+
+// userSource is the package of the tests, to which the repositories are appended.
+const userSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id        int
+	Email     string
+	FirstName string
+}
+`
+
+func TestValidate_ReservedRepositoryMethods(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		Repository string
+		Errors     []string
+	}{
+		{
+			Name: "reserved methods",
+			Repository: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Count(ctx context.Context) (int64, error)
+	ExistsById(ctx context.Context, id int) (bool, error)
+	Delete(ctx context.Context, user *User) error
+	DeleteAll(ctx context.Context) error
+	FindById(ctx context.Context, id int) (*User, error)
+	FindAllById(ctx context.Context, ids []int) ([]*User, error)
+}`,
+		},
+		{
+			Name: "methods without an error",
+			Repository: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Count(ctx context.Context) int64
+	Delete(ctx context.Context, user *User)
+}`,
+			Errors: []string{
+				"the reserved method 'Count' must be in the form of 'Count(context.Context) (int64, error)' or 'Count(context.Context, shelf.Specification) (int64, error)'",
+				"the reserved method 'Delete' must be in the form of 'Delete(context.Context, *User) error'",
+			},
+		},
+		{
+			Name: "wrong id type",
+			Repository: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindById(ctx context.Context, id string) (*User, error)
+}`,
+			Errors: []string{
+				"the reserved method 'FindById' must be in the form of 'FindById(context.Context, int) (*User, error)'",
+			},
+		},
+		{
+			Name: "wrong entity type",
+			Repository: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	SaveAll(ctx context.Context, users []User) error
+}`,
+			Errors: []string{
+				"the reserved method 'SaveAll' must be in the form of 'SaveAll(context.Context, []*User) error'",
+			},
+		},
+		{
+			Name: "reserved method with many forms",
+			Repository: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	DeleteAll(ctx context.Context, ids []int) error
+}`,
+			Errors: []string{
+				"the reserved method 'DeleteAll' must be in the form of 'DeleteAll(context.Context) error' or 'DeleteAll(context.Context, []*User) error'",
+			},
+		},
+		{
+			Name: "missing context",
+			Repository: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Count() (int, error)
+}`,
+			Errors: []string{
+				"repository methods must take in one parameter of type context.Context at least",
+				"the reserved method 'Count' must be in the form of 'Count(context.Context) (int64, error)' or 'Count(context.Context, shelf.Specification) (int64, error)'",
+			},
+		},
+		{
+			Name: "error before the result",
+			Repository: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindById(ctx context.Context, id int) (error, *User)
+}`,
+			Errors: []string{
+				"repository methods can only return error as the last value",
+				"the reserved method 'FindById' must be in the form of 'FindById(context.Context, int) (*User, error)'",
+			},
+		},
+		{
+			Name: "unknown entity",
+			Repository: `
+// +shelf:repository="user-repository", Entity=Account
+type UserRepository interface {
+	Count(ctx context.Context) (int, error)
+}`,
+			Errors: []string{
+				"entity with name 'Account' does not exist, please use a valid entity name",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assertErrors(t, validate(t, "fixture", userSource+testCase.Repository), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_ReservedRepositoryMethods(t *testing.T) {
+	repositories := generate(t, "fixture", userSource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Count(ctx context.Context) (int, error)
+	ExistsById(ctx context.Context, id int) (bool, error)
+	Delete(ctx context.Context, user *User) error
+	DeleteById(ctx context.Context, id int) error
+	DeleteAll(ctx context.Context) error
+	DeleteAllById(ctx context.Context, ids []int) error
+	Save(ctx context.Context, user *User) error
+	SaveAll(ctx context.Context, users []*User) error
+	FindById(ctx context.Context, id int) (*User, error)
+	FindAll(ctx context.Context) ([]*User, error)
+	FindAllById(ctx context.Context, ids []int) ([]*User, error)
+}`)
+
+	assertContains(t, repositories,
+		`"SELECT COUNT(*) FROM users"`,
+		`"SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", idParam`,
+		`"DELETE FROM users WHERE id = $1", user.Id`,
+		`"DELETE FROM users WHERE id = $1", idParam`,
+		`"DELETE FROM users"`,
+		`"DELETE FROM users WHERE id IN ("+shelf.Dollar.Placeholders(1, end-start)+")", args[start:end]...`,
+		`"INSERT INTO users(email, first_name) VALUES($1, $2) RETURNING id", user.Email, user.FirstName).Scan(&user.Id)`,
+		`"UPDATE users SET email = $1, first_name = $2 WHERE id = $3", user.Email, user.FirstName, user.Id`,
+		`"SELECT id, email, first_name FROM users WHERE id = $1", idParam)`,
+		`err := scanUser(row, entity)`,
+		`"SELECT id, email, first_name FROM users"`,
+		`"SELECT id, email, first_name FROM users WHERE id IN ("+shelf.Dollar.Placeholders(1, len(args))+")", args...`,
+	)
+}
+
+func TestGenerate_ReservedTableName(t *testing.T) {
+	repositories := generate(t, "fixture", `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=user
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Email string
+}
+
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Count(ctx context.Context) (int, error)
+	Save(ctx context.Context, user *User) error
+}`)
+
+	assertContains(t, repositories,
+		`"SELECT COUNT(*) FROM \"user\""`,
+		`"INSERT INTO \"user\"(email) VALUES($1) RETURNING id"`,
+	)
+}
+
+func TestGenerate_ErrorReturningRepositoryMethods(t *testing.T) {
+	repositories := generate(t, "fixture", userSource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Count(ctx context.Context) (int, error)
+	Save(ctx context.Context, user *User) error
+	FindById(ctx context.Context, id int) (*User, error)
+}`)
+
+	assertContains(t, repositories,
+		`func (repository *userRepositoryImpl) Count(ctx context.Context) (int, error) {`,
+		`return 0, shelf.TranslateError(err)`,
+		`return count, nil`,
+		`func (repository *userRepositoryImpl) Save(ctx context.Context, user *User) error {`,
+		`return shelf.TranslateError(err)`,
+		`return nil, shelf.TranslateError(err)`,
+		`return entity, nil`,
+	)
+}
+
+func TestGenerate_UnsupportedRepositoryMethod(t *testing.T) {
+	dir, remove := writePackage(t, "fixture", map[string]string{"fixture.go": userSource + `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	LoadEmails(ctx context.Context) []*User
+}`})
+	defer remove()
+
+	assertErrors(t, runShelf(t, dir, "generate", "-o", dir),
+		"the method 'LoadEmails' does not start with any of the query prefixes")
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"testing"
+)
+
+// softDeleteSource is the package of the soft delete tests, whose users are marked as deleted by a flag
+// and whose posts by a status.
+const softDeleteSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+)
+
+// +shelf:entity
+// +shelf:table=users
+// +shelf:named-query="User.findByEmail", Query="FROM User u WHERE u.Email = %1 OR u.Email IS NULL"
+// +shelf:named-query="User.findAllNative", Query="SELECT id, email, deleted FROM users", NativeQuery=true
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Email string
+	// +shelf:soft-delete
+	Deleted bool
+}
+
+type PostStatus string
+
+// +shelf:entity
+// +shelf:table=posts
+type Post struct {
+	// +shelf:id
+	Id    int
+	Title string
+	// +shelf:soft-delete="DELETED"
+	Status PostStatus
+}
+`
+
+func TestValidate_SoftDeletes(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Source string
+		Errors []string
+	}{
+		{
+			Name: "field of an unsupported type",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:soft-delete
+	Deleted float64
+}`,
+			Errors: []string{
+				"the type of the field 'Deleted' marked as 'shelf:soft-delete' must be a bool, a nullable time, a string or an integer",
+			},
+		},
+		{
+			Name: "status without the deleted value",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+	// +shelf:soft-delete
+	Status string
+}`,
+			Errors: []string{
+				"the deleted value of the status field 'Status' must be given, e.g. shelf:soft-delete=\"DELETED\"",
+			},
+		},
+		{
+			Name: "including the deleted entities without a soft-delete field",
+			Source: `
+// +shelf:entity
+type Comment struct {
+	// +shelf:id
+	Id int
+}
+
+// +shelf:repository="comment-repository", Entity=Comment
+type CommentRepository interface {
+	FindAllIncludingDeleted(ctx context.Context) ([]*Comment, error)
+	// +shelf:query="FROM Comment"
+	// +shelf:including-deleted
+	FindComments(ctx context.Context) ([]*Comment, error)
+}`,
+			Errors: []string{
+				"the method 'FindAllIncludingDeleted' cannot include the deleted entities, since the entity 'Comment' has no field marked as 'shelf:soft-delete'",
+				"the method 'FindComments' cannot include the deleted entities, since the entity 'Comment' has no field marked as 'shelf:soft-delete'",
+			},
+		},
+		{
+			Name: "including the deleted entities without a query",
+			Source: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:including-deleted
+	FindByEmail(ctx context.Context, email string) ([]*User, error)
+}`,
+			Errors: []string{
+				"'shelf:including-deleted' marker can only be used with 'shelf:query' marker",
+			},
+		},
+		{
+			Name: "queries which cannot exclude the deleted entities",
+			Source: `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:query="SELECT id, email, deleted FROM users", NativeQuery=true
+	FindNative(ctx context.Context) ([]*User, error)
+	// +shelf:query=Name="User.findAllNative"
+	FindAllNative(ctx context.Context) ([]*User, error)
+	// +shelf:query="SELECT u FROM User u WHERE u.Id = %1 UNION SELECT u FROM User u WHERE u.Email = %2"
+	FindUnion(ctx context.Context, id int, email string) ([]*User, error)
+}`,
+			Errors: []string{
+				"the query of the method 'FindNative' cannot be translated: the soft-deleted entities of 'User' cannot be excluded automatically, exclude them in the query and mark the method as 'shelf:including-deleted'",
+				"the named query 'User.findAllNative' cannot be used by the method 'FindAllNative': the soft-deleted entities of 'User' cannot be excluded automatically, exclude them in the query and mark the method as 'shelf:including-deleted'",
+				"the query of the method 'FindUnion' cannot be translated: the soft-deleted entities of 'User' cannot be excluded automatically, exclude them in the query and mark the method as 'shelf:including-deleted'",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assertErrors(t, validate(t, "fixture", softDeleteSource+testCase.Source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_SoftDeletes(t *testing.T) {
+	repositories := generate(t, "fixture", softDeleteSource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	FindById(ctx context.Context, id int) (*User, error)
+	FindByEmailOrId(ctx context.Context, email string, id int) ([]*User, error)
+	FindAllIncludingDeleted(ctx context.Context) ([]*User, error)
+	Delete(ctx context.Context, user *User) error
+	DeleteByEmail(ctx context.Context, email string) error
+}
+
+// +shelf:repository="post-repository", Entity=Post
+type PostRepository interface {
+	FindAll(ctx context.Context) ([]*Post, error)
+	DeleteById(ctx context.Context, id int) error
+}`)
+
+	assertContains(t, repositories,
+		`"SELECT id, email, deleted FROM users WHERE id = $1 AND deleted = FALSE", id`,
+		`"SELECT id, email, deleted FROM users WHERE (email = $1 OR id = $2) AND deleted = FALSE"`,
+		`"SELECT id, email, deleted FROM users"`,
+		`"UPDATE users SET deleted = TRUE WHERE id = $1 AND deleted = FALSE", user.Id`,
+		`"UPDATE users SET deleted = TRUE WHERE email = $1 AND deleted = FALSE"`,
+		`"SELECT id, title, status FROM posts WHERE status <> 'DELETED'"`,
+		`"UPDATE posts SET status = 'DELETED' WHERE id = $1 AND status <> 'DELETED'", id`,
+	)
+}
+
+func TestGenerate_SoftDeletesOfCustomQueries(t *testing.T) {
+	repositories := generate(t, "fixture", softDeleteSource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:query="SELECT u FROM User u ORDER BY u.Email"
+	FindUsers(ctx context.Context) ([]*User, error)
+	// +shelf:query="FROM User WHERE Email = %1 GROUP BY Id"
+	FindGrouped(ctx context.Context, email string) ([]*User, error)
+	// +shelf:query="SELECT COUNT(u) FROM User u WHERE u.Email = %1"
+	CountByMail(ctx context.Context, email string) (int64, error)
+	// +shelf:query=Name="User.findByEmail"
+	FindNamed(ctx context.Context, email string) ([]*User, error)
+	// +shelf:query=Name="User.findAllNative"
+	// +shelf:including-deleted
+	FindAllNative(ctx context.Context) ([]*User, error)
+	// +shelf:query="FROM User"
+	// +shelf:including-deleted
+	FindEveryone(ctx context.Context) ([]*User, error)
+}`)
+
+	assertContains(t, repositories,
+		`"SELECT u.id, u.email, u.deleted FROM users u WHERE u.deleted = FALSE ORDER BY u.email"`,
+		`"SELECT id, email, deleted FROM users WHERE (email = $1) AND users.deleted = FALSE GROUP BY id"`,
+		`"SELECT COUNT(u.id) FROM users u WHERE (u.email = $1) AND u.deleted = FALSE"`,
+		`"SELECT id, email, deleted FROM users u WHERE (u.email = $1 OR u.email IS NULL) AND u.deleted = FALSE"`,
+		`"SELECT id, email, deleted FROM users"`,
+	)
+}
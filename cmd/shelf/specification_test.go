@@ -0,0 +1,122 @@
+package main
+
+import (
+	"testing"
+)
+
+// specificationSource is the package of the specification tests, to which the repositories are appended.
+const specificationSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+	"time"
+
+	"github.com/procyon-projects/shelf"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id        int
+	Email     string
+	Active    bool
+	CreatedAt time.Time
+}
+
+var _ shelf.Specification
+`
+
+func TestValidate_Specifications(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Method string
+		Errors []string
+	}{
+		{
+			Name:   "specification with conditions",
+			Method: "FindByEmail(ctx context.Context, spec shelf.Specification, email string) []*User",
+			Errors: []string{
+				"the method 'FindByEmail' can only take in a shelf.Specification to find or count without any condition",
+			},
+		},
+		{
+			Name:   "specification pointer",
+			Method: "Count(ctx context.Context, spec *shelf.Specification) (int64, error)",
+			Errors: []string{
+				"the reserved method 'Count' must be in the form of 'Count(context.Context) (int64, error)' or 'Count(context.Context, shelf.Specification) (int64, error)'",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			source := specificationSource + `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	` + testCase.Method + `
+}`
+
+			assertErrors(t, validate(t, "fixture", source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_Specifications(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Count(ctx context.Context, spec shelf.Specification) (int64, error)
+	FindAll(ctx context.Context, spec shelf.Specification, pageable shelf.Pageable) ([]*User, shelf.Page, error)
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				`where, whereArgs := shelf.Where(spec)`,
+				`query := "SELECT COUNT(*) FROM users" + where`,
+				`query := "SELECT id, email, active, created_at FROM users" + where`,
+				`countQuery := "SELECT COUNT(*) FROM users" + where`,
+				`query, args := shelf.Dollar.Bind(query, whereArgs...)`,
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`query, args := shelf.Question.Bind(query, whereArgs...)`,
+				`countQuery, countArgs := shelf.Question.Bind(countQuery, whereArgs...)`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", specificationSource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
+
+func TestGenerate_Metamodels(t *testing.T) {
+	repositories := generate(t, "fixture", specificationSource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Count(ctx context.Context, spec shelf.Specification) (int64, error)
+}`)
+
+	assertContains(t, repositories,
+		`Id:        intAttribute{shelf.Attribute{Column: "id"}},`,
+		`Email:     stringAttribute{shelf.Attribute{Column: "email"}},`,
+		`Active:    boolAttribute{shelf.Attribute{Column: "active"}},`,
+		`CreatedAt: timeTimeAttribute{shelf.Attribute{Column: "created_at"}},`,
+		`func (attribute stringAttribute) Containing(value string) shelf.Predicate {`,
+		`func (attribute boolAttribute) IsTrue() shelf.Predicate {`,
+		`func (attribute timeTimeAttribute) Between(from, to time.Time) shelf.Predicate {`,
+	)
+}
@@ -0,0 +1,2040 @@
+/*
+Copyright © 2021 Shelf Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+const repositoryFileTemplate = `// Code generated by shelf. DO NOT EDIT.
+
+package {{ .PackageName }}
+
+import (
+{{- range $import := .Imports }}
+	{{ $import.Name }} "{{ $import.Path }}"
+{{- end }}
+)
+{{ range $sortProperties := .SortProperties }}
+// {{ $sortProperties.Name }} maps the properties of {{ $sortProperties.Entity }} to the columns they can be sorted by.
+var {{ $sortProperties.Name }} = map[string]string{
+{{- range $property := $sortProperties.Properties }}
+	"{{ $property.Field }}": "{{ $property.Name }}",
+{{- end }}
+}
+{{ end }}
+{{ range $attributeType := .AttributeTypes }}
+{{- $value := "value" }}
+{{- $from := "from" }}
+{{- $to := "to" }}
+{{- $values := "values" }}
+{{- with $attributeType.Converter }}
+{{- $value = printf "%s(value)" . }}
+{{- $from = printf "%s(from)" . }}
+{{- $to = printf "%s(to)" . }}
+{{- $values = printf "%sSlice(values)" . }}
+{{- end }}
+// {{ $attributeType.Name }} is an attribute of type {{ $attributeType.ValueType }} in the entity metamodels
+{{- if $attributeType.Converter }}, whose values are converted by {{ $attributeType.Converter }}{{ end }}.
+type {{ $attributeType.Name }} struct {
+	shelf.Attribute
+}
+
+func (attribute {{ $attributeType.Name }}) Equal(value {{ $attributeType.ValueType }}) shelf.Predicate {
+	return attribute.Compare("=", {{ $value }})
+}
+
+func (attribute {{ $attributeType.Name }}) NotEqual(value {{ $attributeType.ValueType }}) shelf.Predicate {
+	return attribute.Compare("<>", {{ $value }})
+}
+{{- if $attributeType.IsOrdered }}
+
+func (attribute {{ $attributeType.Name }}) LessThan(value {{ $attributeType.ValueType }}) shelf.Predicate {
+	return attribute.Compare("<", {{ $value }})
+}
+
+func (attribute {{ $attributeType.Name }}) LessThanEqual(value {{ $attributeType.ValueType }}) shelf.Predicate {
+	return attribute.Compare("<=", {{ $value }})
+}
+
+func (attribute {{ $attributeType.Name }}) GreaterThan(value {{ $attributeType.ValueType }}) shelf.Predicate {
+	return attribute.Compare(">", {{ $value }})
+}
+
+func (attribute {{ $attributeType.Name }}) GreaterThanEqual(value {{ $attributeType.ValueType }}) shelf.Predicate {
+	return attribute.Compare(">=", {{ $value }})
+}
+
+func (attribute {{ $attributeType.Name }}) Between(from, to {{ $attributeType.ValueType }}) shelf.Predicate {
+	return attribute.Attribute.Between({{ $from }}, {{ $to }})
+}
+{{- end }}
+
+func (attribute {{ $attributeType.Name }}) In(values ...{{ $attributeType.ValueType }}) shelf.Predicate {
+	return attribute.Attribute.In({{ $values }})
+}
+
+func (attribute {{ $attributeType.Name }}) NotIn(values ...{{ $attributeType.ValueType }}) shelf.Predicate {
+	return attribute.Attribute.NotIn({{ $values }})
+}
+{{- if $attributeType.IsString }}
+
+func (attribute {{ $attributeType.Name }}) StartingWith(value string) shelf.Predicate {
+	return attribute.Like(value + "%")
+}
+
+func (attribute {{ $attributeType.Name }}) EndingWith(value string) shelf.Predicate {
+	return attribute.Like("%" + value)
+}
+
+func (attribute {{ $attributeType.Name }}) Containing(value string) shelf.Predicate {
+	return attribute.Like("%" + value + "%")
+}
+{{- end }}
+{{- if $attributeType.IsBool }}
+
+func (attribute {{ $attributeType.Name }}) IsTrue() shelf.Predicate {
+	return attribute.Compare("=", true)
+}
+
+func (attribute {{ $attributeType.Name }}) IsFalse() shelf.Predicate {
+	return attribute.Compare("=", false)
+}
+{{- end }}
+{{ end }}
+{{ range $metamodel := .Metamodels }}
+// {{ $metamodel.Name }} is the metamodel of {{ $metamodel.Entity }}, whose attributes build the specifications.
+var {{ $metamodel.Name }} = struct {
+{{- range $attribute := $metamodel.Attributes }}
+	{{ $attribute.Field }} {{ $attribute.Type }}
+{{- end }}
+}{
+{{- range $attribute := $metamodel.Attributes }}
+	{{ $attribute.Field }}: {{ $attribute.Type }}{shelf.Attribute{Column: "{{ $attribute.Column }}"}},
+{{- end }}
+}
+{{ end }}
+{{ range $enum := .Enums }}
+// {{ $enum.Constants }} contains the constants of {{ $enum.Name }} in the order they are declared.
+var {{ $enum.Constants }} = []{{ $enum.Type }}{
+{{- range $value := $enum.Values }}
+	{{ $value.Constant }},
+{{- end }}
+}
+{{ if $enum.String }}
+// {{ $enum.Names }} contains the names which the constants of {{ $enum.Name }} are stored by, in the order of {{ $enum.Constants }}.
+var {{ $enum.Names }} = []string{
+{{- range $value := $enum.Values }}
+	{{ $value.Name }},
+{{- end }}
+}
+
+// {{ $enum.String }} stores {{ $enum.Name }} by the names of its constants.
+type {{ $enum.String }} {{ $enum.Type }}
+
+func (value {{ $enum.String }}) Value() (driver.Value, error) {
+	for index, constant := range {{ $enum.Constants }} {
+		if constant == {{ $enum.Type }}(value) {
+			return {{ $enum.Names }}[index], nil
+		}
+	}
+
+	return nil, &shelf.UnknownEnumValueError{Enum: "{{ $enum.Name }}", Value: {{ $enum.Type }}(value)}
+}
+
+func (value *{{ $enum.String }}) Scan(src interface{}) error {
+	name, ok := shelf.EnumName(src)
+
+	for index := 0; ok && index < len({{ $enum.Names }}); index++ {
+		if {{ $enum.Names }}[index] == name {
+			*value = {{ $enum.String }}({{ $enum.Constants }}[index])
+			return nil
+		}
+	}
+
+	return &shelf.UnknownEnumValueError{Enum: "{{ $enum.Name }}", Value: src}
+}
+
+// {{ $enum.String }}Slice converts the values of {{ $enum.Name }} bound to the IN operators.
+func {{ $enum.String }}Slice(values []{{ $enum.Type }}) []{{ $enum.String }} {
+	converted := make([]{{ $enum.String }}, len(values))
+
+	for index, value := range values {
+		converted[index] = {{ $enum.String }}(value)
+	}
+
+	return converted
+}
+{{ end }}
+{{- if $enum.Ordinal }}
+// {{ $enum.Ordinal }} stores {{ $enum.Name }} by the values of its constants.
+type {{ $enum.Ordinal }} {{ $enum.Type }}
+
+func (value {{ $enum.Ordinal }}) Value() (driver.Value, error) {
+	for _, constant := range {{ $enum.Constants }} {
+		if constant == {{ $enum.Type }}(value) {
+			return int64(value), nil
+		}
+	}
+
+	return nil, &shelf.UnknownEnumValueError{Enum: "{{ $enum.Name }}", Value: {{ $enum.Type }}(value)}
+}
+
+func (value *{{ $enum.Ordinal }}) Scan(src interface{}) error {
+	ordinal, ok := shelf.EnumOrdinal(src)
+
+	for index := 0; ok && index < len({{ $enum.Constants }}); index++ {
+		if int64({{ $enum.Constants }}[index]) == ordinal {
+			*value = {{ $enum.Ordinal }}({{ $enum.Constants }}[index])
+			return nil
+		}
+	}
+
+	return &shelf.UnknownEnumValueError{Enum: "{{ $enum.Name }}", Value: src}
+}
+
+// {{ $enum.Ordinal }}Slice converts the values of {{ $enum.Name }} bound to the IN operators.
+func {{ $enum.Ordinal }}Slice(values []{{ $enum.Type }}) []{{ $enum.Ordinal }} {
+	converted := make([]{{ $enum.Ordinal }}, len(values))
+
+	for index, value := range values {
+		converted[index] = {{ $enum.Ordinal }}(value)
+	}
+
+	return converted
+}
+{{ end }}
+{{- end }}
+{{ range $scanner := .Scanners }}
+// {{ $scanner.Columns }} contains the columns of {{ $scanner.Entity }} in the order they are scanned by {{ $scanner.Name }}.
+const {{ $scanner.Columns }} = "{{ $scanner.ColumnNames }}"
+
+// {{ $scanner.Pointers }} returns the pointers to the fields of the entity which {{ $scanner.Columns }} are scanned into
+{{- if $scanner.References }}, except for the join columns{{ end }}.
+func {{ $scanner.Pointers }}(entity *{{ $scanner.Type }}) []interface{} {
+	return []interface{}{
+	{{- range $pointer := $scanner.FieldPointers }}
+		{{ $pointer }},
+	{{- end }}
+	}
+}
+{{ range $reference := $scanner.References }}
+// {{ $reference.Name }} returns the id of the {{ $reference.Field }} of the entity, which is saved into its join column.
+func {{ $reference.Name }}(entity *{{ $scanner.Type }}) interface{} {
+{{- if $reference.IsPointer }}
+	if entity.{{ $reference.Field }} == nil {
+		return nil
+	}
+
+{{ end }}
+	return entity.{{ $reference.Field }}.{{ $reference.IdField }}
+}
+{{ end }}
+{{- if $scanner.Derive }}
+// {{ $scanner.Derive }} sets the id fields of the entity mapped by its associations to the ids of the associated entities.
+func {{ $scanner.Derive }}(entity *{{ $scanner.Type }}) {
+{{- range $reference := $scanner.MapsIds }}
+{{- if $reference.IsPointer }}
+	if entity.{{ $reference.Field }} != nil {
+		entity.{{ $reference.MapsId }} = entity.{{ $reference.Field }}.{{ $reference.IdField }}
+	}
+{{- else }}
+	entity.{{ $reference.MapsId }} = entity.{{ $reference.Field }}.{{ $reference.IdField }}
+{{- end }}
+{{- end }}
+}
+{{ end }}
+// {{ $scanner.Name }} scans the current row, whose columns are {{ $scanner.Columns }}, into the entity.
+{{- if or $scanner.References $scanner.MapsIds }}
+// The join columns are scanned into the references to the associated entities, which only hold their ids.
+{{- end }}
+func {{ $scanner.Name }}(scanner shelf.RowScanner, entity *{{ $scanner.Type }}) error {
+{{- if or $scanner.References $scanner.MapsIds }}
+{{- range $reference := $scanner.References }}
+	var {{ $reference.Variable }} *{{ $reference.IdType }}
+{{- end }}
+{{- if $scanner.References }}
+	err := scanner.Scan(append({{ $scanner.Pointers }}(entity){{ range $scanner.References }}, &{{ .Variable }}{{ end }})...)
+{{- else }}
+	err := scanner.Scan({{ $scanner.Pointers }}(entity)...)
+{{- end }}
+
+	if err != nil {
+		return err
+	}
+{{ range $reference := $scanner.References }}
+	if {{ $reference.Variable }} != nil {
+		entity.{{ $reference.Field }} = {{ if $reference.IsPointer }}&{{ end }}{{ $reference.Target }}{ {{- $reference.IdField }}: *{{ $reference.Variable }}}
+	}
+{{ end }}
+{{- range $reference := $scanner.MapsIds }}
+	// the join column is the column of the id field
+	entity.{{ $reference.Field }} = {{ if $reference.IsPointer }}&{{ end }}{{ $reference.Target }}{ {{- $reference.IdField }}: entity.{{ $reference.MapsId }}}
+{{ end }}
+	return nil
+{{- else }}
+	return scanner.Scan({{ $scanner.Pointers }}(entity)...)
+{{- end }}
+}
+{{ end }}
+{{ range $loader := .Loaders }}
+// {{ $loader.Name }} loads the {{ $loader.Field }} of the {{ $loader.Entity }} entities in batches.
+func {{ $loader.Name }}(ctx context.Context, executor shelf.Executor, entities ...*{{ $loader.Type }}) error {
+	entitiesByKey := make(map[{{ $loader.KeyType }}][]*{{ $loader.Type }})
+	args := make([]interface{}, 0, len(entities))
+
+	for _, entity := range entities {
+{{- if $loader.Reference }}
+		if entity == nil{{ if $loader.IsPointer }} || entity.{{ $loader.Field }} == nil{{ end }} {
+			continue
+		}
+
+		key := entity.{{ $loader.Field }}.{{ $loader.Key }}
+{{- else }}
+		if entity == nil {
+			continue
+		}
+
+		key := entity.{{ $loader.Key }}
+{{- if $loader.IsCollection }}
+		entity.{{ $loader.Field }} = make([]{{ if $loader.IsPointer }}*{{ end }}{{ $loader.Target }}, 0)
+{{- end }}
+{{- end }}
+
+		if _, ok := entitiesByKey[key]; !ok {
+			args = append(args, key)
+		}
+
+		entitiesByKey[key] = append(entitiesByKey[key], entity)
+	}
+
+	return shelf.Batch(len(args), {{ $loader.BatchSize }}, func(start, end int) error {
+		rows, err := executor.QueryContext(ctx, "{{ $loader.Query }}"+{{ $loader.PlaceholderFormat }}.Placeholders(1, end-start)+"{{ $loader.Suffix }}", args[start:end]...)
+
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			target := &{{ $loader.Target }}{}
+{{- if $loader.Reference }}
+			err = {{ $loader.Scanner }}(rows, target)
+{{- else }}
+			var key {{ $loader.KeyType }}
+			err = {{ $loader.Scanner }}(shelf.ScanPrefixed(rows, &key), target)
+{{- end }}
+
+			if err != nil {
+				return err
+			}
+
+			for _, entity := range entitiesByKey[{{ if $loader.Reference }}target.{{ $loader.Key }}{{ else }}key{{ end }}] {
+{{- if $loader.IsCollection }}
+				entity.{{ $loader.Field }} = append(entity.{{ $loader.Field }}, {{ if not $loader.IsPointer }}*{{ end }}target)
+{{- else }}
+				entity.{{ $loader.Field }} = {{ if not $loader.IsPointer }}*{{ end }}target
+{{- end }}
+			}
+		}
+
+		return rows.Err()
+	})
+}
+{{ end }}
+{{ range $fetcher := .Fetchers }}
+// {{ $fetcher.Name }} loads the associations of the {{ $fetcher.Entity }} entities given by the fetch plan, or the eager
+// ones if the plan is nil, and registers the other ones in the session of the context.
+func {{ $fetcher.Name }}(ctx context.Context, db *sql.DB, plan shelf.FetchPlan, entities ...*{{ $fetcher.Type }}) error {
+	executor := shelf.ExecutorOf(ctx, db)
+
+	if plan == nil {
+		plan = shelf.NewFetchPlan({{ range $index, $field := $fetcher.Eager }}{{ if $index }}, {{ end }}"{{ $field }}"{{ end }})
+	}
+
+	err := plan.Validate("{{ $fetcher.Entity }}"{{ range $association := $fetcher.Associations }}, "{{ $association.Field }}"{{ end }})
+
+	if err != nil {
+		return err
+	}
+{{ range $association := $fetcher.Associations }}
+	if nested, ok := plan["{{ $association.Field }}"]; ok {
+{{- if not $association.Fetcher }}
+		// the associated entities have no association
+		err = nested.Validate("{{ $association.TargetEntity }}")
+
+		if err != nil {
+			return err
+		}
+{{ end }}
+		err = {{ $association.Loader }}(ctx, executor, entities...)
+
+		if err != nil {
+			return err
+		}
+{{- if $association.Fetcher }}
+
+		if len(nested) != 0 {
+			targets := make([]*{{ $association.Target }}, 0)
+
+			for _, entity := range entities {
+{{- if $association.IsCollection }}
+				if entity == nil {
+					continue
+				}
+
+				for index := range entity.{{ $association.Field }} {
+					targets = append(targets, {{ if not $association.IsPointer }}&{{ end }}entity.{{ $association.Field }}[index])
+				}
+{{- else if $association.IsPointer }}
+				if entity != nil && entity.{{ $association.Field }} != nil {
+					targets = append(targets, entity.{{ $association.Field }})
+				}
+{{- else }}
+				if entity != nil {
+					targets = append(targets, &entity.{{ $association.Field }})
+				}
+{{- end }}
+			}
+
+			err = {{ $association.Fetcher }}(ctx, db, nested, targets...)
+
+			if err != nil {
+				return err
+			}
+		}
+{{- end }}
+	}
+{{ end }}
+	if session := shelf.SessionOf(ctx); session != nil {
+		targets := make([]interface{}, len(entities))
+
+		for index, entity := range entities {
+			targets[index] = entity
+		}
+{{ range $index, $association := $fetcher.Associations }}{{ if $index }}
+{{ end }}
+		if _, ok := plan["{{ $association.Field }}"]; !ok {
+			session.Register(targets, "{{ $association.Field }}", func(ctx context.Context, targets []interface{}) error {
+				entities := make([]*{{ $fetcher.Type }}, len(targets))
+
+				for index, target := range targets {
+					entities[index] = target.(*{{ $fetcher.Type }})
+				}
+
+				// the executor is the one of the context of the access, as the transaction of the query may be over
+				return {{ $association.Loader }}(ctx, shelf.ExecutorOf(ctx, db), entities...)
+			})
+		}
+{{- end }}
+	}
+
+	return nil
+}
+{{ end }}
+{{ range $saver := .Savers }}
+// {{ $saver.Statement }} saves the {{ $saver.Entity }} entity without its associations.
+func {{ $saver.Statement }}(ctx context.Context, executor shelf.Executor, entity *{{ $saver.Type }}) error {
+{{ $saver.Body }}
+}
+{{ if $saver.Before }}
+// {{ $saver.BeforeFunction }} saves the targets of the associations of the {{ $saver.Entity }} entity, which are referred by its join columns.
+func {{ $saver.BeforeFunction }}(ctx context.Context, executor shelf.Executor, cascade *shelf.Cascade, entity *{{ $saver.Type }}) error {
+{{- range $index, $step := $saver.Before }}
+{{- if $index }}
+{{ end }}
+{{- if $step.IsPointer }}
+	if target := entity.{{ $step.Field }}; target != nil{{ with $step.Condition }} && {{ . }}{{ end }} {
+{{- else }}
+	if target := &entity.{{ $step.Field }}; {{ with $step.Condition }}{{ . }}{{ else }}target != nil{{ end }} {
+{{- end }}
+		err := {{ $step.Cascade }}(ctx, executor, cascade, target)
+
+		if err != nil {
+			return err
+		}
+	}
+{{- end }}
+
+	return nil
+}
+{{ end }}
+{{- if $saver.After }}
+// {{ $saver.AfterFunction }} saves the targets of the associations of the {{ $saver.Entity }} entity, whose join columns or join tables refer to it.
+func {{ $saver.AfterFunction }}(ctx context.Context, executor shelf.Executor, cascade *shelf.Cascade, entity *{{ $saver.Type }}) error {
+	var err error
+{{- range $step := $saver.After }}
+{{ if $step.Unlink }}
+	if entity.{{ $step.Field }} != nil {
+		_, err = executor.ExecContext(ctx, "{{ $step.Unlink }}", entity.{{ $step.Key }})
+
+		if err != nil {
+			return err
+		}
+	}
+{{ end }}
+{{- if $step.IsCollection }}
+{{- if $step.IsPointer }}
+	for _, target := range entity.{{ $step.Field }} {
+{{- else }}
+	for index := range entity.{{ $step.Field }} {
+		target := &entity.{{ $step.Field }}[index]
+{{- end }}
+{{- else if $step.IsPointer }}
+	if target := entity.{{ $step.Field }}; target != nil {
+{{- else }}
+	if target := &entity.{{ $step.Field }}; target != nil {
+{{- end }}
+{{- with $step.BackReference }}
+		target.{{ . }} = {{ if not $step.IsBackReferencePointer }}*{{ end }}entity
+{{- end }}
+{{- if $step.Cascade }}
+{{- if or $step.BackReference (and $step.IsCollection (not $step.IsPointer)) }}
+{{ end }}
+{{- if $step.Condition }}
+		if {{ $step.Condition }} {
+			err = {{ $step.Cascade }}(ctx, executor, cascade, target)
+
+			if err != nil {
+				return err
+			}
+		}
+{{- else }}
+		err = {{ $step.Cascade }}(ctx, executor, cascade, target)
+
+		if err != nil {
+			return err
+		}
+{{- end }}
+{{- end }}
+{{- if $step.Link }}
+{{ if or $step.Cascade $step.BackReference (and $step.IsCollection (not $step.IsPointer)) }}
+{{ end }}		_, err = executor.ExecContext(ctx, "{{ $step.Link }}", entity.{{ $step.Key }}, target.{{ $step.TargetId }})
+
+		if err != nil {
+			return err
+		}
+{{- end }}
+	}
+{{- if $step.Orphans }}
+
+	if entity.{{ $step.Field }} != nil {
+		args := []interface{}{entity.{{ $step.Key }}}
+
+		for _, target := range entity.{{ $step.Field }} {
+			args = append(args, target.{{ $step.TargetId }})
+		}
+
+		query := "{{ $step.Orphans }}"
+
+		if len(args) > 1 {
+			query += "{{ $step.OrphansIn }}" + {{ $step.PlaceholderFormat }}.Placeholders(2, len(args)-1) + ")"
+		}
+
+		_, err = executor.ExecContext(ctx, query, args...)
+
+		if err != nil {
+			return err
+		}
+	}
+{{- end }}
+{{- end }}
+
+	return nil
+}
+{{ end }}
+// {{ $saver.Name }} saves the {{ $saver.Entity }} entity and the targets of its associations the save is cascaded to.
+func {{ $saver.Name }}(ctx context.Context, executor shelf.Executor, cascade *shelf.Cascade, entity *{{ $saver.Type }}) error {
+	if entity == nil || !cascade.Visit(entity) {
+		return nil
+	}
+{{- if $saver.Before }}
+
+	err := {{ $saver.BeforeFunction }}(ctx, executor, cascade, entity)
+
+	if err != nil {
+		return err
+	}
+{{- end }}
+{{- if $saver.After }}
+
+	err {{ if $saver.Before }}={{ else }}:={{ end }} {{ $saver.Statement }}(ctx, executor, entity)
+
+	if err != nil {
+		return err
+	}
+
+	return {{ $saver.AfterFunction }}(ctx, executor, cascade, entity)
+{{- else }}
+
+	return {{ $saver.Statement }}(ctx, executor, entity)
+{{- end }}
+}
+{{ end }}
+{{ range $deleter := .Deleters }}
+// {{ $deleter.Entities }} deletes the {{ $deleter.Entity }} entities of a chunk and the targets of their associations the delete is
+// cascaded to. The entities are deleted by a single statement, so their number is limited by the batch size.
+func {{ $deleter.Entities }}(ctx context.Context, executor shelf.Executor, cascade *shelf.Cascade, entities []*{{ $deleter.Type }}) error {
+	if len(entities) == 0 {
+		return nil
+	}
+
+	var err error
+{{- if gt (len $deleter.IdFields) 1 }}
+	ids := make([]interface{}, 0, len(entities)*{{ len $deleter.IdFields }})
+
+	for _, entity := range entities {
+		ids = append(ids{{ range $idField := $deleter.IdFields }}, entity.{{ $idField }}{{ end }})
+	}
+{{- else }}
+	ids := make([]interface{}, len(entities))
+
+	for index, entity := range entities {
+		ids[index] = entity.{{ $deleter.IdField }}
+	}
+{{- end }}
+{{- $unloaded := false }}
+{{- $keys := false }}
+{{- range $step := $deleter.Before }}
+{{ if $step.Loader }}
+{{- if $unloaded }}
+	unloaded = unloaded[:0]
+{{- else }}
+	unloaded := make([]*{{ $deleter.Type }}, 0, len(entities))
+{{- end }}
+{{- $unloaded = true }}
+
+	for _, entity := range entities {
+		if entity.{{ $step.Field }} == nil {
+			unloaded = append(unloaded, entity)
+		}
+	}
+
+	err = {{ $step.Loader }}(ctx, executor, unloaded...)
+
+	if err != nil {
+		return err
+	}
+{{ end }}
+{{- if $step.Unlink }}
+{{- if eq $step.Key $deleter.IdField }}
+	_, err = executor.ExecContext(ctx, "{{ $step.Unlink }}"+{{ $deleter.Ids }}+")", ids...)
+{{- else }}
+{{- if $keys }}
+	keys = keys[:0]
+{{- else }}
+	keys := make([]interface{}, 0, len(entities))
+{{- end }}
+{{- $keys = true }}
+
+	for _, entity := range entities {
+		keys = append(keys, entity.{{ $step.Key }})
+	}
+
+	_, err = executor.ExecContext(ctx, "{{ $step.Unlink }}"+{{ $step.PlaceholderFormat }}.Placeholders(1, len(keys))+")", keys...)
+{{- end }}
+
+	if err != nil {
+		return err
+	}
+{{ end }}
+{{- if $step.Cascade }}
+	for _, entity := range entities {
+{{- if $step.IsCollection }}
+{{- if $step.IsPointer }}
+		for _, target := range entity.{{ $step.Field }} {
+{{- else }}
+		for index := range entity.{{ $step.Field }} {
+			target := &entity.{{ $step.Field }}[index]
+{{- end }}
+{{- else if $step.IsPointer }}
+		if target := entity.{{ $step.Field }}; target != nil {
+{{- else }}
+		if target := &entity.{{ $step.Field }}; target != nil {
+{{- end }}
+			err = {{ $step.Cascade }}(ctx, executor, cascade, target)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+{{ end }}
+{{- end }}
+
+{{- if $deleter.VersionField }}
+
+	// the entities are deleted one at a time, so that the one changed by another transaction is found
+	for index, entity := range entities {
+{{- if gt (len $deleter.IdFields) 1 }}
+		// the ids of the entity are followed by its version
+		args := append(ids[index*{{ len $deleter.IdFields }}:(index+1)*{{ len $deleter.IdFields }}:(index+1)*{{ len $deleter.IdFields }}], entity.{{ $deleter.VersionField }})
+		err = shelf.CheckOptimisticLock(executor.ExecContext(ctx, "{{ $deleter.Statement }}", args...))
+{{- else }}
+		err = shelf.CheckOptimisticLock(executor.ExecContext(ctx, "{{ $deleter.Statement }}", ids[index], entity.{{ $deleter.VersionField }}))
+{{- end }}
+
+		if err != nil {
+			return err
+		}
+	}
+{{- else }}
+
+	_, err = executor.ExecContext(ctx, "{{ $deleter.Statement }}"+{{ $deleter.Ids }}+"{{ $deleter.StatementSuffix }}", ids...)
+
+	if err != nil {
+		return err
+	}
+{{- end }}
+{{- range $step := $deleter.After }}
+
+	for _, entity := range entities {
+		if target := {{ if not $step.IsPointer }}&{{ end }}entity.{{ $step.Field }}; target != nil {
+			err = {{ $step.Cascade }}(ctx, executor, cascade, target)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+{{- end }}
+
+	return nil
+}
+
+// {{ $deleter.Name }} deletes the {{ $deleter.Entity }} entity and the targets of its associations the delete is cascaded to.
+func {{ $deleter.Name }}(ctx context.Context, executor shelf.Executor, cascade *shelf.Cascade, entity *{{ $deleter.Type }}) error {
+	if entity == nil || !cascade.Visit(entity) {
+		return nil
+	}
+
+	return {{ $deleter.Entities }}(ctx, executor, cascade, []*{{ $deleter.Type }}{entity})
+}
+{{ end }}
+{{ range $generator := .IdGenerators }}
+{{- if $generator.Sequence }}
+// {{ $generator.Sequence }} allocates the ids of {{ $generator.Entity }} from {{ $generator.Source }}, which is incremented by {{ $generator.AllocationSize }}.
+var {{ $generator.Sequence }} = shelf.NewSequence({{ $generator.AllocationSize }}, func(ctx context.Context, executor shelf.Executor) (int64, error) {
+{{- if $generator.LastInsertId }}
+	result, err := executor.ExecContext(ctx, "{{ $generator.Query }}")
+
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+
+	// the sequence has no row in the table
+	if err == nil && id == 0 {
+		err = sql.ErrNoRows
+	}
+
+	return id - {{ $generator.AllocationSize }}, err
+{{- else }}
+	var id int64
+	err := executor.QueryRowContext(ctx, "{{ $generator.Query }}").Scan(&id)
+	return id, err
+{{- end }}
+})
+{{ end }}
+// {{ $generator.Name }} assigns the id of {{ $generator.Entity }} before it is inserted.
+func {{ $generator.Name }}(ctx context.Context, executor shelf.Executor, entity *{{ $generator.Type }}) error {
+{{- if $generator.Sequence }}
+	id, err := {{ $generator.Sequence }}.Next(ctx, executor)
+
+	if err != nil {
+		return err
+	}
+
+	entity.{{ $generator.Field }} = {{ $generator.IdType }}(id)
+	return nil
+{{- else }}
+	return shelf.GenerateId(ctx, "{{ $generator.Generator }}", "{{ $generator.Entity }}", &entity.{{ $generator.Field }})
+{{- end }}
+}
+{{ end }}
+{{ range $auditor := .Auditors }}
+// {{ $auditor.Creation }} fills the audit fields of {{ $auditor.Entity }} before it is inserted.
+func {{ $auditor.Creation }}(ctx context.Context, entity *{{ $auditor.Type }}) {
+{{- if or $auditor.CreatedDate $auditor.LastModifiedDate }}
+	now := shelf.Now()
+{{- with $auditor.CreatedDate }}
+	entity.{{ .Field }} = now
+{{- end }}
+{{- with $auditor.LastModifiedDate }}
+	entity.{{ .Field }} = now
+{{- end }}
+{{- end }}
+{{- with $auditor.CreatedBy }}
+{{- if or $auditor.CreatedDate $auditor.LastModifiedDate }}
+{{ end }}
+	if auditor, ok := shelf.CurrentAuditor(ctx).({{ .Type }}); ok {
+		entity.{{ .Field }} = auditor
+	}
+{{- end }}
+{{- with $auditor.LastModifiedBy }}
+{{- if or $auditor.CreatedDate $auditor.LastModifiedDate $auditor.CreatedBy }}
+{{ end }}
+	if auditor, ok := shelf.CurrentAuditor(ctx).({{ .Type }}); ok {
+		entity.{{ .Field }} = auditor
+	}
+{{- end }}
+}
+{{ if $auditor.Modification }}
+// {{ $auditor.Modification }} fills the last-modified audit fields of {{ $auditor.Entity }} before it is updated.
+func {{ $auditor.Modification }}(ctx context.Context, entity *{{ $auditor.Type }}) {
+{{- with $auditor.LastModifiedDate }}
+	entity.{{ .Field }} = shelf.Now()
+{{- end }}
+{{- with $auditor.LastModifiedBy }}
+{{- if $auditor.LastModifiedDate }}
+{{ end }}
+	if auditor, ok := shelf.CurrentAuditor(ctx).({{ .Type }}); ok {
+		entity.{{ .Field }} = auditor
+	}
+{{- end }}
+}
+{{ end }}
+{{ end }}
+{{ range $projection := .Projections }}
+// {{ $projection.Type }} is the generated implementation of the {{ $projection.Name }} projection.
+type {{ $projection.Type }} struct {
+{{- range $field := $projection.Fields }}
+	{{ $field.Field }} {{ $field.FieldType }}
+{{- end }}
+}
+{{ range $field := $projection.Fields }}
+func (projection *{{ $projection.Type }}) {{ $field.Name }}() {{ $field.Type }} {
+	return {{ if $field.IsNested }}&{{ end }}projection.{{ $field.Field }}
+}
+{{ end }}
+{{ end }}
+{{- range $repository := .Repositories }}
+type {{ $repository.Type }} struct {
+	db *sql.DB
+	// fetch is the fetch plan of the queries, which is given by the fluent methods.
+	fetch shelf.FetchPlan
+}
+
+// {{ $repository.Constructor }} returns the generated implementation of {{ $repository.Name }}.
+func {{ $repository.Constructor }}(db *sql.DB) {{ $repository.Name }} {
+	return &{{ $repository.Type }}{
+		db: db,
+	}
+}
+
+// executor returns the transaction of the context, or the database if the context has no transaction.
+func ({{ $repository.ReceiverName }} *{{ $repository.Type }}) executor(ctx context.Context) shelf.Executor {
+	return shelf.ExecutorOf(ctx, {{ $repository.ReceiverName }}.db)
+}
+
+{{ range $method := $repository.Methods -}}
+func ({{ $repository.ReceiverName }} *{{ $repository.Type }}) {{ $method.Name }}(
+	{{- range $index, $parameter := $method.Parameters -}}
+		{{- if $index }}, {{ end }}{{ $parameter.Name }} {{ $parameter.Type }}
+	{{- end -}}
+) {{ if gt (len $method.ReturnValues) 1 }}({{ end }}
+	{{- range $index, $returnValue := $method.ReturnValues -}}
+		{{- if $index }}, {{ end }}{{ $returnValue }}
+	{{- end -}}
+{{ if gt (len $method.ReturnValues) 1 }}){{ end }} {
+{{ $method.Body }}
+}
+
+{{ end }}
+{{- end }}
+`
+
+const metamodelFileTemplate = `// Code generated by shelf. DO NOT EDIT.
+
+package {{ .PackageName }}
+{{ if .Imports }}
+import (
+{{- range $import := .Imports }}
+	{{ $import.Name }} "{{ $import.Path }}"
+{{- end }}
+)
+{{ end }}
+{{- range $entity := .Entities }}
+const (
+	// {{ $entity.StructName }}Entity is the name of the entity {{ $entity.StructName }}.
+	{{ $entity.StructName }}Entity = "{{ $entity.Entity }}"
+	// {{ $entity.StructName }}Table is the table of the entity {{ $entity.StructName }}.
+	{{ $entity.StructName }}Table = "{{ $entity.Table }}"
+)
+
+// {{ $entity.Name }} describes the entity {{ $entity.StructName }}, so that its table, columns and fields
+// can be referred without string literals.
+var {{ $entity.Name }} = {{ $entity.Type }}{
+	Entity:   {{ $entity.StructName }}Entity,
+	Table:    {{ $entity.StructName }}Table,
+	IdField:  "{{ $entity.IdField }}",
+	IdColumn: "{{ $entity.IdColumn }}",
+	Fields: {{ $entity.Type }}Fields{
+	{{- range $column := $entity.Columns }}
+		{{ $column.Property }}: "{{ $column.Field }}",
+	{{- end }}
+	},
+	Columns: {{ $entity.Type }}Columns{
+	{{- range $column := $entity.Columns }}
+		{{ $column.Property }}: "{{ $column.Name }}",
+	{{- end }}
+	},
+	Associations: {{ $entity.Type }}Associations{
+	{{- range $association := $entity.Associations }}
+		{{ $association.Field }}: shelf.Association{
+			Field:    "{{ $association.Field }}",
+			Kind:     shelf.{{ $association.Kind }},
+			Target:   "{{ $association.Target }}",
+			MappedBy: "{{ $association.MappedBy }}",
+		{{- with $association.JoinColumn }}
+			JoinColumn: &shelf.JoinColumn{
+				Table:            "{{ .Table }}",
+				Name:             "{{ .Name }}",
+				ReferencedTable:  "{{ .ReferencedTable }}",
+				ReferencedColumn: "{{ .ReferencedColumn }}",
+				ForeignKeyName:   "{{ .ForeignKeyName }}",
+				Nullable:         {{ .Nullable }},
+				Unique:           {{ .Unique }},
+			},
+		{{- end }}
+		{{- with $association.JoinTable }}
+			JoinTable: &shelf.JoinTable{
+				Name:               "{{ .Name }}",
+				JoinColumns:        []string{ {{- range $index, $column := .JoinColumns }}{{ if $index }}, {{ end }}"{{ $column }}"{{ end -}} },
+				InverseJoinColumns: []string{ {{- range $index, $column := .InverseJoinColumns }}{{ if $index }}, {{ end }}"{{ $column }}"{{ end -}} },
+			},
+		{{- end }}
+		},
+	{{- end }}
+	},
+	Enums: {{ $entity.Type }}Enums{
+	{{- range $enum := $entity.Enums }}
+		{{ $enum.Property }}: shelf.Enum{
+			Column:   "{{ $enum.Column }}",
+			Ordinal:  {{ $enum.Ordinal }},
+			Names:    []string{ {{- range $index, $name := $enum.Names }}{{ if $index }}, {{ end }}{{ $name }}{{ end -}} },
+			Ordinals: []int64{ {{- range $index, $constant := $enum.Constants }}{{ if $index }}, {{ end }}int64({{ $constant }}){{ end -}} },
+		},
+	{{- end }}
+	},
+}
+
+type {{ $entity.Type }} struct {
+	Entity       string
+	Table        string
+	IdField      string
+	IdColumn     string
+	Fields       {{ $entity.Type }}Fields
+	Columns      {{ $entity.Type }}Columns
+	Associations {{ $entity.Type }}Associations
+	Enums        {{ $entity.Type }}Enums
+}
+
+type {{ $entity.Type }}Fields struct {
+{{- range $column := $entity.Columns }}
+	{{ $column.Property }} string
+{{- end }}
+}
+
+type {{ $entity.Type }}Columns struct {
+{{- range $column := $entity.Columns }}
+	{{ $column.Property }} string
+{{- end }}
+}
+
+type {{ $entity.Type }}Associations struct {
+{{- range $association := $entity.Associations }}
+	{{ $association.Field }} shelf.Association
+{{- end }}
+}
+
+type {{ $entity.Type }}Enums struct {
+{{- range $enum := $entity.Enums }}
+	{{ $enum.Property }} shelf.Enum
+{{- end }}
+}
+{{ range $accessor := $entity.Accessors }}
+// {{ $accessor.Name }} returns the association {{ $accessor.Field }}, which is loaded on first access by the session
+// of the context the entity is queried with unless it is fetched with the entity, see shelf.Session.
+func (entity *{{ $entity.StructName }}) {{ $accessor.Name }}(ctx context.Context) ({{ $accessor.Type }}, error) {
+	err := shelf.LoadLazy(ctx, entity, "{{ $accessor.Field }}")
+	return entity.{{ $accessor.Field }}, err
+}
+{{ end }}
+{{- end }}
+`
+
+const transactionalTemplate = `
+{{- if .ResultTypes }}
+{{- range $index, $type := .ResultTypes }}
+var result{{ $index }} {{ $type }}
+{{- end }}
+err := shelf.NewTxManager({{ .Receiver }}.db).WithTx({{ .Context }}, {{ .Options }}, func({{ .Context }} context.Context) error {
+	var err error
+	{{ range $index, $type := .ResultTypes }}result{{ $index }}, {{ end }}err = {{ .Receiver }}.{{ .Inner }}({{ range $index, $argument := .Arguments }}{{ if $index }}, {{ end }}{{ $argument }}{{ end }})
+	return err
+})
+
+if err != nil {
+	return {{ range .ZeroValues }}{{ . }}, {{ end }}shelf.TranslateError(err)
+}
+
+return {{ range $index, $type := .ResultTypes }}result{{ $index }}, {{ end }}nil
+{{- else }}
+err := shelf.NewTxManager({{ .Receiver }}.db).WithTx({{ .Context }}, {{ .Options }}, func({{ .Context }} context.Context) error {
+	return {{ .Receiver }}.{{ .Inner }}({{ range $index, $argument := .Arguments }}{{ if $index }}, {{ end }}{{ $argument }}{{ end }})
+})
+
+return shelf.TranslateError(err)
+{{- end }}`
+
+const countTemplate = `
+var count {{ index .ReturnValues 0 }}
+err := {{ .Receiver }}.executor({{ .Context }}).QueryRowContext({{ .Context }}, "SELECT COUNT(*) FROM {{ .Table }}{{ template "not-deleted" . }}").Scan(&count)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+{{ .Return "count" }}`
+
+const existsByIdTemplate = `
+var exists bool
+err := {{ .Receiver }}.executor({{ .Context }}).QueryRowContext({{ .Context }}, "SELECT EXISTS(SELECT 1 FROM {{ .Table }} WHERE {{ idPredicate 1 .IdColumns }}{{ template "and-not-deleted" . }})", {{ keyFields (index .Parameters 1) .IdColumns }}).Scan(&exists)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+{{ .Return "exists" }}`
+
+const deleteTemplate = `
+if {{ index .Parameters 1 }} == nil {
+	{{ .Return }}
+}
+{{ if .Cascade }}
+{{- template "cascade" . }}
+{{- else if .Version }}
+err := shelf.CheckOptimisticLock({{ .Executor }}.ExecContext({{ .Context }}, "{{ template "delete-versioned" . }}", {{ index .Parameters 1 }}.{{ .IdColumn.Field }}, {{ index .Parameters 1 }}.{{ .Version.Field }}))
+{{- else }}
+_, err := {{ .Executor }}.ExecContext({{ .Context }}, "{{ template "delete" . }} WHERE {{ idPredicate 1 .IdColumns }}{{ template "and-not-deleted" . }}", {{ fields (index .Parameters 1) .IdColumns }})
+{{- end }}
+
+if err != nil {
+	{{ .ErrorReturn }}
+}`
+
+const deleteByIdTemplate = `
+{{- if .Cascade }}
+err := shelf.NewTxManager({{ .Receiver }}.db).WithTx({{ .Context }}, nil, func({{ .Context }} context.Context) error {
+	executor := {{ .Executor }}
+
+	// the entity is selected with its join columns, as the delete is cascaded to its associations
+	entity := &{{ .Entity }}{}
+	row := executor.QueryRowContext({{ .Context }}, "{{ template "select" . }} WHERE {{ idPredicate 1 .IdColumns }}{{ template "and-not-deleted" . }}", {{ keyFields (index .Parameters 1) .IdColumns }})
+	err := {{ .Scanner }}(row, entity)
+
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return {{ .Cascade.Name }}({{ .Context }}, executor, shelf.NewCascade(), entity)
+})
+{{- else }}
+_, err := {{ .Receiver }}.executor({{ .Context }}).ExecContext({{ .Context }}, "{{ template "delete" . }} WHERE {{ idPredicate 1 .IdColumns }}{{ template "and-not-deleted" . }}", {{ keyFields (index .Parameters 1) .IdColumns }})
+{{- end }}
+
+if err != nil {
+	{{ .ErrorReturn }}
+}`
+
+const deleteAllTemplate = `
+_, err := {{ .Receiver }}.executor({{ .Context }}).ExecContext({{ .Context }}, "{{ template "delete" . }}{{ template "not-deleted" . }}")
+
+if err != nil {
+	{{ .ErrorReturn }}
+}`
+
+const deleteAllEntitiesTemplate = `
+{{- if .Cascade }}
+entities := make([]*{{ .Entity }}, 0, len({{ index .Parameters 1 }}))
+cascade := shelf.NewCascade()
+
+for _, entity := range {{ index .Parameters 1 }} {
+	if entity != nil && cascade.Visit(entity) {
+		entities = append(entities, entity)
+	}
+}
+
+if len(entities) == 0 {
+	{{ .Return }}
+}
+
+tx, err := shelf.BeginTx({{ .Context }}, {{ .Receiver }}.db, nil)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+err = shelf.Batch(len(entities), {{ .BatchSize }}, func(start, end int) error {
+	return {{ .Cascade.Entities }}({{ .Context }}, tx, cascade, entities[start:end])
+})
+{{ template "end-tx" . }}
+{{- else if .Version }}
+entities := make([]*{{ .Entity }}, 0, len({{ index .Parameters 1 }}))
+
+for _, entity := range {{ index .Parameters 1 }} {
+	if entity != nil {
+		entities = append(entities, entity)
+	}
+}
+
+if len(entities) == 0 {
+	{{ .Return }}
+}
+
+tx, err := shelf.BeginTx({{ .Context }}, {{ .Receiver }}.db, nil)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+// the entities are deleted one at a time, so that the one changed by another transaction is found
+err = shelf.Batch(len(entities), {{ .BatchSize }}, func(start, end int) error {
+	deleteStmt, err := tx.PrepareContext({{ .Context }}, "{{ template "delete-versioned" . }}")
+
+	if err != nil {
+		return err
+	}
+
+	defer deleteStmt.Close()
+
+	for _, entity := range entities[start:end] {
+		err = shelf.CheckOptimisticLock(deleteStmt.ExecContext({{ .Context }}, entity.{{ .IdColumn.Field }}, entity.{{ .Version.Field }}))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+})
+{{ template "end-tx" . }}
+{{- else }}
+args := make([]interface{}, 0, len({{ index .Parameters 1 }}))
+
+for _, entity := range {{ index .Parameters 1 }} {
+	if entity != nil {
+		args = append(args, {{ fields "entity" .IdColumns }})
+	}
+}
+
+if len(args) == 0 {
+	{{ .Return }}
+}
+{{ template "delete-batch" . }}
+{{- end }}`
+
+const deleteAllByIdTemplate = `
+if len({{ index .Parameters 1 }}) == 0 {
+	{{ .Return }}
+}
+
+{{ template "key-args" . }}
+{{- if .Cascade }}
+
+tx, err := shelf.BeginTx({{ .Context }}, {{ .Receiver }}.db, nil)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+cascade := shelf.NewCascade()
+
+// the entities of each chunk are selected with their join columns, as the delete is cascaded to their associations
+err = shelf.Batch(len({{ index .Parameters 1 }}), {{ .BatchSize }}, func(start, end int) error {
+	rows, err := tx.QueryContext({{ .Context }}, "{{ template "select" . }} WHERE {{ idIn .IdColumns "end-start" }}{{ template "and-not-deleted" . }}", {{ if .CompositeId }}args[start*{{ len .IdColumns }}:end*{{ len .IdColumns }}]{{ else }}args[start:end]{{ end }}...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	entities := make([]*{{ .Entity }}, 0, end-start)
+
+	for rows.Next() {
+		entity := &{{ .Entity }}{}
+		err = {{ .Scanner }}(rows, entity)
+
+		if err != nil {
+			return err
+		}
+
+		cascade.Visit(entity)
+		entities = append(entities, entity)
+	}
+
+	err = rows.Err()
+
+	if err != nil {
+		return err
+	}
+
+	return {{ .Cascade.Entities }}({{ .Context }}, tx, cascade, entities)
+})
+{{ template "end-tx" . }}
+{{- else }}
+{{ template "delete-batch" . }}
+{{- end }}`
+
+const saveTemplate = `
+if {{ index .Parameters 1 }} == nil {
+	{{ .Return }}
+}
+{{ if .Cascade }}
+{{- template "cascade" . }}
+{{ else if or .IdColumn.Generated .IdGenerator }}
+var err error
+
+if {{ index .Parameters 1 }}.{{ .IdColumn.Field }} == {{ .IdColumn.Zero }} {
+{{- template "audit-creation" . }}
+{{- if .IdGenerator }}
+	err = {{ .IdGenerator }}({{ .Context }}, {{ .Executor }}, {{ index .Parameters 1 }})
+
+	if err == nil {
+		_, err = {{ .Executor }}.ExecContext({{ .Context }}, "INSERT INTO {{ .Table }}({{ columns .Columns }}) VALUES({{ placeholders 1 (len .Columns) }})", {{ fields (index .Parameters 1) .Columns }})
+	}
+
+	// the entity is inserted again with a new id when it is saved
+	if err != nil {
+		{{ index .Parameters 1 }}.{{ .IdColumn.Field }} = {{ .IdColumn.Zero }}
+	}
+{{- else if .Dialect.SupportsReturning }}
+	err = {{ .Executor }}.QueryRowContext({{ .Context }}, "{{ template "insert-returning" . }}", {{ fields (index .Parameters 1) .ValueColumns }}).Scan(&{{ index .Parameters 1 }}.{{ .IdColumn.Field }})
+{{- else }}
+	var result sql.Result
+	result, err = {{ .Executor }}.ExecContext({{ .Context }}, "{{ template "insert" . }}", {{ fields (index .Parameters 1) .ValueColumns }})
+
+	if err == nil {
+		var id int64
+		id, err = result.LastInsertId()
+		{{ index .Parameters 1 }}.{{ .IdColumn.Field }} = {{ .IdColumn.Type }}(id)
+	}
+{{- end }}
+} else {
+{{- template "audit-modification" . }}
+{{- if .Version }}
+	{{ template "update-versioned" . }}
+{{- else }}
+	_, err = {{ .Executor }}.ExecContext({{ .Context }}, "{{ template "update" . }}", {{ fields (index .Parameters 1) .UpdateColumns }}, {{ fields (index .Parameters 1) .IdColumns }})
+{{- end }}
+}
+{{ else if .Version }}{{ template "derive" . }}
+var err error
+
+// the entities with the zero version are not saved yet
+if {{ isZeroVersion (index .Parameters 1) .Version }} {
+{{- template "audit-creation" . }}
+	{{ index .Parameters 1 }}.{{ .Version.Field }} = {{ nextVersion (index .Parameters 1) .Version }}
+	_, err = {{ .Executor }}.ExecContext({{ .Context }}, "INSERT INTO {{ .Table }}({{ columns .Columns }}) VALUES({{ placeholders 1 (len .Columns) }})", {{ fields (index .Parameters 1) .Columns }})
+
+	if err != nil {
+		{{ index .Parameters 1 }}.{{ .Version.Field }} = {{ .Version.Zero }}
+	}
+} else {
+{{- template "audit-modification" . }}
+	{{ template "update-versioned" . }}
+}
+{{ else }}{{ template "derive" . }}
+{{- if .Auditor }}
+// the created audit fields are not updated if the entity exists
+{{ .Auditor.Creation }}({{ .Context }}, {{ index .Parameters 1 }})
+{{ end }}
+_, err := {{ .Executor }}.ExecContext({{ .Context }}, "{{ template "upsert" . }}", {{ fields (index .Parameters 1) .Columns }})
+{{ end }}
+if err != nil {
+	{{ .ErrorReturn }}
+}`
+
+const saveAllTemplate = `
+if len({{ index .Parameters 1 }}) == 0 {
+	{{ .Return }}
+}
+{{ if or .IdColumn.Generated .IdGenerator .Version }}
+inserts := make([]*{{ .Entity }}, 0, len({{ index .Parameters 1 }}))
+updates := make([]*{{ .Entity }}, 0)
+{{- if .Version }}
+
+// the versions of the updated entities are restored if the transaction is rolled back
+versions := make([]{{ .Version.Type }}, 0)
+{{- end }}
+{{- template "new-cascade" . }}
+
+for _, entity := range {{ index .Parameters 1 }} {
+	if entity == nil{{ if .Cascade }} || !cascade.Visit(entity){{ end }} {
+		continue
+	}
+{{ if and .Derive (not .CascadesBefore) }}
+	{{ .Derive }}(entity)
+{{ end }}
+	if {{ if or .IdColumn.Generated .IdGenerator }}entity.{{ .IdColumn.Field }} == {{ .IdColumn.Zero }}{{ else }}{{ isZeroVersion "entity" .Version }}{{ end }} {
+{{- if .Auditor }}
+		{{ .Auditor.Creation }}({{ .Context }}, entity)
+{{- end }}
+{{- if .IdGenerator }}
+		err := {{ .IdGenerator }}({{ .Context }}, {{ .Executor }}, entity)
+
+		if err != nil {
+			// the ids assigned to the previous entities are restored, since none of them is saved
+			for _, inserted := range inserts {
+				inserted.{{ .IdColumn.Field }} = {{ .IdColumn.Zero }}
+			}
+
+			{{ .ErrorReturn }}
+		}
+{{ end }}
+		inserts = append(inserts, entity)
+	} else {
+{{- if and .Auditor .Auditor.Modification }}
+		{{ .Auditor.Modification }}({{ .Context }}, entity)
+{{- end }}
+		updates = append(updates, entity)
+{{- if .Version }}
+		versions = append(versions, entity.{{ .Version.Field }})
+{{- end }}
+	}
+}
+{{ else }}
+entities := make([]*{{ .Entity }}, 0, len({{ index .Parameters 1 }}))
+{{- template "new-cascade" . }}
+
+for _, entity := range {{ index .Parameters 1 }} {
+	if entity != nil{{ if .Cascade }} && cascade.Visit(entity){{ end }} {
+{{- if and .Derive (not .CascadesBefore) }}
+		{{ .Derive }}(entity)
+{{- end }}
+{{- if .Auditor }}
+		{{ .Auditor.Creation }}({{ .Context }}, entity)
+{{- end }}
+		entities = append(entities, entity)
+	}
+}
+{{ end }}
+tx, err := shelf.BeginTx({{ .Context }}, {{ .Receiver }}.db, nil)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+{{ if .IdColumn.Generated }}
+err = shelf.Batch(len(inserts), {{ .BatchSize }}, func(start, end int) error {
+	chunk := inserts[start:end]
+{{- template "cascade-before" . }}
+{{- if .Dialect.SupportsReturning }}
+	args := make([]interface{}, 0, len(chunk)*{{ len .ValueColumns }})
+
+	for _, entity := range chunk {
+		args = append(args, {{ fields "entity" .ValueColumns }})
+	}
+
+	rows, err := tx.QueryContext({{ .Context }}, "{{ template "insert-rows" . }}+" RETURNING {{ .IdColumn.Name }}", args...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for index := 0; index < len(chunk) && rows.Next(); index++ {
+		err = rows.Scan(&chunk[index].{{ .IdColumn.Field }})
+
+		if err != nil {
+			return err
+		}
+	}
+{{- if .CascadesAfter }}
+
+	// the rows are closed before the targets are saved in the same transaction
+	rows.Close()
+	err = rows.Err()
+{{- template "end-chunk" . }}
+{{- else }}
+
+	return rows.Err()
+{{- end }}
+{{- else }}
+	// the rows are inserted one at a time, since the ids generated by a multi-row insert are not
+	// guaranteed to be consecutive
+	insertStmt, err := tx.PrepareContext({{ .Context }}, "{{ template "insert" . }}")
+
+	if err != nil {
+		return err
+	}
+
+	defer insertStmt.Close()
+
+	for _, entity := range chunk {
+		result, err := insertStmt.ExecContext({{ .Context }}, {{ fields "entity" .ValueColumns }})
+
+		if err != nil {
+			return err
+		}
+
+		id, err := result.LastInsertId()
+
+		if err != nil {
+			return err
+		}
+
+		entity.{{ .IdColumn.Field }} = {{ .IdColumn.Type }}(id)
+	}
+{{- template "cascade-after" . }}
+
+	return nil
+{{- end }}
+})
+{{ else if or .IdGenerator .Version }}
+err = shelf.Batch(len(inserts), {{ .BatchSize }}, func(start, end int) error {
+	chunk := inserts[start:end]
+{{- template "cascade-before" . }}
+	args := make([]interface{}, 0, len(chunk)*{{ len .Columns }})
+
+	for _, entity := range chunk {
+{{- if .Version }}
+		entity.{{ .Version.Field }} = {{ nextVersion "entity" .Version }}
+{{- end }}
+		args = append(args, {{ fields "entity" .Columns }})
+	}
+
+	_, err := tx.ExecContext({{ .Context }}, "INSERT INTO {{ .Table }}({{ columns .Columns }}) VALUES "+{{ placeholderFormat }}.Rows(len(chunk), {{ len .Columns }}), args...)
+{{- template "end-chunk" . }}
+})
+{{ end }}
+{{- if or .IdColumn.Generated .IdGenerator .Version }}
+if err == nil && len(updates) != 0 {
+	err = shelf.Batch(len(updates), {{ .BatchSize }}, func(start, end int) error {
+		chunk := updates[start:end]
+{{- template "cascade-before" . }}
+		updateStmt, err := tx.PrepareContext({{ .Context }}, "{{ template "update" . }}")
+
+		if err != nil {
+			return err
+		}
+
+		defer updateStmt.Close()
+
+		for _, entity := range chunk {
+{{- if .Version }}
+			version := {{ nextVersion "entity" .Version }}
+			err = shelf.CheckOptimisticLock(updateStmt.ExecContext({{ .Context }}, {{ fields "entity" .UpdateColumns }}, version, {{ fields "entity" .IdColumns }}, entity.{{ .Version.Field }}))
+
+			if err != nil {
+				return err
+			}
+
+			entity.{{ .Version.Field }} = version
+{{- else }}
+			_, err = updateStmt.ExecContext({{ .Context }}, {{ fields "entity" .UpdateColumns }}, {{ fields "entity" .IdColumns }})
+
+			if err != nil {
+				return err
+			}
+{{- end }}
+		}
+{{- template "cascade-after" . }}
+
+		return nil
+	})
+}
+{{ else if .Copy }}
+// COPY can only insert the rows, so they are copied into a staging table from which they are upserted
+_, err = tx.ExecContext({{ .Context }}, "CREATE TEMPORARY TABLE IF NOT EXISTS {{ .StagingTable }} (LIKE {{ .Table }} INCLUDING DEFAULTS) ON COMMIT DROP")
+
+if err != nil {
+	tx.Rollback()
+	{{ .ErrorReturn }}
+}
+
+err = shelf.Batch(len(entities), {{ .BatchSize }}, func(start, end int) error {
+	chunk := entities[start:end]
+{{- template "cascade-before" . }}
+	copyStmt, err := tx.PrepareContext({{ .Context }}, "COPY {{ .StagingTable }} ({{ columns .Columns }}) FROM STDIN")
+
+	if err != nil {
+		return err
+	}
+
+	defer copyStmt.Close()
+
+	for _, entity := range chunk {
+		_, err = copyStmt.ExecContext({{ .Context }}, {{ fields "entity" .Columns }})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// the copied rows are flushed by the execution without arguments
+	_, err = copyStmt.ExecContext({{ .Context }})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext({{ .Context }}, "INSERT INTO {{ .Table }}({{ columns .Columns }}) SELECT {{ columns .Columns }} FROM {{ .StagingTable }} {{ upsert .IdColumns .ValueColumns }}")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext({{ .Context }}, "DELETE FROM {{ .StagingTable }}")
+{{- template "end-chunk" . }}
+})
+{{ else }}
+err = shelf.Batch(len(entities), {{ .BatchSize }}, func(start, end int) error {
+	chunk := entities[start:end]
+{{- template "cascade-before" . }}
+	args := make([]interface{}, 0, len(chunk)*{{ len .Columns }})
+
+	for _, entity := range chunk {
+		args = append(args, {{ fields "entity" .Columns }})
+	}
+
+	_, err := tx.ExecContext({{ .Context }}, "{{ template "upsert-rows" . }}", args...)
+{{- template "end-chunk" . }}
+})
+{{ end }}
+if err != nil {
+	tx.Rollback()
+{{- if or .IdColumn.Generated .IdGenerator .Version }}
+
+	{{ template "restore-entities" . }}
+{{ end }}
+	{{ .ErrorReturn }}
+}
+
+err = tx.Commit()
+
+if err != nil {
+{{- if or .IdColumn.Generated .IdGenerator .Version }}
+	{{ template "restore-entities" . }}
+{{ end }}
+	{{ .ErrorReturn }}
+}`
+
+const loadTemplate = `
+err := {{ .Loader }}({{ .Context }}, {{ .Receiver }}.executor({{ .Context }}), {{ index .Parameters 1 }})
+
+if err != nil {
+	{{ .ErrorReturn }}
+}`
+
+const loadAllTemplate = `
+err := {{ .Loader }}({{ .Context }}, {{ .Receiver }}.executor({{ .Context }}), {{ index .Parameters 1 }}...)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}`
+
+const fetchPlanTemplate = `
+copied := *{{ .Receiver }}
+copied.fetch = {{ .Receiver }}.fetch.With("{{ .FetchPath }}")
+{{ .Return "&copied" }}`
+
+const findByIdTemplate = `
+entity := &{{ .Entity }}{}
+row := {{ .Receiver }}.executor({{ .Context }}).QueryRowContext({{ .Context }}, "{{ template "select" . }} WHERE {{ idPredicate 1 .IdColumns }}{{ template "and-not-deleted" . }}", {{ keyFields (index .Parameters 1) .IdColumns }})
+err := {{ .Scanner }}(row, entity)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+{{ template "fetch-entity" . }}
+{{ .Return "entity" }}`
+
+const findAllTemplate = `
+rows, err := {{ .Receiver }}.executor({{ .Context }}).QueryContext({{ .Context }}, "{{ template "select" . }}{{ template "not-deleted" . }}")
+{{ template "scan-rows" . }}
+
+{{ .Return "entities" }}`
+
+const findAllByIdTemplate = `
+if len({{ index .Parameters 1 }}) == 0 {
+	{{ .Return (print "make([]*" .Entity ", 0)") }}
+}
+
+{{ template "key-args" . }}
+
+rows, err := {{ .Receiver }}.executor({{ .Context }}).QueryContext({{ .Context }}, "{{ template "select" . }} WHERE {{ if .CompositeId }}{{ idIn .IdColumns (print "len(" (index .Parameters 1) ")") }}{{ else }}{{ idIn .IdColumns "len(args)" }}{{ end }}{{ template "and-not-deleted" . }}", args...)
+{{ template "scan-rows" . }}
+
+{{ .Return "entities" }}`
+
+const findTemplate = `
+{{ template "query" . }}
+{{- if .Query.Sort }}
+orderBy, err := {{ .Query.Sort }}.OrderBy({{ .Query.SortProperties }}{{ range .Query.Orders }}, "{{ . }}"{{ end }})
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+query += orderBy
+{{- end }}
+{{- if .Query.Pageable }}
+
+if {{ .Query.Pageable }}.IsPaged() {
+	query += {{ .Query.Pagination }}
+}
+{{- end }}
+{{ template "bind-query" . }}
+{{- if eq .Query.Result "cursor" }}
+scan := func(rows *sql.Rows, dest interface{}) error {
+	entity, ok := dest.(*{{ .ResultType }})
+
+	if !ok {
+		return shelf.ErrDestinationType
+	}
+
+	return {{ template "scan" . }}
+}
+{{ if .Query.FetchSize }}
+cursor, err := shelf.NewFetchCursor({{ .Context }}, {{ .Receiver }}.db, {{ .Query.FetchSize }}, scan, query{{ template "query-args" . }})
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+{{ .Return "cursor" }}
+{{- else }}
+rows, err := {{ .Receiver }}.executor({{ .Context }}).QueryContext({{ .Context }}, query{{ template "query-args" . }})
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+{{ .Return (print "shelf.NewCursor(" .Context ", rows, scan)") }}
+{{- end }}
+{{- else }}
+rows, err := {{ .Receiver }}.executor({{ .Context }}).QueryContext({{ .Context }}, query{{ template "query-args" . }})
+{{- end }}
+{{- if eq .Query.Result "cursor" }}
+{{- else if eq .Query.Result "single" }}
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+defer rows.Close()
+
+if !rows.Next() {
+	err = rows.Err()
+
+	if err != nil {
+		{{ .ErrorReturn }}
+	}
+
+	{{ .ReturnError "shelf.ErrNotFound" }}
+}
+
+entity := &{{ .ResultType }}{}
+err = {{ template "scan" . }}
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+if rows.Next() {
+	{{ .ReturnError "shelf.ErrNonUniqueResult" }}
+}
+{{ template "fetch-entity" . }}
+{{ .Return "entity" }}
+{{- else }}
+{{ template "scan-rows" . }}
+{{- if eq .Query.Result "page" }}
+
+total := int64({{ .Query.Pageable }}.Offset() + len(entities))
+
+if {{ .Query.Pageable }}.IsPaged() && (len(entities) == {{ .Query.Pageable }}.Size || len(entities) == 0 && {{ .Query.Pageable }}.Offset() != 0) {
+	countQuery := "{{ .Query.CountSQL }}"{{ if .Query.Specification }} + where{{ end }}
+	{{- if .Query.Bind }}
+	countQuery, countArgs := {{ placeholderFormat }}.Bind(countQuery{{ range .Query.Arguments }}, {{ . }}{{ end }})
+	err = {{ .Receiver }}.executor({{ .Context }}).QueryRowContext({{ .Context }}, countQuery, countArgs...).Scan(&total)
+	{{- else }}
+	err = {{ .Receiver }}.executor({{ .Context }}).QueryRowContext({{ .Context }}, countQuery{{ range .Query.Arguments }}, {{ . }}{{ end }}).Scan(&total)
+	{{- end }}
+
+	if err != nil {
+		{{ .ErrorReturn }}
+	}
+}
+
+{{ .Return "entities" (print "shelf.NewPage(" .Query.Pageable ", len(entities), total)") }}
+{{- else if eq .Query.Result "slice" }}
+
+hasNext := {{ .Query.Pageable }}.IsPaged() && len(entities) > {{ .Query.Pageable }}.Size
+
+if hasNext {
+	entities = entities[:{{ .Query.Pageable }}.Size]
+}
+
+{{ .Return "entities" (print "shelf.NewSlice(" .Query.Pageable ", len(entities), hasNext)") }}
+{{- else }}
+
+{{ .Return "entities" }}
+{{- end }}
+{{- end }}`
+
+const countByTemplate = `
+var count {{ index .ReturnValues 0 }}
+{{ template "query" . }}
+{{ template "bind-query" . }}
+err := {{ .Receiver }}.executor({{ .Context }}).QueryRowContext({{ .Context }}, query{{ template "query-args" . }}).Scan(&count)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+{{ .Return "count" }}`
+
+const scalarTemplate = `
+var value {{ index .ReturnValues 0 }}
+query := "{{ .Query.SQL }}"
+{{ template "bind-query" . }}
+err := {{ .Receiver }}.executor({{ .Context }}).QueryRowContext({{ .Context }}, query{{ template "query-args" . }}).Scan(&value)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+{{ .Return "value" }}`
+
+const existsByTemplate = `
+var exists bool
+query := "{{ .Query.SQL }}"
+{{ template "bind-query" . }}
+err := {{ .Receiver }}.executor({{ .Context }}).QueryRowContext({{ .Context }}, query{{ template "query-args" . }}).Scan(&exists)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+{{ .Return "exists" }}`
+
+const deleteByTemplate = `
+query := "{{ .Query.SQL }}"
+{{ template "bind-query" . }}
+{{- if eq .Query.Result "count" }}
+result, err := {{ .Receiver }}.executor({{ .Context }}).ExecContext({{ .Context }}, query{{ template "query-args" . }})
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+affected, err := result.RowsAffected()
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+{{- if .Query.ClearCache }}
+
+shelf.ClearCache({{ .Context }}, "{{ .Query.ClearCache }}")
+{{- end }}
+
+{{ .Return (print (index .ReturnValues 0) "(affected)") }}
+{{- else }}
+_, err := {{ .Receiver }}.executor({{ .Context }}).ExecContext({{ .Context }}, query{{ template "query-args" . }})
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+{{- if .Query.ClearCache }}
+
+shelf.ClearCache({{ .Context }}, "{{ .Query.ClearCache }}")
+{{- end }}
+{{- end }}`
+
+// sqlTemplates contains the statements shared by the method templates.
+const sqlTemplates = `
+{{- define "select" -}}
+SELECT {{ columns .Columns }} FROM {{ .Table }}
+{{- end -}}
+
+{{- define "insert" -}}
+INSERT INTO {{ .Table }}({{ columns .ValueColumns }}) VALUES({{ placeholders 1 (len .ValueColumns) }})
+{{- end -}}
+
+{{- define "insert-returning" -}}
+{{ template "insert" . }} RETURNING {{ .IdColumn.Name }}
+{{- end -}}
+
+{{- define "delete" -}}
+{{ if .SoftDelete }}UPDATE {{ .Table }} SET {{ .SoftDelete.Assignment }}{{ else }}DELETE FROM {{ .Table }}{{ end }}
+{{- end -}}
+
+{{- define "not-deleted" -}}
+{{ with .SoftDelete }} WHERE {{ .Predicate }}{{ end }}
+{{- end -}}
+
+{{- define "and-not-deleted" -}}
+{{ with .SoftDelete }} AND {{ .Predicate }}{{ end }}
+{{- end -}}
+
+{{- define "update" -}}
+{{- if .Version -}}
+UPDATE {{ .Table }} SET {{ assignments 1 .UpdateColumns }}, {{ .Version.Name }} = {{ placeholder (add (len .UpdateColumns) 1) }} WHERE {{ idPredicate (add (len .UpdateColumns) 2) .IdColumns }} AND {{ .Version.Name }} = {{ placeholder (add (len .UpdateColumns) (add (len .IdColumns) 2)) }}
+{{- else -}}
+UPDATE {{ .Table }} SET {{ assignments 1 .UpdateColumns }} WHERE {{ idPredicate (add (len .UpdateColumns) 1) .IdColumns }}
+{{- end -}}
+{{- end -}}
+
+{{- define "delete-versioned" -}}
+{{ template "delete" . }} WHERE {{ .IdColumn.Name }} = {{ placeholder 1 }} AND {{ .Version.Name }} = {{ placeholder 2 }}{{ template "and-not-deleted" . }}
+{{- end -}}
+
+{{- define "audit-creation" }}
+{{- if .Auditor }}
+	{{ .Auditor.Creation }}({{ .Context }}, {{ index .Parameters 1 }})
+{{ end }}
+{{- end -}}
+
+{{- define "audit-modification" }}
+{{- if and .Auditor .Auditor.Modification }}
+	{{ .Auditor.Modification }}({{ .Context }}, {{ index .Parameters 1 }})
+{{ end }}
+{{- end -}}
+
+{{- define "update-versioned" -}}
+version := {{ nextVersion (index .Parameters 1) .Version }}
+err = shelf.CheckOptimisticLock({{ .Executor }}.ExecContext({{ .Context }}, "{{ template "update" . }}", {{ fields (index .Parameters 1) .UpdateColumns }}, version, {{ fields (index .Parameters 1) .IdColumns }}, {{ index .Parameters 1 }}.{{ .Version.Field }}))
+
+	if err == nil {
+		{{ index .Parameters 1 }}.{{ .Version.Field }} = version
+	}
+{{- end -}}
+
+{{- define "upsert" -}}
+INSERT INTO {{ .Table }}({{ columns .Columns }}) VALUES({{ placeholders 1 (len .Columns) }}) {{ upsert .IdColumns .UpdateColumns }}
+{{- end -}}
+
+{{- define "insert-rows" -}}
+INSERT INTO {{ .Table }}({{ columns .ValueColumns }}) VALUES "+{{ placeholderFormat }}.Rows(len(chunk), {{ len .ValueColumns }})
+{{- end -}}
+
+{{- define "upsert-rows" -}}
+INSERT INTO {{ .Table }}({{ columns .Columns }}) VALUES "+{{ placeholderFormat }}.Rows(len(chunk), {{ len .Columns }})+" {{ upsert .IdColumns .UpdateColumns }}
+{{- end -}}
+
+{{- define "key-args" -}}
+{{- if .CompositeId -}}
+args := make([]interface{}, 0, len({{ index .Parameters 1 }})*{{ len .IdColumns }})
+
+for _, id := range {{ index .Parameters 1 }} {
+	args = append(args, {{ keyFields "id" .IdColumns }})
+}
+{{- else -}}
+args := make([]interface{}, len({{ index .Parameters 1 }}))
+
+for index, id := range {{ index .Parameters 1 }} {
+	args[index] = id
+}
+{{- end -}}
+{{- end -}}
+
+{{- define "derive" }}{{ if .Derive }}
+{{ .Derive }}({{ index .Parameters 1 }})
+{{ end }}{{ end -}}
+
+{{- define "delete-batch" }}
+tx, err := shelf.BeginTx({{ .Context }}, {{ .Receiver }}.db, nil)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+{{ if .CompositeId }}
+err = shelf.Batch(len(args)/{{ len .IdColumns }}, {{ .BatchSize }}, func(start, end int) error {
+	_, err := tx.ExecContext({{ .Context }}, "{{ template "delete" . }} WHERE {{ idIn .IdColumns "end-start" }}{{ template "and-not-deleted" . }}", args[start*{{ len .IdColumns }}:end*{{ len .IdColumns }}]...)
+	return err
+})
+{{- else }}
+err = shelf.Batch(len(args), {{ .BatchSize }}, func(start, end int) error {
+	_, err := tx.ExecContext({{ .Context }}, "{{ template "delete" . }} WHERE {{ idIn .IdColumns "end-start" }}{{ template "and-not-deleted" . }}", args[start:end]...)
+	return err
+})
+{{- end }}
+{{ template "end-tx" . }}
+{{- end -}}
+
+{{- define "end-tx" }}
+if err != nil {
+	tx.Rollback()
+	{{ .ErrorReturn }}
+}
+
+err = tx.Commit()
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+{{- end -}}
+
+{{- define "restore-entities" -}}
+// the ids and the versions assigned to the saved entities are restored, since their rows are rolled back
+for _, entity := range inserts {
+{{- if or .IdColumn.Generated .IdGenerator }}
+	entity.{{ .IdColumn.Field }} = {{ .IdColumn.Zero }}
+{{- end }}
+{{- if and .Version (not .IdColumn.Generated) }}
+	entity.{{ .Version.Field }} = {{ .Version.Zero }}
+{{- end }}
+}
+{{- if .Version }}
+
+for index, entity := range updates {
+	entity.{{ .Version.Field }} = versions[index]
+}
+{{- end }}
+{{- end -}}
+
+{{- define "new-cascade" }}
+{{- if .Cascade }}
+
+// the entities are visited before their targets are saved, which may cascade the save back to them
+cascade := shelf.NewCascade()
+{{- end }}
+{{- end -}}
+
+{{- define "cascade-before" }}
+{{- if .CascadesBefore }}
+
+	for _, entity := range chunk {
+		err := {{ .Cascade.BeforeFunction }}({{ .Context }}, tx, cascade, entity)
+
+		if err != nil {
+			return err
+		}
+{{- if .Derive }}
+
+		// the ids are derived once the associated entities are saved
+		{{ .Derive }}(entity)
+{{- end }}
+	}
+{{ end }}
+{{- end -}}
+
+{{- define "cascade-after" }}
+{{- if .CascadesAfter }}
+
+	for _, entity := range chunk {
+		err := {{ .Cascade.AfterFunction }}({{ .Context }}, tx, cascade, entity)
+
+		if err != nil {
+			return err
+		}
+	}
+{{- end }}
+{{- end -}}
+
+{{- define "end-chunk" }}
+{{- if .CascadesAfter }}
+
+	if err != nil {
+		return err
+	}
+{{- template "cascade-after" . }}
+
+	return nil
+{{- else }}
+	return err
+{{- end }}
+{{- end -}}
+
+{{- define "cascade" }}
+err := shelf.NewTxManager({{ .Receiver }}.db).WithTx({{ .Context }}, nil, func({{ .Context }} context.Context) error {
+	return {{ .Cascade.Name }}({{ .Context }}, {{ .Executor }}, shelf.NewCascade(), {{ index .Parameters 1 }})
+})
+{{- end -}}
+
+{{- define "scan-rows" }}
+if err != nil {
+	{{ .ErrorReturn }}
+}
+
+defer rows.Close()
+
+entities := make([]{{ .ResultElement }}, 0)
+
+for rows.Next() {
+	entity := &{{ .ResultType }}{}
+	err = {{ template "scan" . }}
+
+	if err != nil {
+		{{ .ErrorReturn }}
+	}
+
+	entities = append(entities, entity)
+}
+
+err = rows.Err()
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+{{- if .Fetch }}
+
+err = {{ .Fetch }}({{ .Context }}, {{ .Receiver }}.db, {{ .FetchPlan }}, entities...)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+{{- end }}
+{{- end -}}
+
+{{- define "fetch-entity" -}}
+{{ if .Fetch }}
+err = {{ .Fetch }}({{ .Context }}, {{ .Receiver }}.db, {{ .FetchPlan }}, entity)
+
+if err != nil {
+	{{ .ErrorReturn }}
+}
+{{ end }}
+{{- end -}}
+
+{{- define "scan" -}}
+{{ if .Scanner }}{{ .Scanner }}(rows, entity){{ else }}rows.Scan({{ pointers "entity" .ResultFields .ResultConverters }}){{ end }}
+{{- end -}}
+
+{{- define "query" -}}
+{{ if .Query.Specification -}}
+where, whereArgs := shelf.Where({{ .Query.Specification }})
+query := "{{ .Query.SQL }}" + where{{ if .Query.Suffix }} + "{{ .Query.Suffix }}"{{ end }}
+{{- else -}}
+query := "{{ .Query.SQL }}"
+{{- end }}
+{{- end -}}
+
+{{- define "query-args" -}}
+{{ if .Query.Bind }}, args...{{ else }}{{ range .Query.Arguments }}, {{ . }}{{ end }}{{ end }}
+{{- end -}}
+
+{{- define "bind-query" -}}
+{{ if .Query.Bind }}
+query, args := {{ placeholderFormat }}.Bind(query{{ range .Query.Arguments }}, {{ . }}{{ end }})
+{{ end }}
+{{- end -}}
+`
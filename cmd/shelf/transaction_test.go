@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestValidate_TransactionalMethods(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Method string
+		Errors []string
+	}{
+		{
+			Name: "method without error",
+			Method: `// +shelf:transactional
+	DeleteByEmail(ctx context.Context, email string) int64`,
+			Errors: []string{
+				"the method 'DeleteByEmail' marked as 'shelf:transactional' must return an error as the last value",
+			},
+		},
+		{
+			Name: "invalid propagation",
+			Method: `// +shelf:transactional:Propagation=MANDATORY
+	DeleteByEmail(ctx context.Context, email string) (int64, error)`,
+			Errors: []string{
+				"invalid Propagation option. Here is the list of valid options REQUIRED, REQUIRES_NEW, SUPPORTS, NEVER, NESTED",
+			},
+		},
+		{
+			Name: "invalid isolation",
+			Method: `// +shelf:transactional:Isolation=SNAPSHOT
+	DeleteByEmail(ctx context.Context, email string) (int64, error)`,
+			Errors: []string{
+				"invalid Isolation option. Here is the list of valid options DEFAULT, READ_UNCOMMITTED, READ_COMMITTED, REPEATABLE_READ, SERIALIZABLE",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			source := userSource + `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	` + testCase.Method + `
+}`
+
+			assertErrors(t, validate(t, "fixture", source), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_TransactionalMethods(t *testing.T) {
+	repositories := generate(t, "fixture", userSource+`
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	// +shelf:transactional
+	Save(ctx context.Context, user *User) error
+	// +shelf:transactional:Propagation=REQUIRES_NEW,Isolation=SERIALIZABLE,ReadOnly=true
+	FindById(ctx context.Context, id int) (*User, error)
+}`)
+
+	assertContains(t, repositories,
+		`return shelf.ExecutorOf(ctx, repository.db)`,
+		`err := shelf.NewTxManager(repository.db).WithTx(ctx, nil, func(ctx context.Context) error {`,
+		`return repository.saveInTx(ctx, user)`,
+		`func (repository *userRepositoryImpl) saveInTx(ctx context.Context, user *User) error {`,
+		`repository.executor(ctx).QueryRowContext(ctx, "INSERT INTO users(email, first_name) VALUES($1, $2) RETURNING id"`,
+		`WithTx(ctx, &shelf.TxOptions{Propagation: shelf.PropagationRequiresNew, Isolation: sql.LevelSerializable, ReadOnly: true}, func(ctx context.Context) error {`,
+		`result0, err = repository.findByIdInTx(ctx, idParam)`,
+	)
+}
@@ -30,12 +30,19 @@ var (
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate markers' syntax and arguments",
-	Long:  `The validate command helps you validate markers' syntax and arguments'`,
+	Long:  `The validate command helps you validate markers' syntax and arguments, and the relationships between the entities`,
 	Run: func(cmd *cobra.Command, args []string) {
+		dialect, err := GetDialectOption(validateArgs)
+
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
 		packages, err := marker.LoadPackages(validatePaths...)
 
 		if err != nil {
-			log.Errorln(err)
+			log.Println(err)
 			return
 		}
 
@@ -43,13 +50,20 @@ var validateCmd = &cobra.Command{
 		err = RegisterDefinitions(registry)
 
 		if err != nil {
-			log.Errorln(err)
+			log.Println(err)
 			return
 		}
 
 		collector := marker.NewCollector(registry)
 		err = ValidateMarkers(collector, packages)
 
+		if err != nil {
+			PrintError(err)
+			return
+		}
+
+		err = ProcessMarkers(collector, packages, dialect)
+
 		if err != nil {
 			PrintError(err)
 		}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"testing"
+)
+
+// versionSource is the package of the version tests, whose users have generated ids and integer versions,
+// and whose tags have the timestamps as versions.
+const versionSource = `package fixture
+
+// +import=shelf, Pkg=github.com/procyon-projects/shelf
+import (
+	"context"
+	"time"
+)
+
+// +shelf:entity
+// +shelf:table=users
+type User struct {
+	// +shelf:id
+	// +shelf:generated-value
+	Id    int
+	Email string
+	// +shelf:version
+	Version int
+}
+
+// +shelf:entity
+// +shelf:table=tags
+type Tag struct {
+	// +shelf:id
+	Name  string
+	Color string
+	// +shelf:version
+	UpdatedAt time.Time
+}
+`
+
+func TestValidate_VersionFields(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Entity string
+		Errors []string
+	}{
+		{
+			Name: "version of an unsupported type",
+			Entity: `
+// +shelf:entity
+type Post struct {
+	// +shelf:id
+	Id int
+	// +shelf:version
+	Version string
+}`,
+			Errors: []string{
+				"the type of the field 'Version' marked as 'shelf:version' must be an integer or time.Time",
+			},
+		},
+		{
+			Name: "version of the id",
+			Entity: `
+// +shelf:entity
+type Post struct {
+	// +shelf:id
+	// +shelf:version
+	Id int
+}`,
+			Errors: []string{
+				"'shelf:version' marker cannot be used with 'shelf:id' marker",
+			},
+		},
+		{
+			Name: "more than one version",
+			Entity: `
+// +shelf:entity
+type Post struct {
+	// +shelf:id
+	Id int
+	// +shelf:version
+	Version int
+	// +shelf:version
+	UpdatedAt time.Time
+}`,
+			Errors: []string{
+				"the entity 'Post' cannot have more than one field marked as 'shelf:version'",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			assertErrors(t, validate(t, "fixture", versionSource+testCase.Entity), testCase.Errors...)
+		})
+	}
+}
+
+func TestGenerate_CopyOfVersionedEntities(t *testing.T) {
+	dir, remove := writePackage(t, "fixture", map[string]string{"fixture.go": versionSource + `
+// +shelf:repository="tag-repository", Entity=Tag
+type TagRepository interface {
+	// +shelf:batch:Copy=true
+	SaveAll(ctx context.Context, tags []*Tag) error
+}`})
+	defer remove()
+
+	assertErrors(t, runShelf(t, dir, "generate", "-o", dir),
+		"the method 'SaveAll' cannot use COPY, which cannot check the versions of the entities",
+	)
+}
+
+func TestGenerate_VersionedEntities(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Save(ctx context.Context, user *User) error
+	SaveAll(ctx context.Context, users []*User) error
+	Delete(ctx context.Context, user *User) error
+	DeleteAll(ctx context.Context, users []*User) error
+}
+
+// +shelf:repository="tag-repository", Entity=Tag
+type TagRepository interface {
+	Save(ctx context.Context, tag *Tag) error
+	SaveAll(ctx context.Context, tags []*Tag) error
+}`
+
+	testCases := []struct {
+		Dialect    string
+		Statements []string
+	}{
+		{
+			Dialect: "postgres",
+			Statements: []string{
+				`"INSERT INTO users(email, version) VALUES($1, $2) RETURNING id", user.Email, user.Version`,
+				`err = shelf.CheckOptimisticLock(repository.executor(ctx).ExecContext(ctx, "UPDATE users SET email = $1, version = $2 WHERE id = $3 AND version = $4", user.Email, version, user.Id, user.Version))`,
+				`version := time.Now().Truncate(time.Microsecond)`,
+				`"UPDATE tags SET color = $1, updated_at = $2 WHERE name = $3 AND updated_at = $4", tag.Color, version, tag.Name, tag.UpdatedAt`,
+				`updateStmt, err := tx.PrepareContext(ctx, "UPDATE users SET email = $1, version = $2 WHERE id = $3 AND version = $4")`,
+				"versions = append(versions, entity.Version)",
+				"for index, entity := range updates {\n\t\t\tentity.Version = versions[index]\n\t\t}",
+				"for _, entity := range inserts {\n\t\t\tentity.UpdatedAt = time.Time{}\n\t\t}",
+				`err := shelf.CheckOptimisticLock(repository.executor(ctx).ExecContext(ctx, "DELETE FROM users WHERE id = $1 AND version = $2", user.Id, user.Version))`,
+				`deleteStmt, err := tx.PrepareContext(ctx, "DELETE FROM users WHERE id = $1 AND version = $2")`,
+				`err = shelf.CheckOptimisticLock(deleteStmt.ExecContext(ctx, entity.Id, entity.Version))`,
+			},
+		},
+		{
+			Dialect: "mysql",
+			Statements: []string{
+				`"UPDATE users SET email = ?, version = ? WHERE id = ? AND version = ?", user.Email, version, user.Id, user.Version`,
+				`"UPDATE tags SET color = ?, updated_at = ? WHERE name = ? AND updated_at = ?", tag.Color, version, tag.Name, tag.UpdatedAt`,
+			},
+		},
+		{
+			Dialect: "sqlite",
+			Statements: []string{
+				`"UPDATE users SET email = ?, version = ? WHERE id = ? AND version = ?", user.Email, version, user.Id, user.Version`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Dialect, func(t *testing.T) {
+			repositories := generate(t, "fixture", versionSource+repository, "-a", "dialect="+testCase.Dialect)
+			assertContains(t, repositories, testCase.Statements...)
+		})
+	}
+}
+
+func TestRun_VersionedEntities(t *testing.T) {
+	repository := `
+// +shelf:repository="user-repository", Entity=User
+type UserRepository interface {
+	Save(ctx context.Context, user *User) error
+	Delete(ctx context.Context, user *User) error
+	DeleteAll(ctx context.Context, users []*User) error
+}`
+
+	runFixture(t, "fixture", versionSource+repository, `package fixture
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/procyon-projects/shelf"
+)
+
+func TestVersionedEntities(t *testing.T) {
+	// the rows of the users whose version is 3 were changed by another transaction
+	connector := &testConnector{
+		Exec: func(query string, args []driver.Value) (driver.Result, error) {
+			if args[len(args)-1] == int64(3) {
+				return driver.RowsAffected(0), nil
+			}
+
+			return driver.RowsAffected(1), nil
+		},
+	}
+	db := openTestDB(connector)
+	defer db.Close()
+
+	ctx := context.Background()
+	repository := NewUserRepository(db)
+	user := &User{Id: 1, Email: "anna@example.com", Version: 1}
+
+	if err := repository.Save(ctx, user); err != nil || user.Version != 2 {
+		t.Errorf("Save should increment the version, but got %d and %v", user.Version, err)
+	}
+
+	if err := repository.Delete(ctx, &User{Id: 2, Version: 3}); !errors.Is(err, shelf.ErrOptimisticLock) {
+		t.Errorf("Delete of a stale user should return ErrOptimisticLock, but got %v", err)
+	}
+
+	if err := repository.Delete(ctx, user); err != nil {
+		t.Errorf("Delete should delete the user, but got %v", err)
+	}
+
+	err := repository.DeleteAll(ctx, []*User{{Id: 4, Version: 1}, {Id: 5, Version: 3}})
+
+	if !errors.Is(err, shelf.ErrOptimisticLock) {
+		t.Errorf("DeleteAll of a stale user should return ErrOptimisticLock, but got %v", err)
+	}
+
+	expected := []testStatement{
+		{Query: "UPDATE users SET email = $1, version = $2 WHERE id = $3 AND version = $4",
+			Args: []driver.Value{"anna@example.com", int64(2), int64(1), int64(1)}},
+		{Query: "DELETE FROM users WHERE id = $1 AND version = $2", Args: []driver.Value{int64(2), int64(3)}},
+		{Query: "DELETE FROM users WHERE id = $1 AND version = $2", Args: []driver.Value{int64(1), int64(2)}},
+		{Query: "BEGIN"},
+		{Query: "DELETE FROM users WHERE id = $1 AND version = $2", Args: []driver.Value{int64(4), int64(1)}},
+		{Query: "DELETE FROM users WHERE id = $1 AND version = $2", Args: []driver.Value{int64(5), int64(3)}},
+		{Query: "ROLLBACK"},
+	}
+
+	if statements := connector.Statements(); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("the statements should be %v, but got %v", expected, statements)
+	}
+}
+`)
+}
@@ -0,0 +1,177 @@
+package shelf
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync/atomic"
+)
+
+// cursorCount is used to give each server-side cursor a unique name, so that the cursors
+// declared in the same transaction do not collide.
+var cursorCount uint64
+
+// ScanFunc scans the current row into the destination. It returns ErrDestinationType if the
+// destination is not of the type the cursor was generated for.
+type ScanFunc func(rows *sql.Rows, dest interface{}) error
+
+// Cursor iterates over the results of a query without loading all of them into memory.
+// The cursor holds a database connection until it is closed, so it must always be closed.
+// If the context of the query is cancelled, the cursor is closed and the connection is released.
+type Cursor struct {
+	ctx    context.Context
+	rows   *sql.Rows
+	scan   ScanFunc
+	err    error
+	closed bool
+
+	// fetch returns the next batch of the rows of a server-side cursor.
+	fetch     func() (*sql.Rows, error)
+	fetchSize int
+	fetched   int
+	release   func() error
+}
+
+// NewCursor returns a cursor iterating over the rows of the query run with the context.
+func NewCursor(ctx context.Context, rows *sql.Rows, scan ScanFunc) *Cursor {
+	return &Cursor{
+		ctx:  ctx,
+		rows: rows,
+		scan: scan,
+	}
+}
+
+// NewFetchCursor declares a server-side cursor for the query and returns a cursor which fetches its
+// results in batches of the fetch size, so that the driver never buffers the whole result. It is only
+// supported by Postgres. The server-side cursor is declared in a read-only transaction, which is
+// rolled back when the cursor is closed or the context is cancelled. If the context has a transaction,
+// the server-side cursor is declared in it instead and closed along with the cursor.
+func NewFetchCursor(ctx context.Context, db *sql.DB, fetchSize int, scan ScanFunc, query string, args ...interface{}) (*Cursor, error) {
+	name := "shelf_cursor_" + strconv.FormatUint(atomic.AddUint64(&cursorCount, 1), 10)
+	tx, ok := TxFrom(ctx)
+	release := func() error {
+		_, err := tx.ExecContext(ctx, "CLOSE "+name)
+		return err
+	}
+
+	if !ok {
+		var err error
+		tx, err = db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+
+		if err != nil {
+			return nil, err
+		}
+
+		release = tx.Rollback
+	}
+
+	_, err := tx.ExecContext(ctx, "DECLARE "+name+" NO SCROLL CURSOR FOR "+query, args...)
+
+	if err != nil {
+		_ = release()
+		return nil, err
+	}
+
+	fetchQuery := "FETCH FORWARD " + strconv.Itoa(fetchSize) + " FROM " + name
+
+	cursor := &Cursor{
+		ctx:       ctx,
+		scan:      scan,
+		fetchSize: fetchSize,
+		fetch: func() (*sql.Rows, error) {
+			return tx.QueryContext(ctx, fetchQuery)
+		},
+		release: release,
+	}
+
+	cursor.rows, err = cursor.fetch()
+
+	if err != nil {
+		_ = release()
+		return nil, err
+	}
+
+	return cursor, nil
+}
+
+// Next prepares the next result to be read with Scan. It returns false when there are no more
+// results or an error occurs, in which case the cursor is closed and Err returns the error.
+func (c *Cursor) Next() bool {
+	if c.closed {
+		return false
+	}
+
+	// database/sql closes the rows of a cancelled query asynchronously, so the context is checked as well
+	if c.err = c.ctx.Err(); c.err != nil {
+		c.closeWith(c.err)
+		return false
+	}
+
+	if c.rows.Next() {
+		c.fetched++
+		return true
+	}
+
+	c.err = c.rows.Err()
+
+	// a batch smaller than the fetch size is the last one
+	if c.err == nil && c.fetch != nil && c.fetched == c.fetchSize {
+		c.err = c.rows.Close()
+
+		if c.err == nil {
+			c.fetched = 0
+			c.rows, c.err = c.fetch()
+
+			if c.err == nil {
+				return c.Next()
+			}
+		}
+	}
+
+	c.closeWith(c.err)
+	return false
+}
+
+// Scan copies the current result into the destination, which must be a pointer to the struct
+// the cursor was generated for.
+func (c *Cursor) Scan(dest interface{}) error {
+	err := c.scan(c.rows, dest)
+
+	if err != nil {
+		return TranslateError(err)
+	}
+
+	return nil
+}
+
+// Err returns the error which ended the iteration, if any.
+func (c *Cursor) Err() error {
+	return TranslateError(c.err)
+}
+
+// Close closes the cursor and releases its connection. It is safe to call it more than once.
+func (c *Cursor) Close() error {
+	if c.closed {
+		return nil
+	}
+
+	return c.closeWith(nil)
+}
+
+func (c *Cursor) closeWith(err error) error {
+	c.closed = true
+
+	if c.rows != nil {
+		if closeErr := c.rows.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if c.release != nil {
+		if releaseErr := c.release(); err == nil && releaseErr != sql.ErrTxDone {
+			err = releaseErr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,211 @@
+package shelf
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type cursorTestValue struct {
+	Value int64
+}
+
+func scanCursorTestValue(rows *sql.Rows, dest interface{}) error {
+	value, ok := dest.(*cursorTestValue)
+
+	if !ok {
+		return ErrDestinationType
+	}
+
+	return rows.Scan(&value.Value)
+}
+
+func TestCursor(t *testing.T) {
+	db, _ := openCursorTestDB(5)
+	defer db.Close()
+
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "SELECT value FROM cursor_test")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cursor := NewCursor(ctx, rows, scanCursorTestValue)
+	values := readCursor(t, cursor)
+
+	if len(values) != 5 {
+		t.Errorf("cursor should return 5 values, but got %d", len(values))
+	}
+
+	if err = cursor.Scan(&struct{}{}); err != ErrDestinationType {
+		t.Errorf("scanning into another type should return ErrDestinationType, but got %v", err)
+	}
+
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("cursor should release the connection, but %d connections are in use", inUse)
+	}
+}
+
+func TestNewFetchCursor(t *testing.T) {
+	db, _ := openCursorTestDB(5)
+	defer db.Close()
+
+	cursor, err := NewFetchCursor(context.Background(), db, 2, scanCursorTestValue, "SELECT value FROM cursor_test")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := readCursor(t, cursor)
+
+	if len(values) != 5 {
+		t.Errorf("cursor should return 5 values, but got %d", len(values))
+	}
+
+	for index, value := range values {
+		if value != int64(index) {
+			t.Errorf("value at %d should be %d, but got %d", index, index, value)
+		}
+	}
+
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("cursor should release the connection, but %d connections are in use", inUse)
+	}
+}
+
+func TestNewFetchCursor_UniqueNames(t *testing.T) {
+	db, connector := openCursorTestDB(5)
+	defer db.Close()
+
+	first, err := NewFetchCursor(context.Background(), db, 2, scanCursorTestValue, "SELECT value FROM cursor_test")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewFetchCursor(context.Background(), db, 2, scanCursorTestValue, "SELECT value FROM cursor_test")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readCursor(t, first)
+	readCursor(t, second)
+
+	declared := declaredCursors(connector)
+
+	if len(declared) != 2 || declared[0] == declared[1] {
+		t.Errorf("each cursor should be declared with a unique name, but got %v", declared)
+	}
+}
+
+func TestCursor_Cancel(t *testing.T) {
+	db, _ := openCursorTestDB(5)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := db.QueryContext(ctx, "SELECT value FROM cursor_test")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cursor := NewCursor(ctx, rows, scanCursorTestValue)
+
+	if !cursor.Next() {
+		t.Fatal("cursor should return a value")
+	}
+
+	cancel()
+
+	for cursor.Next() {
+	}
+
+	if !errors.Is(cursor.Err(), context.Canceled) {
+		t.Errorf("cursor should return context.Canceled, but got %v", cursor.Err())
+	}
+
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("cursor should release the connection, but %d connections are in use", inUse)
+	}
+}
+
+func readCursor(t *testing.T, cursor *Cursor) []int64 {
+	values := make([]int64, 0)
+
+	for cursor.Next() {
+		value := &cursorTestValue{}
+
+		if err := cursor.Scan(value); err != nil {
+			t.Fatal(err)
+		}
+
+		values = append(values, value.Value)
+	}
+
+	if err := cursor.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cursor.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+}
+
+// cursorTestConnector returns the given number of rows for the select statements, and fetches them
+// in batches for the FETCH statements. It records the names of the declared server-side cursors.
+// openCursorTestDB opens a fake database whose queries return the values from 0 to count, which are fetched
+// two at a time by the cursors.
+func openCursorTestDB(count int) (*sql.DB, *testConnector) {
+	var mu sync.Mutex
+	fetched := make(map[string]int)
+	connector := &testConnector{
+		Query: func(query string, args []driver.Value) (driver.Rows, error) {
+			rows := newTestRows([]string{"value"})
+			start, end := 0, count
+
+			if strings.HasPrefix(query, "FETCH FORWARD 2 FROM ") {
+				name := strings.TrimPrefix(query, "FETCH FORWARD 2 FROM ")
+
+				mu.Lock()
+				start = fetched[name]
+				end = start + 2
+
+				if end > count {
+					end = count
+				}
+
+				fetched[name] = end
+				mu.Unlock()
+			}
+
+			for value := start; value < end; value++ {
+				rows.values = append(rows.values, []driver.Value{int64(value)})
+			}
+
+			return rows, nil
+		},
+	}
+
+	return openTestDB(connector), connector
+}
+
+// declaredCursors returns the names of the cursors declared on the fake database.
+func declaredCursors(connector *testConnector) []string {
+	names := make([]string, 0)
+
+	for _, query := range connector.Queries() {
+		if strings.HasPrefix(query, "DECLARE ") {
+			names = append(names, strings.Fields(query)[1])
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,171 @@
+package shelf
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// testConnector is the connector of a fake database, which records the statements run on it
+// and answers them with the results of its Query and Exec functions.
+type testConnector struct {
+	// Query returns the rows of a query. No rows are returned if it is nil.
+	Query func(query string, args []driver.Value) (driver.Rows, error)
+	// Exec returns the result of a statement. No rows are affected if it is nil.
+	Exec func(query string, args []driver.Value) (driver.Result, error)
+
+	mu         sync.Mutex
+	statements []testStatement
+}
+
+// testStatement is a statement run on the fake database. The transactions are recorded as the
+// statements BEGIN, COMMIT and ROLLBACK.
+type testStatement struct {
+	Query string
+	Args  []driver.Value
+}
+
+func openTestDB(connector *testConnector) *sql.DB {
+	return sql.OpenDB(connector)
+}
+
+func (c *testConnector) Connect(context.Context) (driver.Conn, error) {
+	return &testConn{connector: c}, nil
+}
+
+func (c *testConnector) Driver() driver.Driver {
+	return nil
+}
+
+func (c *testConnector) record(query string, args []driver.Value) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.statements = append(c.statements, testStatement{Query: query, Args: args})
+}
+
+// Statements returns the statements run on the fake database in order.
+func (c *testConnector) Statements() []testStatement {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]testStatement{}, c.statements...)
+}
+
+// Queries returns the queries of the statements run on the fake database in order.
+func (c *testConnector) Queries() []string {
+	queries := make([]string, 0)
+
+	for _, statement := range c.Statements() {
+		queries = append(queries, statement.Query)
+	}
+
+	return queries
+}
+
+type testConn struct {
+	connector *testConnector
+}
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) {
+	return &testStmt{conn: c, query: query}, nil
+}
+
+func (c *testConn) Close() error {
+	return nil
+}
+
+func (c *testConn) Begin() (driver.Tx, error) {
+	c.connector.record("BEGIN", nil)
+	return c, nil
+}
+
+func (c *testConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return c.Begin()
+}
+
+func (c *testConn) Commit() error {
+	c.connector.record("COMMIT", nil)
+	return nil
+}
+
+func (c *testConn) Rollback() error {
+	c.connector.record("ROLLBACK", nil)
+	return nil
+}
+
+type testStmt struct {
+	conn  *testConn
+	query string
+}
+
+func (s *testStmt) Close() error {
+	return nil
+}
+
+func (s *testStmt) NumInput() int {
+	return -1
+}
+
+func (s *testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.connector.record(s.query, args)
+
+	if s.conn.connector.Exec == nil {
+		return driver.RowsAffected(0), nil
+	}
+
+	return s.conn.connector.Exec(s.query, args)
+}
+
+func (s *testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.conn.connector.record(s.query, args)
+
+	if s.conn.connector.Query == nil {
+		return &testRows{}, nil
+	}
+
+	return s.conn.connector.Query(s.query, args)
+}
+
+// testResult is the result of a statement inserting rows with generated ids.
+type testResult struct {
+	lastInsertId int64
+	rowsAffected int64
+}
+
+func (r testResult) LastInsertId() (int64, error) {
+	return r.lastInsertId, nil
+}
+
+func (r testResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// testRows are the rows returned by the fake database.
+type testRows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func newTestRows(columns []string, values ...[]driver.Value) *testRows {
+	return &testRows{columns: columns, values: values}
+}
+
+func (r *testRows) Columns() []string {
+	return r.columns
+}
+
+func (r *testRows) Close() error {
+	return nil
+}
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if r.next == len(r.values) {
+		return io.EOF
+	}
+
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,70 @@
+package shelf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UnknownEnumValueError is returned when an enum field is saved with a value which is not one of the constants
+// of its type, or when a column of an enum field holds a value which is not stored by any of them, e.g. the
+// name of a removed constant.
+type UnknownEnumValueError struct {
+	// Enum is the name of the enum type, e.g. UserStatus.
+	Enum  string
+	Value interface{}
+}
+
+func (e *UnknownEnumValueError) Error() string {
+	if bytes, ok := e.Value.([]byte); ok {
+		return fmt.Sprintf("shelf: unknown value '%s' of the enum %s", bytes, e.Enum)
+	}
+
+	return fmt.Sprintf("shelf: unknown value '%v' of the enum %s", e.Value, e.Enum)
+}
+
+// EnumName returns the name read from the column of an enum mapped by the names of its constants, which the
+// database drivers return as a string or as bytes.
+func EnumName(src interface{}) (string, bool) {
+	switch value := src.(type) {
+	case string:
+		return value, true
+	case []byte:
+		return string(value), true
+	}
+
+	return "", false
+}
+
+// EnumOrdinal returns the value read from the column of an enum mapped by the values of its constants, which
+// the database drivers return as an integer, or as the digits of the integer.
+func EnumOrdinal(src interface{}) (int64, bool) {
+	var digits string
+
+	switch value := src.(type) {
+	case int64:
+		return value, true
+	case int32:
+		return int64(value), true
+	case string:
+		digits = value
+	case []byte:
+		digits = string(value)
+	default:
+		return 0, false
+	}
+
+	ordinal, err := strconv.ParseInt(strings.TrimSpace(digits), 10, 64)
+	return ordinal, err == nil
+}
+
+// Enum describes the column of an enum field marked as shelf:enumerated in the generated metamodels, which
+// stores the constants of the enum type by their names or by their values.
+type Enum struct {
+	Column string
+	// Ordinal reports whether the constants are stored by their values instead of their names.
+	Ordinal bool
+	// Names are the names which the constants are stored by, and Ordinals are their values.
+	Names    []string
+	Ordinals []int64
+}
@@ -0,0 +1,43 @@
+package shelf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestEnumOrdinal(t *testing.T) {
+	for _, src := range []interface{}{int64(2), int32(2), "2", []byte("2")} {
+		if ordinal, ok := EnumOrdinal(src); !ok || ordinal != 2 {
+			t.Errorf("expected 2 for %#v, got %d", src, ordinal)
+		}
+	}
+
+	if _, ok := EnumOrdinal("BLOCKED"); ok {
+		t.Errorf("a name should not be an ordinal")
+	}
+
+	if _, ok := EnumOrdinal(nil); ok {
+		t.Errorf("NULL should not be an ordinal")
+	}
+}
+
+func TestEnumName(t *testing.T) {
+	if name, ok := EnumName([]byte("BLOCKED")); !ok || name != "BLOCKED" {
+		t.Errorf("expected BLOCKED, got %s", name)
+	}
+
+	if _, ok := EnumName(int64(1)); ok {
+		t.Errorf("an integer should not be a name")
+	}
+}
+
+func TestUnknownEnumValueError(t *testing.T) {
+	err := fmt.Errorf("sql: Scan error: %w", &UnknownEnumValueError{Enum: "UserStatus", Value: []byte("DELETED")})
+
+	var enumErr *UnknownEnumValueError
+
+	if !errors.As(err, &enumErr) || enumErr.Error() != "shelf: unknown value 'DELETED' of the enum UserStatus" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package shelf
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrNotFound is returned when a query expected to find an entity returns no rows.
+	ErrNotFound = errors.New("shelf: entity not found")
+	// ErrNonUniqueResult is returned when a query expected to find at most one entity returns more rows.
+	ErrNonUniqueResult = errors.New("shelf: query did not return a unique result")
+	// ErrOptimisticLock is returned when an entity was updated or deleted by another transaction.
+	ErrOptimisticLock = errors.New("shelf: entity was updated or deleted by another transaction")
+	// ErrDestinationType is returned when a cursor scans into a destination of another type.
+	ErrDestinationType = errors.New("shelf: destination type does not match the cursor")
+)
+
+// ConstraintViolationError is returned when a statement violates a database constraint.
+type ConstraintViolationError struct {
+	// Constraint is the name of the violated constraint. It is empty if the database does not report it.
+	Constraint string
+	// Column is the qualified name of the column, e.g. users.email, reported by the databases such as sqlite
+	// which do not report the name of the constraint.
+	Column string
+	// Err is the error returned by the database driver.
+	Err error
+}
+
+func (e *ConstraintViolationError) Error() string {
+	if e.Constraint != "" {
+		return fmt.Sprintf("shelf: constraint '%s' violation: %v", e.Constraint, e.Err)
+	}
+
+	if e.Column != "" {
+		return fmt.Sprintf("shelf: constraint violation on '%s': %v", e.Column, e.Err)
+	}
+
+	return fmt.Sprintf("shelf: constraint violation: %v", e.Err)
+}
+
+func (e *ConstraintViolationError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorTranslator converts a driver-specific error into one of the errors defined by shelf.
+// It returns nil if the error is not recognized.
+type ErrorTranslator func(err error) error
+
+var (
+	errorTranslatorsMu sync.RWMutex
+	errorTranslators   = []ErrorTranslator{
+		translatePostgresError,
+		translateMysqlError,
+		translateSqliteError,
+	}
+)
+
+// RegisterErrorTranslator adds a translator which is consulted before the built-in ones.
+func RegisterErrorTranslator(translator ErrorTranslator) {
+	if translator == nil {
+		return
+	}
+
+	errorTranslatorsMu.Lock()
+	defer errorTranslatorsMu.Unlock()
+	errorTranslators = append([]ErrorTranslator{translator}, errorTranslators...)
+}
+
+// TranslateError converts the errors returned by database/sql and the database drivers
+// into the errors defined by shelf. Unrecognized errors are returned as they are.
+func TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	// the errors of the methods called in a transaction are already translated
+	var violationErr *ConstraintViolationError
+
+	if errors.As(err, &violationErr) {
+		return err
+	}
+
+	errorTranslatorsMu.RLock()
+	translators := errorTranslators
+	errorTranslatorsMu.RUnlock()
+
+	for _, translator := range translators {
+		if translatedErr := translator(err); translatedErr != nil {
+			return translatedErr
+		}
+	}
+
+	return err
+}
+
+// CheckOptimisticLock returns the error of the statement updating a versioned entity, or ErrOptimisticLock
+// if the statement affected no rows, since the version of the entity was changed by another transaction.
+func CheckOptimisticLock(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrOptimisticLock
+	}
+
+	return nil
+}
+
+// translatePostgresError handles *pq.Error and *pgconn.PgError, both of which report
+// the SQLSTATE code and the constraint name.
+func translatePostgresError(err error) error {
+	var stateErr interface {
+		SQLState() string
+	}
+
+	if !errors.As(err, &stateErr) {
+		return nil
+	}
+
+	// class 23 contains the integrity constraint violations
+	if !strings.HasPrefix(stateErr.SQLState(), "23") {
+		return nil
+	}
+
+	constraint, _ := getErrorField(stateErr, "ConstraintName")
+
+	if constraint == "" {
+		constraint, _ = getErrorField(stateErr, "Constraint")
+	}
+
+	return &ConstraintViolationError{
+		Constraint: constraint,
+		Err:        err,
+	}
+}
+
+var (
+	mysqlDuplicateKeyPattern = regexp.MustCompile("for key '([^']+)'")
+	mysqlConstraintPattern   = regexp.MustCompile("CONSTRAINT `([^`]+)`")
+	mysqlCheckPattern        = regexp.MustCompile("Check constraint '([^']+)'")
+)
+
+// translateMysqlError handles *mysql.MySQLError whose constraint name is only available in the message.
+func translateMysqlError(err error) error {
+	var driverErr error = err
+
+	for driverErr != nil {
+		value := reflect.Indirect(reflect.ValueOf(driverErr))
+
+		if value.Kind() == reflect.Struct && value.Type().Name() == "MySQLError" {
+			break
+		}
+
+		driverErr = errors.Unwrap(driverErr)
+	}
+
+	if driverErr == nil {
+		return nil
+	}
+
+	number := reflect.Indirect(reflect.ValueOf(driverErr)).FieldByName("Number")
+
+	if !number.IsValid() || number.Kind() != reflect.Uint16 {
+		return nil
+	}
+
+	message, _ := getErrorField(driverErr, "Message")
+
+	switch number.Uint() {
+	// duplicate entry
+	case 1062:
+		return &ConstraintViolationError{Constraint: findSubmatch(mysqlDuplicateKeyPattern, message), Err: err}
+	// foreign key violations
+	case 1451, 1452:
+		return &ConstraintViolationError{Constraint: findSubmatch(mysqlConstraintPattern, message), Err: err}
+	// check constraint violation
+	case 3819:
+		return &ConstraintViolationError{Constraint: findSubmatch(mysqlCheckPattern, message), Err: err}
+	// column cannot be null
+	case 1048:
+		return &ConstraintViolationError{Err: err}
+	}
+
+	return nil
+}
+
+var sqliteConstraintPattern = regexp.MustCompile(`(UNIQUE|NOT NULL|CHECK|FOREIGN KEY|PRIMARY KEY) constraint failed(?::\s*(\S+))?`)
+
+// translateSqliteError handles the sqlite drivers which report the violated column instead of the constraint
+// in the message.
+func translateSqliteError(err error) error {
+	matches := sqliteConstraintPattern.FindStringSubmatch(err.Error())
+
+	if matches == nil {
+		return nil
+	}
+
+	return &ConstraintViolationError{
+		Column: matches[2],
+		Err:    err,
+	}
+}
+
+// getErrorField returns the value of a string field of the error struct.
+func getErrorField(err interface{}, name string) (string, bool) {
+	value := reflect.Indirect(reflect.ValueOf(err))
+
+	if value.Kind() != reflect.Struct {
+		return "", false
+	}
+
+	field := value.FieldByName(name)
+
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return "", false
+	}
+
+	return field.String(), true
+}
+
+func findSubmatch(pattern *regexp.Regexp, text string) string {
+	matches := pattern.FindStringSubmatch(text)
+
+	if len(matches) < 2 {
+		return ""
+	}
+
+	return matches[1]
+}
@@ -0,0 +1,106 @@
+package shelf
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type PgError struct {
+	Code           string
+	ConstraintName string
+}
+
+func (e *PgError) Error() string {
+	return "pg error"
+}
+
+func (e *PgError) SQLState() string {
+	return e.Code
+}
+
+type MySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e *MySQLError) Error() string {
+	return e.Message
+}
+
+func TestTranslateError(t *testing.T) {
+	testCases := []struct {
+		err        error
+		expected   error
+		constraint string
+		column     string
+	}{
+		{err: sql.ErrNoRows, expected: ErrNotFound},
+		{err: fmt.Errorf("query failed: %w", sql.ErrNoRows), expected: ErrNotFound},
+		{err: &PgError{Code: "23505", ConstraintName: "users_email_key"}, constraint: "users_email_key"},
+		{err: fmt.Errorf("exec failed: %w", &PgError{Code: "23503", ConstraintName: "posts_user_id_fkey"}), constraint: "posts_user_id_fkey"},
+		{err: &MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key 'users.email'"}, constraint: "users.email"},
+		{err: &MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails " +
+			"(`db`.`posts`, CONSTRAINT `fk_posts_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`))"}, constraint: "fk_posts_user"},
+		{err: errors.New("UNIQUE constraint failed: users.email"), column: "users.email"},
+	}
+
+	for _, testCase := range testCases {
+		translatedErr := TranslateError(testCase.err)
+
+		if testCase.expected != nil {
+			if translatedErr != testCase.expected {
+				t.Errorf("expected %v, got %v", testCase.expected, translatedErr)
+			}
+
+			continue
+		}
+
+		var constraintErr *ConstraintViolationError
+
+		if !errors.As(translatedErr, &constraintErr) {
+			t.Errorf("expected a constraint violation error for %v, got %v", testCase.err, translatedErr)
+			continue
+		}
+
+		if constraintErr.Constraint != testCase.constraint {
+			t.Errorf("expected constraint '%s', got '%s'", testCase.constraint, constraintErr.Constraint)
+		}
+
+		if constraintErr.Column != testCase.column {
+			t.Errorf("expected column '%s', got '%s'", testCase.column, constraintErr.Column)
+		}
+
+		if !errors.Is(translatedErr, testCase.err) {
+			t.Errorf("the driver error must be wrapped")
+		}
+	}
+
+	unknownErr := &PgError{Code: "42P01"}
+
+	if TranslateError(unknownErr) != unknownErr {
+		t.Errorf("unrecognized errors must be returned as they are")
+	}
+}
+
+func TestCheckOptimisticLock(t *testing.T) {
+	execErr := errors.New("exec failed")
+
+	testCases := []struct {
+		result   sql.Result
+		err      error
+		expected error
+	}{
+		{result: driver.RowsAffected(1)},
+		{result: driver.RowsAffected(0), expected: ErrOptimisticLock},
+		{err: execErr, expected: execErr},
+	}
+
+	for _, testCase := range testCases {
+		if err := CheckOptimisticLock(testCase.result, testCase.err); err != testCase.expected {
+			t.Errorf("expected %v, got %v", testCase.expected, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package shelf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FetchPlan contains the association paths loaded with the queried entities, which override the fetch types
+// of the associations for one query. The nested plans are the paths of the associated entities, e.g. the plan
+// of Posts.Tags contains Posts, whose plan contains Tags. The associations out of the plan are lazy.
+type FetchPlan map[string]FetchPlan
+
+// NewFetchPlan returns the fetch plan of the given association paths, whose fields are separated by dots,
+// e.g. NewFetchPlan("Posts.Tags", "CreditCard").
+func NewFetchPlan(paths ...string) FetchPlan {
+	return FetchPlan{}.With(paths...)
+}
+
+// With returns a copy of the fetch plan containing the given association paths too.
+func (p FetchPlan) With(paths ...string) FetchPlan {
+	plan := p.copy()
+
+	for _, path := range paths {
+		current := plan
+
+		for _, field := range strings.Split(path, ".") {
+			field = strings.TrimSpace(field)
+
+			if field == "" {
+				continue
+			}
+
+			if _, ok := current[field]; !ok {
+				current[field] = FetchPlan{}
+			}
+
+			current = current[field]
+		}
+	}
+
+	return plan
+}
+
+func (p FetchPlan) copy() FetchPlan {
+	plan := make(FetchPlan, len(p))
+
+	for field, nested := range p {
+		plan[field] = nested.copy()
+	}
+
+	return plan
+}
+
+// Validate returns an UnknownAssociationError if the fetch plan contains a field which is not one of the
+// given associations of the entity.
+func (p FetchPlan) Validate(entity string, associations ...string) error {
+	for field := range p {
+		if !containsOption(associations, field) {
+			return &UnknownAssociationError{
+				Entity: entity,
+				Field:  field,
+			}
+		}
+	}
+
+	return nil
+}
+
+// UnknownAssociationError is returned by the queries whose fetch plan contains a field which is not an
+// association of the queried entity.
+type UnknownAssociationError struct {
+	Entity string
+	Field  string
+}
+
+func (e *UnknownAssociationError) Error() string {
+	return fmt.Sprintf("shelf: the fetch plan contains '%s' which is not an association of the entity '%s'", e.Field, e.Entity)
+}
+
+type fetchPlanContextKey struct{}
+
+// WithFetchPlan returns a copy of the context carrying the fetch plan of the given association paths, which
+// overrides the fetch plans of the repository methods called with the context.
+func WithFetchPlan(ctx context.Context, paths ...string) context.Context {
+	return context.WithValue(ctx, fetchPlanContextKey{}, NewFetchPlan(paths...))
+}
+
+// FetchPlanOf returns the fetch plan of the context. If the context has no fetch plan, the first of the given
+// plans which is not nil is returned, or nil if there is no such plan.
+func FetchPlanOf(ctx context.Context, plans ...FetchPlan) FetchPlan {
+	if plan, ok := ctx.Value(fetchPlanContextKey{}).(FetchPlan); ok {
+		return plan
+	}
+
+	for _, plan := range plans {
+		if plan != nil {
+			return plan
+		}
+	}
+
+	return nil
+}
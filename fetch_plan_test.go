@@ -0,0 +1,64 @@
+package shelf
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNewFetchPlan(t *testing.T) {
+	plan := NewFetchPlan("Posts.Tags", "Posts.Author", " CreditCard ")
+	expected := FetchPlan{
+		"Posts": FetchPlan{
+			"Tags":   FetchPlan{},
+			"Author": FetchPlan{},
+		},
+		"CreditCard": FetchPlan{},
+	}
+
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("expected %v, got %v", expected, plan)
+	}
+
+	extended := plan.With("Posts.Comments")
+
+	if _, ok := plan["Posts"]["Comments"]; ok {
+		t.Errorf("the plan should not be modified by With")
+	}
+
+	if _, ok := extended["Posts"]["Comments"]; !ok {
+		t.Errorf("the extended plan should contain Posts.Comments")
+	}
+}
+
+func TestFetchPlanValidate(t *testing.T) {
+	plan := NewFetchPlan("Posts", "Comments")
+
+	if err := plan.Validate("User", "Posts", "Comments", "CreditCard"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	err := plan.Validate("User", "Posts")
+
+	if unknown, ok := err.(*UnknownAssociationError); !ok || unknown.Field != "Comments" || unknown.Entity != "User" {
+		t.Errorf("expected an unknown association error for Comments, got %v", err)
+	}
+}
+
+func TestFetchPlanOf(t *testing.T) {
+	declared := NewFetchPlan("Posts")
+
+	if plan := FetchPlanOf(context.Background()); plan != nil {
+		t.Errorf("expected no plan, got %v", plan)
+	}
+
+	if plan := FetchPlanOf(context.Background(), nil, declared); !reflect.DeepEqual(plan, declared) {
+		t.Errorf("expected %v, got %v", declared, plan)
+	}
+
+	ctx := WithFetchPlan(context.Background(), "CreditCard")
+
+	if plan := FetchPlanOf(ctx, declared); !reflect.DeepEqual(plan, NewFetchPlan("CreditCard")) {
+		t.Errorf("the plan of the context should override the given plans, got %v", plan)
+	}
+}
@@ -1,12 +1,16 @@
 module github.com/procyon-projects/shelf
 
-go 1.13
+go 1.25.0
 
 require (
-	github.com/go-gdbc/gdbc v1.0.2
-	github.com/go-gdbc/gdbc-postgresql v1.1.0
 	github.com/procyon-projects/marker v0.2.2-dev
 	github.com/spf13/cobra v1.2.1
-	gorm.io/driver/postgres v1.1.0
-	gorm.io/gorm v1.21.11
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/mod v0.37.0 // indirect
+	golang.org/x/sync v0.21.0 // indirect
+	golang.org/x/tools v0.47.0 // indirect
 )
@@ -0,0 +1,239 @@
+package shelf
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// IdGenerator generates the ids of the entities before they are inserted, see the UUID, ULID, SNOWFLAKE and
+// CUSTOM strategies of shelf:generated-value. The entity is the name of the entity whose id is generated.
+type IdGenerator interface {
+	NextId(ctx context.Context, entity string) (interface{}, error)
+}
+
+// IdGeneratorFunc is a function used as an IdGenerator.
+type IdGeneratorFunc func(ctx context.Context, entity string) (interface{}, error)
+
+func (f IdGeneratorFunc) NextId(ctx context.Context, entity string) (interface{}, error) {
+	return f(ctx, entity)
+}
+
+var (
+	idGeneratorsMu sync.RWMutex
+	idGenerators   = map[string]IdGenerator{
+		"UUID": IdGeneratorFunc(func(ctx context.Context, entity string) (interface{}, error) {
+			return NewUUID(), nil
+		}),
+		"ULID": IdGeneratorFunc(func(ctx context.Context, entity string) (interface{}, error) {
+			return NewULID(), nil
+		}),
+		"SNOWFLAKE": NewSnowflake(0),
+	}
+)
+
+// RegisterIdGenerator registers the generator with the name given by the Generator argument of the CUSTOM
+// strategy. The generators of the UUID, ULID and SNOWFLAKE strategies are registered with the names of the
+// strategies, and they can be replaced, e.g. by a Snowflake of another node.
+func RegisterIdGenerator(name string, generator IdGenerator) {
+	if generator == nil {
+		return
+	}
+
+	idGeneratorsMu.Lock()
+	defer idGeneratorsMu.Unlock()
+	idGenerators[name] = generator
+}
+
+// GenerateId sets the id pointed by the target to the next id of the generator registered with the name.
+// The id is converted to the type of the target, which can be a named type of the same kind.
+func GenerateId(ctx context.Context, name string, entity string, target interface{}) error {
+	idGeneratorsMu.RLock()
+	generator, ok := idGenerators[name]
+	idGeneratorsMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("shelf: there is no id generator registered with the name '%s'", name)
+	}
+
+	id, err := generator.NextId(ctx, entity)
+
+	if err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(id)
+	destination := reflect.ValueOf(target).Elem()
+
+	if !value.IsValid() || !isConvertibleId(value.Type(), destination.Type()) {
+		return fmt.Errorf("shelf: the id %v generated by '%s' cannot be assigned to the id of the entity '%s' of type %s",
+			id, name, entity, destination.Type())
+	}
+
+	destination.Set(value.Convert(destination.Type()))
+	return nil
+}
+
+// isConvertibleId reports whether the generated id can be converted to the type of the id field. The integers
+// are not converted to strings, which would be the characters of their values.
+func isConvertibleId(from reflect.Type, to reflect.Type) bool {
+	if from.AssignableTo(to) {
+		return true
+	}
+
+	if (from.Kind() == reflect.String) != (to.Kind() == reflect.String) {
+		return false
+	}
+
+	return from.ConvertibleTo(to)
+}
+
+// NewUUID returns a random version 4 UUID in its canonical form, e.g. 7c5e3d0a-2f0b-4c1e-9a64-5b3f0f6f2a1d.
+func NewUUID() string {
+	var bytes [16]byte
+	_, _ = rand.Read(bytes[:])
+
+	bytes[6] = bytes[6]&0x0f | 0x40
+	bytes[8] = bytes[8]&0x3f | 0x80
+
+	encoded := hex.EncodeToString(bytes[:])
+	return encoded[0:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:]
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a ULID of the current time, whose 26 characters are the 48-bit timestamp in milliseconds
+// and 80 random bits encoded in Crockford's base32. The ULIDs of different milliseconds sort by time.
+func NewULID() string {
+	var bytes [16]byte
+	_, _ = rand.Read(bytes[6:])
+
+	milliseconds := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+
+	for index := 5; index >= 0; index-- {
+		bytes[index] = byte(milliseconds)
+		milliseconds >>= 8
+	}
+
+	var high, low uint64
+
+	for index := 0; index < 8; index++ {
+		high = high<<8 | uint64(bytes[index])
+		low = low<<8 | uint64(bytes[index+8])
+	}
+
+	var encoded [26]byte
+
+	for index := len(encoded) - 1; index >= 0; index-- {
+		encoded[index] = crockfordAlphabet[low&31]
+		low = low>>5 | high<<59
+		high >>= 5
+	}
+
+	return string(encoded[:])
+}
+
+// snowflakeEpoch is the time which the timestamps of the Snowflake ids start from.
+var snowflakeEpoch = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake generates the 63-bit ids of the SNOWFLAKE strategy, which are composed of a 41-bit timestamp in
+// milliseconds, the 10-bit node and a 12-bit sequence of the ids generated in the same millisecond. The ids
+// of a node increase, and the ids of the nodes differ.
+type Snowflake struct {
+	mu        sync.Mutex
+	node      int64
+	timestamp int64
+	sequence  int64
+}
+
+// NewSnowflake returns the Snowflake of the node, which is between 0 and 1023.
+func NewSnowflake(node int64) *Snowflake {
+	return &Snowflake{
+		node: node & 1023,
+	}
+}
+
+func (s *Snowflake) NextId(ctx context.Context, entity string) (interface{}, error) {
+	return s.Next(), nil
+}
+
+// Next returns the next id of the node.
+func (s *Snowflake) Next() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timestamp := time.Since(snowflakeEpoch).Milliseconds()
+
+	// the timestamp is not decreased if the clock goes back
+	if timestamp < s.timestamp {
+		timestamp = s.timestamp
+	}
+
+	if timestamp == s.timestamp {
+		s.sequence = (s.sequence + 1) & 4095
+
+		// the sequence of the millisecond is exhausted
+		for s.sequence == 0 && timestamp <= s.timestamp {
+			time.Sleep(100 * time.Microsecond)
+			timestamp = time.Since(snowflakeEpoch).Milliseconds()
+		}
+	} else {
+		s.sequence = 0
+	}
+
+	s.timestamp = timestamp
+	return timestamp<<22 | s.node<<12 | s.sequence
+}
+
+// SequenceAllocator returns the first id of a new block of the ids of a sequence, whose size is the
+// allocation size of the sequence.
+type SequenceAllocator func(ctx context.Context, executor Executor) (int64, error)
+
+// Sequence hands out the ids of the SEQUENCE and TABLE strategies, which are allocated in blocks of the
+// allocation size, so that the database is queried once per block.
+type Sequence struct {
+	mu       sync.Mutex
+	size     int64
+	next     int64
+	end      int64
+	allocate SequenceAllocator
+}
+
+// NewSequence returns a sequence allocating the blocks of the given size by the allocator.
+func NewSequence(allocationSize int, allocate SequenceAllocator) *Sequence {
+	return &Sequence{
+		size:     int64(allocationSize),
+		allocate: allocate,
+	}
+}
+
+// Next returns the next id of the current block, or the first id of a new block allocated by the executor.
+// The blocks are allocated out of the transaction of the context, since the ids of the block would be
+// allocated again if the transaction were rolled back.
+func (s *Sequence) Next(ctx context.Context, executor Executor) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next == s.end {
+		if state := txStateFrom(ctx); state != nil && state.db != nil {
+			executor = state.db
+		}
+
+		first, err := s.allocate(ctx, executor)
+
+		if err != nil {
+			return 0, err
+		}
+
+		s.next = first
+		s.end = first + s.size
+	}
+
+	id := s.next
+	s.next++
+	return id, nil
+}
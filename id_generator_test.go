@@ -0,0 +1,120 @@
+package shelf
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func TestNewUUID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	if uuid := NewUUID(); !pattern.MatchString(uuid) {
+		t.Errorf("%s is not a version 4 UUID", uuid)
+	}
+
+	if NewUUID() == NewUUID() {
+		t.Errorf("the UUIDs should be random")
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	ulid := NewULID()
+
+	if !pattern.MatchString(ulid) {
+		t.Errorf("%s is not a ULID", ulid)
+	}
+
+	if ulid == NewULID() {
+		t.Errorf("the ULIDs should be random")
+	}
+}
+
+func TestSnowflake(t *testing.T) {
+	snowflake := NewSnowflake(3)
+	previous := int64(0)
+
+	for index := 0; index < 10000; index++ {
+		id := snowflake.Next()
+
+		if id <= previous {
+			t.Fatalf("the ids should increase, but %d follows %d", id, previous)
+		}
+
+		if node := id >> 12 & 1023; node != 3 {
+			t.Fatalf("the node of the id should be 3, but got %d", node)
+		}
+
+		previous = id
+	}
+}
+
+type OrderNumber string
+
+func TestGenerateId(t *testing.T) {
+	RegisterIdGenerator("order-number", IdGeneratorFunc(func(ctx context.Context, entity string) (interface{}, error) {
+		return "ORD-" + entity, nil
+	}))
+
+	var number OrderNumber
+	err := GenerateId(context.Background(), "order-number", "Order", &number)
+
+	if err != nil || number != "ORD-Order" {
+		t.Errorf("expected ORD-Order, got %v and %v", number, err)
+	}
+
+	var id int
+	err = GenerateId(context.Background(), "SNOWFLAKE", "Order", &id)
+
+	if err != nil || id == 0 {
+		t.Errorf("expected a snowflake id, got %v and %v", id, err)
+	}
+
+	if err = GenerateId(context.Background(), "order-number", "Order", &id); err == nil {
+		t.Errorf("a string id should not be assigned to an integer")
+	}
+
+	if err = GenerateId(context.Background(), "unknown", "Order", &id); err == nil {
+		t.Errorf("an unknown generator should return an error")
+	}
+}
+
+func TestSequence(t *testing.T) {
+	allocations := 0
+	errAllocate := errors.New("allocation error")
+	sequence := NewSequence(3, func(ctx context.Context, executor Executor) (int64, error) {
+		allocations++
+
+		if allocations == 3 {
+			return 0, errAllocate
+		}
+
+		return int64(allocations * 100), nil
+	})
+
+	ids := make([]int64, 0)
+
+	for index := 0; index < 6; index++ {
+		id, err := sequence.Next(context.Background(), nil)
+
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	expected := []int64{100, 101, 102, 200, 201, 202}
+
+	for index := range expected {
+		if ids[index] != expected[index] {
+			t.Fatalf("expected %v, got %v", expected, ids)
+		}
+	}
+
+	if _, err := sequence.Next(context.Background(), nil); !errors.Is(err, errAllocate) {
+		t.Errorf("expected the allocation error, got %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package shelf
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// NPlusOneMode is what a session does when a lazy association of the entities queried together is
+// loaded one entity at a time, which is usually a loop causing N+1 queries.
+type NPlusOneMode int
+
+const (
+	// NPlusOneIgnore does not detect the N+1 lazy loads.
+	NPlusOneIgnore NPlusOneMode = iota
+	// NPlusOneLog logs the first N+1 lazy load of each association of the entities queried together.
+	NPlusOneLog
+	// NPlusOneFail returns an NPlusOneError from the N+1 lazy loads instead of loading the associations.
+	NPlusOneFail
+)
+
+// SessionOptions are the options of a session, see WithSession.
+type SessionOptions struct {
+	// BatchFetch loads a lazy association of all the entities queried together in one query when it is
+	// accessed for any of them.
+	BatchFetch bool
+	NPlusOne   NPlusOneMode
+}
+
+// LazyLoad loads a lazy association of the given entities.
+type LazyLoad func(ctx context.Context, entities []interface{}) error
+
+// NPlusOneError is returned by the lazy loads detected as N+1 if the session fails on them.
+type NPlusOneError struct {
+	Field string
+	// Loads is the number of the loads of the association for the entities queried together.
+	Loads int
+}
+
+func (e *NPlusOneError) Error() string {
+	return fmt.Sprintf("shelf: the lazy association %s is loaded %d times for the entities queried together, "+
+		"it should be fetched or batch fetched", e.Field, e.Loads)
+}
+
+type sessionContextKey struct{}
+
+type lazyKey struct {
+	entity interface{}
+	field  string
+}
+
+// lazyGroup contains the entities queried together whose lazy association is not loaded yet.
+type lazyGroup struct {
+	entities []interface{}
+	load     LazyLoad
+	loads    int
+}
+
+// lazyLoading is a load of a lazy association in progress, which the other accesses to the association wait for.
+type lazyLoading struct {
+	done chan struct{}
+	err  error
+}
+
+// Session keeps the lazy associations of the entities queried with its context, so that they are loaded
+// on first access with the executor of the context of the access. The associations are accessed through the generated
+// Get methods of the entities, or by LoadLazy, with a context carrying the session.
+type Session struct {
+	mu      sync.Mutex
+	options SessionOptions
+	groups  map[lazyKey]*lazyGroup
+	loading map[lazyKey]*lazyLoading
+}
+
+// WithSession returns a copy of the context carrying a new session, see Session.
+func WithSession(ctx context.Context, options SessionOptions) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, &Session{
+		options: options,
+		groups:  make(map[lazyKey]*lazyGroup),
+		loading: make(map[lazyKey]*lazyLoading),
+	})
+}
+
+// SessionOf returns the session of the context, or nil if the context has no session.
+func SessionOf(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return session
+}
+
+// Register keeps the lazy association of the entities queried together, which is loaded by the function
+// when it is accessed.
+func (s *Session) Register(entities []interface{}, field string, load LazyLoad) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group := &lazyGroup{
+		entities: entities,
+		load:     load,
+	}
+
+	for _, entity := range entities {
+		s.groups[lazyKey{entity: entity, field: field}] = group
+	}
+}
+
+// Load loads the lazy association of the entity unless it is already loaded. The association is left as
+// it is if the entity is not queried in the session. The session is not locked while the association is
+// loaded, the accesses to the association in the meantime wait for the load and return its error.
+func (s *Session) Load(ctx context.Context, entity interface{}, field string) error {
+	key := lazyKey{entity: entity, field: field}
+	s.mu.Lock()
+
+	if loading, ok := s.loading[key]; ok {
+		s.mu.Unlock()
+		<-loading.done
+		return loading.err
+	}
+
+	group, ok := s.groups[key]
+
+	if !ok {
+		s.mu.Unlock()
+		return nil
+	}
+
+	entities := []interface{}{entity}
+
+	if s.options.BatchFetch {
+		entities = make([]interface{}, 0, len(group.entities))
+
+		for _, candidate := range group.entities {
+			if _, ok := s.groups[lazyKey{entity: candidate, field: field}]; ok {
+				entities = append(entities, candidate)
+			}
+		}
+	}
+
+	group.loads++
+
+	// the association of another entity queried together is already loaded alone
+	if group.loads > 1 && s.options.NPlusOne != NPlusOneIgnore {
+		err := &NPlusOneError{
+			Field: field,
+			Loads: group.loads,
+		}
+
+		if s.options.NPlusOne == NPlusOneFail {
+			s.mu.Unlock()
+			return err
+		}
+
+		if group.loads == 2 {
+			log.Print(err)
+		}
+	}
+
+	loading := &lazyLoading{done: make(chan struct{})}
+
+	for _, candidate := range entities {
+		candidateKey := lazyKey{entity: candidate, field: field}
+		delete(s.groups, candidateKey)
+		s.loading[candidateKey] = loading
+	}
+
+	s.mu.Unlock()
+	err := group.load(ctx, entities)
+	s.mu.Lock()
+
+	for _, candidate := range entities {
+		candidateKey := lazyKey{entity: candidate, field: field}
+		delete(s.loading, candidateKey)
+
+		// the association is loaded again on the next access
+		if err != nil {
+			s.groups[candidateKey] = group
+		}
+	}
+
+	loading.err = err
+	close(loading.done)
+	s.mu.Unlock()
+
+	return err
+}
+
+// LoadLazy loads the lazy association of the entity with the session of the context, see Session.Load.
+// It does nothing if the context has no session.
+func LoadLazy(ctx context.Context, entity interface{}, field string) error {
+	session := SessionOf(ctx)
+
+	if session == nil {
+		return nil
+	}
+
+	return session.Load(ctx, entity, field)
+}
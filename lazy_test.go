@@ -0,0 +1,133 @@
+package shelf
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type lazyEntity struct {
+	Id     int
+	Loaded bool
+}
+
+func registerLazyEntities(ctx context.Context, loads *int) []*lazyEntity {
+	entities := []*lazyEntity{{Id: 1}, {Id: 2}, {Id: 3}}
+	targets := make([]interface{}, len(entities))
+
+	for index, entity := range entities {
+		targets[index] = entity
+	}
+
+	SessionOf(ctx).Register(targets, "Children", func(ctx context.Context, entities []interface{}) error {
+		*loads++
+
+		for _, entity := range entities {
+			entity.(*lazyEntity).Loaded = true
+		}
+
+		return nil
+	})
+
+	return entities
+}
+
+func TestLoadLazyBatchFetch(t *testing.T) {
+	ctx := WithSession(context.Background(), SessionOptions{BatchFetch: true})
+	loads := 0
+	entities := registerLazyEntities(ctx, &loads)
+
+	for _, entity := range entities {
+		if err := LoadLazy(ctx, entity, "Children"); err != nil {
+			t.Fatalf("the association should be loaded, but got %v", err)
+		}
+
+		if !entity.Loaded {
+			t.Errorf("the association of the entity %d should be loaded", entity.Id)
+		}
+	}
+
+	if loads != 1 {
+		t.Errorf("the association of the entities should be loaded at once, but it is loaded %d times", loads)
+	}
+}
+
+func TestLoadLazyNPlusOne(t *testing.T) {
+	ctx := WithSession(context.Background(), SessionOptions{NPlusOne: NPlusOneFail})
+	loads := 0
+	entities := registerLazyEntities(ctx, &loads)
+
+	if err := LoadLazy(ctx, entities[0], "Children"); err != nil {
+		t.Fatalf("the first load should not fail, but got %v", err)
+	}
+
+	if err := LoadLazy(ctx, entities[0], "Children"); err != nil || loads != 1 {
+		t.Fatalf("the loaded association should not be loaded again, but got %v and %d loads", err, loads)
+	}
+
+	var nPlusOneErr *NPlusOneError
+
+	if err := LoadLazy(ctx, entities[1], "Children"); !errors.As(err, &nPlusOneErr) || nPlusOneErr.Loads != 2 {
+		t.Errorf("the second load should be detected as N+1, but got %v", err)
+	}
+
+	if entities[1].Loaded {
+		t.Errorf("the association should not be loaded by the failing load")
+	}
+}
+
+func TestLoadLazyWithoutSession(t *testing.T) {
+	if err := LoadLazy(context.Background(), &lazyEntity{}, "Children"); err != nil {
+		t.Errorf("the association should be left as it is without a session, but got %v", err)
+	}
+}
+
+func TestLoadLazyRegisteringWhileLoading(t *testing.T) {
+	ctx := WithSession(context.Background(), SessionOptions{})
+	entity := &lazyEntity{Id: 1}
+	child := &lazyEntity{Id: 2}
+
+	// the loader registers the lazy associations of the loaded entities, which needs the session
+	SessionOf(ctx).Register([]interface{}{entity}, "Children", func(ctx context.Context, entities []interface{}) error {
+		SessionOf(ctx).Register([]interface{}{child}, "Children", func(ctx context.Context, entities []interface{}) error {
+			child.Loaded = true
+			return nil
+		})
+
+		entity.Loaded = true
+		return nil
+	})
+
+	if err := LoadLazy(ctx, entity, "Children"); err != nil || !entity.Loaded {
+		t.Fatalf("the association should be loaded, but got %v", err)
+	}
+
+	if err := LoadLazy(ctx, child, "Children"); err != nil || !child.Loaded {
+		t.Errorf("the association registered by the load should be loaded, but got %v", err)
+	}
+}
+
+func TestLoadLazyFailing(t *testing.T) {
+	ctx := WithSession(context.Background(), SessionOptions{})
+	entity := &lazyEntity{Id: 1}
+	loads := 0
+
+	SessionOf(ctx).Register([]interface{}{entity}, "Children", func(ctx context.Context, entities []interface{}) error {
+		loads++
+
+		if loads == 1 {
+			return errors.New("connection lost")
+		}
+
+		entity.Loaded = true
+		return nil
+	})
+
+	if err := LoadLazy(ctx, entity, "Children"); err == nil {
+		t.Fatalf("the error of the load should be returned")
+	}
+
+	if err := LoadLazy(ctx, entity, "Children"); err != nil || !entity.Loaded {
+		t.Errorf("the association should be loaded again after a failing load, but got %v", err)
+	}
+}
@@ -11,16 +11,22 @@ const (
 	MarkerTable  = "shelf:table"
 
 	MarkerId             = "shelf:id"
+	MarkerIdClass        = "shelf:id-class"
+	MarkerEmbeddedId     = "shelf:embedded-id"
 	MarkerGeneratedValue = "shelf:generated-value"
 
-	MarkerColumn    = "shelf:column"
-	MarkerTransient = "shelf:transient"
-	MarkerLob       = "shelf:lob"
+	MarkerColumn     = "shelf:column"
+	MarkerTransient  = "shelf:transient"
+	MarkerLob        = "shelf:lob"
+	MarkerVersion    = "shelf:version"
+	MarkerSoftDelete = "shelf:soft-delete"
 
 	MarkerEnumerated = "shelf:enumerated"
 
-	MarkerRepository = "shelf:repository"
-	MarkerQuery      = "shelf:query"
+	MarkerRepository    = "shelf:repository"
+	MarkerQuery         = "shelf:query"
+	MarkerTransactional = "shelf:transactional"
+	MarkerBatch         = "shelf:batch"
 
 	MarkerEmbeddable        = "shelf:embeddable"
 	MarkerEmbedded          = "shelf:embedded"
@@ -31,8 +37,24 @@ const (
 	MarkerOneToMany  = "shelf:one-to-many"
 	MarkerManyToOne  = "shelf:many-to-one"
 	MarkerManyToMany = "shelf:many-to-many"
+	MarkerJoinColumn = "shelf:join-column"
+	MarkerJoinTable  = "shelf:join-table"
 
 	MarkerTemporal = "shelf:temporal"
+
+	MarkerCreatedDate      = "shelf:created-date"
+	MarkerLastModifiedDate = "shelf:last-modified-date"
+	MarkerCreatedBy        = "shelf:created-by"
+	MarkerLastModifiedBy   = "shelf:last-modified-by"
+
+	MarkerValue = "shelf:value"
+
+	MarkerFetch     = "shelf:fetch"
+	MarkerFetchSize = "shelf:fetch-size"
+
+	MarkerModifying        = "shelf:modifying"
+	MarkerIncludingDeleted = "shelf:including-deleted"
+	MarkerNamedQuery       = "shelf:named-query"
 )
 
 // +marker="shelf:entity", UseValueSyntax=true, Description="Specifies that the class is an entity."
@@ -50,9 +72,69 @@ type TableMarker struct {
 // +marker="shelf:id", Description="Specifies the primary key of an entity."
 type IdMarker struct{}
 
+// +marker="shelf:id-class", UseValueSyntax=true, Description="Specifies the struct holding the composite key of an \
+//	entity, whose fields are the ones marked as shelf:id."
+type IdClassMarker struct {
+	// +marker:argument="Value", Description="The name of the struct declared in the package of the entity."
+	Name string `marker:"Value,useValueSyntax"`
+}
+
+func (i IdClassMarker) Validate() error {
+	if strings.TrimSpace(i.Name) == "" {
+		return errors.New("'Value' cannot be empty or nil")
+	}
+
+	return nil
+}
+
+// +marker="shelf:embedded-id", Description="Specifies the embeddable field holding the composite key of an entity."
+type EmbeddedIdMarker struct{}
+
 // +marker="shelf:generated-value", Description="Provides for the specification of generation strategies \
 //			for the values of primary keys."
-type GeneratedValueMarker struct{}
+type GeneratedValueMarker struct {
+	// +marker:argument="Strategy", \
+	//	Options={IDENTITY, SEQUENCE, TABLE, UUID, ULID, SNOWFLAKE, CUSTOM}, \
+	//	Optional=true, Description="How the ids are generated, which defaults to IDENTITY."
+	Strategy string `marker:"Strategy,optional"`
+	// +marker:argument="SequenceName", Optional=true, Description="The sequence of the SEQUENCE strategy, or the \
+	//	name of the row of the shelf_sequences table of the TABLE strategy. It defaults to the table of the entity \
+	//	suffixed with _seq."
+	SequenceName string `marker:"SequenceName,optional"`
+	// +marker:argument="AllocationSize", Optional=true, Description="The number of the ids allocated at once by \
+	//	the SEQUENCE and TABLE strategies, which is the increment of the sequence. It defaults to 50."
+	AllocationSize int `marker:"AllocationSize,optional"`
+	// +marker:argument="Generator", Optional=true, Description="The name of the IdGenerator of the CUSTOM strategy."
+	Generator string `marker:"Generator,optional"`
+}
+
+func (g GeneratedValueMarker) Validate() error {
+	strategyOptions := []string{"IDENTITY", "SEQUENCE", "TABLE", "UUID", "ULID", "SNOWFLAKE", "CUSTOM"}
+
+	if !containsOption(strategyOptions, g.Strategy) {
+		return fmt.Errorf("invalid Strategy option. Here is the list of valid options %s", strings.Join(strategyOptions, ", "))
+	}
+
+	strategy := strings.TrimSpace(g.Strategy)
+
+	if g.AllocationSize < 0 {
+		return errors.New("'AllocationSize' cannot be negative")
+	}
+
+	if (strings.TrimSpace(g.SequenceName) != "" || g.AllocationSize != 0) && strategy != "SEQUENCE" && strategy != "TABLE" {
+		return errors.New("'SequenceName' and 'AllocationSize' can only be used with the SEQUENCE and TABLE strategies")
+	}
+
+	if strategy == "CUSTOM" && strings.TrimSpace(g.Generator) == "" {
+		return errors.New("'Generator' must be given for the CUSTOM strategy")
+	}
+
+	if strategy != "CUSTOM" && strings.TrimSpace(g.Generator) != "" {
+		return errors.New("'Generator' can only be used with the CUSTOM strategy")
+	}
+
+	return nil
+}
 
 // +marker="shelf:column", UseValueSyntax=true, Description="Specifies the mapped column for a persistent field."
 type ColumnMarker struct {
@@ -71,6 +153,17 @@ type TransientMarker struct{}
 //	to a database-supported large object type.
 type LobMarker struct{}
 
+// +marker="shelf:version", Description="Specifies the version field of an entity, which is used for the \
+//	optimistic locking. The field must be an integer or a time.Time."
+type VersionMarker struct{}
+
+// +marker="shelf:soft-delete", UseValueSyntax=true, Description="Specifies the field marking an entity as deleted \
+//	instead of deleting its row. The field must be a bool, a nullable time or a status whose deleted value is given."
+type SoftDeleteMarker struct {
+	// +marker:argument="Value", Optional=true, Description="The value of the status field for the deleted entities."
+	Value string `marker:"Value,useValueSyntax,optional"`
+}
+
 // +marker="shelf:enumerated", UseValueSyntax=true, \
 //			Description="Specifies that a persistent field should be persisted as a enumerated type."
 type EnumeratedMarker struct {
@@ -119,17 +212,49 @@ func (r RepositoryMarker) Validate() error {
 
 // +marker="shelf:query", UseValueSyntax=true, Description="Specifies a query."
 type QueryMarker struct {
-	// +marker:argument="Value", Description="The query string."
-	Value string `marker:"Value,useValueSyntax"`
+	// +marker:argument="Value", Optional=true, Description="The query string."
+	Value string `marker:"Value,useValueSyntax,optional"`
+	// +marker:argument="Name", Optional=true, Description="The name of the named query declared on the entity."
+	Name string `marker:"Name,optional"`
 	// +marker:argument="Unique", Optional=true, Description="Whether the query is a native queery."
 	NativeQuery bool `marker:"NativeQuery,optional"`
 }
 
 func (q QueryMarker) Validate() error {
-	if strings.TrimSpace(q.Value) == "" {
+	if strings.TrimSpace(q.Value) == "" && strings.TrimSpace(q.Name) == "" {
+		return errors.New("either 'Value' or 'Name' must be specified")
+	}
+
+	if strings.TrimSpace(q.Value) != "" && strings.TrimSpace(q.Name) != "" {
+		return errors.New("'Value' and 'Name' cannot be used together")
+	}
+
+	if q.NativeQuery && strings.TrimSpace(q.Name) != "" {
+		return errors.New("'NativeQuery' must be specified on the named query")
+	}
+
+	return nil
+}
+
+// +marker="shelf:named-query", UseValueSyntax=true, Description="Specifies a query which can be referred by name from the repositories."
+type NamedQueryMarker struct {
+	// +marker:argument="Value", Description="The name of the query."
+	Name string `marker:"Value,useValueSyntax"`
+	// +marker:argument="Query", Description="The query string."
+	Query string `marker:"Query"`
+	// +marker:argument="NativeQuery", Optional=true, Description="Whether the query is a native query."
+	NativeQuery bool `marker:"NativeQuery,optional"`
+}
+
+func (n NamedQueryMarker) Validate() error {
+	if strings.TrimSpace(n.Name) == "" {
 		return errors.New("'Value' cannot be empty or nil")
 	}
 
+	if strings.TrimSpace(n.Query) == "" {
+		return errors.New("'Query' cannot be empty or nil")
+	}
+
 	return nil
 }
 
@@ -137,11 +262,27 @@ func (q QueryMarker) Validate() error {
 type EmbeddableMarker struct{}
 
 // +marker="shelf:embedded", Description="Specifies that an entity embed a struct"
-type EmbeddedMarker struct{}
+type EmbeddedMarker struct {
+	// +marker:argument="Prefix", Optional=true, Description="The prefix of the columns of the embedded fields, \
+	//	which defaults to the name of the field in snake case followed by an underscore, e.g. address_."
+	Prefix string `marker:"Prefix,optional"`
+	// +marker:argument="NoPrefix", Optional=true, Description="Whether the columns are named after the embedded \
+	//	fields without a prefix."
+	NoPrefix bool `marker:"NoPrefix,optional"`
+}
+
+func (e EmbeddedMarker) Validate() error {
+	if e.NoPrefix && strings.TrimSpace(e.Prefix) != "" {
+		return errors.New("'Prefix' cannot be used with 'NoPrefix'")
+	}
+
+	return nil
+}
 
 // +marker="shelf:attribute-override", Description="Specifies that the column property of embedded type will be overridden."
 type AttributeOverrideMarker struct {
-	// +marker:argument="Value", Description=" The association override mappings that are to be applied to the relationship field."
+	// +marker:argument="Value", Description="The field of the embeddable whose column is overridden, which is \
+	//	the path of the field for the nested embeddables, e.g. Location.Latitude."
 	Name string `marker:"Value,useValueSyntax"`
 	// +marker:argument="ColumnName", Optional=true, Description="The name of the column."
 	ColumnName string `marker:"ColumnName"`
@@ -163,8 +304,12 @@ func (a AttributeOverrideMarker) Validate() error {
 	return nil
 }
 
-// +marker="shelf:maps-id", Description=""
-type MapsIdMarker struct{}
+// +marker="shelf:maps-id", UseValueSyntax=true, Description="Specifies that the join column of a many-to-one or \
+//	one-to-one association is a column of the primary key, whose value is derived from the associated entity."
+type MapsIdMarker struct {
+	// +marker:argument="Value", Optional=true, Description="The field of the composite key mapped by the association."
+	Value string `marker:"Value,useValueSyntax,optional"`
+}
 
 // +marker="shelf:one-to-one", Description="Specifies a single-valued association to another entity."
 type OneToOneMarker struct {
@@ -193,22 +338,7 @@ func (o OneToOneMarker) Validate() error {
 		}
 	}
 
-	if o.Cascade != nil && len(o.Cascade) != 0 {
-		matched := false
-		cascadeOptions := []string{"ALL", "PERSIST", "SAVE_UPDATE", "REMOVE"}
-
-		for _, option := range cascadeOptions {
-			if strings.TrimSpace(o.FetchType) == option {
-				matched = true
-			}
-		}
-
-		if !matched {
-			return fmt.Errorf("invalid FetchType option. Here is the list of valid options %s", strings.Join(cascadeOptions, ", "))
-		}
-	}
-
-	return nil
+	return validateCascade(o.Cascade)
 }
 
 // +marker="shelf:one-to-many", Description="Specifies a many-valued association."
@@ -219,6 +349,8 @@ type OneToManyMarker struct {
 	FetchType string `marker:"FetchType,optional"`
 	// +marker:argument="MappedBy", Description="The field that owns the relationship."
 	MappedBy string `marker:"MappedBy,optional"`
+	// +marker:argument="OrphanRemoval", Description="Whether the targets removed from the association must be deleted."
+	OrphanRemoval bool `marker:"OrphanRemoval,optional"`
 }
 
 func (o OneToManyMarker) Validate() error {
@@ -238,22 +370,7 @@ func (o OneToManyMarker) Validate() error {
 		}
 	}
 
-	if o.Cascade != nil && len(o.Cascade) != 0 {
-		matched := false
-		cascadeOptions := []string{"ALL", "PERSIST", "SAVE_UPDATE", "REMOVE"}
-
-		for _, option := range cascadeOptions {
-			if strings.TrimSpace(o.FetchType) == option {
-				matched = true
-			}
-		}
-
-		if !matched {
-			return fmt.Errorf("invalid FetchType option. Here is the list of valid options %s", strings.Join(cascadeOptions, ", "))
-		}
-	}
-
-	return nil
+	return validateCascade(o.Cascade)
 }
 
 // +marker="shelf:many-to-one", Description="Specifies a single-valued association to another entity class that has many-to-one multiplicity"
@@ -283,22 +400,7 @@ func (o ManyToOneMarker) Validate() error {
 		}
 	}
 
-	if o.Cascade != nil && len(o.Cascade) != 0 {
-		matched := false
-		cascadeOptions := []string{"ALL", "PERSIST", "SAVE_UPDATE", "REMOVE"}
-
-		for _, option := range cascadeOptions {
-			if strings.TrimSpace(o.FetchType) == option {
-				matched = true
-			}
-		}
-
-		if !matched {
-			return fmt.Errorf("invalid FetchType option. Here is the list of valid options %s", strings.Join(cascadeOptions, ", "))
-		}
-	}
-
-	return nil
+	return validateCascade(o.Cascade)
 }
 
 // +marker="shelf:many-to-many", Description="Specifies a many-valued association with many-to-many multiplicity."
@@ -328,22 +430,34 @@ func (o ManyToManyMarker) Validate() error {
 		}
 	}
 
-	if o.Cascade != nil && len(o.Cascade) != 0 {
-		matched := false
-		cascadeOptions := []string{"ALL", "PERSIST", "SAVE_UPDATE", "REMOVE"}
-
-		for _, option := range cascadeOptions {
-			if strings.TrimSpace(o.FetchType) == option {
-				matched = true
-			}
-		}
+	return validateCascade(o.Cascade)
+}
 
-		if !matched {
-			return fmt.Errorf("invalid FetchType option. Here is the list of valid options %s", strings.Join(cascadeOptions, ", "))
-		}
-	}
+// +marker="shelf:join-column", UseValueSyntax=true, Description="Specifies the foreign key column of an association. \
+//	The options of the foreign key are only described by the metamodel, the schema is not created."
+type JoinColumnMarker struct {
+	// +marker:argument="Value", Optional=true, Description="The name of the foreign key column."
+	Name string `marker:"Value,useValueSyntax,optional"`
+	// +marker:argument="ReferencedColumn", Optional=true, \
+	//	Description="The column referenced by the foreign key, which is the id column of the associated entity by default."
+	ReferencedColumn string `marker:"ReferencedColumn,optional"`
+	// +marker:argument="ForeignKeyName", Optional=true, Description="The name of the foreign key."
+	ForeignKeyName string `marker:"ForeignKeyName,optional"`
+	// +marker:argument="Nullable", Optional=true, Description="Whether the foreign key column is nullable."
+	Nullable bool `marker:"Nullable,optional"`
+	// +marker:argument="Unique", Optional=true, Description="Whether the foreign key column is a unique key."
+	Unique bool `marker:"Unique,optional"`
+}
 
-	return nil
+// +marker="shelf:join-table", UseValueSyntax=true, Description="Specifies the join table of a many-to-many association."
+type JoinTableMarker struct {
+	// +marker:argument="Value", Optional=true, Description="The name of the join table."
+	Name string `marker:"Value,useValueSyntax,optional"`
+	// +marker:argument="JoinColumns", Optional=true, Description="The foreign key columns of the join table referring to the owning entity."
+	JoinColumns []string `marker:"JoinColumns,optional"`
+	// +marker:argument="InverseJoinColumns", Optional=true, \
+	//	Description="The foreign key columns of the join table referring to the associated entity."
+	InverseJoinColumns []string `marker:"InverseJoinColumns,optional"`
 }
 
 // +marker="shelf:temporal", UseValueSyntax=true
@@ -370,3 +484,158 @@ func (t TemporalMarker) Validate() error {
 
 	return nil
 }
+
+// +marker="shelf:created-date", Description="Specifies the field which is assigned the time the entity is created. \
+//	The field must be a time.Time."
+type CreatedDateMarker struct{}
+
+// +marker="shelf:last-modified-date", Description="Specifies the field which is assigned the time the entity is \
+//	last saved. The field must be a time.Time."
+type LastModifiedDateMarker struct{}
+
+// +marker="shelf:created-by", Description="Specifies the field which is assigned the auditor creating the entity."
+type CreatedByMarker struct{}
+
+// +marker="shelf:last-modified-by", Description="Specifies the field which is assigned the auditor last saving the entity."
+type LastModifiedByMarker struct{}
+
+// +marker="shelf:value", UseValueSyntax=true, Description="Specifies the SQL expression computing the value of a projection field."
+type ValueMarker struct {
+	// +marker:argument="Value", Description="The SQL expression."
+	Value string `marker:"Value,useValueSyntax"`
+}
+
+func (v ValueMarker) Validate() error {
+	if strings.TrimSpace(v.Value) == "" {
+		return errors.New("'Value' cannot be empty or nil")
+	}
+
+	return nil
+}
+
+// +marker="shelf:fetch", UseValueSyntax=true, Description="Specifies the association paths loaded with the entities returned by the method."
+type FetchMarker struct {
+	// +marker:argument="Value", Description="The association paths separated by commas, whose fields are separated by dots."
+	Value string `marker:"Value,useValueSyntax"`
+}
+
+func (f FetchMarker) Validate() error {
+	if strings.TrimSpace(f.Value) == "" {
+		return errors.New("'Value' cannot be empty or nil")
+	}
+
+	return nil
+}
+
+// Paths returns the association paths of the marker.
+func (f FetchMarker) Paths() []string {
+	paths := make([]string, 0)
+
+	for _, path := range strings.Split(f.Value, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// +marker="shelf:fetch-size", Description="Specifies the number of the rows fetched at once by a cursor."
+type FetchSizeMarker struct {
+	// +marker:argument="Size", Description="The fetch size."
+	Size int `marker:"Size"`
+}
+
+func (f FetchSizeMarker) Validate() error {
+	if f.Size <= 0 {
+		return errors.New("'Size' must be greater than zero")
+	}
+
+	return nil
+}
+
+// +marker="shelf:batch", Description="Specifies how the batch operations of SaveAll and DeleteAll methods are run."
+type BatchMarker struct {
+	// +marker:argument="Size", Optional=true, Description="The maximum number of the rows in a statement."
+	Size int `marker:"Size,optional"`
+	// +marker:argument="Copy", Optional=true, Description="Whether the entities are upserted through COPY, which is only supported by Postgres with the github.com/lib/pq driver."
+	Copy bool `marker:"Copy,optional"`
+}
+
+func (b BatchMarker) Validate() error {
+	if b.Size < 0 {
+		return errors.New("'Size' cannot be negative")
+	}
+
+	return nil
+}
+
+// +marker="shelf:modifying", Description="Specifies that the query of the method updates or deletes the rows."
+type ModifyingMarker struct {
+	// +marker:argument="ClearAutomatically", Optional=true, Description="Whether the cache of the entity is cleared after the query."
+	ClearAutomatically bool `marker:"ClearAutomatically,optional"`
+}
+
+// +marker="shelf:including-deleted", Description="Specifies that the query of the method also finds the soft-deleted \
+//	entities, which are excluded by default."
+type IncludingDeletedMarker struct{}
+
+// +marker="shelf:transactional", Description="Specifies that the method runs in a transaction."
+type TransactionalMarker struct {
+	// +marker:argument="Propagation", \
+	//	Options={REQUIRED, REQUIRES_NEW, SUPPORTS, NEVER, NESTED}, \
+	//	Optional=true, Description="How the method behaves when the context already has a transaction."
+	Propagation string `marker:"Propagation,optional"`
+	// +marker:argument="Isolation", \
+	//	Options={DEFAULT, READ_UNCOMMITTED, READ_COMMITTED, REPEATABLE_READ, SERIALIZABLE}, \
+	//	Optional=true, Description="The isolation level of the new transactions."
+	Isolation string `marker:"Isolation,optional"`
+	// +marker:argument="ReadOnly", Optional=true, Description="Whether the new transactions are read-only."
+	ReadOnly bool `marker:"ReadOnly,optional"`
+}
+
+func (t TransactionalMarker) Validate() error {
+	propagationOptions := []string{"REQUIRED", "REQUIRES_NEW", "SUPPORTS", "NEVER", "NESTED"}
+
+	if !containsOption(propagationOptions, t.Propagation) {
+		return fmt.Errorf("invalid Propagation option. Here is the list of valid options %s", strings.Join(propagationOptions, ", "))
+	}
+
+	isolationOptions := []string{"DEFAULT", "READ_UNCOMMITTED", "READ_COMMITTED", "REPEATABLE_READ", "SERIALIZABLE"}
+
+	if !containsOption(isolationOptions, t.Isolation) {
+		return fmt.Errorf("invalid Isolation option. Here is the list of valid options %s", strings.Join(isolationOptions, ", "))
+	}
+
+	return nil
+}
+
+// validateCascade checks that each of the cascaded operations of an association is one of the cascade options.
+func validateCascade(cascade []string) error {
+	cascadeOptions := []string{"ALL", "PERSIST", "SAVE_UPDATE", "REMOVE"}
+
+	for _, operation := range cascade {
+		if strings.TrimSpace(operation) == "" || !containsOption(cascadeOptions, operation) {
+			return fmt.Errorf("invalid Cascade option '%s'. Here is the list of valid options %s", operation, strings.Join(cascadeOptions, ", "))
+		}
+	}
+
+	return nil
+}
+
+// containsOption reports whether the value is one of the options. An empty value is the default option.
+func containsOption(options []string, value string) bool {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return true
+	}
+
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,46 @@
+package shelf
+
+// AssociationKind is the kind of the relationship between two entities.
+type AssociationKind string
+
+const (
+	OneToOne   AssociationKind = "OneToOne"
+	OneToMany  AssociationKind = "OneToMany"
+	ManyToOne  AssociationKind = "ManyToOne"
+	ManyToMany AssociationKind = "ManyToMany"
+)
+
+// Association describes a relationship of an entity in its generated metamodel.
+type Association struct {
+	// Field is the name of the Go field holding the association.
+	Field string
+	Kind  AssociationKind
+	// Target is the name of the associated entity.
+	Target string
+	// MappedBy is the field of the target entity owning the relationship, or empty if the entity owns it.
+	MappedBy string
+	// JoinColumn is the foreign key of the relationship, which is nil for many-to-many associations.
+	JoinColumn *JoinColumn
+	// JoinTable is the join table of a many-to-many association, which is nil for the other associations.
+	JoinTable *JoinTable
+}
+
+// JoinColumn describes the foreign key column of an association, see the shelf:join-column marker.
+type JoinColumn struct {
+	// Table is the table of the column, which is the table of either the entity or the target entity.
+	Table            string
+	Name             string
+	ReferencedTable  string
+	ReferencedColumn string
+	ForeignKeyName   string
+	Nullable         bool
+	Unique           bool
+}
+
+// JoinTable describes the join table of a many-to-many association, see the shelf:join-table marker.
+type JoinTable struct {
+	Name string
+	// JoinColumns refer to the owning entity of the relationship, and InverseJoinColumns to the other one.
+	JoinColumns        []string
+	InverseJoinColumns []string
+}
@@ -0,0 +1,207 @@
+package shelf
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Direction int
+
+const (
+	Ascending Direction = iota
+	Descending
+)
+
+func (d Direction) String() string {
+	if d == Descending {
+		return "DESC"
+	}
+
+	return "ASC"
+}
+
+// Order is a sort instruction for a single property of an entity.
+type Order struct {
+	Property  string
+	Direction Direction
+}
+
+// Sort is the list of orders applied to a query.
+type Sort struct {
+	Orders []Order
+}
+
+// SortBy returns a sort for the given properties in the given direction.
+func SortBy(direction Direction, properties ...string) Sort {
+	sort := Sort{}
+
+	for _, property := range properties {
+		sort.Orders = append(sort.Orders, Order{
+			Property:  property,
+			Direction: direction,
+		})
+	}
+
+	return sort
+}
+
+// Unsorted returns a sort without any orders.
+func Unsorted() Sort {
+	return Sort{}
+}
+
+// And returns a new sort containing the orders of both sorts.
+func (s Sort) And(other Sort) Sort {
+	orders := make([]Order, 0, len(s.Orders)+len(other.Orders))
+	orders = append(orders, s.Orders...)
+	orders = append(orders, other.Orders...)
+
+	return Sort{
+		Orders: orders,
+	}
+}
+
+func (s Sort) IsSorted() bool {
+	return len(s.Orders) != 0
+}
+
+// OrderBy returns the ORDER BY clause containing the given orders followed by the orders of the sort.
+// The properties map the entity properties which can be sorted to their columns. It returns an
+// error if any of the sort properties does not exist, so that the clause is never built from
+// unchecked input.
+func (s Sort) OrderBy(properties map[string]string, orders ...string) (string, error) {
+	for _, order := range s.Orders {
+		column, ok := properties[order.Property]
+
+		if !ok {
+			return "", fmt.Errorf("shelf: no property '%s' found to sort by", order.Property)
+		}
+
+		orders = append(orders, column+" "+order.Direction.String())
+	}
+
+	if len(orders) == 0 {
+		return "", nil
+	}
+
+	return " ORDER BY " + strings.Join(orders, ", "), nil
+}
+
+// Pageable is the pagination information of a query. The page number is zero-based.
+// A pageable whose size is zero or less is unpaged.
+type Pageable struct {
+	Page int
+	Size int
+	Sort Sort
+}
+
+// PageRequest returns a pageable for the given page and size.
+func PageRequest(page int, size int, sort ...Sort) Pageable {
+	pageable := Pageable{
+		Page: page,
+		Size: size,
+	}
+
+	for _, item := range sort {
+		pageable.Sort = pageable.Sort.And(item)
+	}
+
+	return pageable
+}
+
+// Unpaged returns a pageable which fetches all results.
+func Unpaged() Pageable {
+	return Pageable{}
+}
+
+func (p Pageable) IsPaged() bool {
+	return p.Size > 0
+}
+
+// Offset returns the number of the results to skip.
+func (p Pageable) Offset() int {
+	if !p.IsPaged() || p.Page < 0 {
+		return 0
+	}
+
+	return p.Page * p.Size
+}
+
+// Next returns the pageable for the next page.
+func (p Pageable) Next() Pageable {
+	p.Page++
+	return p
+}
+
+// Page contains the information about a page of the query results and the total number of results.
+type Page struct {
+	Number           int
+	Size             int
+	NumberOfElements int
+	TotalElements    int64
+	TotalPages       int
+	Sort             Sort
+}
+
+// NewPage returns the page information for the results fetched with the given pageable.
+func NewPage(pageable Pageable, numberOfElements int, totalElements int64) Page {
+	page := Page{
+		Number:           pageable.Page,
+		Size:             pageable.Size,
+		NumberOfElements: numberOfElements,
+		TotalElements:    totalElements,
+		TotalPages:       1,
+		Sort:             pageable.Sort,
+	}
+
+	if pageable.IsPaged() {
+		page.TotalPages = int((totalElements + int64(pageable.Size) - 1) / int64(pageable.Size))
+	}
+
+	return page
+}
+
+func (p Page) HasNext() bool {
+	return p.Number+1 < p.TotalPages
+}
+
+func (p Page) HasPrevious() bool {
+	return p.Number > 0
+}
+
+func (p Page) IsFirst() bool {
+	return !p.HasPrevious()
+}
+
+func (p Page) IsLast() bool {
+	return !p.HasNext()
+}
+
+// Slice contains the information about a slice of the query results. Unlike a page,
+// it only knows whether there are more results, so no count query is needed.
+type Slice struct {
+	Number           int
+	Size             int
+	NumberOfElements int
+	Sort             Sort
+	hasNext          bool
+}
+
+// NewSlice returns the slice information for the results fetched with the given pageable.
+func NewSlice(pageable Pageable, numberOfElements int, hasNext bool) Slice {
+	return Slice{
+		Number:           pageable.Page,
+		Size:             pageable.Size,
+		NumberOfElements: numberOfElements,
+		Sort:             pageable.Sort,
+		hasNext:          hasNext,
+	}
+}
+
+func (s Slice) HasNext() bool {
+	return s.hasNext
+}
+
+func (s Slice) HasPrevious() bool {
+	return s.Number > 0
+}
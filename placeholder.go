@@ -0,0 +1,144 @@
+package shelf
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PlaceholderFormat is the format of the positional parameters used by a database.
+type PlaceholderFormat int
+
+const (
+	// Dollar is the format used by Postgres, e.g. $1, $2.
+	Dollar PlaceholderFormat = iota
+	// Question is the format used by MySQL and SQLite, e.g. ?, ?.
+	Question
+)
+
+// Placeholder returns the positional parameter at the given index starting from 1.
+func (f PlaceholderFormat) Placeholder(index int) string {
+	if f == Dollar {
+		return "$" + strconv.Itoa(index)
+	}
+
+	return "?"
+}
+
+// Placeholders returns the comma separated positional parameters starting from the given index.
+func (f PlaceholderFormat) Placeholders(start int, count int) string {
+	placeholders := make([]string, 0, count)
+
+	for index := start; index < start+count; index++ {
+		placeholders = append(placeholders, f.Placeholder(index))
+	}
+
+	return strings.Join(placeholders, ", ")
+}
+
+// Rows returns the comma separated rows of the positional parameters of a multi-row insert,
+// e.g. ($1, $2), ($3, $4) for two rows of two columns.
+func (f PlaceholderFormat) Rows(rows int, columns int) string {
+	values := make([]string, 0, rows)
+
+	for row := 0; row < rows; row++ {
+		values = append(values, "("+f.Placeholders(row*columns+1, columns)+")")
+	}
+
+	return strings.Join(values, ", ")
+}
+
+var byteType = reflect.TypeOf(byte(0))
+
+// Bind replaces the question marks of the query with the positional parameters of the format.
+// The slice arguments are expanded into comma separated parameters, so that they can be used
+// with IN operators. Since neither IN (NULL) nor NOT IN (NULL) matches any row, a predicate
+// of the form 'column IN (?)' with an empty slice is replaced with 1 = 0, and a predicate of
+// the form 'column NOT IN (?)' with 1 = 1. Otherwise, an empty slice is bound as NULL. Byte
+// slices are bound as they are, but the slices of the named byte types, e.g. the enums, are expanded.
+func (f PlaceholderFormat) Bind(query string, args ...interface{}) (string, []interface{}) {
+	var builder strings.Builder
+	boundArgs := make([]interface{}, 0, len(args))
+	argIndex := 0
+	inLiteral := false
+	skipParenthesis := false
+
+	for _, character := range query {
+		if skipParenthesis {
+			skipParenthesis = false
+
+			if character == ')' {
+				continue
+			}
+		}
+
+		if character == '\'' {
+			inLiteral = !inLiteral
+		}
+
+		if character != '?' || inLiteral || argIndex >= len(args) {
+			builder.WriteRune(character)
+			continue
+		}
+
+		arg := args[argIndex]
+		argIndex++
+
+		value := reflect.ValueOf(arg)
+
+		if value.Kind() != reflect.Slice || value.Type().Elem() == byteType {
+			boundArgs = append(boundArgs, arg)
+			builder.WriteString(f.Placeholder(len(boundArgs)))
+			continue
+		}
+
+		if value.Len() == 0 {
+			bound, ok := bindEmptyInPredicate(builder.String())
+
+			if !ok {
+				builder.WriteString("NULL")
+				continue
+			}
+
+			builder.Reset()
+			builder.WriteString(bound)
+			skipParenthesis = true
+			continue
+		}
+
+		for index := 0; index < value.Len(); index++ {
+			if index != 0 {
+				builder.WriteString(", ")
+			}
+
+			boundArgs = append(boundArgs, value.Index(index).Interface())
+			builder.WriteString(f.Placeholder(len(boundArgs)))
+		}
+	}
+
+	return builder.String(), boundArgs
+}
+
+// bindEmptyInPredicate replaces the IN or NOT IN predicate at the end of the query, whose values
+// would be an empty slice, with a predicate matching no rows or all the rows respectively.
+func bindEmptyInPredicate(query string) (string, bool) {
+	predicate := "1 = 0"
+	prefix := strings.TrimSuffix(query, " IN (")
+
+	if prefix == query {
+		return "", false
+	}
+
+	if strings.HasSuffix(prefix, " NOT") {
+		predicate = "1 = 1"
+		prefix = strings.TrimSuffix(prefix, " NOT")
+	}
+
+	start := strings.LastIndexAny(prefix, " (") + 1
+
+	if start == len(prefix) {
+		return "", false
+	}
+
+	return prefix[:start] + predicate, true
+}
@@ -0,0 +1,90 @@
+package shelf
+
+import (
+	"reflect"
+	"testing"
+)
+
+type invoiceKind uint8
+
+func TestPlaceholderFormat_Bind(t *testing.T) {
+	testCases := []struct {
+		format        PlaceholderFormat
+		query         string
+		args          []interface{}
+		expectedQuery string
+		expectedArgs  []interface{}
+	}{
+		{
+			format:        Dollar,
+			query:         "SELECT id FROM users WHERE id IN (?) AND name = ?",
+			args:          []interface{}{[]int{1, 2, 3}, "anna"},
+			expectedQuery: "SELECT id FROM users WHERE id IN ($1, $2, $3) AND name = $4",
+			expectedArgs:  []interface{}{1, 2, 3, "anna"},
+		},
+		{
+			format:        Question,
+			query:         "SELECT id FROM users WHERE id IN (?) AND name = ?",
+			args:          []interface{}{[]string{"a", "b"}, "anna"},
+			expectedQuery: "SELECT id FROM users WHERE id IN (?, ?) AND name = ?",
+			expectedArgs:  []interface{}{"a", "b", "anna"},
+		},
+		{
+			format:        Dollar,
+			query:         "SELECT id FROM users WHERE id IN (?) AND data = ?",
+			args:          []interface{}{[]int{}, []byte("data")},
+			expectedQuery: "SELECT id FROM users WHERE 1 = 0 AND data = $1",
+			expectedArgs:  []interface{}{[]byte("data")},
+		},
+		{
+			format:        Question,
+			query:         "SELECT id FROM users WHERE (id NOT IN (?) OR name = ?)",
+			args:          []interface{}{[]int{}, "anna"},
+			expectedQuery: "SELECT id FROM users WHERE (1 = 1 OR name = ?)",
+			expectedArgs:  []interface{}{"anna"},
+		},
+		{
+			format:        Dollar,
+			query:         "SELECT id FROM users WHERE id = ANY(?)",
+			args:          []interface{}{[]int{}},
+			expectedQuery: "SELECT id FROM users WHERE id = ANY(NULL)",
+			expectedArgs:  []interface{}{},
+		},
+		{
+			format:        Dollar,
+			query:         "SELECT id FROM invoices WHERE kind IN (?)",
+			args:          []interface{}{[]invoiceKind{1, 2}},
+			expectedQuery: "SELECT id FROM invoices WHERE kind IN ($1, $2)",
+			expectedArgs:  []interface{}{invoiceKind(1), invoiceKind(2)},
+		},
+		{
+			format:        Dollar,
+			query:         "SELECT id FROM users WHERE name = '?' AND id = ?",
+			args:          []interface{}{1},
+			expectedQuery: "SELECT id FROM users WHERE name = '?' AND id = $1",
+			expectedArgs:  []interface{}{1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		query, args := testCase.format.Bind(testCase.query, testCase.args...)
+
+		if query != testCase.expectedQuery {
+			t.Errorf("query should be %q, but got %q", testCase.expectedQuery, query)
+		}
+
+		if !reflect.DeepEqual(args, testCase.expectedArgs) {
+			t.Errorf("args should be %v, but got %v", testCase.expectedArgs, args)
+		}
+	}
+}
+
+func TestPlaceholderFormat_Rows(t *testing.T) {
+	if rows := Dollar.Rows(2, 3); rows != "($1, $2, $3), ($4, $5, $6)" {
+		t.Errorf("rows should be %q, but got %q", "($1, $2, $3), ($4, $5, $6)", rows)
+	}
+
+	if rows := Question.Rows(2, 1); rows != "(?), (?)" {
+		t.Errorf("rows should be %q, but got %q", "(?), (?)", rows)
+	}
+}
@@ -0,0 +1,28 @@
+package shelf
+
+// RowScanner scans the columns of a row into the destinations. It is implemented by both *sql.Row
+// and *sql.Rows, so that the scan functions generated for the entities can read either of them.
+type RowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+type prefixedScanner struct {
+	scanner RowScanner
+	prefix  []interface{}
+}
+
+// ScanPrefixed returns a RowScanner scanning the leading columns of the row into the given destinations and
+// the remaining ones into the destinations passed to its Scan method. The generated loaders select the join
+// column before the columns of the associated entity, which are scanned by the scan function of the entity.
+func ScanPrefixed(scanner RowScanner, prefix ...interface{}) RowScanner {
+	return prefixedScanner{
+		scanner: scanner,
+		prefix:  prefix,
+	}
+}
+
+func (s prefixedScanner) Scan(dest ...interface{}) error {
+	values := make([]interface{}, 0, len(s.prefix)+len(dest))
+	values = append(values, s.prefix...)
+	return s.scanner.Scan(append(values, dest...)...)
+}
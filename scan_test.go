@@ -0,0 +1,243 @@
+package shelf
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+type scanTestStatus int
+
+type scanTestAddress struct {
+	City string `column:"address_city"`
+}
+
+type scanTestUser struct {
+	Id       int64          `column:"id"`
+	Email    string         `column:"email"`
+	Status   scanTestStatus `column:"status"`
+	Address  scanTestAddress
+	Nickname *string `column:"nickname"`
+}
+
+// scanTestUserPointers and scanScanTestUser have the shape of the functions generated for the entities.
+func scanTestUserPointers(entity *scanTestUser) []interface{} {
+	return []interface{}{
+		&entity.Id,
+		&entity.Email,
+		(*int)(&entity.Status),
+		&entity.Address.City,
+		&entity.Nickname,
+	}
+}
+
+func scanScanTestUser(scanner RowScanner, entity *scanTestUser) error {
+	return scanner.Scan(scanTestUserPointers(entity)...)
+}
+
+// reflectionMapper scans the rows into the struct fields tagged with the column names, which is
+// how the mappers without generated code work.
+type reflectionMapper struct {
+	indexes [][]int
+}
+
+func newReflectionMapper(typ reflect.Type, columns []string) *reflectionMapper {
+	fieldIndexes := make(map[string][]int)
+	collectFieldIndexes(typ, nil, fieldIndexes)
+
+	mapper := &reflectionMapper{}
+
+	for _, column := range columns {
+		mapper.indexes = append(mapper.indexes, fieldIndexes[column])
+	}
+
+	return mapper
+}
+
+func collectFieldIndexes(typ reflect.Type, parent []int, fieldIndexes map[string][]int) {
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+		fieldIndex := append(append([]int{}, parent...), index)
+
+		if column, ok := field.Tag.Lookup("column"); ok {
+			fieldIndexes[column] = fieldIndex
+		} else if field.Type.Kind() == reflect.Struct {
+			collectFieldIndexes(field.Type, fieldIndex, fieldIndexes)
+		}
+	}
+}
+
+func (m *reflectionMapper) scan(rows *sql.Rows, dest interface{}) error {
+	value := reflect.ValueOf(dest).Elem()
+	pointers := make([]interface{}, len(m.indexes))
+
+	for index, fieldIndex := range m.indexes {
+		pointers[index] = value.FieldByIndex(fieldIndex).Addr().Interface()
+	}
+
+	return rows.Scan(pointers...)
+}
+
+func TestScan(t *testing.T) {
+	db := openScanTestDB(4)
+	defer db.Close()
+
+	generated := scanTestUsers(t, db, func(rows *sql.Rows, entity *scanTestUser) error {
+		return scanScanTestUser(rows, entity)
+	})
+
+	var mapper *reflectionMapper
+	reflected := scanTestUsers(t, db, func(rows *sql.Rows, entity *scanTestUser) error {
+		if mapper == nil {
+			columns, err := rows.Columns()
+
+			if err != nil {
+				return err
+			}
+
+			mapper = newReflectionMapper(reflect.TypeOf(scanTestUser{}), columns)
+		}
+
+		return mapper.scan(rows, entity)
+	})
+
+	if len(generated) != 4 {
+		t.Fatalf("scan should return 4 users, but got %d", len(generated))
+	}
+
+	for index, user := range generated {
+		if user.Id != int64(index) || user.Status != scanTestStatus(index%2) || user.Address.City != "Istanbul" {
+			t.Errorf("user at %d is not scanned correctly: %+v", index, user)
+		}
+
+		if (index%2 == 0) != (user.Nickname == nil) {
+			t.Errorf("nickname of the user at %d should only be nil for the null values", index)
+		}
+
+		if !reflect.DeepEqual(user, reflected[index]) {
+			t.Errorf("user at %d should be scanned the same as the reflection-based mapper, but got %+v and %+v",
+				index, user, reflected[index])
+		}
+	}
+}
+
+type recordingScanner struct {
+	dest []interface{}
+}
+
+func (s *recordingScanner) Scan(dest ...interface{}) error {
+	s.dest = dest
+	return nil
+}
+
+func TestScanPrefixed(t *testing.T) {
+	scanner := &recordingScanner{}
+	var ownerId int64
+	user := &scanTestUser{}
+
+	err := scanScanTestUser(ScanPrefixed(scanner, &ownerId), user)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scanner.dest) != 6 {
+		t.Fatalf("the row should be scanned into 6 destinations, but got %d", len(scanner.dest))
+	}
+
+	if scanner.dest[0] != &ownerId || scanner.dest[1] != &user.Id || scanner.dest[5] != &user.Nickname {
+		t.Errorf("the prefix should be scanned before the fields of the entity, but got %v", scanner.dest)
+	}
+}
+
+func BenchmarkScan_Generated(b *testing.B) {
+	db := openScanTestDB(100)
+	defer db.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		scanTestUsers(b, db, func(rows *sql.Rows, entity *scanTestUser) error {
+			return scanScanTestUser(rows, entity)
+		})
+	}
+}
+
+func BenchmarkScan_Reflection(b *testing.B) {
+	db := openScanTestDB(100)
+	defer db.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var mapper *reflectionMapper
+
+		scanTestUsers(b, db, func(rows *sql.Rows, entity *scanTestUser) error {
+			if mapper == nil {
+				columns, err := rows.Columns()
+
+				if err != nil {
+					return err
+				}
+
+				mapper = newReflectionMapper(reflect.TypeOf(scanTestUser{}), columns)
+			}
+
+			return mapper.scan(rows, entity)
+		})
+	}
+}
+
+func scanTestUsers(tb testing.TB, db *sql.DB, scan func(rows *sql.Rows, entity *scanTestUser) error) []*scanTestUser {
+	rows, err := db.QueryContext(context.Background(), "SELECT id, email, status, address_city, nickname FROM users")
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	defer rows.Close()
+
+	users := make([]*scanTestUser, 0)
+
+	for rows.Next() {
+		user := &scanTestUser{}
+
+		if err = scan(rows, user); err != nil {
+			tb.Fatal(err)
+		}
+
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		tb.Fatal(err)
+	}
+
+	return users
+}
+
+// openScanTestDB opens a fake database which returns the given number of users for any query. The nicknames
+// of the users are null at the even ids.
+func openScanTestDB(count int) *sql.DB {
+	return openTestDB(&testConnector{
+		Query: func(query string, args []driver.Value) (driver.Rows, error) {
+			rows := newTestRows([]string{"id", "email", "status", "address_city", "nickname"})
+
+			for id := 0; id < count; id++ {
+				var nickname driver.Value
+
+				if id%2 == 1 {
+					nickname = "nickname"
+				}
+
+				rows.values = append(rows.values, []driver.Value{int64(id), "user@example.com", int64(id % 2), "Istanbul", nickname})
+			}
+
+			return rows, nil
+		},
+	})
+}
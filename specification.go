@@ -0,0 +1,161 @@
+package shelf
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Specification is a condition on the properties of an entity which is built at runtime. The conditions
+// are usually built with the metamodel generated for the entity, e.g. User_.Email.Equal("john@example.com"),
+// so that the typos in the property names fail to compile.
+type Specification interface {
+	// Predicate returns the condition whose parameters are question marks, and the values bound to them.
+	Predicate() (string, []interface{})
+}
+
+// Predicate is a condition which can be combined with the other specifications.
+type Predicate struct {
+	condition string
+	args      []interface{}
+}
+
+// NewPredicate returns a predicate of the condition whose parameters are question marks.
+func NewPredicate(condition string, args ...interface{}) Predicate {
+	return Predicate{
+		condition: condition,
+		args:      args,
+	}
+}
+
+func (p Predicate) Predicate() (string, []interface{}) {
+	return p.condition, p.args
+}
+
+// And returns a predicate matching both the predicate and the specification.
+func (p Predicate) And(spec Specification) Predicate {
+	return AllOf(p, spec)
+}
+
+// Or returns a predicate matching either the predicate or the specification.
+func (p Predicate) Or(spec Specification) Predicate {
+	return AnyOf(p, spec)
+}
+
+// Not returns a predicate matching the entities which do not match the specification.
+func Not(spec Specification) Predicate {
+	condition, args := predicateOf(spec)
+
+	if condition == "" {
+		return Predicate{}
+	}
+
+	return NewPredicate("NOT ("+condition+")", args...)
+}
+
+// AllOf returns a predicate matching all the specifications. Nil and empty specifications are ignored.
+func AllOf(specs ...Specification) Predicate {
+	return combine(" AND ", specs)
+}
+
+// AnyOf returns a predicate matching any of the specifications. Nil and empty specifications are ignored.
+func AnyOf(specs ...Specification) Predicate {
+	return combine(" OR ", specs)
+}
+
+// Where returns the WHERE clause of the specification with a leading space and its arguments.
+// It returns an empty clause if the specification is nil or empty, which matches all the entities.
+func Where(spec Specification) (string, []interface{}) {
+	condition, args := predicateOf(spec)
+
+	if condition == "" {
+		return "", nil
+	}
+
+	return " WHERE " + condition, args
+}
+
+func combine(operator string, specs []Specification) Predicate {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+
+	for _, spec := range specs {
+		condition, specArgs := predicateOf(spec)
+
+		if condition == "" {
+			continue
+		}
+
+		conditions = append(conditions, condition)
+		args = append(args, specArgs...)
+	}
+
+	if len(conditions) < 2 {
+		return NewPredicate(strings.Join(conditions, ""), args...)
+	}
+
+	return NewPredicate("("+strings.Join(conditions, ")"+operator+"(")+")", args...)
+}
+
+func predicateOf(spec Specification) (string, []interface{}) {
+	if spec == nil {
+		return "", nil
+	}
+
+	return spec.Predicate()
+}
+
+// Attribute is a column of an entity in its metamodel. The generated metamodels wrap it into the
+// attributes whose predicates only take in the values of the field type.
+type Attribute struct {
+	Column string
+}
+
+// Compare returns a predicate comparing the column with the value by the operator such as = or <.
+func (a Attribute) Compare(operator string, value interface{}) Predicate {
+	return NewPredicate(a.Column+" "+operator+" ?", value)
+}
+
+// Between returns a predicate matching the values between the bounds, both inclusive.
+func (a Attribute) Between(from, to interface{}) Predicate {
+	return NewPredicate(a.Column+" BETWEEN ? AND ?", from, to)
+}
+
+// In returns a predicate matching any of the values, which must be a slice. It matches no entities
+// if there are no values.
+func (a Attribute) In(values interface{}) Predicate {
+	if isEmptySlice(values) {
+		return NewPredicate("1 = 0")
+	}
+
+	return NewPredicate(a.Column+" IN (?)", values)
+}
+
+// NotIn returns a predicate matching none of the values, which must be a slice. It matches all
+// the entities if there are no values.
+func (a Attribute) NotIn(values interface{}) Predicate {
+	if isEmptySlice(values) {
+		return NewPredicate("1 = 1")
+	}
+
+	return NewPredicate(a.Column+" NOT IN (?)", values)
+}
+
+// Like returns a predicate matching the pattern.
+func (a Attribute) Like(pattern string) Predicate {
+	return NewPredicate(a.Column+" LIKE ?", pattern)
+}
+
+func (a Attribute) IsNull() Predicate {
+	return NewPredicate(a.Column + " IS NULL")
+}
+
+func (a Attribute) IsNotNull() Predicate {
+	return NewPredicate(a.Column + " IS NOT NULL")
+}
+
+// isEmptySlice reports whether the values are nil or an empty slice, which cannot be bound to
+// an IN operator since Bind turns it into NULL.
+func isEmptySlice(values interface{}) bool {
+	value := reflect.ValueOf(values)
+	return !value.IsValid() || (value.Kind() == reflect.Slice && value.Len() == 0)
+}
@@ -0,0 +1,67 @@
+package shelf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWhere(t *testing.T) {
+	email := Attribute{Column: "email"}
+	age := Attribute{Column: "age"}
+
+	testCases := []struct {
+		spec          Specification
+		expectedWhere string
+		expectedArgs  []interface{}
+	}{
+		{
+			spec:          nil,
+			expectedWhere: "",
+		},
+		{
+			spec:          AllOf(nil, Predicate{}),
+			expectedWhere: "",
+		},
+		{
+			spec:          email.Compare("=", "anna@example.com"),
+			expectedWhere: " WHERE email = ?",
+			expectedArgs:  []interface{}{"anna@example.com"},
+		},
+		{
+			spec:          email.Like("%@example.com").And(age.Between(18, 30)).Or(email.IsNull()),
+			expectedWhere: " WHERE ((email LIKE ?) AND (age BETWEEN ? AND ?)) OR (email IS NULL)",
+			expectedArgs:  []interface{}{"%@example.com", 18, 30},
+		},
+		{
+			spec:          Not(AnyOf(age.In([]int{1, 2}), nil)),
+			expectedWhere: " WHERE NOT (age IN (?))",
+			expectedArgs:  []interface{}{[]int{1, 2}},
+		},
+		{
+			spec:          age.In([]int{}).Or(email.IsNull()),
+			expectedWhere: " WHERE (1 = 0) OR (email IS NULL)",
+		},
+		{
+			spec:          age.NotIn([]int{}).And(email.IsNull()),
+			expectedWhere: " WHERE (1 = 1) AND (email IS NULL)",
+		},
+		{
+			spec:          Not(age.NotIn(nil)),
+			expectedWhere: " WHERE NOT (1 = 1)",
+		},
+	}
+
+	for _, testCase := range testCases {
+		where, args := Where(testCase.spec)
+
+		if where != testCase.expectedWhere {
+			t.Errorf("where clause should be %q, but got %q", testCase.expectedWhere, where)
+		}
+
+		if len(args) != 0 || len(testCase.expectedArgs) != 0 {
+			if !reflect.DeepEqual(args, testCase.expectedArgs) {
+				t.Errorf("args should be %v, but got %v", testCase.expectedArgs, args)
+			}
+		}
+	}
+}
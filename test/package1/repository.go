@@ -9,22 +9,30 @@ import (
 type UserRepository interface {
 	LoadPosts() UserRepository
 
-	Count(ctx context.Context) int
-	ExistsById(ctx context.Context, id int) bool
+	Count(ctx context.Context) (int, error)
+	ExistsById(ctx context.Context, id int) (bool, error)
 
-	Delete(ctx context.Context, user *User)
-	DeleteById(ctx context.Context, id int)
-	DeleteAll(ctx context.Context, user []*User)
-	DeleteAllById(ctx context.Context, ids []int)
+	Delete(ctx context.Context, user *User) error
+	DeleteById(ctx context.Context, id int) error
+	DeleteAll(ctx context.Context, user []*User) error
+	DeleteAllById(ctx context.Context, ids []int) error
 
-	Save(ctx context.Context, user *User)
-	SaveAll(ctx context.Context, user []*User)
+	Save(ctx context.Context, user *User) error
+	SaveAll(ctx context.Context, user []*User) error
 
-	FindById(ctx context.Context, id int) *User
-	FindAll(ctx context.Context) []*User
-	FindAllById(ctx context.Context, ids []int) []*User
+	FindById(ctx context.Context, id int) (*User, error)
+	FindAll(ctx context.Context) ([]*User, error)
+	FindAllById(ctx context.Context, ids []int) ([]*User, error)
 
 	FindByFirstNameAndLastName(ctx context.Context, firstName, lastName string) *User
 	// +shelf:query="FROM User WHERE FirstName = %1 AND LastName = %2"
-	CustomQuery(ctx context.Context, firstName string) *User
+	CustomQuery(ctx context.Context, firstName, lastName string) *User
+	FindNameById(ctx context.Context, id int) (*UserName, error)
+}
+
+type UserName struct {
+	FirstName string
+	LastName  string
+	// +shelf:value="first_name || ' ' || last_name"
+	FullName string
 }
@@ -0,0 +1,285 @@
+package shelf
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+var (
+	// ErrExistingTransaction is returned when a transaction with PropagationNever is started in a transaction.
+	ErrExistingTransaction = errors.New("shelf: transaction is not allowed in an existing transaction")
+	// ErrRollbackOnly is returned when the transaction is rolled back instead of committed, since one of
+	// the methods joining it failed.
+	ErrRollbackOnly = errors.New("shelf: transaction is marked as rollback-only")
+)
+
+// Propagation specifies how a transaction behaves when the context already has a transaction.
+type Propagation int
+
+const (
+	// PropagationRequired joins the transaction of the context, or starts a new one if there is none.
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew always starts a new transaction, which is independent of the transaction of the context.
+	PropagationRequiresNew
+	// PropagationSupports joins the transaction of the context, or runs without a transaction if there is none.
+	PropagationSupports
+	// PropagationNever runs without a transaction, and fails if the context has a transaction.
+	PropagationNever
+	// PropagationNested runs in a savepoint of the transaction of the context, so that only the changes made
+	// in the savepoint are rolled back if it fails. It starts a new transaction if there is none.
+	PropagationNested
+)
+
+// TxOptions holds the options of the transactions started by TxManager. The isolation level and the
+// read-only flag only apply to the new transactions.
+type TxOptions struct {
+	Propagation Propagation
+	Isolation   sql.IsolationLevel
+	ReadOnly    bool
+}
+
+// Executor runs the statements either on the database or in a transaction.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+type txContextKey struct{}
+
+// txState is the transaction stored in the contexts derived by TxManager.
+type txState struct {
+	tx *sql.Tx
+	// db is the database of the transaction, which runs the statements which must not be rolled back.
+	db           *sql.DB
+	rollbackOnly bool
+	savepoints   int
+}
+
+func txStateFrom(ctx context.Context) *txState {
+	state, _ := ctx.Value(txContextKey{}).(*txState)
+
+	if state == nil || state.tx == nil {
+		return nil
+	}
+
+	return state
+}
+
+// ExecutorOf returns the transaction of the context, or the database if the context has no transaction.
+// The generated repositories run all their statements on it, so that they join the transactions started
+// by TxManager.
+func ExecutorOf(ctx context.Context, db *sql.DB) Executor {
+	if state := txStateFrom(ctx); state != nil {
+		return state.tx
+	}
+
+	return db
+}
+
+// TxFrom returns the transaction of the context.
+func TxFrom(ctx context.Context) (*sql.Tx, bool) {
+	if state := txStateFrom(ctx); state != nil {
+		return state.tx, true
+	}
+
+	return nil, false
+}
+
+// TxManager runs functions in the transactions of a database, which are passed to them through the context.
+type TxManager struct {
+	db *sql.DB
+}
+
+// NewTxManager returns a transaction manager of the database.
+func NewTxManager(db *sql.DB) *TxManager {
+	return &TxManager{
+		db: db,
+	}
+}
+
+// WithTx runs the function in a transaction according to the propagation of the options, which defaults to
+// PropagationRequired if the options are nil. The context passed to the function carries the transaction.
+// A new transaction is committed if the function returns nil, and rolled back if it returns an error or panics.
+// A function joining the transaction of the context marks it as rollback-only if it fails.
+func (m *TxManager) WithTx(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) error {
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+
+	state := txStateFrom(ctx)
+
+	switch opts.Propagation {
+	case PropagationRequired:
+		if state != nil {
+			return join(ctx, state, fn)
+		}
+	case PropagationSupports:
+		if state != nil {
+			return join(ctx, state, fn)
+		}
+
+		return fn(ctx)
+	case PropagationNever:
+		if state != nil {
+			return ErrExistingTransaction
+		}
+
+		return fn(ctx)
+	case PropagationNested:
+		if state != nil {
+			return nest(ctx, state, fn)
+		}
+	}
+
+	return m.begin(ctx, opts, fn)
+}
+
+// begin runs the function in a new transaction.
+func (m *TxManager) begin(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) error) (err error) {
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: opts.Isolation,
+		ReadOnly:  opts.ReadOnly,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	state := &txState{
+		tx: tx,
+		db: m.db,
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			_ = tx.Rollback()
+			panic(recovered)
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, txContextKey{}, state))
+
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if state.rollbackOnly {
+		_ = tx.Rollback()
+		return ErrRollbackOnly
+	}
+
+	return tx.Commit()
+}
+
+// join runs the function in the transaction of the context.
+func join(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	completed := false
+
+	defer func() {
+		if !completed {
+			state.rollbackOnly = true
+		}
+	}()
+
+	err := fn(ctx)
+	completed = true
+
+	if err != nil {
+		state.rollbackOnly = true
+	}
+
+	return err
+}
+
+// nest runs the function in a savepoint of the transaction of the context. The functions joining the
+// transaction in the savepoint may mark it as rollback-only, which is undone along with their changes
+// when the savepoint is rolled back.
+func nest(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	state.savepoints++
+	savepoint := "shelf_savepoint_" + strconv.Itoa(state.savepoints)
+	rollbackOnly := state.rollbackOnly
+
+	_, err := state.tx.ExecContext(ctx, "SAVEPOINT "+savepoint)
+
+	if err != nil {
+		return err
+	}
+
+	rollback := func() {
+		_, rollbackErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+
+		if rollbackErr == nil {
+			state.rollbackOnly = rollbackOnly
+		}
+	}
+
+	completed := false
+
+	defer func() {
+		if !completed {
+			rollback()
+		}
+	}()
+
+	err = fn(ctx)
+	completed = true
+
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	_, err = state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	return err
+}
+
+// Tx is a transaction of a repository method, which either starts a new transaction or joins the transaction
+// of the context. Committing a joined transaction has no effect, and rolling it back marks it as rollback-only.
+type Tx struct {
+	*sql.Tx
+	state  *txState
+	joined bool
+}
+
+// BeginTx starts a new transaction unless the context already has one, in which case it is joined.
+func BeginTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*Tx, error) {
+	if state := txStateFrom(ctx); state != nil {
+		return &Tx{
+			Tx:     state.tx,
+			state:  state,
+			joined: true,
+		}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{
+		Tx: tx,
+	}, nil
+}
+
+func (tx *Tx) Commit() error {
+	if tx.joined {
+		return nil
+	}
+
+	return tx.Tx.Commit()
+}
+
+func (tx *Tx) Rollback() error {
+	if tx.joined {
+		tx.state.rollbackOnly = true
+		return nil
+	}
+
+	return tx.Tx.Rollback()
+}
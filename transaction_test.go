@@ -0,0 +1,167 @@
+package shelf
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTxManager_WithTx(t *testing.T) {
+	errTest := errors.New("test error")
+
+	testCases := []struct {
+		Name       string
+		Outer      *TxOptions
+		Inner      *TxOptions
+		InnerErr   error
+		Err        error
+		Statements []string
+	}{
+		{
+			Name:       "required joins the transaction",
+			Inner:      &TxOptions{Propagation: PropagationRequired},
+			Statements: []string{"BEGIN", "INNER", "COMMIT"},
+		},
+		{
+			Name:       "failed required marks the transaction as rollback-only",
+			Inner:      &TxOptions{Propagation: PropagationRequired},
+			InnerErr:   errTest,
+			Err:        ErrRollbackOnly,
+			Statements: []string{"BEGIN", "INNER", "ROLLBACK"},
+		},
+		{
+			Name:       "requires new starts a new transaction",
+			Inner:      &TxOptions{Propagation: PropagationRequiresNew},
+			Statements: []string{"BEGIN", "BEGIN", "INNER", "COMMIT", "COMMIT"},
+		},
+		{
+			Name:       "failed requires new only rolls back the new transaction",
+			Inner:      &TxOptions{Propagation: PropagationRequiresNew},
+			InnerErr:   errTest,
+			Statements: []string{"BEGIN", "BEGIN", "INNER", "ROLLBACK", "COMMIT"},
+		},
+		{
+			Name:       "supports joins the transaction",
+			Inner:      &TxOptions{Propagation: PropagationSupports},
+			Statements: []string{"BEGIN", "INNER", "COMMIT"},
+		},
+		{
+			Name:       "never fails in a transaction",
+			Inner:      &TxOptions{Propagation: PropagationNever},
+			Err:        ErrExistingTransaction,
+			Statements: []string{"BEGIN", "ROLLBACK"},
+		},
+		{
+			Name:       "nested runs in a savepoint",
+			Inner:      &TxOptions{Propagation: PropagationNested},
+			Statements: []string{"BEGIN", "SAVEPOINT shelf_savepoint_1", "INNER", "RELEASE SAVEPOINT shelf_savepoint_1", "COMMIT"},
+		},
+		{
+			Name:     "failed nested only rolls back the savepoint",
+			Inner:    &TxOptions{Propagation: PropagationNested},
+			InnerErr: errTest,
+			Statements: []string{"BEGIN", "SAVEPOINT shelf_savepoint_1", "INNER", "ROLLBACK TO SAVEPOINT shelf_savepoint_1",
+				"COMMIT"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			connector := &testConnector{}
+			db := openTestDB(connector)
+			defer db.Close()
+
+			manager := NewTxManager(db)
+			err := manager.WithTx(context.Background(), testCase.Outer, func(ctx context.Context) error {
+				err := manager.WithTx(ctx, testCase.Inner, func(ctx context.Context) error {
+					_, err := ExecutorOf(ctx, db).ExecContext(ctx, "INNER")
+
+					if err != nil {
+						return err
+					}
+
+					return testCase.InnerErr
+				})
+
+				// the outer function ignores the failure of the inner one
+				if errors.Is(err, testCase.InnerErr) {
+					return nil
+				}
+
+				return err
+			})
+
+			if !errors.Is(err, testCase.Err) {
+				t.Errorf("WithTx should return %v, but got %v", testCase.Err, err)
+			}
+
+			if !reflect.DeepEqual(connector.Queries(), testCase.Statements) {
+				t.Errorf("statements should be %v, but got %v", testCase.Statements, connector.Queries())
+			}
+		})
+	}
+}
+
+func TestTxManager_WithTx_RequiredInNested(t *testing.T) {
+	errTest := errors.New("test error")
+	connector := &testConnector{}
+	db := openTestDB(connector)
+	defer db.Close()
+
+	manager := NewTxManager(db)
+	err := manager.WithTx(context.Background(), nil, func(ctx context.Context) error {
+		err := manager.WithTx(ctx, &TxOptions{Propagation: PropagationNested}, func(ctx context.Context) error {
+			return manager.WithTx(ctx, &TxOptions{Propagation: PropagationRequired}, func(ctx context.Context) error {
+				_, err := ExecutorOf(ctx, db).ExecContext(ctx, "INNER")
+
+				if err != nil {
+					return err
+				}
+
+				return errTest
+			})
+		})
+
+		// the outer function recovers from the failure of the savepoint
+		if errors.Is(err, errTest) {
+			return nil
+		}
+
+		return err
+	})
+
+	if err != nil {
+		t.Errorf("the rolled back savepoint should not mark the transaction as rollback-only, but got %v", err)
+	}
+
+	statements := []string{"BEGIN", "SAVEPOINT shelf_savepoint_1", "INNER", "ROLLBACK TO SAVEPOINT shelf_savepoint_1", "COMMIT"}
+
+	if !reflect.DeepEqual(connector.Queries(), statements) {
+		t.Errorf("statements should be %v, but got %v", statements, connector.Queries())
+	}
+}
+
+func TestBeginTx(t *testing.T) {
+	connector := &testConnector{}
+	db := openTestDB(connector)
+	defer db.Close()
+
+	err := NewTxManager(db).WithTx(context.Background(), nil, func(ctx context.Context) error {
+		tx, err := BeginTx(ctx, db, nil)
+
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if statements := []string{"BEGIN", "COMMIT"}; !reflect.DeepEqual(connector.Queries(), statements) {
+		t.Errorf("joined transaction should not be committed, statements should be %v, but got %v", statements, connector.Queries())
+	}
+}